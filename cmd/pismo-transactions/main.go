@@ -3,24 +3,81 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"os"
 	"time"
+	// the service runs from a scratch image without the zoneinfo database
+	_ "time/tzdata"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/jmoiron/sqlx"
 	"github.com/kelseyhightower/envconfig"
 	_ "github.com/lib/pq"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/deprecation"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/ratelimit"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/server"
 	"github.com/sathishs-dev/pismo-transactions/internal/meta/signal"
+	"github.com/sathishs-dev/pismo-transactions/pkg/auth"
+	"github.com/sathishs-dev/pismo-transactions/pkg/fieldcrypt"
 	"github.com/sathishs-dev/pismo-transactions/pkg/handler"
+	"github.com/sathishs-dev/pismo-transactions/pkg/notify"
+	"github.com/sathishs-dev/pismo-transactions/pkg/openapi"
+	"github.com/sathishs-dev/pismo-transactions/pkg/outbox"
 	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/sathishs-dev/pismo-transactions/pkg/rotation"
+	"github.com/sathishs-dev/pismo-transactions/pkg/snapshot"
+	"github.com/sathishs-dev/pismo-transactions/pkg/webhook"
 )
 
 type env struct {
-	PismoDBDSN      string        `envconfig:"PISMO_DB_DSN" required:"true"`
-	Loglevel        string        `envconfig:"LOG_LEVEL" default:"info"`
-	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"5s"`
+	PismoDBDSN       string        `envconfig:"PISMO_DB_DSN" required:"true"`
+	Loglevel         string        `envconfig:"LOG_LEVEL" default:"info"`
+	ShutdownTimeout  time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"5s"`
+	SnapshotEnabled  bool          `envconfig:"SNAPSHOT_ENABLED" default:"true"`
+	SnapshotDelay    time.Duration `envconfig:"SNAPSHOT_DELAY" default:"5m"`
+	SnapshotTZ       string        `envconfig:"SNAPSHOT_TIMEZONE" default:"UTC"`
+	OutboxSink       string        `envconfig:"OUTBOX_SINK"`
+	OutboxInterval   time.Duration `envconfig:"OUTBOX_INTERVAL" default:"1s"`
+	OutboxBatchSize  int           `envconfig:"OUTBOX_BATCH_SIZE" default:"100"`
+	WebhooksEnabled  bool          `envconfig:"WEBHOOKS_ENABLED" default:"true"`
+	WebhookTimeout   time.Duration `envconfig:"WEBHOOK_TIMEOUT" default:"10s"`
+	WebhookAttempts  int           `envconfig:"WEBHOOK_MAX_ATTEMPTS" default:"10"`
+	WebhookDisable   int           `envconfig:"WEBHOOK_DISABLE_AFTER" default:"20"`
+	WebhookPrivate   bool          `envconfig:"WEBHOOK_ALLOW_PRIVATE" default:"false"`
+	NotifyEnabled    bool          `envconfig:"NOTIFY_ENABLED" default:"true"`
+	Keyring          string        `envconfig:"KEYRING"`
+	KeyringFile      string        `envconfig:"KEYRING_FILE"`
+	RotationPeriod   time.Duration `envconfig:"KEY_ROTATION_INTERVAL" default:"1m"`
+	RotationBatch    int           `envconfig:"KEY_ROTATION_BATCH_SIZE" default:"100"`
+	DeprecatedAt     time.Time     `envconfig:"UNVERSIONED_DEPRECATED_AT" default:"2026-10-19T00:00:00Z"`
+	Sunset           time.Time     `envconfig:"UNVERSIONED_SUNSET" default:"2027-04-19T00:00:00Z"`
+	MaxBatchSize     int           `envconfig:"TRANSACTIONS_BATCH_MAX_SIZE" default:"1000"`
+	EventsHeartbeat  time.Duration `envconfig:"EVENTS_HEARTBEAT_INTERVAL" default:"15s"`
+	EventsPoll       time.Duration `envconfig:"EVENTS_POLL_INTERVAL" default:"5s"`
+	RateLimitEnabled bool          `envconfig:"RATE_LIMIT_ENABLED" default:"true"`
+	RateLimitStore   string        `envconfig:"RATE_LIMIT_STORE" default:"memory"`
+	RateLimitDefault string        `envconfig:"RATE_LIMIT_DEFAULT" default:"50/s:100"`
+	RateLimitRoutes  string        `envconfig:"RATE_LIMIT_ROUTES"`
+	RateLimitClients string        `envconfig:"RATE_LIMIT_CLIENTS"`
+	AuthEnabled      bool          `envconfig:"AUTH_ENABLED" default:"true"`
+	BootstrapAPIKey  string        `envconfig:"BOOTSTRAP_API_KEY"`
+	APIKeyCacheTTL   time.Duration `envconfig:"API_KEY_CACHE_TTL" default:"30s"`
+	APIKeyLookups    string        `envconfig:"API_KEY_LOOKUP_LIMIT" default:"5/s:20"`
+	JWTHMACSecret    string        `envconfig:"JWT_HMAC_SECRET"`
+	JWTPublicKeyFile string        `envconfig:"JWT_PUBLIC_KEY_FILE"`
+	JWTIssuer        string        `envconfig:"JWT_ISSUER"`
+	JWTAudience      string        `envconfig:"JWT_AUDIENCE"`
+	JWTMaxTTL        time.Duration `envconfig:"JWT_MAX_TTL" default:"15m"`
+	TLSCertFile      string        `envconfig:"TLS_CERT_FILE"`
+	TLSKeyFile       string        `envconfig:"TLS_KEY_FILE"`
+	TLSClientCAFile  string        `envconfig:"TLS_CLIENT_CA_FILE"`
+	TLSMinVersion    string        `envconfig:"TLS_MIN_VERSION" default:"1.2"`
+	TLSCipherSuites  string        `envconfig:"TLS_CIPHER_SUITES"`
+	TLSReloadPeriod  time.Duration `envconfig:"TLS_RELOAD_INTERVAL" default:"10s"`
 }
 
 func main() {
@@ -44,11 +101,142 @@ func main() {
 
 	dbx := sqlx.NewDb(db, "postgres")
 
-	repo := repository.NewPismoRepo(dbx)
+	keyring, err := loadKeyring(conf)
+	failOnError(err, "failed to load the keyring")
 
-	h := handler.NewHandler(repo)
+	var repoOpts []repository.Option
+	if keyring != nil {
+		repoOpts = append(repoOpts, repository.WithKeyring(keyring))
+	} else {
+		log.Warn().Msg("no keyring configured, document numbers are stored in plaintext")
+	}
+
+	repo := repository.NewPismoRepo(dbx, repoOpts...)
+
+	if conf.SnapshotEnabled {
+		loc, err := time.LoadLocation(conf.SnapshotTZ)
+		failOnError(err, "invalid snapshot timezone")
+
+		job := snapshot.NewJob(repo, snapshot.WithDelay(conf.SnapshotDelay), snapshot.WithLocation(loc))
+
+		jobCtx, stopJob := context.WithCancel(context.Background())
+		go job.Start(jobCtx)
+		signal.Add(stopJob)
+	}
+
+	if keyring != nil {
+		job := rotation.NewJob(repo, rotation.WithInterval(conf.RotationPeriod), rotation.WithBatchSize(conf.RotationBatch))
+
+		rotationCtx, stopRotation := context.WithCancel(context.Background())
+		go job.Start(rotationCtx)
+		signal.Add(stopRotation)
+	}
+
+	var sinks []outbox.Sink
+	if conf.OutboxSink != "" {
+		sink, err := outbox.NewSink(conf.OutboxSink)
+		failOnError(err, "invalid outbox sink")
+
+		sinks = append(sinks, sink)
+	}
+
+	if conf.WebhooksEnabled {
+		sinks = append(sinks, webhook.NewEnqueuer(repo))
+
+		dispatcherOpts := []webhook.Option{
+			webhook.WithTimeout(conf.WebhookTimeout),
+			webhook.WithMaxAttempts(conf.WebhookAttempts),
+			webhook.WithDisableAfter(conf.WebhookDisable),
+		}
+		if conf.WebhookPrivate {
+			dispatcherOpts = append(dispatcherOpts, webhook.WithPrivateDestinations())
+		}
+
+		dispatcher := webhook.NewDispatcher(repo, dispatcherOpts...)
+
+		dispatcherCtx, stopDispatcher := context.WithCancel(context.Background())
+		go dispatcher.Start(dispatcherCtx)
+		signal.Add(stopDispatcher)
+	}
+
+	if len(sinks) > 0 {
+		relay := outbox.NewRelay(repo, outbox.Fanout(sinks...), outbox.WithInterval(conf.OutboxInterval), outbox.WithBatchSize(conf.OutboxBatchSize))
+
+		relayCtx, stopRelay := context.WithCancel(context.Background())
+		go relay.Start(relayCtx)
+		signal.Add(stopRelay)
+	}
+
+	// the event streams end as soon as the web server starts shutting down, it would wait for them otherwise
+	streamsCtx, stopStreams := context.WithCancel(context.Background())
+
+	handlerOpts := []handler.Option{
+		handler.WithMaxBatchSize(conf.MaxBatchSize),
+		handler.WithHeartbeat(conf.EventsHeartbeat),
+		handler.WithEventsPollInterval(conf.EventsPoll),
+		handler.WithShutdown(streamsCtx.Done()),
+	}
+
+	if conf.NotifyEnabled {
+		listener := notify.NewListener(conf.PismoDBDSN, repo)
+
+		listenerCtx, stopListener := context.WithCancel(context.Background())
+		go listener.Start(listenerCtx)
+		signal.Add(stopListener)
+
+		handlerOpts = append(handlerOpts, handler.WithNotifications(listener))
+	}
+
+	h := handler.NewHandler(repo, handlerOpts...)
+
+	spec, err := openapi.Load()
+	failOnError(err, "failed to load the openapi document")
+
+	var authn *auth.Authenticator
+	if conf.AuthEnabled {
+		failOnError(checkBootstrapKey(ctx, repo, conf.BootstrapAPIKey), "authentication can't be bootstrapped")
+
+		lookupLimit, err := ratelimit.ParseLimit(conf.APIKeyLookups)
+		failOnError(err, "invalid API_KEY_LOOKUP_LIMIT")
+
+		// the keys are looked up before the requests are rate limited, the lookups of each address are limited apart
+		authOpts := []auth.Option{
+			auth.WithBootstrapKey(conf.BootstrapAPIKey),
+			auth.WithCacheTTL(conf.APIKeyCacheTTL),
+			auth.WithLookupLimit(ratelimit.NewMemoryStore(), lookupLimit),
+		}
+
+		tokens, err := loadTokenVerifier(conf)
+		failOnError(err, "failed to load the token verifier")
+		if tokens != nil {
+			// the customers are identified by a pseudonym of their document number keyed by the keyring
+			if keyring == nil {
+				failOnError(errors.New("KEYRING or KEYRING_FILE is required"), "customer tokens can't be accepted")
+			}
+			authOpts = append(authOpts, auth.WithTokenVerifier(tokens), auth.WithKeyring(keyring))
+		}
+
+		authn = auth.New(repo, authOpts...)
+	} else {
+		log.Warn().Msg("authentication is disabled, the api is open to anyone reaching it")
+	}
+
+	// the requests are identified before being limited, the limits of the clients follow their credentials
+	var mws chi.Middlewares
+	if conf.RateLimitEnabled {
+		limiter, err := newRateLimiter(conf)
+		failOnError(err, "invalid rate limits")
+
+		mws = append(mws, limiter)
+	}
 
-	webServer := initWebServer(log.Output(os.Stderr), h)
+	tlsOpts, err := tlsOptions(conf)
+	failOnError(err, "failed to configure tls")
+
+	webServer := initWebServer(log.Output(os.Stderr), h, spec, deprecation.Policy{
+		DeprecatedAt: conf.DeprecatedAt,
+		Sunset:       conf.Sunset,
+	}, authn, mws, append(tlsOpts, server.WithOnShutdown(stopStreams), server.WithErrorLogger(newErrorLogger(log.Logger)))...)
 	go func() {
 		if err := webServer.Start(); err != nil {
 			logOnError(err, "failed to start webserver")
@@ -75,6 +263,109 @@ func loadConfig(cfg *env) error {
 	return envconfig.Process("", cfg)
 }
 
+// loadKeyring loads the keyring from KEYRING_FILE or KEYRING, it returns nil when neither is set
+func loadKeyring(cfg env) (*fieldcrypt.Keyring, error) {
+	switch {
+	case cfg.KeyringFile != "":
+		return fieldcrypt.LoadKeyringFile(cfg.KeyringFile)
+	case cfg.Keyring != "":
+		return fieldcrypt.ParseKeyring([]byte(cfg.Keyring))
+	}
+
+	return nil, nil
+}
+
+// loadTokenVerifier loads the verifier of the customer tokens signed with JWT_HMAC_SECRET or the key of
+// JWT_PUBLIC_KEY_FILE, it returns nil when neither is set
+func loadTokenVerifier(cfg env) (*auth.TokenVerifier, error) {
+	opts := []auth.TokenOption{
+		auth.WithIssuer(cfg.JWTIssuer),
+		auth.WithAudience(cfg.JWTAudience),
+		auth.WithMaxTokenTTL(cfg.JWTMaxTTL),
+	}
+
+	if cfg.JWTHMACSecret != "" {
+		opts = append(opts, auth.WithHMACSecret([]byte(cfg.JWTHMACSecret)))
+	}
+
+	if cfg.JWTPublicKeyFile != "" {
+		data, err := os.ReadFile(cfg.JWTPublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the public key: %w", err)
+		}
+
+		key, err := auth.ParseRSAPublicKey(data)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, auth.WithRSAPublicKey("", key))
+	}
+
+	if cfg.JWTHMACSecret == "" && cfg.JWTPublicKeyFile == "" {
+		return nil, nil
+	}
+
+	return auth.NewTokenVerifier(opts...)
+}
+
+// checkBootstrapKey fails when there is neither a usable api key nor a bootstrap key, every request would be refused
+// and no api key could be created otherwise
+func checkBootstrapKey(ctx context.Context, repo repository.PismoRepo, bootstrapKey string) error {
+	if bootstrapKey != "" {
+		return nil
+	}
+
+	keys, err := repo.ListAPIKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, k := range keys {
+		if k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt)) {
+			return nil
+		}
+	}
+
+	return errors.New("there is no usable api key, BOOTSTRAP_API_KEY is required to create the first ones")
+}
+
+// tlsOptions returns the options serving https with TLS_CERT_FILE and TLS_KEY_FILE, and requiring the client
+// certificates issued by TLS_CLIENT_CA_FILE when it's set, none when there is no certificate
+func tlsOptions(cfg env) ([]server.Option, error) {
+	switch {
+	case cfg.TLSCertFile == "" && cfg.TLSKeyFile == "":
+		if cfg.TLSClientCAFile != "" {
+			return nil, errors.New("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		return nil, nil
+	case cfg.TLSCertFile == "" || cfg.TLSKeyFile == "":
+		return nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE are both required")
+	}
+
+	minVersion, err := server.ParseTLSVersion(cfg.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherSuites, err := server.ParseCipherSuites(cfg.TLSCipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []server.Option{
+		server.WithTLS(cfg.TLSCertFile, cfg.TLSKeyFile),
+		server.WithTLSMinVersion(minVersion),
+		server.WithTLSCipherSuites(cipherSuites...),
+		server.WithTLSReloadInterval(cfg.TLSReloadPeriod),
+	}
+	if cfg.TLSClientCAFile != "" {
+		opts = append(opts, server.WithClientCAs(cfg.TLSClientCAFile))
+	}
+
+	return opts, nil
+}
+
 // logOnError receives error and message, if there is an error it logs
 func logOnError(err error, msg string) {
 	if err != nil {
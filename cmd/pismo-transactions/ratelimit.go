@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/ratelimit"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/signal"
+	"github.com/sathishs-dev/pismo-transactions/pkg/audit"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+)
+
+const (
+	// bucketsPruneInterval is how often the idle buckets of the postgres store are deleted
+	bucketsPruneInterval = 10 * time.Minute
+	// bucketsIdleFor is how long a bucket is kept once it's no longer taken from, longer than any limit takes to
+	// refill its bucket
+	bucketsIdleFor = time.Hour
+	// rateLimitDBConns keeps the limiter connections apart from the ones of the service, a client being limited
+	// must not take them from the others
+	rateLimitDBConns = 2
+)
+
+// newRateLimiter returns the rate limit middleware configured by the env, the postgres store shares the buckets
+// between the replicas and the memory store, the default, keeps them per replica
+func newRateLimiter(conf env) (func(http.Handler) http.Handler, error) {
+	p := ratelimit.Policy{
+		Route:  getCanonicalPath,
+		Client: rateLimitClient,
+	}
+
+	var err error
+	if p.Default, err = ratelimit.ParseLimit(conf.RateLimitDefault); err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_DEFAULT: %w", err)
+	}
+	if p.Routes, err = ratelimit.ParseLimits(conf.RateLimitRoutes); err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_ROUTES: %w", err)
+	}
+	if p.Clients, err = ratelimit.ParseLimits(conf.RateLimitClients); err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_CLIENTS: %w", err)
+	}
+
+	var store ratelimit.Store
+	switch conf.RateLimitStore {
+	case "memory":
+		store = ratelimit.NewMemoryStore()
+	case "postgres":
+		if store, err = newPostgresRateLimitStore(conf.PismoDBDSN); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid RATE_LIMIT_STORE %q, it's one of memory or postgres", conf.RateLimitStore)
+	}
+
+	return ratelimit.Middleware(store, p), nil
+}
+
+// newPostgresRateLimitStore returns the store keeping the buckets in postgres over connections of its own, its idle
+// buckets are deleted in the background until the service shuts down
+func newPostgresRateLimitStore(dsn string) (ratelimit.Store, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the rate limit database connection: %w", err)
+	}
+	db.SetMaxOpenConns(rateLimitDBConns)
+
+	repo := repository.NewPismoRepo(sqlx.NewDb(db, "postgres"))
+
+	pruneCtx, stopPrune := context.WithCancel(context.Background())
+	go pruneRateLimitBuckets(pruneCtx, repo)
+	signal.Add(func() {
+		stopPrune()
+		logOnError(db.Close(), "closing rate limit db connection failed")
+	})
+
+	return ratelimit.StoreFunc(func(ctx context.Context, key string, l ratelimit.Limit) (float64, bool, error) {
+		return repo.TakeRateLimitToken(ctx, key, l.Rate, l.Burst)
+	}), nil
+}
+
+// pruneRateLimitBuckets deletes the idle buckets at every interval until the context is cancelled
+func pruneRateLimitBuckets(ctx context.Context, repo repository.PismoRepo) {
+	ticker := time.NewTicker(bucketsPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := repo.DeleteIdleRateLimitBuckets(ctx, time.Now().Add(-bucketsIdleFor))
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Error().Err(err).Msg("failed to delete the idle rate limit buckets")
+				}
+				continue
+			}
+			log.Debug().Int64("count", count).Msg("idle rate limit buckets deleted")
+		}
+	}
+}
+
+// rateLimitClient identifies the client of the request by its actor once authenticated, eg: api_key:12 for the key
+// with the id 12, and by the address it connects from otherwise, eg: ip:10.0.0.1
+func rateLimitClient(r *http.Request) string {
+	if actor := audit.ActorFromContext(r.Context()); actor.Type != audit.ActorAnonymous {
+		return string(actor.Type) + ":" + actor.ID
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
@@ -1,24 +1,161 @@
 package main
 
 import (
+	"expvar"
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/decode"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/deprecation"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/requestid"
 	"github.com/sathishs-dev/pismo-transactions/internal/meta/server"
+	"github.com/sathishs-dev/pismo-transactions/pkg/auth"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
 	"github.com/sathishs-dev/pismo-transactions/pkg/handler"
+	"github.com/sathishs-dev/pismo-transactions/pkg/openapi"
+)
+
+const (
+	// maxSettlementFileBytes is the size of the settlement files accepted
+	maxSettlementFileBytes = 32 << 20
+	// maxRPCBodyBytes leaves room for a batch of json-rpc calls
+	maxRPCBodyBytes = 4 << 20
+	// maxBatchBodyBytes leaves room for batches of about 100k transactions
+	maxBatchBodyBytes = 16 << 20
 )
 
-func initWebServer(l zerolog.Logger, h handler.Handler) server.HTTPServer {
+func initWebServer(l zerolog.Logger, h handler.Handler, spec *openapi.Document, unversioned deprecation.Policy, authn *auth.Authenticator, mws chi.Middlewares, opts ...server.Option) server.HTTPServer {
+	return server.New(newRouter(l, h, spec, unversioned, authn, mws...), opts...)
+}
+
+// newRouter registers every route of the api, each one has to be described in the openapi document. The api is
+// served under /v1 and /v2, the unversioned routes are deprecated aliases of /v1 announced following the policy.
+// The requests are identified by the authenticator before being logged and every route requires the scopes it's
+// registered with, a nil authenticator leaves the api open. The middlewares given run after the logger, on every
+// request.
+func newRouter(l zerolog.Logger, h handler.Handler, spec *openapi.Document, unversioned deprecation.Policy, authn *auth.Authenticator, mws ...func(http.Handler) http.Handler) *chi.Mux {
 	web := chi.NewMux()
 	web.Use(
+		requestid.Middleware,
+		authn.Middleware,
 		loggerMiddleware(l),
 	)
+	web.Use(mws...)
+	web.NotFound(problem.NotFound)
+	web.MethodNotAllowed(problem.MethodNotAllowed)
+
+	web.Get("/openapi.json", openapi.Handler())
+	// the metrics expose the internals of the process, eg: its command line and memory stats
+	web.With(authn.Require(enums.ScopeMetricsRead)).Get("/debug/vars", expvar.Handler().ServeHTTP)
+
+	web.Route("/v1", func(r chi.Router) {
+		mountV1(r, h, spec, authn)
+	})
+
+	web.Route("/v2", func(r chi.Router) {
+		mountV2(r, h, spec, authn)
+	})
+
+	if unversioned.Successor == nil {
+		unversioned.Successor = func(path string) string {
+			return "/v1" + path
+		}
+	}
+
+	web.Group(func(r chi.Router) {
+		// the deprecation headers are sent on the rejected requests too
+		r.Use(deprecation.Middleware(unversioned))
+		mountV1(r, h, spec, authn)
+	})
+
+	return web
+}
+
+// mountV1 registers the v1 routes
+func mountV1(r chi.Router, h handler.Handler, spec *openapi.Document, authn *auth.Authenticator) {
+	mountRoutes(r, h, spec, authn)
+
+	r.With(owned(authn, enums.ScopeTransactionsWrite, spec)...).Post("/transactions", h.CreateTransaction())
+	r.With(owned(authn, enums.ScopeTransactionsWrite, spec, decode.WithMaxBytes(maxBatchBodyBytes))...).
+		Post("/transactions:batch", h.CreateTransactionBatch())
+}
 
-	web.Route("/accounts", func(r chi.Router) {
-		r.Post("/", h.CreateAccount())
-		r.Get("/{accountId}", h.GetAccount())
+// mountV2 registers the v2 routes, only the ones whose payloads changed have their own handlers
+func mountV2(r chi.Router, h handler.Handler, spec *openapi.Document, authn *auth.Authenticator) {
+	mountRoutes(r, h, spec, authn)
+
+	// amounts are decimal strings
+	r.With(owned(authn, enums.ScopeTransactionsWrite, spec)...).Post("/transactions", h.CreateTransactionV2())
+	r.With(owned(authn, enums.ScopeTransactionsWrite, spec, decode.WithMaxBytes(maxBatchBodyBytes))...).
+		Post("/transactions:batch", h.CreateTransactionBatchV2())
+}
+
+// mountRoutes registers the routes whose payloads are the same in every version
+func mountRoutes(r chi.Router, h handler.Handler, spec *openapi.Document, authn *auth.Authenticator) {
+	r.Route("/accounts", func(r chi.Router) {
+		read, write := scoped(authn, enums.ScopeAccountsRead, spec), scoped(authn, enums.ScopeAccountsWrite, spec)
+		// the customers read their own accounts
+		readOwned := owned(authn, enums.ScopeAccountsRead, spec)
+
+		r.With(write...).Post("/", h.CreateAccount())
+		r.With(read...).Get("/", h.ListAccounts())
+		r.With(readOwned...).Get("/{accountId}", h.GetAccount())
+		r.With(readOwned...).Get("/{accountId}/balance", h.GetAccountBalance())
+		r.With(readOwned...).Get("/{accountId}/events", h.AccountEvents())
+		r.With(readOwned...).Get("/{accountId}/transactions/export", h.ExportTransactions())
+		r.With(write...).Patch("/{accountId}/metadata", h.UpdateAccountMetadata())
+		r.With(write...).Post("/{accountId}/erasure", h.EraseAccount())
+	})
+
+	r.With(scoped(authn, enums.ScopeAccountsRead, spec)...).Get("/changes", h.ListChanges())
+
+	// settlement files are csv streamed to the reconciliation
+	r.With(scoped(authn, enums.ScopeReconciliationsWrite, spec, decode.WithMediaTypes("text/csv"), decode.WithMaxBytes(maxSettlementFileBytes))...).
+		Post("/reconciliations", h.CreateReconciliation())
+	r.With(scoped(authn, enums.ScopeReconciliationsRead, spec)...).Get("/reconciliations/{reconciliationId}", h.GetReconciliation())
+
+	r.Route("/webhooks", func(r chi.Router) {
+		read, write := scoped(authn, enums.ScopeWebhooksRead, spec), scoped(authn, enums.ScopeWebhooksWrite, spec)
+
+		r.With(write...).Post("/", h.CreateWebhook())
+		r.With(read...).Get("/", h.ListWebhooks())
+		r.With(read...).Get("/{webhookId}", h.GetWebhook())
+		r.With(write...).Delete("/{webhookId}", h.DeleteWebhook())
+		r.With(write...).Post("/{webhookId}/enable", h.EnableWebhook())
+		r.With(read...).Get("/{webhookId}/dead-letters", h.ListWebhookDeadLetters())
+		r.With(write...).Post("/{webhookId}/dead-letters/replay", h.ReplayWebhookDeadLetters())
 	})
 
-	web.Post("/transactions", h.CreateTransaction())
+	r.With(scoped(authn, enums.ScopeAuditRead, spec)...).Get("/audit-log", h.ListAuditLog())
+
+	r.Route("/api-keys", func(r chi.Router) {
+		read, write := scoped(authn, enums.ScopeAPIKeysRead, spec), scoped(authn, enums.ScopeAPIKeysWrite, spec)
+
+		r.With(write...).Post("/", h.CreateAPIKey())
+		r.With(read...).Get("/", h.ListAPIKeys())
+		r.With(read...).Get("/{keyId}", h.GetAPIKey())
+		r.With(write...).Post("/{keyId}/revoke", h.RevokeAPIKey())
+	})
+
+	// every call requires the scope of its method, the customers only have the ones of the calls on their accounts
+	r.With(authn.RequireOwned()).With(body(spec, decode.WithMaxBytes(maxRPCBodyBytes))...).Post("/rpc", h.RPC())
+}
+
+// scoped returns the middlewares of a route requiring the scope, the credentials are checked before the body is
+// decoded so the callers without them don't learn anything about the payloads
+func scoped(authn *auth.Authenticator, scope enums.Scope, spec *openapi.Document, opts ...decode.Option) chi.Middlewares {
+	return append(chi.Middlewares{authn.Require(scope)}, body(spec, opts...)...)
+}
+
+// owned is scoped for the routes the customers can use on their own accounts
+func owned(authn *auth.Authenticator, scope enums.Scope, spec *openapi.Document, opts ...decode.Option) chi.Middlewares {
+	return append(chi.Middlewares{authn.RequireOwned(scope)}, body(spec, opts...)...)
+}
 
-	return server.New(web)
+// body returns the middlewares of a route, its body is decoded following the options, json up to
+// decode.DefaultMaxBytes by default, before being validated against the document
+func body(spec *openapi.Document, opts ...decode.Option) chi.Middlewares {
+	return chi.Middlewares{decode.Middleware(opts...), spec.Middleware}
 }
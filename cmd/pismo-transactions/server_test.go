@@ -0,0 +1,466 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/decode"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/deprecation"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/ratelimit"
+	"github.com/sathishs-dev/pismo-transactions/pkg/auth"
+	"github.com/sathishs-dev/pismo-transactions/pkg/fieldcrypt"
+	"github.com/sathishs-dev/pismo-transactions/pkg/handler"
+	"github.com/sathishs-dev/pismo-transactions/pkg/mocks"
+	"github.com/sathishs-dev/pismo-transactions/pkg/openapi"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRoutesDescribed keeps the openapi document and the router in sync, every route has to be described and
+// every described operation has to be routed
+func TestRoutesDescribed(t *testing.T) {
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+
+	router := newRouter(zerolog.Nop(), handler.NewHandler(new(mocks.PismoRepo)), spec, deprecation.Policy{}, nil)
+
+	routed := map[string]bool{}
+	err = chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if route != "/" {
+			route = strings.TrimSuffix(route, "/")
+		}
+
+		routed[method+" "+route] = true
+		if spec.Operation(method, route) == nil {
+			t.Errorf("%s %s is not described in the openapi document", method, route)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	spec.Operations(func(method, pattern string) {
+		if !routed[method+" "+pattern] {
+			t.Errorf("%s %s is described in the openapi document but not routed", method, pattern)
+		}
+	})
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+
+	router := newRouter(zerolog.Nop(), handler.NewHandler(new(mocks.PismoRepo)), spec, deprecation.Policy{
+		DeprecatedAt: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		Sunset:       time.Date(2027, 4, 19, 0, 0, 0, 0, time.UTC),
+	}, nil)
+
+	tcs := []struct {
+		name               string
+		target             string
+		body               string
+		expectedStatusCode int
+		expectedSuccessor  string
+	}{
+		{
+			name:               "Test V1 Route",
+			target:             "/v1/transactions",
+			body:               `{"account_id": 1, "operation_type_id": 4, "amount": "10"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Test V2 Route",
+			target:             "/v2/transactions",
+			body:               `{"account_id": 1, "operation_type_id": 4, "amount": 10}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Test Deprecated Alias",
+			target:             "/transactions",
+			body:               `{"account_id": 1, "operation_type_id": 4, "amount": "10"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedSuccessor:  `</v1/transactions>; rel="successor-version"`,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tc.target, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.expectedStatusCode, rec.Code)
+			require.Equal(t, tc.expectedSuccessor, rec.Header().Get("Link"))
+			if tc.expectedSuccessor != "" {
+				require.Equal(t, "@1792368000", rec.Header().Get("Deprecation"))
+				require.Equal(t, "Mon, 19 Apr 2027 00:00:00 GMT", rec.Header().Get("Sunset"))
+			} else {
+				require.Empty(t, rec.Header().Get("Deprecation"))
+			}
+		})
+	}
+
+	require.NotNil(t, deprecation.Requests.Get("POST /transactions"))
+	require.Nil(t, deprecation.Requests.Get("POST /v1/transactions"))
+}
+
+func TestRouterProblems(t *testing.T) {
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+
+	router := newRouter(zerolog.Nop(), handler.NewHandler(new(mocks.PismoRepo)), spec, deprecation.Policy{}, nil)
+
+	tcs := []struct {
+		name               string
+		method             string
+		target             string
+		contentType        string
+		body               string
+		expectedStatusCode int
+		expectedCode       problem.Code
+	}{
+		{
+			name:               "Test Unknown Route",
+			method:             http.MethodGet,
+			target:             "/v1/cards",
+			expectedStatusCode: http.StatusNotFound,
+			expectedCode:       problem.CodeRouteNotFound,
+		},
+		{
+			name:               "Test Method Not Allowed",
+			method:             http.MethodPut,
+			target:             "/v1/accounts/1",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedCode:       problem.CodeMethodNotAllowed,
+		},
+		{
+			name:               "Test Unsupported Media Type",
+			method:             http.MethodPost,
+			target:             "/v1/accounts",
+			contentType:        "text/plain",
+			body:               `{"document_number": "1234567890"}`,
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+			expectedCode:       problem.CodeUnsupportedMediaType,
+		},
+		{
+			name:               "Test Settlement File As Json",
+			method:             http.MethodPost,
+			target:             "/v1/reconciliations",
+			contentType:        "application/json",
+			body:               `{}`,
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+			expectedCode:       problem.CodeUnsupportedMediaType,
+		},
+		{
+			name:               "Test Body Too Large",
+			method:             http.MethodPost,
+			target:             "/v1/accounts",
+			contentType:        "application/json",
+			body:               `{"document_number": "` + strings.Repeat("1", int(decode.DefaultMaxBytes)) + `"}`,
+			expectedStatusCode: http.StatusRequestEntityTooLarge,
+			expectedCode:       problem.CodeBodyTooLarge,
+		},
+		{
+			name:               "Test Validation Failed",
+			method:             http.MethodGet,
+			target:             "/v1/accounts/abc",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       problem.CodeValidationFailed,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			var body io.Reader
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			}
+			req := httptest.NewRequest(tc.method, tc.target, body)
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.expectedStatusCode, rec.Code)
+			require.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+
+			var p problem.Problem
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+			require.Equal(t, tc.expectedCode, p.Code)
+			require.Equal(t, tc.target, p.Instance)
+			require.NotEmpty(t, p.RequestID)
+		})
+	}
+}
+
+func TestRouterRateLimit(t *testing.T) {
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+
+	limiter := ratelimit.Middleware(ratelimit.NewMemoryStore(), ratelimit.Policy{
+		Default: ratelimit.Limit{Rate: 1, Burst: 5},
+		Routes:  map[string]ratelimit.Limit{"GET /v1/accounts/{accountId}/events": {Rate: 1, Burst: 1}},
+		Route:   getCanonicalPath,
+		Client:  rateLimitClient,
+	})
+	router := newRouter(zerolog.Nop(), handler.NewHandler(new(mocks.PismoRepo)), spec, deprecation.Policy{}, nil, limiter)
+
+	// the events of every account are limited by the route pattern, invalid ids don't reach the repository
+	for i, expected := range []int{http.StatusBadRequest, http.StatusTooManyRequests} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/v1/accounts/x"+strconv.Itoa(i)+"/events", nil)
+		router.ServeHTTP(rec, req)
+
+		require.Equal(t, expected, rec.Code)
+		require.Equal(t, "1", rec.Header().Get("RateLimit-Limit"))
+	}
+
+	// the other routes share the default bucket of the client
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/cards", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, "5", rec.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "4", rec.Header().Get("RateLimit-Remaining"))
+}
+
+func TestRouterAuth(t *testing.T) {
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+
+	repo := new(mocks.PismoRepo)
+	repo.On("GetAPIKeyByHash", mock.Anything, auth.HashKey("pk_reader")).
+		Return(&repository.APIKey{KeyID: 7, Scopes: pq.StringArray{"accounts:read"}}, nil)
+	repo.On("GetAPIKeyByHash", mock.Anything, mock.Anything).Return(nil, nil)
+	repo.On("ListWebhooks", mock.Anything).Return(nil, nil)
+	// the customer's account is found, the others are left out by the repository
+	repo.On("GetAccountByAccountID", mock.MatchedBy(func(ctx context.Context) bool {
+		owner, _ := repository.OwnerFromContext(ctx)
+		return owner == "12345678900"
+	}), 1).Return(&repository.Account{AccountID: 1, DocumentNo: "12345678900"}, nil)
+	repo.On("GetAccountByAccountID", mock.Anything, mock.Anything).Return(nil, nil)
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	tokens, err := auth.NewTokenVerifier(auth.WithHMACSecret(secret))
+	require.NoError(t, err)
+	token := signHS256(t, secret, fmt.Sprintf(`{"sub":"12345678900","exp":%d}`, time.Now().Add(time.Minute).Unix()))
+
+	var clients []string
+	limiter := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			clients = append(clients, rateLimitClient(r))
+			next.ServeHTTP(w, r)
+		})
+	}
+	keyring, err := fieldcrypt.ParseKeyring([]byte(`{"primary":"k1","keys":{"k1":"` + base64.StdEncoding.EncodeToString(secret) + `"},"blind_index_key":"` + base64.StdEncoding.EncodeToString(secret) + `"}`))
+	require.NoError(t, err)
+	customer := "user:customer_" + hex.EncodeToString(keyring.Pseudonym("12345678900")[:8])
+
+	authn := auth.New(repo, auth.WithBootstrapKey("pk_bootstrap"), auth.WithTokenVerifier(tokens), auth.WithKeyring(keyring))
+	router := newRouter(zerolog.Nop(), handler.NewHandler(repo), spec, deprecation.Policy{}, authn, limiter)
+
+	tcs := []struct {
+		name               string
+		method             string
+		target             string
+		key                string
+		token              string
+		body               string
+		expectedStatusCode int
+		expectedCode       problem.Code
+		expectedClient     string
+	}{
+		{
+			name:               "Test Missing Key",
+			method:             http.MethodGet,
+			target:             "/v1/accounts/x",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedCode:       problem.CodeUnauthorized,
+			expectedClient:     "ip:192.0.2.1",
+		},
+		{
+			name:               "Test Unknown Key",
+			method:             http.MethodGet,
+			target:             "/v1/accounts/x",
+			key:                "pk_unknown",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedCode:       problem.CodeUnauthorized,
+			expectedClient:     "ip:192.0.2.1",
+		},
+		{
+			name:               "Test Granted Scope",
+			method:             http.MethodGet,
+			target:             "/v1/accounts/x",
+			key:                "pk_reader",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       problem.CodeValidationFailed,
+			expectedClient:     "api_key:7",
+		},
+		{
+			name:               "Test Missing Scope Before Body",
+			method:             http.MethodPost,
+			target:             "/transactions",
+			key:                "pk_reader",
+			body:               `not json`,
+			expectedStatusCode: http.StatusForbidden,
+			expectedCode:       problem.CodeInsufficientScope,
+			expectedClient:     "api_key:7",
+		},
+		{
+			name:               "Test Metrics Without Key",
+			method:             http.MethodGet,
+			target:             "/debug/vars",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedCode:       problem.CodeUnauthorized,
+			expectedClient:     "ip:192.0.2.1",
+		},
+		{
+			name:               "Test Metrics Missing Scope",
+			method:             http.MethodGet,
+			target:             "/debug/vars",
+			key:                "pk_reader",
+			expectedStatusCode: http.StatusForbidden,
+			expectedCode:       problem.CodeInsufficientScope,
+			expectedClient:     "api_key:7",
+		},
+		{
+			name:               "Test Metrics",
+			method:             http.MethodGet,
+			target:             "/debug/vars",
+			key:                "pk_bootstrap",
+			expectedStatusCode: http.StatusOK,
+			expectedClient:     "api_key:bootstrap",
+		},
+		{
+			name:               "Test Bootstrap Key",
+			method:             http.MethodGet,
+			target:             "/v2/webhooks",
+			key:                "pk_bootstrap",
+			expectedStatusCode: http.StatusOK,
+			expectedClient:     "api_key:bootstrap",
+		},
+		{
+			name:               "Test Customer Own Account",
+			method:             http.MethodGet,
+			target:             "/v1/accounts/1",
+			token:              token,
+			expectedStatusCode: http.StatusOK,
+			expectedClient:     customer,
+		},
+		{
+			name:               "Test Customer Other Account",
+			method:             http.MethodGet,
+			target:             "/v1/accounts/2",
+			token:              token,
+			expectedStatusCode: http.StatusNotFound,
+			expectedCode:       problem.CodeAccountNotFound,
+			expectedClient:     customer,
+		},
+		{
+			name:               "Test Customer Staff Route",
+			method:             http.MethodGet,
+			target:             "/v1/accounts",
+			token:              token,
+			expectedStatusCode: http.StatusForbidden,
+			expectedCode:       problem.CodeInsufficientScope,
+			expectedClient:     customer,
+		},
+		{
+			name:               "Test Customer Missing Scope",
+			method:             http.MethodPatch,
+			target:             "/v1/accounts/1/metadata",
+			token:              token,
+			body:               `{}`,
+			expectedStatusCode: http.StatusForbidden,
+			expectedCode:       problem.CodeInsufficientScope,
+			expectedClient:     customer,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			clients = nil
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.key != "" {
+				req.Header.Set(auth.Header, tc.key)
+			}
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.expectedStatusCode, rec.Code)
+			require.Equal(t, []string{tc.expectedClient}, clients)
+			if tc.expectedCode != "" {
+				var p problem.Problem
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+				require.Equal(t, tc.expectedCode, p.Code)
+			}
+		})
+	}
+
+	// the document stays public
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+// signHS256 returns the HS256 token of the claims
+func signHS256(t *testing.T, secret []byte, claims string) string {
+	t.Helper()
+
+	input := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(claims))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(input))
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestErrorLogger(t *testing.T) {
+	var buf strings.Builder
+	newErrorLogger(zerolog.New(&buf)).Printf("failed to reload the tls certificate: %s", "no such file")
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal([]byte(buf.String()), &line))
+	require.Equal(t, "error", line["level"])
+	require.Equal(t, "failed to reload the tls certificate: no such file", line["message"])
+}
+
+func TestCheckBootstrapKey(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+	revoked := time.Now().Add(-time.Minute)
+
+	repo := mocks.NewPismoRepo(t)
+	repo.On("ListAPIKeys", mock.Anything).Return([]repository.APIKey{
+		{KeyID: 1, ExpiresAt: &expired},
+		{KeyID: 2, RevokedAt: &revoked},
+	}, nil).Once()
+	require.Error(t, checkBootstrapKey(context.Background(), repo, ""))
+
+	// the first keys can be created with the bootstrap key
+	require.NoError(t, checkBootstrapKey(context.Background(), repo, "pk_bootstrap"))
+
+	repo.On("ListAPIKeys", mock.Anything).Return([]repository.APIKey{{KeyID: 3}}, nil).Once()
+	require.NoError(t, checkBootstrapKey(context.Background(), repo, ""))
+}
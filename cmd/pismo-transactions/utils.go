@@ -9,6 +9,9 @@ import (
 	"github.com/go-chi/chi/v5"
 
 	"github.com/rs/zerolog"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/requestid"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/server"
+	"github.com/sathishs-dev/pismo-transactions/pkg/audit"
 )
 
 // getLoglevel receives loglevel string and converts its to zerolog.Level
@@ -34,6 +37,23 @@ func getLoglevel(l string) zerolog.Level {
 	}
 }
 
+// errorLogWriter writes the lines of a standard logger as zerolog errors
+type errorLogWriter struct {
+	logger zerolog.Logger
+}
+
+// Write logs the line, without the newline the standard logger ends it with
+func (w errorLogWriter) Write(p []byte) (int, error) {
+	w.logger.Error().Msg(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+// newErrorLogger returns the error logger of the web server, its failures and the ones reloading the tls certificate
+// are logged as zerolog errors
+func newErrorLogger(logger zerolog.Logger) *log.Logger {
+	return log.New(errorLogWriter{logger: logger}, "", 0)
+}
+
 // responseLogWriter wrapper for http.ResponseWriter
 type responseLogWriter struct {
 	http.ResponseWriter
@@ -53,6 +73,11 @@ func (r *responseLogWriter) WriteHeader(code int) {
 	r.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap lets http.ResponseController reach the flusher and the write deadline of the wrapped writer
+func (r *responseLogWriter) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 // loggerMiddleware logs the each http request with its necessary fields to the log.Output
 func loggerMiddleware(logger zerolog.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -76,6 +101,14 @@ func loggerMiddleware(logger zerolog.Logger) func(next http.Handler) http.Handle
 				event.Str("http_request_path", r.URL.Path)
 				event.Float64("http_response_time", float64(reqEndedAt.Sub(reqStartedAt).Nanoseconds())/100000.0)
 				event.Int("http_status", nrw.statusCode)
+				event.Str("request_id", requestid.FromContext(r.Context()))
+				// the request is identified before it's logged
+				actor := audit.ActorFromContext(r.Context())
+				event.Str("actor_type", string(actor.Type))
+				event.Str("actor_id", actor.ID)
+				if client, ok := server.ClientIdentityFromContext(r.Context()); ok {
+					event.Str("tls_client_cn", client.CommonName)
+				}
 
 				event.Msgf("%s %s %d", r.Method, r.URL.RequestURI(), nrw.statusCode)
 			}()
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kelseyhightower/envconfig"
+	_ "github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/pkg/audit"
+	"github.com/sathishs-dev/pismo-transactions/pkg/reconcile"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+)
+
+const (
+	driver = "postgres"
+)
+
+type config struct {
+	PismoDBDSN     string        `envconfig:"PISMO_DB_DSN" required:"true"`
+	SettlementFile string        `envconfig:"SETTLEMENT_FILE" required:"true"`
+	Columns        string        `envconfig:"SETTLEMENT_COLUMNS"`
+	DateLayout     string        `envconfig:"SETTLEMENT_DATE_LAYOUT" default:"2006-01-02"`
+	Delimiter      string        `envconfig:"SETTLEMENT_DELIMITER" default:","`
+	DateTolerance  time.Duration `envconfig:"DATE_TOLERANCE" default:"24h"`
+	Timeout        time.Duration `envconfig:"RECONCILE_TIMEOUT" default:"10m"`
+}
+
+func main() {
+	var cfg config
+	failOnError(loadConfig(&cfg), "failed to load config")
+
+	mapping, err := reconcile.ParseMapping(cfg.Columns)
+	failOnError(err, "invalid column mapping")
+
+	mapping.DateLayout = cfg.DateLayout
+	failOnError(mapping.SetDelimiter(cfg.Delimiter), "invalid delimiter")
+
+	file, err := os.Open(cfg.SettlementFile)
+	failOnError(err, "unable to open settlement file")
+	defer file.Close()
+
+	db, err := sql.Open(driver, cfg.PismoDBDSN)
+	failOnError(err, "failed to open database connection")
+	defer func() {
+		if err := db.Close(); err != nil {
+			logOnError(err, "closing db connection failed")
+		}
+	}()
+
+	db.SetMaxOpenConns(1)
+
+	// the reconciliation is audited as made by the reconciler
+	ctx := audit.WithActor(context.Background(), audit.Actor{Type: audit.ActorSystem, ID: "reconciler"})
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	repo := repository.NewPismoRepo(sqlx.NewDb(db, driver))
+
+	rec, err := reconcile.New(repo).Run(ctx, file, reconcile.Options{
+		Source:        filepath.Base(cfg.SettlementFile),
+		Mapping:       mapping,
+		DateTolerance: cfg.DateTolerance,
+	})
+	failOnError(err, "reconciliation failed")
+
+	log.Info().
+		Int("reconciliation_id", rec.ReconciliationID).
+		Int("matched", rec.Matched).
+		Int("missing_in_ledger", rec.MissingInLedger).
+		Int("missing_in_file", rec.MissingInFile).
+		Int("amount_mismatches", rec.AmountMismatches).
+		Msg("reconciliation saved")
+}
+
+func loadConfig(cfg *config) error {
+	return envconfig.Process("", cfg)
+}
+
+func logOnError(err error, msg string) {
+	log.Error().Err(err).Msg(msg)
+}
+
+func failOnError(err error, msg string) {
+	if err == nil {
+		return
+	}
+
+	logOnError(err, msg)
+	os.Exit(1)
+}
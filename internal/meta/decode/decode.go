@@ -0,0 +1,283 @@
+package decode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+)
+
+// DefaultMaxBytes is the size of the bodies accepted by the routes not configuring their own
+const DefaultMaxBytes int64 = 1 << 20
+
+// unknownFieldPrefix starts the error encoding/json returns for the fields missing from the payload
+const unknownFieldPrefix = "json: unknown field "
+
+type (
+	// Config is how the bodies of a route are decoded
+	Config struct {
+		// MaxBytes is the size of the bodies accepted, larger ones are answered with 413
+		MaxBytes int64
+		// MediaTypes are the content types accepted, other ones are answered with 415
+		MediaTypes []string
+		// AllowUnknownFields accepts json fields the payload doesn't have instead of failing
+		AllowUnknownFields bool
+	}
+
+	// Option configures the decoding of a route
+	Option func(*Config)
+
+	// Error is a body that can't be decoded, it's reported to the caller as is
+	Error struct {
+		Status int
+		Code   problem.Code
+		Detail string
+		Fields []problem.FieldError
+
+		err error
+	}
+
+	ctxKey struct{}
+)
+
+// WithMaxBytes - Will set the size of the bodies accepted
+func WithMaxBytes(n int64) Option {
+	return func(c *Config) {
+		c.MaxBytes = n
+	}
+}
+
+// WithMediaTypes - Will set the content types accepted, eg: text/csv
+func WithMediaTypes(mediaTypes ...string) Option {
+	return func(c *Config) {
+		c.MediaTypes = mediaTypes
+	}
+}
+
+// WithUnknownFields - Will accept json fields the payload doesn't have
+func WithUnknownFields() Option {
+	return func(c *Config) {
+		c.AllowUnknownFields = true
+	}
+}
+
+// NewConfig returns the config of the options, json bodies up to DefaultMaxBytes by default
+func NewConfig(opts ...Option) Config {
+	c := Config{
+		MaxBytes:   DefaultMaxBytes,
+		MediaTypes: []string{"application/json"},
+	}
+
+	for _, o := range opts {
+		o(&c)
+	}
+
+	return c
+}
+
+// Middleware enforces the config on the routes it wraps, bodies of other media types are refused before being read
+// and the body is capped so whatever reads it next can't read more, the handlers decode it with JSON
+func Middleware(opts ...Option) func(http.Handler) http.Handler {
+	cfg := NewConfig(opts...)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if hasBody(r) && !cfg.accepts(r.Header.Get("Content-Type")) {
+				_ = problem.Error(w, r, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType,
+					"content type must be "+strings.Join(cfg.MediaTypes, " or "))
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxBytes)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey{}, cfg)))
+		})
+	}
+}
+
+// FromContext returns the config of the route, the default one outside of Middleware
+func FromContext(ctx context.Context) Config {
+	if cfg, ok := ctx.Value(ctxKey{}).(Config); ok {
+		return cfg
+	}
+
+	return NewConfig()
+}
+
+// JSON decodes the json body of the request into v following the config of the route, the body has to be a single
+// json value. An empty body is an Error wrapping io.EOF
+func JSON(r *http.Request, v any) error {
+	cfg := FromContext(r.Context())
+	if r.Body == nil {
+		return invalidBody("body is required", io.EOF)
+	}
+
+	return decode(http.MaxBytesReader(nil, r.Body, cfg.MaxBytes), v, cfg)
+}
+
+// Unmarshal decodes data into v as strictly as JSON does with the default config
+func Unmarshal(data []byte, v any) error {
+	return decode(bytes.NewReader(data), v, NewConfig())
+}
+
+// Read returns the body of the request, it's an Error when the body is larger than the route accepts
+func Read(r *http.Request) ([]byte, error) {
+	cfg := FromContext(r.Context())
+	if r.Body == nil {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, cfg.MaxBytes))
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	return data, nil
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// TooLarge returns the error of a body larger than the route accepts, nil for any other error
+func TooLarge(err error) *Error {
+	var mbe *http.MaxBytesError
+	if !errors.As(err, &mbe) {
+		return nil
+	}
+
+	return &Error{
+		Status: http.StatusRequestEntityTooLarge,
+		Code:   problem.CodeBodyTooLarge,
+		Detail: fmt.Sprintf("body must be at most %d bytes", mbe.Limit),
+		err:    err,
+	}
+}
+
+func decode(body io.Reader, v any, cfg Config) error {
+	dec := json.NewDecoder(body)
+	if !cfg.AllowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	if err := dec.Decode(v); err != nil {
+		return toError(err)
+	}
+
+	// anything but the end of the body after the value is trailing data
+	var trailing json.RawMessage
+	switch err := dec.Decode(&trailing); {
+	case errors.Is(err, io.EOF):
+		return nil
+	case err == nil:
+		return invalidBody("body must contain a single json value", nil)
+	default:
+		if e := TooLarge(err); e != nil {
+			return e
+		}
+		return invalidBody("body must contain a single json value", err)
+	}
+}
+
+// toError maps the errors of encoding/json to the field and the reason they failed
+func toError(err error) error {
+	if e := TooLarge(err); e != nil {
+		return e
+	}
+
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.Is(err, io.EOF):
+		return invalidBody("body is required", err)
+
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return invalidBody("body must be valid json, unexpected end of json", err)
+
+	case errors.As(err, &syntaxErr):
+		return invalidBody(fmt.Sprintf("body must be valid json, %s at offset %d", syntaxErr, syntaxErr.Offset), err)
+
+	case errors.As(err, &typeErr):
+		name := typeErr.Field
+		if name == "" {
+			name = "body"
+		}
+		return invalidFields(err, problem.FieldError{In: "body", Name: name, Message: "must be " + typeName(typeErr.Type)})
+
+	case strings.HasPrefix(err.Error(), unknownFieldPrefix):
+		name, uerr := strconv.Unquote(strings.TrimPrefix(err.Error(), unknownFieldPrefix))
+		if uerr != nil {
+			name = strings.TrimPrefix(err.Error(), unknownFieldPrefix)
+		}
+		return invalidFields(err, problem.FieldError{In: "body", Name: name, Message: "is not a known field"})
+	}
+
+	// the errors of the payloads decoding themselves
+	return invalidBody(err.Error(), err)
+}
+
+func invalidBody(detail string, err error) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: problem.CodeInvalidBody, Detail: detail, err: err}
+}
+
+func invalidFields(err error, fields ...problem.FieldError) *Error {
+	return &Error{
+		Status: http.StatusBadRequest,
+		Code:   problem.CodeValidationFailed,
+		Detail: fields[0].Name + " " + fields[0].Message,
+		Fields: fields,
+		err:    err,
+	}
+}
+
+// typeName is the json type of the go type, worded like the openapi validation errors
+func typeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+func (c Config) accepts(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return slices.Contains(c.MediaTypes, mediaType)
+}
+
+// hasBody reports whether the request has a body, a chunked body has an unknown length
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+}
@@ -0,0 +1,197 @@
+package decode
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/stretchr/testify/require"
+)
+
+type payload struct {
+	AccountID int               `json:"account_id"`
+	Amount    float64           `json:"amount"`
+	Metadata  map[string]string `json:"metadata"`
+	Nested    struct {
+		Enabled bool `json:"enabled"`
+	} `json:"nested"`
+}
+
+func TestJSON(t *testing.T) {
+	tcs := []struct {
+		name           string
+		body           string
+		opts           []Option
+		expectedStatus int
+		expectedCode   problem.Code
+		expectedDetail string
+		expectedFields []problem.FieldError
+	}{
+		{
+			name: "Test Valid Body",
+			body: `{"account_id": 1, "amount": 10.5} ` + "\n",
+		},
+		{
+			name:           "Test Empty Body",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   problem.CodeInvalidBody,
+			expectedDetail: "body is required",
+		},
+		{
+			name:           "Test Type Mismatch",
+			body:           `{"account_id": 1, "amount": "10"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   problem.CodeValidationFailed,
+			expectedDetail: "amount must be a number",
+			expectedFields: []problem.FieldError{{In: "body", Name: "amount", Message: "must be a number"}},
+		},
+		{
+			name:           "Test Nested Type Mismatch",
+			body:           `{"nested": {"enabled": "yes"}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   problem.CodeValidationFailed,
+			expectedDetail: "nested.enabled must be a boolean",
+			expectedFields: []problem.FieldError{{In: "body", Name: "nested.enabled", Message: "must be a boolean"}},
+		},
+		{
+			name:           "Test Wrong Body Type",
+			body:           `[1]`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   problem.CodeValidationFailed,
+			expectedDetail: "body must be an object",
+			expectedFields: []problem.FieldError{{In: "body", Name: "body", Message: "must be an object"}},
+		},
+		{
+			name:           "Test Unknown Field",
+			body:           `{"account_id": 1, "accountId": 1}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   problem.CodeValidationFailed,
+			expectedDetail: "accountId is not a known field",
+			expectedFields: []problem.FieldError{{In: "body", Name: "accountId", Message: "is not a known field"}},
+		},
+		{
+			name: "Test Unknown Field Allowed",
+			body: `{"account_id": 1, "accountId": 1}`,
+			opts: []Option{WithUnknownFields()},
+		},
+		{
+			name:           "Test Trailing Data",
+			body:           `{"account_id": 1}{"account_id": 2}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   problem.CodeInvalidBody,
+			expectedDetail: "body must contain a single json value",
+		},
+		{
+			name:           "Test Trailing Garbage",
+			body:           `{"account_id": 1} garbage`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   problem.CodeInvalidBody,
+			expectedDetail: "body must contain a single json value",
+		},
+		{
+			name:           "Test Syntax Error",
+			body:           `{"account_id": 1,}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   problem.CodeInvalidBody,
+			expectedDetail: "body must be valid json, invalid character '}' looking for beginning of object key string at offset 18",
+		},
+		{
+			name:           "Test Truncated Body",
+			body:           `{"account_id": 1`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   problem.CodeInvalidBody,
+			expectedDetail: "body must be valid json, unexpected end of json",
+		},
+		{
+			name:           "Test Body Too Large",
+			body:           `{"metadata": {"k": "` + strings.Repeat("x", 64) + `"}}`,
+			opts:           []Option{WithMaxBytes(32)},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCode:   problem.CodeBodyTooLarge,
+			expectedDetail: "body must be at most 32 bytes",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			h := Middleware(tc.opts...)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				var p payload
+				err = JSON(r, &p)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json; charset=utf-8")
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			if tc.expectedCode == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			var de *Error
+			require.ErrorAs(t, err, &de)
+			require.Equal(t, tc.expectedStatus, de.Status)
+			require.Equal(t, tc.expectedCode, de.Code)
+			require.Equal(t, tc.expectedDetail, de.Detail)
+			require.Equal(t, tc.expectedFields, de.Fields)
+		})
+	}
+}
+
+func TestJSONEmptyBody(t *testing.T) {
+	var p payload
+	err := JSON(httptest.NewRequest(http.MethodPost, "/", nil), &p)
+	require.True(t, errors.Is(err, io.EOF))
+}
+
+func TestMiddleware(t *testing.T) {
+	tcs := []struct {
+		name           string
+		contentType    string
+		body           string
+		opts           []Option
+		expectedStatus int
+	}{
+		{name: "Test Json", contentType: "application/json", body: `{}`, expectedStatus: http.StatusOK},
+		{name: "Test Missing Content Type", body: `{}`, expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "Test Wrong Content Type", contentType: "text/plain", body: `{}`, expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "Test No Body", expectedStatus: http.StatusOK},
+		{
+			name:           "Test Configured Media Type",
+			contentType:    "text/csv",
+			body:           "a,b\n",
+			opts:           []Option{WithMediaTypes("text/csv")},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Middleware(tc.opts...)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			var body io.Reader
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/", body)
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.expectedStatus, rec.Code)
+			if tc.expectedStatus == http.StatusUnsupportedMediaType {
+				require.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+				require.Contains(t, rec.Body.String(), `"code":"unsupported_media_type"`)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package deprecation
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Requests counts the requests served by deprecated routes, keyed by method and route pattern, it's published
+// with the other expvar metrics as deprecated_requests
+var Requests = expvar.NewMap("deprecated_requests")
+
+// Policy describes how deprecated routes are announced to the callers
+type Policy struct {
+	// DeprecatedAt is the date the routes got deprecated, sent as the Deprecation header (RFC 9745)
+	DeprecatedAt time.Time
+	// Sunset is the date the routes stop being served, sent as the Sunset header (RFC 8594)
+	Sunset time.Time
+	// Successor returns the path replacing the requested one, sent as a successor-version link when not empty
+	Successor func(path string) string
+}
+
+// Middleware announces the deprecation of the routes it wraps and counts their usage
+func Middleware(p Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", fmt.Sprintf("@%d", p.DeprecatedAt.Unix()))
+			w.Header().Set("Sunset", p.Sunset.UTC().Format(http.TimeFormat))
+			if p.Successor != nil {
+				if successor := p.Successor(r.URL.Path); successor != "" {
+					w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+				}
+			}
+
+			next.ServeHTTP(w, r)
+
+			// the route pattern is only complete once the router has matched the request
+			pattern := r.URL.Path
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				pattern = rctx.RoutePattern()
+			}
+			Requests.Add(r.Method+" "+pattern, 1)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package deprecation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	p := Policy{
+		DeprecatedAt: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		Sunset:       time.Date(2027, 4, 19, 0, 0, 0, 0, time.UTC),
+		Successor: func(path string) string {
+			return "/v1" + path
+		},
+	}
+
+	r := chi.NewRouter()
+	r.With(Middleware(p)).Get("/accounts/{accountId}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	before := counter("GET /accounts/{accountId}")
+
+	for _, path := range []string{"/accounts/1", "/accounts/2"} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "@1792368000", rec.Header().Get("Deprecation"))
+		require.Equal(t, "Mon, 19 Apr 2027 00:00:00 GMT", rec.Header().Get("Sunset"))
+		require.Equal(t, `</v1`+path+`>; rel="successor-version"`, rec.Header().Get("Link"))
+	}
+
+	require.Equal(t, before+2, counter("GET /accounts/{accountId}"))
+}
+
+func counter(key string) int64 {
+	if v := Requests.Get(key); v != nil {
+		return v.(interface{ Value() int64 }).Value()
+	}
+
+	return 0
+}
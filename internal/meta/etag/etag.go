@@ -0,0 +1,55 @@
+package etag
+
+import (
+	"strings"
+)
+
+// Any is the If-Match and If-None-Match value matching any current representation
+const Any = "*"
+
+// Strong returns the strong entity tag of the opaque value, eg: "3"
+func Strong(v string) string {
+	return `"` + v + `"`
+}
+
+// Parse returns the entity tags of an If-Match or If-None-Match header, in the order they are listed. Tags that
+// aren't quoted are dropped since they can't match any entity tag
+func Parse(header string) []string {
+	var tags []string
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == Any {
+			tags = append(tags, t)
+			continue
+		}
+
+		v := strings.TrimPrefix(t, "W/")
+		if len(v) < 2 || v[0] != '"' || v[len(v)-1] != '"' {
+			continue
+		}
+		tags = append(tags, t)
+	}
+
+	return tags
+}
+
+// Opaque returns the value of a strong entity tag, it's false for weak tags and Any
+func Opaque(tag string) (string, bool) {
+	if strings.HasPrefix(tag, "W/") || len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return "", false
+	}
+
+	return tag[1 : len(tag)-1], true
+}
+
+// NoneMatch reports whether the If-None-Match header of the request matches the entity tag of the current
+// representation, comparing the tags weakly as RFC 9110 requires. A match answers a GET with 304
+func NoneMatch(header, tag string) bool {
+	for _, t := range Parse(header) {
+		if t == Any || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(tag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
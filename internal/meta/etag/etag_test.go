@@ -0,0 +1,42 @@
+package etag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	require.Equal(t, []string{`"1"`, `W/"2"`, Any}, Parse(` "1", W/"2" ,*, 3, "`))
+	require.Nil(t, Parse(""))
+}
+
+func TestOpaque(t *testing.T) {
+	tcs := []struct {
+		tag      string
+		expected string
+		ok       bool
+	}{
+		{tag: `"3"`, expected: "3", ok: true},
+		{tag: `""`, expected: "", ok: true},
+		{tag: `W/"3"`},
+		{tag: Any},
+		{tag: `"3`},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.tag, func(t *testing.T) {
+			v, ok := Opaque(tc.tag)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.expected, v)
+		})
+	}
+}
+
+func TestNoneMatch(t *testing.T) {
+	require.True(t, NoneMatch(`"1", "3"`, Strong("3")))
+	require.True(t, NoneMatch(`W/"3"`, Strong("3")))
+	require.True(t, NoneMatch(Any, Strong("3")))
+	require.False(t, NoneMatch(`"2"`, Strong("3")))
+	require.False(t, NoneMatch("", Strong("3")))
+}
@@ -0,0 +1,159 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/requestid"
+)
+
+// ContentType is the media type of the problem details (RFC 7807)
+const ContentType = "application/problem+json"
+
+// typePrefix makes the codes problem type URIs, eg: urn:pismo-transactions:problem:account_not_found
+const typePrefix = "urn:pismo-transactions:problem:"
+
+// Code is a stable machine readable error code, callers branch on it instead of the detail which is meant for humans
+type Code string
+
+// the error code catalog, a code is never renamed or reused for another problem once released
+const (
+	CodeInvalidRequest             Code = "invalid_request"
+	CodeInvalidBody                Code = "invalid_body"
+	CodeValidationFailed           Code = "validation_failed"
+	CodeBatchRejected              Code = "batch_rejected"
+	CodeUnsupportedMediaType       Code = "unsupported_media_type"
+	CodeBodyTooLarge               Code = "body_too_large"
+	CodeRouteNotFound              Code = "route_not_found"
+	CodeMethodNotAllowed           Code = "method_not_allowed"
+	CodeRateLimited                Code = "rate_limited"
+	CodeUnauthorized               Code = "unauthorized"
+	CodeInsufficientScope          Code = "insufficient_scope"
+	CodePreconditionRequired       Code = "precondition_required"
+	CodePreconditionFailed         Code = "precondition_failed"
+	CodeAccountNotFound            Code = "account_not_found"
+	CodeAccountErased              Code = "account_erased"
+	CodeAccountBalanceNotZero      Code = "account_balance_not_zero"
+	CodeDuplicateDocumentNumber    Code = "duplicate_document_number"
+	CodeDuplicateExternalReference Code = "duplicate_external_reference"
+	CodeReconciliationNotFound     Code = "reconciliation_not_found"
+	CodeWebhookNotFound            Code = "webhook_not_found"
+	CodeAPIKeyNotFound             Code = "api_key_not_found"
+	CodeInternal                   Code = "internal_error"
+)
+
+// titles is the short summary of every code, it's the same for every occurrence of the problem
+var titles = map[Code]string{
+	CodeInvalidRequest:             "Invalid request",
+	CodeInvalidBody:                "Invalid body",
+	CodeValidationFailed:           "Validation failed",
+	CodeBatchRejected:              "Batch rejected",
+	CodeUnsupportedMediaType:       "Unsupported media type",
+	CodeBodyTooLarge:               "Body too large",
+	CodeRouteNotFound:              "Route not found",
+	CodeMethodNotAllowed:           "Method not allowed",
+	CodeRateLimited:                "Rate limited",
+	CodeUnauthorized:               "Unauthorized",
+	CodeInsufficientScope:          "Insufficient scope",
+	CodePreconditionRequired:       "Precondition required",
+	CodePreconditionFailed:         "Precondition failed",
+	CodeAccountNotFound:            "Account not found",
+	CodeAccountErased:              "Account erased",
+	CodeAccountBalanceNotZero:      "Account balance not zero",
+	CodeDuplicateDocumentNumber:    "Duplicate document number",
+	CodeDuplicateExternalReference: "Duplicate external reference",
+	CodeReconciliationNotFound:     "Reconciliation not found",
+	CodeWebhookNotFound:            "Webhook not found",
+	CodeAPIKeyNotFound:             "API key not found",
+	CodeInternal:                   "Internal error",
+}
+
+type (
+	// Problem is the body of every error response
+	Problem struct {
+		Type      string       `json:"type"`
+		Title     string       `json:"title"`
+		Status    int          `json:"status"`
+		Detail    string       `json:"detail,omitempty"`
+		Instance  string       `json:"instance,omitempty"`
+		Code      Code         `json:"code"`
+		RequestID string       `json:"request_id,omitempty"`
+		Errors    []FieldError `json:"errors,omitempty"`
+	}
+
+	// FieldError is a parameter or body field failing validation, name is the path to body fields eg: tags[1]
+	FieldError struct {
+		In      string `json:"in"`
+		Name    string `json:"name"`
+		Message string `json:"message"`
+	}
+)
+
+// Codes returns the catalog
+func Codes() map[Code]string {
+	res := make(map[Code]string, len(titles))
+	for c, t := range titles {
+		res[c] = t
+	}
+
+	return res
+}
+
+// Title returns the title of the code, an unknown code is an internal error
+func (c Code) Title() string {
+	if t, ok := titles[c]; ok {
+		return t
+	}
+
+	return titles[CodeInternal]
+}
+
+// Type returns the problem type URI of the code
+func (c Code) Type() string {
+	return typePrefix + string(c)
+}
+
+// New returns the problem of the code for the request, the instance is the request path
+func New(r *http.Request, status int, code Code, detail string) *Problem {
+	if _, ok := titles[code]; !ok {
+		code = CodeInternal
+	}
+
+	return &Problem{
+		Type:      code.Type(),
+		Title:     code.Title(),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: requestid.FromContext(r.Context()),
+	}
+}
+
+// WithErrors adds the fields failing validation to the problem
+func (p *Problem) WithErrors(errs ...FieldError) *Problem {
+	p.Errors = append(p.Errors, errs...)
+	return p
+}
+
+// Write responds with the problem
+func (p *Problem) Write(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	return json.NewEncoder(w).Encode(p)
+}
+
+// Error responds with the problem of the code for the request
+func Error(w http.ResponseWriter, r *http.Request, status int, code Code, detail string) error {
+	return New(r, status, code, detail).Write(w)
+}
+
+// NotFound answers the requests no route matches
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	_ = Error(w, r, http.StatusNotFound, CodeRouteNotFound, "no route matches "+r.URL.Path)
+}
+
+// MethodNotAllowed answers the requests to a route not serving their method
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	_ = Error(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path)
+}
@@ -0,0 +1,69 @@
+package problem
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/requestid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestError(t *testing.T) {
+	tcs := []struct {
+		name         string
+		status       int
+		code         Code
+		detail       string
+		errors       []FieldError
+		expectedBody string
+	}{
+		{
+			name:   "Test Problem",
+			status: http.StatusNotFound,
+			code:   CodeAccountNotFound,
+			detail: "account not found",
+			expectedBody: `{"type": "urn:pismo-transactions:problem:account_not_found", "title": "Account not found",
+				"status": 404, "detail": "account not found", "instance": "/v1/accounts/1", "code": "account_not_found",
+				"request_id": "req-1"}`,
+		},
+		{
+			name:   "Test Problem With Errors",
+			status: http.StatusBadRequest,
+			code:   CodeValidationFailed,
+			detail: "amount must not be zero",
+			errors: []FieldError{{In: "body", Name: "amount", Message: "must not be zero"}},
+			expectedBody: `{"type": "urn:pismo-transactions:problem:validation_failed", "title": "Validation failed",
+				"status": 400, "detail": "amount must not be zero", "instance": "/v1/accounts/1", "code": "validation_failed",
+				"request_id": "req-1", "errors": [{"in": "body", "name": "amount", "message": "must not be zero"}]}`,
+		},
+		{
+			name:   "Test Unknown Code",
+			status: http.StatusInternalServerError,
+			code:   Code("unknown"),
+			expectedBody: `{"type": "urn:pismo-transactions:problem:internal_error", "title": "Internal error",
+				"status": 500, "instance": "/v1/accounts/1", "code": "internal_error", "request_id": "req-1"}`,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/accounts/1", nil)
+			r = r.WithContext(requestid.NewContext(r.Context(), "req-1"))
+			rec := httptest.NewRecorder()
+
+			require.NoError(t, New(r, tc.status, tc.code, tc.detail).WithErrors(tc.errors...).Write(rec))
+
+			require.Equal(t, tc.status, rec.Code)
+			require.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+			require.JSONEq(t, tc.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func TestCodes(t *testing.T) {
+	for code, title := range Codes() {
+		require.NotEmpty(t, title, code)
+		require.Equal(t, title, code.Title())
+	}
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is the number of takes between two sweeps of the full buckets
+const sweepEvery = 1024
+
+type (
+	bucket struct {
+		tokens float64
+		at     time.Time
+		limit  Limit
+	}
+
+	// MemoryStore keeps the buckets in memory, each replica limits the requests it serves on its own
+	MemoryStore struct {
+		mu      sync.Mutex
+		buckets map[string]*bucket
+		takes   int
+		now     func() time.Time
+	}
+)
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+// Take takes a token from the bucket of the key, a bucket is created full
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (float64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), at: now}
+		s.buckets[key] = b
+	}
+	b.refill(now, limit)
+
+	taken := b.tokens >= 1
+	if taken {
+		b.tokens--
+	}
+
+	if s.takes++; s.takes%sweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	return b.tokens, taken, nil
+}
+
+// refill adds the tokens earned since the bucket was last taken from
+func (b *bucket) refill(now time.Time, limit Limit) {
+	b.tokens = min(float64(limit.Burst), b.tokens+now.Sub(b.at).Seconds()*limit.Rate)
+	b.at, b.limit = now, limit
+}
+
+// sweep drops the buckets refilled since, they'd be created full again
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if b.tokens+now.Sub(b.at).Seconds()*b.limit.Rate >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
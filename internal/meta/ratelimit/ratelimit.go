@@ -0,0 +1,189 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+)
+
+type (
+	// Limit is a token bucket holding up to Burst tokens and refilled at Rate tokens per second, every request
+	// takes a token. The zero Limit doesn't limit the requests.
+	Limit struct {
+		Rate  float64
+		Burst int
+	}
+
+	// Store keeps the token buckets, Take refills the bucket of the key at the rate of the limit since it was last
+	// taken from and takes a token when there is one, it returns the tokens left and whether a token was taken
+	Store interface {
+		Take(ctx context.Context, key string, limit Limit) (tokens float64, ok bool, err error)
+	}
+
+	// StoreFunc adapts a function to a Store
+	StoreFunc func(ctx context.Context, key string, limit Limit) (tokens float64, ok bool, err error)
+
+	// Policy tells the limit of every request, the limit of the client takes precedence over the limit of the route
+	// and the default one applies to the others. The requests of a client share a bucket, except for the routes
+	// with their own limit which get a bucket of their own.
+	Policy struct {
+		Default Limit
+		// Routes are keyed by route pattern, optionally prefixed by the method eg: POST /v1/transactions:batch
+		Routes map[string]Limit
+		// Clients are keyed by client identity
+		Clients map[string]Limit
+		// Route returns the route pattern of the request
+		Route func(r *http.Request) string
+		// Client returns the identity of the client making the request
+		Client func(r *http.Request) string
+	}
+)
+
+// Take calls f
+func (f StoreFunc) Take(ctx context.Context, key string, limit Limit) (float64, bool, error) {
+	return f(ctx, key, limit)
+}
+
+// unlimited reports whether the limit lets every request through
+func (l Limit) unlimited() bool {
+	return l.Rate <= 0
+}
+
+// ParseLimit parses a limit written as <requests>/<s|m|h>[:<burst>] eg: 20/s, 600/m:50 or off, the burst defaults
+// to the number of requests
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "off" {
+		return Limit{}, nil
+	}
+
+	rate, burst, hasBurst := strings.Cut(s, ":")
+
+	n, unit, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%q is not a valid limit, eg: 20/s:40", s)
+	}
+
+	requests, err := strconv.Atoi(n)
+	if err != nil || requests <= 0 {
+		return Limit{}, fmt.Errorf("%q is not a valid number of requests", n)
+	}
+
+	var period time.Duration
+	switch unit {
+	case "s":
+		period = time.Second
+	case "m":
+		period = time.Minute
+	case "h":
+		period = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("%q is not a valid period, it's one of s, m or h", unit)
+	}
+
+	l := Limit{Rate: float64(requests) / period.Seconds(), Burst: requests}
+	if hasBurst {
+		if l.Burst, err = strconv.Atoi(burst); err != nil || l.Burst <= 0 {
+			return Limit{}, fmt.Errorf("%q is not a valid burst", burst)
+		}
+	}
+
+	return l, nil
+}
+
+// ParseLimits parses comma separated <key>=<limit> pairs eg: POST /v1/transactions:batch=1/s:5,/v1/changes=2/s
+func ParseLimits(s string) (map[string]Limit, error) {
+	limits := map[string]Limit{}
+	if strings.TrimSpace(s) == "" {
+		return limits, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		i := strings.LastIndex(pair, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("%q is not a valid key=limit pair", pair)
+		}
+
+		l, err := ParseLimit(pair[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		limits[strings.TrimSpace(pair[:i])] = l
+	}
+
+	return limits, nil
+}
+
+// limit returns the limit of the request along with the key of its bucket
+func (p Policy) limit(r *http.Request) (Limit, string) {
+	client := p.Client(r)
+	if l, ok := p.Clients[client]; ok {
+		return l, client
+	}
+
+	route := p.Route(r)
+	for _, k := range []string{r.Method + " " + route, route} {
+		if l, ok := p.Routes[k]; ok {
+			return l, client + " " + k
+		}
+	}
+
+	return p.Default, client
+}
+
+// Middleware limits the requests following the policy, the responses carry the RateLimit headers of the bucket and
+// the requests over the limit are answered with 429 and the Retry-After header. Requests are let through when the
+// store fails, the limiter isn't worth an outage.
+func Middleware(store Store, p Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit, key := p.limit(r)
+			if limit.unlimited() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			tokens, ok, err := store.Take(r.Context(), key, limit)
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					log.Error().Err(err).Str("key", key).Msg("failed to take a rate limit token")
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+			h.Set("RateLimit-Remaining", strconv.Itoa(int(math.Max(0, math.Floor(tokens)))))
+			h.Set("RateLimit-Reset", strconv.Itoa(seconds(float64(limit.Burst)-tokens, limit.Rate)))
+			h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Burst, seconds(float64(limit.Burst), limit.Rate)))
+
+			if !ok {
+				retryAfter := max(1, seconds(1-tokens, limit.Rate))
+				h.Set("Retry-After", strconv.Itoa(retryAfter))
+				_ = problem.Error(w, r, http.StatusTooManyRequests, problem.CodeRateLimited,
+					fmt.Sprintf("rate limit exceeded, retry in %d seconds", retryAfter))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// seconds returns the whole seconds it takes to refill the tokens at the rate
+func seconds(tokens, rate float64) int {
+	if tokens <= 0 {
+		return 0
+	}
+
+	return int(math.Ceil(tokens / rate))
+}
@@ -0,0 +1,161 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	tcs := []struct {
+		in       string
+		expected Limit
+		err      bool
+	}{
+		{in: "20/s", expected: Limit{Rate: 20, Burst: 20}},
+		{in: "600/m:50", expected: Limit{Rate: 10, Burst: 50}},
+		{in: "3600/h", expected: Limit{Rate: 1, Burst: 3600}},
+		{in: "off", expected: Limit{}},
+		{in: "20", err: true},
+		{in: "0/s", err: true},
+		{in: "20/d", err: true},
+		{in: "20/s:0", err: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.in, func(t *testing.T) {
+			l, err := ParseLimit(tc.in)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, l)
+		})
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits("POST /v1/transactions:batch=1/s:5, /v1/changes=2/s")
+	require.NoError(t, err)
+	require.Equal(t, map[string]Limit{
+		"POST /v1/transactions:batch": {Rate: 1, Burst: 5},
+		"/v1/changes":                 {Rate: 2, Burst: 2},
+	}, limits)
+
+	_, err = ParseLimits("/v1/changes")
+	require.Error(t, err)
+}
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Date(2024, 12, 5, 10, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	limit := Limit{Rate: 2, Burst: 2}
+
+	for _, expected := range []float64{1, 0} {
+		tokens, ok, err := s.Take(context.Background(), "ip:10.0.0.1", limit)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, expected, tokens)
+	}
+
+	_, ok, _ := s.Take(context.Background(), "ip:10.0.0.1", limit)
+	require.False(t, ok, "the bucket is empty")
+
+	_, ok, _ = s.Take(context.Background(), "ip:10.0.0.2", limit)
+	require.True(t, ok, "every key has a bucket of its own")
+
+	now = now.Add(750 * time.Millisecond)
+	tokens, ok, _ := s.Take(context.Background(), "ip:10.0.0.1", limit)
+	require.True(t, ok)
+	require.Equal(t, 0.5, tokens)
+
+	now = now.Add(time.Hour)
+	s.sweep(now)
+	require.Empty(t, s.buckets, "refilled buckets are swept")
+}
+
+func TestMiddleware(t *testing.T) {
+	store := NewMemoryStore()
+	p := Policy{
+		Default: Limit{Rate: 1, Burst: 2},
+		Routes: map[string]Limit{
+			"POST /transactions:batch": {Rate: 0.5, Burst: 1},
+			"/debug/vars":              {},
+		},
+		Clients: map[string]Limit{"api_key:partner": {Rate: 10, Burst: 10}},
+		Route:   func(r *http.Request) string { return r.URL.Path },
+		Client:  func(r *http.Request) string { return r.Header.Get("X-Client") },
+	}
+
+	h := Middleware(store, p)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(method, path, client string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-Client", client)
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/accounts/1", "ip:10.0.0.1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "1", rec.Header().Get("RateLimit-Reset"))
+	require.Equal(t, "2;w=2", rec.Header().Get("RateLimit-Policy"))
+
+	// the routes without a limit of their own share the bucket of the client
+	require.Equal(t, http.StatusOK, serve(http.MethodGet, "/accounts/2", "ip:10.0.0.1").Code)
+
+	rec = serve(http.MethodGet, "/accounts/1", "ip:10.0.0.1")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "1", rec.Header().Get("Retry-After"))
+	require.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+	require.JSONEq(t, `{"type": "urn:pismo-transactions:problem:rate_limited", "title": "Rate limited", "status": 429,
+		"detail": "rate limit exceeded, retry in 1 seconds", "instance": "/accounts/1", "code": "rate_limited"}`,
+		rec.Body.String())
+
+	// a route with a limit of its own has a bucket of its own
+	require.Equal(t, http.StatusOK, serve(http.MethodPost, "/transactions:batch", "ip:10.0.0.1").Code)
+	rec = serve(http.MethodPost, "/transactions:batch", "ip:10.0.0.1")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "2", rec.Header().Get("Retry-After"))
+
+	rec = serve(http.MethodGet, "/debug/vars", "ip:10.0.0.1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Header().Get("RateLimit-Limit"), "off routes aren't limited")
+
+	// the limit of the client takes precedence over the limit of the route
+	for range 3 {
+		require.Equal(t, http.StatusOK, serve(http.MethodPost, "/transactions:batch", "api_key:partner").Code)
+	}
+}
+
+func TestMiddlewareStoreFailure(t *testing.T) {
+	store := StoreFunc(func(context.Context, string, Limit) (float64, bool, error) {
+		return 0, false, errors.New("err")
+	})
+	p := Policy{
+		Default: Limit{Rate: 1, Burst: 1},
+		Route:   func(r *http.Request) string { return r.URL.Path },
+		Client:  func(*http.Request) string { return "ip:10.0.0.1" },
+	}
+
+	h := Middleware(store, p)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/accounts/1", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
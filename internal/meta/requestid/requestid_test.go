@@ -0,0 +1,46 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	tcs := []struct {
+		name       string
+		header     string
+		expectedID string
+	}{
+		{name: "Test Caller Request ID", header: "req-123", expectedID: "req-123"},
+		{name: "Test Generated Request ID"},
+		{name: "Test Too Long Request ID", header: strings.Repeat("a", maxLen+1)},
+		{name: "Test Non Printable Request ID", header: "req 123\n"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			h := Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				got = FromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.header != "" {
+				req.Header.Set(Header, tc.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if tc.expectedID != "" {
+				require.Equal(t, tc.expectedID, got)
+			} else {
+				require.Len(t, got, 32)
+			}
+			require.Equal(t, got, rec.Header().Get(Header))
+		})
+	}
+}
@@ -11,6 +11,8 @@ type options struct {
 	readTimeout    time.Duration
 	writeTimeout   time.Duration
 	errorLogger    *log.Logger
+	onShutdown     []func()
+	tls            tlsOptions
 }
 
 // Option - Used to extend the server's functionalities during initialization
@@ -50,3 +52,50 @@ func WithoutRecover() Option {
 		o.disableRecover = true
 	}
 }
+
+// WithOnShutdown - Will call fn once the server starts shutting down, long lived requests like event streams end
+// on it so the shutdown doesn't wait for them
+func WithOnShutdown(fn func()) Option {
+	return func(o *options) {
+		o.onShutdown = append(o.onShutdown, fn)
+	}
+}
+
+// WithTLS - Will serve https with the certificate and key files, they are loaded again once they change so a renewed
+// certificate is served without a restart
+func WithTLS(certFile, keyFile string) Option {
+	return func(o *options) {
+		o.tls.certFile = certFile
+		o.tls.keyFile = keyFile
+	}
+}
+
+// WithTLSMinVersion - Will refuse the tls versions older than v, eg: tls.VersionTLS13, it's tls 1.2 by default
+func WithTLSMinVersion(v uint16) Option {
+	return func(o *options) {
+		o.tls.minVersion = v
+	}
+}
+
+// WithTLSCipherSuites - Will only negotiate the cipher suites given with tls 1.2, the ones of tls 1.3 aren't
+// configurable
+func WithTLSCipherSuites(ids ...uint16) Option {
+	return func(o *options) {
+		o.tls.cipherSuites = ids
+	}
+}
+
+// WithClientCAs - Will require a client certificate verified against the ca bundle file, reloaded along with the
+// certificate. The identity of the client is available to the handlers through ClientIdentityFromContext.
+func WithClientCAs(caFile string) Option {
+	return func(o *options) {
+		o.tls.clientCAFile = caFile
+	}
+}
+
+// WithTLSReloadInterval - Will check the certificate files for changes every d, DefaultTLSReloadInterval by default
+func WithTLSReloadInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.tls.reloadInterval = d
+	}
+}
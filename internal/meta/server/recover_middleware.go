@@ -5,7 +5,8 @@ import (
 	"net/http"
 	"runtime/debug"
 
-	"github.com/sathishs-dev/pismo-transactions/internal/meta/writer"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/requestid"
 )
 
 func withRecovery(next http.Handler, tracesCh chan<- string) http.HandlerFunc {
@@ -14,17 +15,15 @@ func withRecovery(next http.Handler, tracesCh chan<- string) http.HandlerFunc {
 			if err := recover(); err != nil {
 				stackTrace := debug.Stack()
 
-				writer.WriteJSON(
-					w,
-					http.StatusInternalServerError,
-					writer.ErrorResponse{
-						Code:  "recover_error",
-						Title: "recover",
-						Trace: fmt.Sprintf("%v, %s", err, stackTrace),
-					},
-				)
+				// the trace is only logged, the request id set by the router ties the response to it
+				p := problem.New(r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+				if p.RequestID == "" {
+					p.RequestID = w.Header().Get(requestid.Header)
+				}
+				_ = p.Write(w)
+
 				if tracesCh != nil {
-					tracesCh <- string(stackTrace)
+					tracesCh <- fmt.Sprintf("%v\n%s", err, stackTrace)
 				}
 			}
 		}()
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"runtime"
 )
@@ -15,6 +16,8 @@ type (
 		server   *http.Server
 		tracesCh chan string
 		errorLog *log.Logger
+		// certs is set when serving https
+		certs *certReloader
 	}
 
 	HTTPServer interface {
@@ -23,6 +26,8 @@ type (
 	}
 )
 
+// New returns a server of the handler, plain http unless WithTLS is given. The certificate files are loaded on Start,
+// which fails when they can't be.
 func New(h http.Handler, opts ...Option) HTTPServer {
 	setup := options{
 		port: DefaultWebPort,
@@ -37,23 +42,54 @@ func New(h http.Handler, opts ...Option) HTTPServer {
 		h = withRecovery(h, tracesCh)
 	}
 
+	var certs *certReloader
+	if setup.tls.certFile != "" {
+		certs = newCertReloader(setup.tls, setup.errorLogger)
+		h = withClientIdentity(h)
+	}
+
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", setup.port),
+		Handler:      h,
+		ErrorLog:     setup.errorLogger,
+		ReadTimeout:  setup.readTimeout,
+		WriteTimeout: setup.writeTimeout,
+	}
+	if certs != nil {
+		srv.TLSConfig = setup.tls.tlsConfig(certs)
+	}
+	for _, fn := range setup.onShutdown {
+		srv.RegisterOnShutdown(fn)
+	}
+
 	return &httpServer{
 		tracesCh: tracesCh,
 		errorLog: setup.errorLogger,
-		server: &http.Server{
-			Addr:         fmt.Sprintf(":%d", setup.port),
-			Handler:      h,
-			ErrorLog:     setup.errorLogger,
-			ReadTimeout:  setup.readTimeout,
-			WriteTimeout: setup.writeTimeout,
-		},
+		server:   srv,
+		certs:    certs,
 	}
 }
 
 func (h *httpServer) Start() error {
+	if h.certs != nil {
+		if err := h.certs.load(); err != nil {
+			return err
+		}
+	}
+
+	ln, err := net.Listen("tcp", h.server.Addr)
+	if err != nil {
+		return err
+	}
+
 	go h.outputStackTraces()
 
-	return h.server.ListenAndServe()
+	if h.certs != nil {
+		// the certificate is served by the tls config
+		return h.server.ServeTLS(ln, "", "")
+	}
+
+	return h.server.Serve(ln)
 }
 
 func (h *httpServer) Stop(ctx context.Context) error {
@@ -3,13 +3,19 @@ package server
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"log"
 	"net/http"
+	"net/http/httptest"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/requestid"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -39,8 +45,8 @@ func (h *httpServerTestSuite) TestSuccessHTTPServerWithOptions() {
 	req, err := http.NewRequest(http.MethodGet, "http://localhost:8081/", nil)
 	h.NoError(err)
 
-	res, err := http.DefaultClient.Do(req)
-	h.NoError(err)
+	res, err := do(http.DefaultClient, req)
+	h.Require().NoError(err)
 	defer res.Body.Close()
 
 	body, err := io.ReadAll(res.Body)
@@ -63,8 +69,8 @@ func (h *httpServerTestSuite) TestSuccessHTTPServerWithDefaultOptions() {
 	req, err := http.NewRequest(http.MethodGet, "http://localhost:8080/", nil)
 	h.NoError(err)
 
-	res, err := http.DefaultClient.Do(req)
-	h.NoError(err)
+	res, err := do(http.DefaultClient, req)
+	h.Require().NoError(err)
 	defer res.Body.Close()
 
 	resBody, err := io.ReadAll(res.Body)
@@ -92,8 +98,8 @@ func (h *httpServerTestSuite) TestInternalServerErrorWithPanicAndRecoverMiddlewa
 	r, err := http.NewRequest(http.MethodGet, "http://localhost:8080/", nil)
 	h.NoError(err)
 
-	resp, err := http.DefaultClient.Do(r)
-	h.NoError(err)
+	resp, err := do(http.DefaultClient, r)
+	h.Require().NoError(err)
 	defer resp.Body.Close()
 
 	h.Equal(http.StatusInternalServerError, resp.StatusCode)
@@ -102,6 +108,19 @@ func (h *httpServerTestSuite) TestInternalServerErrorWithPanicAndRecoverMiddlewa
 	h.Regexp("recovered from panic", buf.String())
 }
 
+// do sends the request once the server accepts connections, the tests start it in the background so it may not be
+// listening yet
+func do(client *http.Client, req *http.Request) (*http.Response, error) {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		res, err := client.Do(req)
+		if err == nil || !errors.Is(err, syscall.ECONNREFUSED) || time.Now().After(deadline) {
+			return res, err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 type mockWriter struct {
 	buf   *bytes.Buffer
 	mutex *sync.Mutex
@@ -127,3 +146,21 @@ func (m *mockWriter) String() string {
 
 	return m.buf.String()
 }
+
+func TestRecoveryProblem(t *testing.T) {
+	tracesCh := make(chan string, 1)
+	h := withRecovery(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(requestid.Header, "req-1")
+		panic("test")
+	}), tracesCh)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/accounts", nil))
+
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"type": "urn:pismo-transactions:problem:internal_error", "title": "Internal error", "status": 500,
+		"detail": "please try again later.", "instance": "/v1/accounts", "code": "internal_error", "request_id": "req-1"}`,
+		rec.Body.String())
+	require.Contains(t, <-tracesCh, "test\n")
+}
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTLSReloadInterval is how often the certificate files are checked for changes
+const DefaultTLSReloadInterval = 10 * time.Second
+
+type (
+	tlsOptions struct {
+		certFile       string
+		keyFile        string
+		clientCAFile   string
+		minVersion     uint16
+		cipherSuites   []uint16
+		reloadInterval time.Duration
+	}
+
+	// ClientIdentity is the client certificate of a mutual tls request, verified against the client ca bundle
+	ClientIdentity struct {
+		CommonName   string
+		DNSNames     []string
+		URIs         []string
+		SerialNumber string
+		Certificate  *x509.Certificate
+	}
+
+	// certReloader serves the certificate and client ca bundle of the files, loaded again once they change. The files
+	// are checked at most once per interval on the handshakes, a failed reload keeps the previous ones.
+	certReloader struct {
+		certFile     string
+		keyFile      string
+		clientCAFile string
+		interval     time.Duration
+		errorLog     *log.Logger
+		now          func() time.Time
+
+		mu        sync.RWMutex
+		cert      *tls.Certificate
+		clientCAs *x509.CertPool
+		stamps    map[string]fileStamp
+		checkedAt time.Time
+	}
+
+	// fileStamp tells a file changed, the files are usually replaced rather than written in place
+	fileStamp struct {
+		modTime time.Time
+		size    int64
+	}
+
+	clientIdentityCtxKey struct{}
+)
+
+// ParseTLSVersion parses a tls version, eg: 1.2 or 1.3, the older ones aren't accepted
+func ParseTLSVersion(s string) (uint16, error) {
+	switch s {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+
+	return 0, fmt.Errorf("unsupported tls version %q, supported versions are 1.2/1.3", s)
+}
+
+// ParseCipherSuites parses a comma separated list of cipher suite names, eg:
+// TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, only the ones without known security issues are accepted
+func ParseCipherSuites(s string) ([]uint16, error) {
+	secure := map[string]uint16{}
+	for _, cs := range tls.CipherSuites() {
+		secure[cs.Name] = cs.ID
+	}
+
+	var ids []uint16
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		id, ok := secure[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// ClientIdentityFromContext returns the verified client certificate of the request, false when the request isn't
+// made over mutual tls
+func ClientIdentityFromContext(ctx context.Context) (ClientIdentity, bool) {
+	id, ok := ctx.Value(clientIdentityCtxKey{}).(ClientIdentity)
+	return id, ok
+}
+
+// withClientIdentity makes the verified client certificate of the requests available through
+// ClientIdentityFromContext, the certificates presented without being verified are ignored
+func withClientIdentity(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cert := r.TLS.VerifiedChains[0][0]
+		id := ClientIdentity{
+			CommonName:   cert.Subject.CommonName,
+			DNSNames:     cert.DNSNames,
+			SerialNumber: cert.SerialNumber.String(),
+			Certificate:  cert,
+		}
+		for _, u := range cert.URIs {
+			id.URIs = append(id.URIs, u.String())
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIdentityCtxKey{}, id)))
+	}
+}
+
+// tlsConfig returns the config of the server, the certificate and client ca bundle are taken from the reloader on
+// every handshake. TLS 1.2 is the minimum version by default.
+func (o tlsOptions) tlsConfig(certs *certReloader) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion:     o.minVersion,
+		CipherSuites:   o.cipherSuites,
+		GetCertificate: certs.getCertificate,
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+
+	if o.clientCAFile != "" {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			certs.reloadIfChanged()
+
+			c := cfg.Clone()
+			c.GetConfigForClient = nil
+			c.ClientCAs = certs.clientCertPool()
+			return c, nil
+		}
+	}
+
+	return cfg
+}
+
+func newCertReloader(o tlsOptions, errorLog *log.Logger) *certReloader {
+	interval := o.reloadInterval
+	if interval == 0 {
+		interval = DefaultTLSReloadInterval
+	}
+
+	return &certReloader{
+		certFile:     o.certFile,
+		keyFile:      o.keyFile,
+		clientCAFile: o.clientCAFile,
+		interval:     interval,
+		errorLog:     errorLog,
+		now:          time.Now,
+	}
+}
+
+// load loads the files, the certificate and client ca bundle are only replaced once every file is loaded
+func (c *certReloader) load() error {
+	stamps, err := c.stat()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load the certificate: %w", err)
+	}
+
+	var pool *x509.CertPool
+	if c.clientCAFile != "" {
+		pem, err := os.ReadFile(c.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read the client ca bundle: %w", err)
+		}
+
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("no certificate found in the client ca bundle")
+		}
+	}
+
+	c.mu.Lock()
+	c.cert, c.clientCAs, c.stamps = &cert, pool, stamps
+	c.mu.Unlock()
+
+	return nil
+}
+
+// reloadIfChanged loads the files again when any of them changed since they were loaded, it checks them at most
+// once per interval
+func (c *certReloader) reloadIfChanged() {
+	now := c.now()
+
+	c.mu.Lock()
+	if now.Sub(c.checkedAt) < c.interval {
+		c.mu.Unlock()
+		return
+	}
+	c.checkedAt = now
+	loaded := c.stamps
+	c.mu.Unlock()
+
+	stamps, err := c.stat()
+	if err == nil && equalStamps(stamps, loaded) {
+		return
+	}
+
+	if err == nil {
+		err = c.load()
+	}
+	if err != nil && c.errorLog != nil {
+		c.errorLog.Printf("failed to reload the tls certificate, the previous one is still served: %v", err)
+	}
+}
+
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.reloadIfChanged()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.cert, nil
+}
+
+func (c *certReloader) clientCertPool() *x509.CertPool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.clientCAs
+}
+
+func (c *certReloader) stat() (map[string]fileStamp, error) {
+	stamps := map[string]fileStamp{}
+	for _, name := range []string{c.certFile, c.keyFile, c.clientCAFile} {
+		if name == "" {
+			continue
+		}
+
+		fi, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		stamps[name] = fileStamp{modTime: fi.ModTime(), size: fi.Size()}
+	}
+
+	return stamps, nil
+}
+
+func equalStamps(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for name, s := range a {
+		if t, ok := b[name]; !ok || !s.modTime.Equal(t.modTime) || s.size != t.size {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testCA issues the certificates of the tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the pem encoded certificate and key of the common name, a server certificate for localhost or a
+// client certificate
+func (ca *testCA) issue(t *testing.T, cn string, serial int64, client bool) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	if client {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+		tmpl.DNSNames = []string{cn + ".internal"}
+		tmpl.IPAddresses = nil
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, name string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(name, data, 0o600))
+}
+
+// freePort returns a port nothing listens on, it's free again once returned so the server can listen on it
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+
+	certPEM, keyPEM := ca.issue(t, "localhost", 2, false)
+	writeFile(t, filepath.Join(dir, "tls.crt"), certPEM)
+	writeFile(t, filepath.Join(dir, "tls.key"), keyPEM)
+	writeFile(t, filepath.Join(dir, "ca.crt"), ca.pem)
+
+	port := freePort(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := ClientIdentityFromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(id.CommonName + " " + strings.Join(id.DNSNames, ",")))
+	})
+	s := New(handler,
+		WithPort(port),
+		WithTLS(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")),
+		WithClientCAs(filepath.Join(dir, "ca.crt")),
+		WithTLSMinVersion(tls.VersionTLS13),
+	)
+	go s.Start()
+	defer s.Stop(context.Background())
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	url := fmt.Sprintf("https://localhost:%d/", port)
+
+	clientCertPEM, clientKeyPEM := ca.issue(t, "billing", 3, true)
+	clientCert, err := tls.X509KeyPair(clientCertPEM, clientKeyPEM)
+	require.NoError(t, err)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{clientCert},
+	}}}
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	res, err := do(client, req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "billing billing.internal", string(body))
+	require.Equal(t, tls.VersionTLS13, int(res.TLS.Version))
+
+	// the clients without a certificate, or with one the ca didn't issue, are refused on the handshake
+	other := newTestCA(t)
+	otherCertPEM, otherKeyPEM := other.issue(t, "billing", 3, true)
+	otherCert, err := tls.X509KeyPair(otherCertPEM, otherKeyPEM)
+	require.NoError(t, err)
+
+	for _, certs := range [][]tls.Certificate{nil, {otherCert}} {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}}
+		res, err := client.Get(url)
+		if err == nil {
+			res.Body.Close()
+		}
+		require.Error(t, err)
+	}
+
+	// tls 1.2 is below the minimum version
+	client = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{clientCert},
+		MaxVersion:   tls.VersionTLS12,
+	}}}
+	_, err = client.Get(url)
+	require.Error(t, err)
+}
+
+func TestTLSStartFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	s := New(http.NotFoundHandler(), WithTLS(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")))
+	require.Error(t, s.Start())
+}
+
+func TestCertReload(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+	certPEM, keyPEM := ca.issue(t, "localhost", 2, false)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	now := time.Now()
+	c := newCertReloader(tlsOptions{certFile: certFile, keyFile: keyFile, reloadInterval: time.Minute}, nil)
+	c.now = func() time.Time { return now }
+	require.NoError(t, c.load())
+
+	serial := func() int64 {
+		cert, err := c.getCertificate(nil)
+		require.NoError(t, err)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		require.NoError(t, err)
+		return leaf.SerialNumber.Int64()
+	}
+	require.Equal(t, int64(2), serial())
+
+	// the renewed certificate is served once the files are checked again
+	certPEM, keyPEM = ca.issue(t, "localhost", 4, false)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	renewedAt := now.Add(time.Second)
+	require.NoError(t, os.Chtimes(certFile, renewedAt, renewedAt))
+	require.NoError(t, os.Chtimes(keyFile, renewedAt, renewedAt))
+
+	require.Equal(t, int64(2), serial())
+	now = now.Add(time.Minute)
+	require.Equal(t, int64(4), serial())
+
+	// a broken certificate isn't served, the previous one is kept
+	writeFile(t, certFile, []byte("not a certificate"))
+	now = now.Add(time.Minute)
+	require.Equal(t, int64(4), serial())
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	v, err := ParseTLSVersion("1.3")
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS13), v)
+
+	_, err = ParseTLSVersion("1.0")
+	require.Error(t, err)
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	ids, err := ParseCipherSuites("TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384")
+	require.NoError(t, err)
+	require.Equal(t, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}, ids)
+
+	// the insecure ones are refused
+	_, err = ParseCipherSuites("TLS_RSA_WITH_RC4_128_SHA")
+	require.Error(t, err)
+}
@@ -5,13 +5,6 @@ import (
 	"net/http"
 )
 
-type ErrorResponse struct {
-	Code    string `json:"code,omitempty"`
-	Title   string `json:"title,omitempty"`
-	Trace   string `json:"trace,omitempty"`
-	Message string `json:"message"`
-}
-
 func WriteJSON(w http.ResponseWriter, status int, data interface{}) error {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -0,0 +1,38 @@
+package audit
+
+import "context"
+
+// ActorType is the kind of principal behind a change
+type ActorType string
+
+const (
+	ActorAnonymous ActorType = "anonymous"
+	ActorAPIKey    ActorType = "api_key"
+	ActorUser      ActorType = "user"
+	ActorSystem    ActorType = "system"
+)
+
+// Actor is who made a change, recorded in the audit log along with it
+type Actor struct {
+	Type ActorType
+	ID   string
+}
+
+// Anonymous is the actor of the requests made without credentials
+var Anonymous = Actor{Type: ActorAnonymous, ID: "anonymous"}
+
+type ctxKey struct{}
+
+// WithActor returns a copy of the context carrying the actor
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, ctxKey{}, actor)
+}
+
+// ActorFromContext returns the actor of the context, Anonymous when there is none
+func ActorFromContext(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(ctxKey{}).(Actor); ok {
+		return actor
+	}
+
+	return Anonymous
+}
@@ -0,0 +1,15 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestActorFromContext(t *testing.T) {
+	require.Equal(t, Anonymous, ActorFromContext(context.Background()))
+
+	actor := Actor{Type: ActorAPIKey, ID: "key_1"}
+	require.Equal(t, actor, ActorFromContext(WithActor(context.Background(), actor)))
+}
@@ -0,0 +1,392 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/ratelimit"
+	"github.com/sathishs-dev/pismo-transactions/pkg/audit"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/fieldcrypt"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+)
+
+// Header carries the api key of the request
+const Header = "X-API-Key"
+
+// bearerPrefix starts the Authorization header carrying a customer token
+const bearerPrefix = "Bearer "
+
+const (
+	DefaultCacheTTL = 30 * time.Second
+
+	// maxCachedKeys bounds the cache, the expired keys are swept once it's reached
+	maxCachedKeys = 10000
+	// bootstrapActorID is the actor of the requests made with the bootstrap key
+	bootstrapActorID = "bootstrap"
+)
+
+// customerScopes are granted to the customer tokens, on their own accounts only
+var customerScopes = []enums.Scope{enums.ScopeAccountsRead, enums.ScopeTransactionsWrite}
+
+var (
+	ErrInvalidKey = errors.New("invalid api key")
+	ErrKeyRevoked = errors.New("api key is revoked")
+	ErrKeyExpired = errors.New("api key is expired")
+	// ErrTooManyLookups refuses the keys of a client that had too many of them looked up, they aren't looked up
+	ErrTooManyLookups = errors.New("too many api keys looked up")
+)
+
+type (
+	// Identity is who made a request along with the scopes granted to it, the anonymous identity has none. Owner is
+	// the document number the customers are restricted to, it's empty for the api keys.
+	Identity struct {
+		Actor  audit.Actor
+		Scopes []enums.Scope
+		Owner  string
+	}
+
+	// Authenticator identifies the requests by their api key, or by the bearer token of a customer when it has a
+	// token verifier. The keys are looked up by their hash and cached for a while, so a revoked key is refused within
+	// the cache ttl.
+	Authenticator struct {
+		repo      repository.PismoRepo
+		bootstrap []byte
+		tokens    *TokenVerifier
+		keyring   *fieldcrypt.Keyring
+		cacheTTL  time.Duration
+		now       func() time.Time
+
+		lookups     ratelimit.Store
+		lookupLimit ratelimit.Limit
+
+		mu    sync.Mutex
+		cache map[string]cachedKey
+	}
+
+	// Option - Used to configure the authenticator during initialization
+	Option func(*Authenticator)
+
+	// cachedKey is a looked up key, nil when there is no key with the hash
+	cachedKey struct {
+		key *repository.APIKey
+		at  time.Time
+	}
+
+	// authentication is the outcome of identifying a request, err is set when its credentials were refused
+	authentication struct {
+		identity Identity
+		err      error
+	}
+
+	ctxKey struct{}
+)
+
+// WithBootstrapKey - Will accept the key with every scope, it's meant to create the first api keys
+func WithBootstrapKey(key string) Option {
+	return func(a *Authenticator) {
+		if key != "" {
+			a.bootstrap = HashKey(key)
+		}
+	}
+}
+
+// WithCacheTTL - Will look the keys up again once they are cached for d, 0 looks them up on every request
+func WithCacheTTL(d time.Duration) Option {
+	return func(a *Authenticator) {
+		a.cacheTTL = d
+	}
+}
+
+// WithTokenVerifier - Will accept the bearer tokens of the customers verified by v, their subject is the document
+// number whose accounts they are restricted to. It requires WithKeyring to identify the customers. The subjects
+// aren't mapped from a customer id, the accounts have no customer they belong to other than their document number.
+func WithTokenVerifier(v *TokenVerifier) Option {
+	return func(a *Authenticator) {
+		a.tokens = v
+	}
+}
+
+// WithKeyring - Will identify the customers by a pseudonym of their document number keyed by the keyring
+func WithKeyring(kr *fieldcrypt.Keyring) Option {
+	return func(a *Authenticator) {
+		a.keyring = kr
+	}
+}
+
+// WithLookupLimit - Will limit the keys looked up in the repository per client address, the cached ones aren't
+// counted. The keys of a client over the limit are refused with 429 without being looked up, so a flood of made up
+// keys can't take the db connections.
+func WithLookupLimit(store ratelimit.Store, limit ratelimit.Limit) Option {
+	return func(a *Authenticator) {
+		a.lookups, a.lookupLimit = store, limit
+	}
+}
+
+func New(repo repository.PismoRepo, opts ...Option) *Authenticator {
+	a := &Authenticator{
+		repo:     repo,
+		cacheTTL: DefaultCacheTTL,
+		now:      time.Now,
+		cache:    map[string]cachedKey{},
+	}
+	for _, o := range opts {
+		o(a)
+	}
+
+	return a
+}
+
+// HasScope reports whether the scope is granted to the identity
+func (id Identity) HasScope(scope enums.Scope) bool {
+	return slices.Contains(id.Scopes, scope)
+}
+
+// FromContext returns the identity of the request, false when the requests aren't authenticated
+func FromContext(ctx context.Context) (Identity, bool) {
+	a, ok := ctx.Value(ctxKey{}).(authentication)
+	return a.identity, ok
+}
+
+// Allowed reports whether the request is allowed to use the scope, every scope is allowed when the requests
+// aren't authenticated
+func Allowed(ctx context.Context, scope enums.Scope) bool {
+	id, ok := FromContext(ctx)
+	return !ok || id.HasScope(scope)
+}
+
+// Middleware identifies the request by its api key, or its bearer token, and makes its identity available through
+// FromContext and audit.ActorFromContext. The requests of the customers are restricted to their accounts with
+// repository.WithOwner. It doesn't refuse any request, the routes requiring credentials are guarded by Require and
+// RequireOwned. A nil authenticator leaves the requests unauthenticated.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := authentication{identity: Identity{Actor: audit.Anonymous}}
+		token, bearer := strings.CutPrefix(r.Header.Get("Authorization"), bearerPrefix)
+		switch key := r.Header.Get(Header); {
+		case key != "":
+			res.identity, res.err = a.authenticate(r.Context(), key, clientAddress(r))
+		case bearer && a.tokens != nil:
+			res.identity, res.err = a.authenticateToken(token)
+		}
+
+		ctx := context.WithValue(r.Context(), ctxKey{}, res)
+		if res.err == nil {
+			ctx = audit.WithActor(ctx, res.identity.Actor)
+			if res.identity.Owner != "" {
+				ctx = repository.WithOwner(ctx, res.identity.Owner)
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Require refuses the requests without valid credentials with 401 and the ones missing any of the scopes with 403,
+// the customers too since the route isn't restricted to their accounts. A nil authenticator lets every request
+// through.
+func (a *Authenticator) Require(scopes ...enums.Scope) func(http.Handler) http.Handler {
+	return a.require(false, scopes)
+}
+
+// RequireOwned is Require for the routes whose accounts are looked up through the repository, so the customers
+// only reach their own accounts and get the others as not found
+func (a *Authenticator) RequireOwned(scopes ...enums.Scope) func(http.Handler) http.Handler {
+	return a.require(true, scopes)
+}
+
+func (a *Authenticator) require(owned bool, scopes []enums.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if a == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, _ := r.Context().Value(ctxKey{}).(authentication)
+
+			if errors.Is(res.err, ErrTooManyLookups) {
+				retryAfter := max(1, int(math.Ceil(1/a.lookupLimit.Rate)))
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				_ = problem.Error(w, r, http.StatusTooManyRequests, problem.CodeRateLimited,
+					fmt.Sprintf("too many api keys tried, retry in %d seconds", retryAfter))
+				return
+			}
+
+			if res.err != nil {
+				if !refused(res.err) {
+					log.Error().Err(res.err).Msg("failed to authenticate the request")
+					_ = problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+					return
+				}
+				a.unauthorized(w, r, res.err.Error())
+				return
+			}
+
+			if res.identity.Actor.Type == "" || res.identity.Actor.Type == audit.ActorAnonymous {
+				a.unauthorized(w, r, "credentials are required, send an api key in the "+Header+" header")
+				return
+			}
+
+			if res.identity.Owner != "" && !owned {
+				_ = problem.Error(w, r, http.StatusForbidden, problem.CodeInsufficientScope,
+					"the route isn't available to customer tokens")
+				return
+			}
+
+			for _, scope := range scopes {
+				if !res.identity.HasScope(scope) {
+					_ = problem.Error(w, r, http.StatusForbidden, problem.CodeInsufficientScope,
+						fmt.Sprintf("the %s scope is required", scope))
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// refused reports whether the credentials were refused, the other failures are ours
+func refused(err error) bool {
+	return errors.Is(err, ErrInvalidKey) || errors.Is(err, ErrKeyRevoked) || errors.Is(err, ErrKeyExpired) ||
+		errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrTokenExpired)
+}
+
+// unauthorized answers with a challenge for every kind of credentials accepted
+func (a *Authenticator) unauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", `ApiKey realm="pismo-transactions"`)
+	if a.tokens != nil {
+		w.Header().Add("WWW-Authenticate", `Bearer realm="pismo-transactions"`)
+	}
+	_ = problem.Error(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, detail)
+}
+
+// authenticate returns the identity the key belongs to, client is the address the key is looked up for
+func (a *Authenticator) authenticate(ctx context.Context, key, client string) (Identity, error) {
+	hash := HashKey(key)
+	if a.bootstrap != nil && subtle.ConstantTimeCompare(hash, a.bootstrap) == 1 {
+		return Identity{Actor: audit.Actor{Type: audit.ActorAPIKey, ID: bootstrapActorID}, Scopes: enums.Scopes()}, nil
+	}
+
+	k, err := a.lookup(ctx, hash, client)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	switch {
+	case k == nil:
+		return Identity{}, ErrInvalidKey
+	case k.RevokedAt != nil:
+		return Identity{}, ErrKeyRevoked
+	case k.ExpiresAt != nil && !a.now().Before(*k.ExpiresAt):
+		return Identity{}, ErrKeyExpired
+	}
+
+	id := Identity{Actor: audit.Actor{Type: audit.ActorAPIKey, ID: strconv.Itoa(k.KeyID)}}
+	for _, s := range k.Scopes {
+		// the scopes are validated when the key is created, the ones dropped since aren't granted anymore
+		if scope, err := enums.ParseScope(s); err == nil {
+			id.Scopes = append(id.Scopes, scope)
+		}
+	}
+
+	return id, nil
+}
+
+// authenticateToken returns the identity of the customer the token was issued to, its actor id is a pseudonym of the
+// document number keyed by the keyring, so the audit log, the logs and the rate limit buckets don't carry it and it
+// can't be brute forced out of them without the key
+func (a *Authenticator) authenticateToken(token string) (Identity, error) {
+	claims, err := a.tokens.Verify(token)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	if a.keyring == nil {
+		return Identity{}, errors.New("a keyring is required to identify the customers")
+	}
+
+	return Identity{
+		Actor:  audit.Actor{Type: audit.ActorUser, ID: "customer_" + hex.EncodeToString(a.keyring.Pseudonym(claims.Subject)[:8])},
+		Scopes: customerScopes,
+		Owner:  claims.Subject,
+	}, nil
+}
+
+// lookup returns the key with the hash, from the cache while it's fresh. The lookups of the client in the repository
+// are limited, they are let through when the limiter fails.
+func (a *Authenticator) lookup(ctx context.Context, hash []byte, client string) (*repository.APIKey, error) {
+	now := a.now()
+
+	a.mu.Lock()
+	c, ok := a.cache[string(hash)]
+	a.mu.Unlock()
+	if ok && now.Sub(c.at) < a.cacheTTL {
+		return c.key, nil
+	}
+
+	if a.lookups != nil && a.lookupLimit.Rate > 0 {
+		_, taken, err := a.lookups.Take(ctx, "api_key_lookup "+client, a.lookupLimit)
+		if err != nil {
+			log.Error().Err(err).Str("client", client).Msg("failed to take an api key lookup token")
+		} else if !taken {
+			return nil, ErrTooManyLookups
+		}
+	}
+
+	key, err := a.repo.GetAPIKeyByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.cacheTTL > 0 {
+		a.mu.Lock()
+		if len(a.cache) >= maxCachedKeys {
+			a.sweep(now)
+		}
+		a.cache[string(hash)] = cachedKey{key: key, at: now}
+		a.mu.Unlock()
+	}
+
+	return key, nil
+}
+
+// clientAddress returns the address the request connects from, eg: 10.0.0.1
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// sweep drops the stale keys, and every key when none is stale so the cache stays bounded
+func (a *Authenticator) sweep(now time.Time) {
+	for hash, c := range a.cache {
+		if now.Sub(c.at) >= a.cacheTTL {
+			delete(a.cache, hash)
+		}
+	}
+
+	if len(a.cache) >= maxCachedKeys {
+		clear(a.cache)
+	}
+}
@@ -0,0 +1,340 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/ratelimit"
+	"github.com/sathishs-dev/pismo-transactions/pkg/audit"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/fieldcrypt"
+	"github.com/sathishs-dev/pismo-transactions/pkg/mocks"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequire(t *testing.T) {
+	now := time.Date(2024, 12, 5, 10, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	repo := new(mocks.PismoRepo)
+	a := New(repo, WithBootstrapKey("pk_bootstrap"), WithCacheTTL(0))
+	a.now = func() time.Time { return now }
+
+	router := a.Middleware(a.Require(enums.ScopeAccountsRead)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := audit.ActorFromContext(r.Context())
+		_, _ = w.Write([]byte(string(actor.Type) + ":" + actor.ID))
+	})))
+
+	tcs := []struct {
+		name               string
+		key                string
+		apiKey             *repository.APIKey
+		err                error
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "Test Valid Key",
+			key:                "pk_valid",
+			apiKey:             &repository.APIKey{KeyID: 7, Scopes: pq.StringArray{"accounts:read"}, ExpiresAt: &future},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "api_key:7",
+		},
+		{
+			name:               "Test Bootstrap Key",
+			key:                "pk_bootstrap",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "api_key:bootstrap",
+		},
+		{
+			name:               "Test Missing Key",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedBody:       "credentials are required",
+		},
+		{
+			name:               "Test Unknown Key",
+			key:                "pk_unknown",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedBody:       "invalid api key",
+		},
+		{
+			name:               "Test Revoked Key",
+			key:                "pk_revoked",
+			apiKey:             &repository.APIKey{KeyID: 7, Scopes: pq.StringArray{"accounts:read"}, RevokedAt: &past},
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedBody:       "api key is revoked",
+		},
+		{
+			name:               "Test Expired Key",
+			key:                "pk_expired",
+			apiKey:             &repository.APIKey{KeyID: 7, Scopes: pq.StringArray{"accounts:read"}, ExpiresAt: &now},
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedBody:       "api key is expired",
+		},
+		{
+			name:               "Test Missing Scope",
+			key:                "pk_writer",
+			apiKey:             &repository.APIKey{KeyID: 7, Scopes: pq.StringArray{"accounts:write"}},
+			expectedStatusCode: http.StatusForbidden,
+			expectedBody:       "the accounts:read scope is required",
+		},
+		{
+			name:               "Test Lookup Failure",
+			key:                "pk_valid",
+			err:                errors.New("connection refused"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       "please try again later.",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			repo.ExpectedCalls = nil
+			repo.On("GetAPIKeyByHash", mock.Anything, HashKey(tc.key)).Return(tc.apiKey, tc.err)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/v1/accounts", nil)
+			if tc.key != "" {
+				req.Header.Set(Header, tc.key)
+			}
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.expectedStatusCode, rec.Code)
+			require.Contains(t, rec.Body.String(), tc.expectedBody)
+			if tc.expectedStatusCode == http.StatusUnauthorized {
+				require.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+// testKeyring returns a keyring whose blind index key is made of the byte b
+func testKeyring(t *testing.T, b byte) *fieldcrypt.Keyring {
+	t.Helper()
+
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+	kr, err := fieldcrypt.ParseKeyring([]byte(fmt.Sprintf(`{"primary":"k1","keys":{"k1":%q},"blind_index_key":%q}`, key, key)))
+	require.NoError(t, err)
+	return kr
+}
+
+func TestRequireOwned(t *testing.T) {
+	v, err := NewTokenVerifier(WithHMACSecret(testSecret))
+	require.NoError(t, err)
+	kr := testKeyring(t, 1)
+	a := New(new(mocks.PismoRepo), WithTokenVerifier(v), WithKeyring(kr))
+	customer := "user:customer_" + hex.EncodeToString(kr.Pseudonym("12345678900")[:8])
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		owner, _ := repository.OwnerFromContext(r.Context())
+		actor := audit.ActorFromContext(r.Context())
+		_, _ = w.Write([]byte(string(actor.Type) + ":" + actor.ID + " " + owner))
+	})
+	owned := a.Middleware(a.RequireOwned(enums.ScopeAccountsRead)(next))
+	staff := a.Middleware(a.Require(enums.ScopeAccountsRead)(next))
+
+	valid := signToken(t, map[string]any{"alg": "HS256"}, map[string]any{
+		"sub": "12345678900",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}, testSecret)
+	expired := signToken(t, map[string]any{"alg": "HS256"}, map[string]any{
+		"sub": "12345678900",
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	}, testSecret)
+
+	tcs := []struct {
+		name               string
+		router             http.Handler
+		authorization      string
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "Test Customer Token",
+			router:             owned,
+			authorization:      "Bearer " + valid,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       customer + " 12345678900",
+		},
+		{
+			name:               "Test Customer Token On Staff Route",
+			router:             staff,
+			authorization:      "Bearer " + valid,
+			expectedStatusCode: http.StatusForbidden,
+			expectedBody:       "the route isn't available to customer tokens",
+		},
+		{
+			name:               "Test Expired Token",
+			router:             owned,
+			authorization:      "Bearer " + expired,
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedBody:       "bearer token is expired",
+		},
+		{
+			name:               "Test Invalid Token",
+			router:             owned,
+			authorization:      "Bearer " + valid + "x",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedBody:       "invalid bearer token",
+		},
+		{
+			name:               "Test Other Scheme",
+			router:             owned,
+			authorization:      "Basic dXNlcjpwYXNz",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedBody:       "credentials are required",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/v1/accounts/1", nil)
+			req.Header.Set("Authorization", tc.authorization)
+			tc.router.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.expectedStatusCode, rec.Code)
+			require.Contains(t, rec.Body.String(), tc.expectedBody)
+			if tc.expectedStatusCode == http.StatusOK {
+				// the document number is the owner but never the actor
+				require.NotContains(t, rec.Body.String(), "12345678900 ")
+				require.True(t, strings.HasSuffix(rec.Body.String(), " 12345678900"))
+			}
+			if tc.expectedStatusCode == http.StatusUnauthorized {
+				require.Equal(t, []string{`ApiKey realm="pismo-transactions"`, `Bearer realm="pismo-transactions"`},
+					rec.Header().Values("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestLookupCache(t *testing.T) {
+	now := time.Date(2024, 12, 5, 10, 0, 0, 0, time.UTC)
+
+	repo := new(mocks.PismoRepo)
+	a := New(repo, WithCacheTTL(time.Minute))
+	a.now = func() time.Time { return now }
+
+	key := &repository.APIKey{KeyID: 7, Scopes: pq.StringArray{"accounts:read", "dropped:scope"}}
+	repo.On("GetAPIKeyByHash", mock.Anything, HashKey("pk_valid")).Return(key, nil).Once()
+
+	for range 2 {
+		id, err := a.authenticate(context.Background(), "pk_valid", "192.0.2.1")
+		require.NoError(t, err)
+		require.Equal(t, Identity{
+			Actor:  audit.Actor{Type: audit.ActorAPIKey, ID: "7"},
+			Scopes: []enums.Scope{enums.ScopeAccountsRead},
+		}, id)
+	}
+
+	// the revocation is seen once the cached key is stale
+	revokedAt := now
+	repo.On("GetAPIKeyByHash", mock.Anything, HashKey("pk_valid")).
+		Return(&repository.APIKey{KeyID: 7, RevokedAt: &revokedAt}, nil).Once()
+
+	now = now.Add(time.Minute)
+	_, err := a.authenticate(context.Background(), "pk_valid", "192.0.2.1")
+	require.ErrorIs(t, err, ErrKeyRevoked)
+
+	repo.AssertExpectations(t)
+}
+
+func TestCustomerActor(t *testing.T) {
+	v, err := NewTokenVerifier(WithHMACSecret(testSecret))
+	require.NoError(t, err)
+	token := signToken(t, map[string]any{"alg": "HS256"}, map[string]any{
+		"sub": "12345678900",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}, testSecret)
+
+	// the actor id depends on the key, it can't be derived from the document number alone
+	id, err := New(nil, WithTokenVerifier(v), WithKeyring(testKeyring(t, 1))).authenticateToken(token)
+	require.NoError(t, err)
+	other, err := New(nil, WithTokenVerifier(v), WithKeyring(testKeyring(t, 2))).authenticateToken(token)
+	require.NoError(t, err)
+	require.NotEqual(t, id.Actor, other.Actor)
+	require.NotContains(t, id.Actor.ID, "12345678900")
+
+	_, err = New(nil, WithTokenVerifier(v)).authenticateToken(token)
+	require.Error(t, err)
+}
+
+func TestLookupLimit(t *testing.T) {
+	repo := new(mocks.PismoRepo)
+	a := New(repo, WithLookupLimit(ratelimit.NewMemoryStore(), ratelimit.Limit{Rate: 0.01, Burst: 3}))
+	router := a.Middleware(a.Require(enums.ScopeAccountsRead)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	repo.On("GetAPIKeyByHash", mock.Anything, mock.Anything).Return(nil, nil).Times(4)
+
+	send := func(key, addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(Header, key)
+		req.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := range 3 {
+		require.Equal(t, http.StatusUnauthorized, send(fmt.Sprintf("pk_made_up_%d", i), "192.0.2.1:4000").Code)
+	}
+
+	// the made up keys past the burst don't reach the repository anymore
+	for i := range 3 {
+		w := send(fmt.Sprintf("pk_made_up_%d", i+3), "192.0.2.1:4001")
+		require.Equal(t, http.StatusTooManyRequests, w.Code)
+		require.Equal(t, "100", w.Header().Get("Retry-After"))
+	}
+
+	// the cached ones are still answered, and the other clients are looked up
+	require.Equal(t, http.StatusUnauthorized, send("pk_made_up_0", "192.0.2.1:4002").Code)
+	require.Equal(t, http.StatusUnauthorized, send("pk_made_up_9", "192.0.2.2:4000").Code)
+
+	repo.AssertExpectations(t)
+}
+
+func TestAllowed(t *testing.T) {
+	var a *Authenticator
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.True(t, Allowed(r.Context(), enums.ScopeAPIKeysWrite), "every scope is allowed without authentication")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	rec := httptest.NewRecorder()
+	a.Middleware(a.Require(enums.ScopeAPIKeysWrite)(next)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/api-keys", nil))
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	a = New(new(mocks.PismoRepo), WithBootstrapKey("pk_bootstrap"))
+	next = func(w http.ResponseWriter, r *http.Request) {
+		require.True(t, Allowed(r.Context(), enums.ScopeAPIKeysWrite))
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/api-keys", nil)
+	req.Header.Set(Header, "pk_bootstrap")
+	a.Middleware(next).ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestGenerateKey(t *testing.T) {
+	key, err := GenerateKey()
+	require.NoError(t, err)
+	require.Len(t, key, len(keyPrefix)+64)
+	require.Equal(t, key[:11], DisplayPrefix(key))
+	require.Len(t, HashKey(key), 32)
+}
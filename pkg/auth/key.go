@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const (
+	// keyPrefix tells the api keys apart from other secrets, eg: in leaked credentials scans
+	keyPrefix = "pk_"
+	// displayLen is the length of the start of a key kept to tell the keys apart
+	displayLen = len(keyPrefix) + 8
+)
+
+// GenerateKey returns a random api key, it's only shown to the partner once and stored as its hash
+func GenerateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return keyPrefix + hex.EncodeToString(b), nil
+}
+
+// HashKey returns the hash the key is stored and looked up by, the keys are random so a plain sha-256 is enough
+func HashKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+// DisplayPrefix returns the start of the key shown along with the key once it's stored
+func DisplayPrefix(key string) string {
+	if len(key) < displayLen {
+		return key
+	}
+
+	return key[:displayLen]
+}
@@ -0,0 +1,261 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxTokenTTL bounds how long the tokens are valid for from their iat, they are meant to be short lived
+	DefaultMaxTokenTTL = 15 * time.Minute
+
+	// tokenLeeway absorbs the clock skew between the issuer and us
+	tokenLeeway = 30 * time.Second
+	// minHMACSecretLen is the size of the sha256 output, shorter secrets can be brute forced from a token
+	minHMACSecretLen = 32
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid bearer token")
+	ErrTokenExpired = errors.New("bearer token is expired")
+)
+
+type (
+	// Claims are the registered claims of a token the verifier relies on
+	Claims struct {
+		Subject   string   `json:"sub"`
+		Issuer    string   `json:"iss,omitempty"`
+		Audience  Audience `json:"aud,omitempty"`
+		ExpiresAt int64    `json:"exp"`
+		NotBefore int64    `json:"nbf,omitempty"`
+		IssuedAt  int64    `json:"iat,omitempty"`
+	}
+
+	// Audience is the aud claim, either a single string or an array of them
+	Audience []string
+
+	// TokenVerifier verifies the HS256 and RS256 tokens signed with the local keys, only the algorithms it has keys
+	// for are accepted so a token can't pick the one it's verified with
+	TokenVerifier struct {
+		secret   []byte
+		keys     map[string]*rsa.PublicKey
+		issuer   string
+		audience string
+		maxTTL   time.Duration
+		now      func() time.Time
+	}
+
+	// TokenOption - Used to configure the token verifier during initialization
+	TokenOption func(*TokenVerifier)
+
+	tokenHeader struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid,omitempty"`
+	}
+)
+
+// WithHMACSecret - Will accept the HS256 tokens signed with the secret
+func WithHMACSecret(secret []byte) TokenOption {
+	return func(v *TokenVerifier) {
+		v.secret = secret
+	}
+}
+
+// WithRSAPublicKey - Will accept the RS256 tokens signed by the key, kid is the key id the tokens carry in their
+// header, the tokens with an unknown or no kid are checked against every key
+func WithRSAPublicKey(kid string, key *rsa.PublicKey) TokenOption {
+	return func(v *TokenVerifier) {
+		v.keys[kid] = key
+	}
+}
+
+// WithIssuer - Will only accept the tokens whose iss is the issuer
+func WithIssuer(issuer string) TokenOption {
+	return func(v *TokenVerifier) {
+		v.issuer = issuer
+	}
+}
+
+// WithAudience - Will only accept the tokens whose aud contains the audience
+func WithAudience(audience string) TokenOption {
+	return func(v *TokenVerifier) {
+		v.audience = audience
+	}
+}
+
+// WithMaxTokenTTL - Will refuse the tokens valid for longer than d
+func WithMaxTokenTTL(d time.Duration) TokenOption {
+	return func(v *TokenVerifier) {
+		v.maxTTL = d
+	}
+}
+
+// NewTokenVerifier returns a verifier of the tokens signed with the keys given, at least one is required
+func NewTokenVerifier(opts ...TokenOption) (*TokenVerifier, error) {
+	v := &TokenVerifier{
+		keys:   map[string]*rsa.PublicKey{},
+		maxTTL: DefaultMaxTokenTTL,
+		now:    time.Now,
+	}
+	for _, o := range opts {
+		o(v)
+	}
+
+	switch {
+	case v.secret == nil && len(v.keys) == 0:
+		return nil, errors.New("a hmac secret or a rsa public key is required to verify the tokens")
+	case v.secret != nil && len(v.secret) < minHMACSecretLen:
+		return nil, fmt.Errorf("the hmac secret must be at least %d bytes", minHMACSecretLen)
+	}
+
+	return v, nil
+}
+
+// ParseRSAPublicKey parses a PEM encoded rsa public key, either PKIX or PKCS#1
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the public key: %w", err)
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%T isn't a rsa public key", key)
+	}
+
+	return rsaKey, nil
+}
+
+// Verify checks the signature of the token and its claims, the token must expire, within the max ttl of its iat
+// when it has one, and have a subject. An iat in the future is refused.
+func (v *TokenVerifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Claims{}, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+
+	if !v.verifySignature(header, parts[0]+"."+parts[1], sig) {
+		return Claims{}, fmt.Errorf("%w: signature doesn't match", ErrInvalidToken)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+
+	if err := v.checkClaims(claims); err != nil {
+		return Claims{}, err
+	}
+
+	return claims, nil
+}
+
+// verifySignature reports whether the signature of the signing input is valid for the algorithm of the header
+func (v *TokenVerifier) verifySignature(header tokenHeader, input string, sig []byte) bool {
+	switch header.Alg {
+	case "HS256":
+		if v.secret == nil {
+			return false
+		}
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write([]byte(input))
+		return hmac.Equal(sig, mac.Sum(nil))
+	case "RS256":
+		digest := sha256.Sum256([]byte(input))
+		if key, ok := v.keys[header.Kid]; ok && header.Kid != "" {
+			return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil
+		}
+		for _, key := range v.keys {
+			if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (v *TokenVerifier) checkClaims(c Claims) error {
+	now := v.now()
+
+	switch {
+	case c.Subject == "":
+		return fmt.Errorf("%w: the sub claim is required", ErrInvalidToken)
+	case c.ExpiresAt == 0:
+		return fmt.Errorf("%w: the exp claim is required", ErrInvalidToken)
+	case !now.Before(time.Unix(c.ExpiresAt, 0).Add(tokenLeeway)):
+		return ErrTokenExpired
+	case c.NotBefore != 0 && now.Add(tokenLeeway).Before(time.Unix(c.NotBefore, 0)):
+		return fmt.Errorf("%w: the token isn't valid yet", ErrInvalidToken)
+	// the max ttl is measured from the iat, one in the future would stretch the validity as far as the issuer likes
+	case c.IssuedAt != 0 && now.Add(tokenLeeway).Before(time.Unix(c.IssuedAt, 0)):
+		return fmt.Errorf("%w: the token is issued in the future", ErrInvalidToken)
+	case c.IssuedAt != 0 && time.Unix(c.ExpiresAt, 0).Sub(time.Unix(c.IssuedAt, 0)) > v.maxTTL:
+		return fmt.Errorf("%w: the token is valid for longer than %s", ErrInvalidToken, v.maxTTL)
+	// without an iat the token can't be valid for longer than the max ttl from now on
+	case c.IssuedAt == 0 && time.Unix(c.ExpiresAt, 0).Sub(now) > v.maxTTL:
+		return fmt.Errorf("%w: the token is valid for longer than %s", ErrInvalidToken, v.maxTTL)
+	case v.issuer != "" && c.Issuer != v.issuer:
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	case v.audience != "" && !slices.Contains(c.Audience, v.audience):
+		return fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+
+	return nil
+}
+
+// UnmarshalJSON accepts both forms of the aud claim
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+
+	return nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// signToken returns the token of the claims signed with the key, a []byte secret for HS256 and a rsa key for RS256
+func signToken(t *testing.T, header map[string]any, claims map[string]any, key any) string {
+	t.Helper()
+
+	h, err := json.Marshal(header)
+	require.NoError(t, err)
+	c, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	input := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(input))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(input))
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	}
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerifyToken(t *testing.T) {
+	now := time.Date(2024, 12, 5, 10, 0, 0, 0, time.UTC)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	v, err := NewTokenVerifier(
+		WithHMACSecret(testSecret),
+		WithRSAPublicKey("mobile-1", &rsaKey.PublicKey),
+		WithIssuer("https://id.pismo.io"),
+		WithAudience("pismo-transactions"),
+	)
+	require.NoError(t, err)
+	v.now = func() time.Time { return now }
+
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"sub": "12345678900",
+			"iss": "https://id.pismo.io",
+			"aud": []string{"pismo-transactions", "other"},
+			"iat": now.Unix(),
+			"exp": now.Add(5 * time.Minute).Unix(),
+		}
+		for k, val := range overrides {
+			if val == nil {
+				delete(c, k)
+				continue
+			}
+			c[k] = val
+		}
+		return c
+	}
+	hs256 := map[string]any{"alg": "HS256", "typ": "JWT"}
+	rs256 := map[string]any{"alg": "RS256", "typ": "JWT", "kid": "mobile-1"}
+
+	tcs := []struct {
+		name          string
+		token         string
+		expectedError error
+	}{
+		{
+			name:  "Test HS256",
+			token: signToken(t, hs256, claims(nil), testSecret),
+		},
+		{
+			name:  "Test RS256",
+			token: signToken(t, rs256, claims(map[string]any{"aud": "pismo-transactions"}), rsaKey),
+		},
+		{
+			name:  "Test RS256 Without Kid",
+			token: signToken(t, map[string]any{"alg": "RS256"}, claims(nil), rsaKey),
+		},
+		{
+			name:          "Test RS256 Unknown Key",
+			token:         signToken(t, rs256, claims(nil), otherKey),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "Test Wrong Secret",
+			token:         signToken(t, hs256, claims(nil), []byte("fedcba9876543210fedcba9876543210")),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "Test Unsigned",
+			token:         signToken(t, map[string]any{"alg": "none"}, claims(nil), nil),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "Test Tampered Claims",
+			token:         signToken(t, hs256, claims(nil), testSecret)[:10] + "x" + signToken(t, hs256, claims(nil), testSecret)[11:],
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "Test Expired",
+			token:         signToken(t, hs256, claims(map[string]any{"iat": now.Add(-10 * time.Minute).Unix(), "exp": now.Add(-time.Minute).Unix()}), testSecret),
+			expectedError: ErrTokenExpired,
+		},
+		{
+			name:          "Test Missing Exp",
+			token:         signToken(t, hs256, claims(map[string]any{"exp": nil}), testSecret),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "Test Missing Subject",
+			token:         signToken(t, hs256, claims(map[string]any{"sub": nil}), testSecret),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "Test Not Yet Valid",
+			token:         signToken(t, hs256, claims(map[string]any{"nbf": now.Add(time.Minute).Unix()}), testSecret),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "Test Too Long Lived",
+			token:         signToken(t, hs256, claims(map[string]any{"exp": now.Add(time.Hour).Unix()}), testSecret),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "Test Too Long Lived Without Iat",
+			token:         signToken(t, hs256, claims(map[string]any{"iat": nil, "exp": now.Add(time.Hour).Unix()}), testSecret),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "Test Issued In The Future",
+			token:         signToken(t, hs256, claims(map[string]any{"iat": now.Add(time.Hour).Unix(), "exp": now.Add(time.Hour + time.Minute).Unix()}), testSecret),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:  "Test Issued Within The Leeway",
+			token: signToken(t, hs256, claims(map[string]any{"iat": now.Add(10 * time.Second).Unix()}), testSecret),
+		},
+		{
+			name:          "Test Wrong Issuer",
+			token:         signToken(t, hs256, claims(map[string]any{"iss": "https://evil.example"}), testSecret),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "Test Wrong Audience",
+			token:         signToken(t, hs256, claims(map[string]any{"aud": "other"}), testSecret),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "Test Malformed",
+			token:         "not-a-token",
+			expectedError: ErrInvalidToken,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := v.Verify(tc.token)
+			if tc.expectedError != nil {
+				require.ErrorIs(t, err, tc.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, "12345678900", c.Subject)
+		})
+	}
+}
+
+func TestVerifyTokenAlgorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	// without a secret the HS256 tokens are refused, even when signed with the public key
+	v, err := NewTokenVerifier(WithRSAPublicKey("", &rsaKey.PublicKey))
+	require.NoError(t, err)
+
+	pub, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	claims := map[string]any{"sub": "12345678900", "exp": time.Now().Add(time.Minute).Unix()}
+
+	_, err = v.Verify(signToken(t, map[string]any{"alg": "HS256"}, claims, pub))
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = NewTokenVerifier()
+	require.Error(t, err)
+
+	_, err = NewTokenVerifier(WithHMACSecret([]byte("short")))
+	require.Error(t, err)
+}
+
+func TestParseRSAPublicKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	pkix, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+
+	for _, block := range []*pem.Block{
+		{Type: "PUBLIC KEY", Bytes: pkix},
+		{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)},
+	} {
+		key, err := ParseRSAPublicKey(pem.EncodeToMemory(block))
+		require.NoError(t, err)
+		require.True(t, rsaKey.PublicKey.Equal(key))
+	}
+
+	_, err = ParseRSAPublicKey([]byte("not pem"))
+	require.Error(t, err)
+}
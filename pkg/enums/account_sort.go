@@ -0,0 +1,22 @@
+package enums
+
+import "fmt"
+
+// AccountSort is the order of the account listing, the - prefix sorts descending
+type AccountSort string
+
+const (
+	SortAccountID          AccountSort = "account_id"
+	SortAccountIDDesc      AccountSort = "-account_id"
+	SortAccountCreated     AccountSort = "created_at"
+	SortAccountCreatedDesc AccountSort = "-created_at"
+)
+
+func ParseAccountSort(s string) (AccountSort, error) {
+	switch AccountSort(s) {
+	case SortAccountID, SortAccountIDDesc, SortAccountCreated, SortAccountCreatedDesc:
+		return AccountSort(s), nil
+	}
+
+	return "", fmt.Errorf("%q is not a valid account sort", s)
+}
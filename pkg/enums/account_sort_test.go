@@ -0,0 +1,39 @@
+package enums
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAccountSort(t *testing.T) {
+	tcs := []struct {
+		name         string
+		sort         string
+		expectedEnum AccountSort
+		expectedErr  bool
+	}{
+		{
+			name:         "Test ParseAccountSort_Success",
+			sort:         "-created_at",
+			expectedEnum: SortAccountCreatedDesc,
+		},
+		{
+			name:        "Test ParseAccountSort_Failure",
+			sort:        "document_number",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := ParseAccountSort(tc.sort)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedEnum, s)
+		})
+	}
+}
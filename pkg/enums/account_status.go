@@ -0,0 +1,23 @@
+package enums
+
+import "fmt"
+
+type AccountStatus string
+
+const (
+	// AccountActive the account takes transactions
+	AccountActive AccountStatus = "active"
+	// AccountClosed the account is closed for good, erased accounts are closed
+	AccountClosed AccountStatus = "closed"
+)
+
+func ParseAccountStatus(s string) (AccountStatus, error) {
+	switch AccountStatus(s) {
+	case AccountActive:
+		return AccountActive, nil
+	case AccountClosed:
+		return AccountClosed, nil
+	}
+
+	return "", fmt.Errorf("%q is not a valid account status", s)
+}
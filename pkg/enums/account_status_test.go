@@ -0,0 +1,39 @@
+package enums
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAccountStatus(t *testing.T) {
+	tcs := []struct {
+		name         string
+		status       string
+		expectedEnum AccountStatus
+		expectedErr  bool
+	}{
+		{
+			name:         "Test ParseAccountStatus_Success",
+			status:       "closed",
+			expectedEnum: AccountClosed,
+		},
+		{
+			name:        "Test ParseAccountStatus_Failure",
+			status:      "frozen",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := ParseAccountStatus(tc.status)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedEnum, s)
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package enums
+
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+	AuditEnable AuditAction = "enable"
+	AuditReplay AuditAction = "replay"
+	AuditErase  AuditAction = "erase"
+	AuditRevoke AuditAction = "revoke"
+)
@@ -0,0 +1,33 @@
+package enums
+
+import "fmt"
+
+// BatchMode is how the transactions of a batch are created
+type BatchMode string
+
+const (
+	// BatchAtomic creates every transaction of the batch or none of them
+	BatchAtomic BatchMode = "atomic"
+	// BatchPartial creates the transactions of the batch that can be created and reports the other ones
+	BatchPartial BatchMode = "partial"
+)
+
+// ParseBatchMode parses the mode of a batch, atomic when it's empty
+func ParseBatchMode(s string) (BatchMode, error) {
+	switch BatchMode(s) {
+	case "", BatchAtomic:
+		return BatchAtomic, nil
+	case BatchPartial:
+		return BatchPartial, nil
+	}
+
+	return "", fmt.Errorf("%q is not a valid batch mode", s)
+}
+
+// BatchItemStatus is the outcome of a transaction of a batch
+type BatchItemStatus string
+
+const (
+	BatchItemCreated BatchItemStatus = "created"
+	BatchItemFailed  BatchItemStatus = "failed"
+)
@@ -0,0 +1,43 @@
+package enums
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBatchMode(t *testing.T) {
+	tcs := []struct {
+		name         string
+		mode         string
+		expectedEnum BatchMode
+		expectedErr  bool
+	}{
+		{
+			name:         "Test ParseBatchMode_Success",
+			mode:         "partial",
+			expectedEnum: BatchPartial,
+		},
+		{
+			name:         "Test ParseBatchMode_Default",
+			expectedEnum: BatchAtomic,
+		},
+		{
+			name:        "Test ParseBatchMode_Failure",
+			mode:        "best_effort",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := ParseBatchMode(tc.mode)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedEnum, m)
+		})
+	}
+}
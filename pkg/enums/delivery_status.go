@@ -0,0 +1,12 @@
+package enums
+
+type DeliveryStatus string
+
+const (
+	// DeliveryPending the delivery is waiting for its next attempt
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliveryDelivered the endpoint acknowledged the delivery
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryDead the delivery ran out of attempts and sits in the dead letters until it's replayed
+	DeliveryDead DeliveryStatus = "dead"
+)
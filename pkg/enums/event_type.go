@@ -0,0 +1,24 @@
+package enums
+
+import "fmt"
+
+type EventType string
+
+const (
+	AccountCreated     EventType = "account.created"
+	AccountErased      EventType = "account.erased"
+	TransactionCreated EventType = "transaction.created"
+)
+
+func ParseEventType(s string) (EventType, error) {
+	switch EventType(s) {
+	case AccountCreated:
+		return AccountCreated, nil
+	case AccountErased:
+		return AccountErased, nil
+	case TransactionCreated:
+		return TransactionCreated, nil
+	}
+
+	return "", fmt.Errorf("%q is not a valid event type", s)
+}
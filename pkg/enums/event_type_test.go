@@ -0,0 +1,39 @@
+package enums
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEventType(t *testing.T) {
+	tcs := []struct {
+		name         string
+		eventType    string
+		expectedEnum EventType
+		expectedErr  bool
+	}{
+		{
+			name:         "Test ParseEventType_Success",
+			eventType:    "transaction.created",
+			expectedEnum: TransactionCreated,
+		},
+		{
+			name:        "Test ParseEventType_Failure",
+			eventType:   "transaction.deleted",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			et, err := ParseEventType(tc.eventType)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedEnum, et)
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package enums
+
+type ReconciliationStatus string
+
+const (
+	// Matched the settlement line has a ledger transaction with the same amount
+	Matched ReconciliationStatus = "matched"
+	// MissingInLedger the settlement line has no ledger transaction
+	MissingInLedger ReconciliationStatus = "missing_in_ledger"
+	// MissingInFile the ledger transaction has no settlement line
+	MissingInFile ReconciliationStatus = "missing_in_file"
+	// AmountMismatch the settlement line and the ledger transaction share the reference but not the amount
+	AmountMismatch ReconciliationStatus = "amount_mismatch"
+)
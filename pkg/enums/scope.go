@@ -0,0 +1,47 @@
+package enums
+
+import "fmt"
+
+// Scope is a permission granted to an api key, every route requires one of them
+type Scope string
+
+const (
+	ScopeAccountsRead         Scope = "accounts:read"
+	ScopeAccountsWrite        Scope = "accounts:write"
+	ScopeTransactionsWrite    Scope = "transactions:write"
+	ScopeReconciliationsRead  Scope = "reconciliations:read"
+	ScopeReconciliationsWrite Scope = "reconciliations:write"
+	ScopeWebhooksRead         Scope = "webhooks:read"
+	ScopeWebhooksWrite        Scope = "webhooks:write"
+	ScopeAuditRead            Scope = "audit:read"
+	ScopeAPIKeysRead          Scope = "api_keys:read"
+	ScopeAPIKeysWrite         Scope = "api_keys:write"
+	ScopeMetricsRead          Scope = "metrics:read"
+)
+
+// Scopes returns every scope
+func Scopes() []Scope {
+	return []Scope{
+		ScopeAccountsRead,
+		ScopeAccountsWrite,
+		ScopeTransactionsWrite,
+		ScopeReconciliationsRead,
+		ScopeReconciliationsWrite,
+		ScopeWebhooksRead,
+		ScopeWebhooksWrite,
+		ScopeAuditRead,
+		ScopeAPIKeysRead,
+		ScopeAPIKeysWrite,
+		ScopeMetricsRead,
+	}
+}
+
+func ParseScope(s string) (Scope, error) {
+	for _, scope := range Scopes() {
+		if Scope(s) == scope {
+			return scope, nil
+		}
+	}
+
+	return "", fmt.Errorf("%q is not a valid scope", s)
+}
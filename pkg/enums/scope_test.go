@@ -0,0 +1,39 @@
+package enums
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScope(t *testing.T) {
+	tcs := []struct {
+		name         string
+		scope        string
+		expectedEnum Scope
+		expectedErr  bool
+	}{
+		{
+			name:         "Test ParseScope_Success",
+			scope:        "transactions:write",
+			expectedEnum: ScopeTransactionsWrite,
+		},
+		{
+			name:        "Test ParseScope_Failure",
+			scope:       "transactions:delete",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := ParseScope(tc.scope)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedEnum, s)
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	keySize     = 32
+	maxKeyIDLen = 64
+
+	// envelopeVersion is the first byte of every envelope, bumped on format changes
+	envelopeVersion byte = 1
+)
+
+var (
+	ErrUnknownKey      = errors.New("envelope encrypted with a key missing from the keyring")
+	ErrInvalidEnvelope = errors.New("invalid envelope")
+)
+
+type (
+	// Keyring holds the key encryption keys and the blind index key, values are encrypted under the primary key
+	// and decrypted with whichever key they were encrypted under, so older keys stay in the keyring until every
+	// value has been rotated to the primary one
+	Keyring struct {
+		primary  string
+		keys     map[string]cipher.AEAD
+		indexKey []byte
+	}
+
+	// keyringFile is the json representation of the keyring, keys are base64 encoded 32 byte keys
+	keyringFile struct {
+		Primary       string            `json:"primary"`
+		Keys          map[string]string `json:"keys"`
+		BlindIndexKey string            `json:"blind_index_key"`
+	}
+)
+
+// ParseKeyring parses the json keyring,
+//
+//	{"primary": "2024-12", "keys": {"2024-06": "<base64>", "2024-12": "<base64>"}, "blind_index_key": "<base64>"}
+//
+// the blind index key can't be rotated as the index of every value would change along with it
+func ParseKeyring(data []byte) (*Keyring, error) {
+	var f keyringFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid keyring: %w", err)
+	}
+
+	if _, ok := f.Keys[f.Primary]; !ok {
+		return nil, fmt.Errorf("invalid keyring: primary key %q not found", f.Primary)
+	}
+
+	kr := &Keyring{primary: f.Primary, keys: map[string]cipher.AEAD{}}
+
+	for id, v := range f.Keys {
+		if id == "" || len(id) > maxKeyIDLen {
+			return nil, fmt.Errorf("invalid keyring: key id %q must have 1 to %d characters", id, maxKeyIDLen)
+		}
+
+		key, err := decodeKey(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keyring: key %q: %w", id, err)
+		}
+
+		if kr.keys[id], err = newAEAD(key); err != nil {
+			return nil, fmt.Errorf("invalid keyring: key %q: %w", id, err)
+		}
+	}
+
+	var err error
+	if kr.indexKey, err = decodeKey(f.BlindIndexKey); err != nil {
+		return nil, fmt.Errorf("invalid keyring: blind_index_key: %w", err)
+	}
+
+	return kr, nil
+}
+
+// LoadKeyringFile reads and parses the keyring file
+func LoadKeyringFile(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+
+	return ParseKeyring(data)
+}
+
+// PrimaryKeyID returns the id of the key new values are encrypted under
+func (kr *Keyring) PrimaryKeyID() string {
+	return kr.primary
+}
+
+// Encrypt seals the plaintext under a fresh data key wrapped with the primary key, aad binds the envelope to
+// where it's stored so it can't be moved elsewhere
+func (kr *Keyring) Encrypt(plaintext, aad []byte) (envelope []byte, keyID string, err error) {
+	dek := make([]byte, keySize)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, "", err
+	}
+
+	dataAEAD, err := newAEAD(dek)
+	if err != nil {
+		return nil, "", err
+	}
+
+	envelope = []byte{envelopeVersion, byte(len(kr.primary))}
+	envelope = append(envelope, kr.primary...)
+
+	if envelope, err = seal(kr.keys[kr.primary], envelope, dek, []byte(kr.primary)); err != nil {
+		return nil, "", err
+	}
+
+	if envelope, err = seal(dataAEAD, envelope, plaintext, aad); err != nil {
+		return nil, "", err
+	}
+
+	return envelope, kr.primary, nil
+}
+
+// Decrypt opens the envelope with the key it was encrypted under
+func (kr *Keyring) Decrypt(envelope, aad []byte) ([]byte, error) {
+	_, dek, data, err := kr.unwrap(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	dataAEAD, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := open(dataAEAD, data, aad)
+	if err != nil {
+		return nil, ErrInvalidEnvelope
+	}
+
+	return plaintext, nil
+}
+
+// Rewrap re-wraps the data key of the envelope with the primary key, the encrypted value itself is left untouched
+func (kr *Keyring) Rewrap(envelope []byte) (rewrapped []byte, keyID string, err error) {
+	keyID, dek, data, err := kr.unwrap(envelope)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if keyID == kr.primary {
+		return envelope, keyID, nil
+	}
+
+	rewrapped = []byte{envelopeVersion, byte(len(kr.primary))}
+	rewrapped = append(rewrapped, kr.primary...)
+
+	if rewrapped, err = seal(kr.keys[kr.primary], rewrapped, dek, []byte(kr.primary)); err != nil {
+		return nil, "", err
+	}
+
+	return append(rewrapped, data...), kr.primary, nil
+}
+
+// BlindIndex returns the deterministic HMAC-SHA256 of the value, equal values have equal indexes so it can be
+// looked up and kept unique without decrypting
+func (kr *Keyring) BlindIndex(value string) []byte {
+	m := hmac.New(sha256.New, kr.indexKey)
+	m.Write([]byte(value))
+	return m.Sum(nil)
+}
+
+// PrefixIndexes returns the blind index of every prefix of the value from min up to max characters long, any of
+// those prefixes can then be looked up with PrefixIndex. Rows sharing a prefix share its index, which is what's
+// given away to make the prefix searchable.
+func (kr *Keyring) PrefixIndexes(value string, min, max int) [][]byte {
+	runes := []rune(value)
+
+	var indexes [][]byte
+	for n := min; n <= len(runes) && n <= max; n++ {
+		indexes = append(indexes, kr.PrefixIndex(string(runes[:n])))
+	}
+
+	return indexes
+}
+
+// PrefixIndex returns the blind index of the prefix, it's kept apart from BlindIndex so a full value and its
+// prefix index can't be matched against each other
+func (kr *Keyring) PrefixIndex(prefix string) []byte {
+	m := hmac.New(sha256.New, kr.indexKey)
+	m.Write([]byte("prefix:"))
+	m.Write([]byte(prefix))
+	return m.Sum(nil)
+}
+
+// Pseudonym returns the keyed HMAC-SHA256 of the value that stands for it where it's written out, eg: in the logs,
+// it's kept apart from BlindIndex so it can't be matched against the stored indexes
+func (kr *Keyring) Pseudonym(value string) []byte {
+	m := hmac.New(sha256.New, kr.indexKey)
+	m.Write([]byte("pseudonym:"))
+	m.Write([]byte(value))
+	return m.Sum(nil)
+}
+
+// unwrap parses the envelope and returns its key id, data key and the sealed data
+func (kr *Keyring) unwrap(envelope []byte) (keyID string, dek, data []byte, err error) {
+	if len(envelope) < 2 || envelope[0] != envelopeVersion || len(envelope) < 2+int(envelope[1]) {
+		return "", nil, nil, ErrInvalidEnvelope
+	}
+
+	keyID = string(envelope[2 : 2+int(envelope[1])])
+	rest := envelope[2+int(envelope[1]):]
+
+	kek, ok := kr.keys[keyID]
+	if !ok {
+		return "", nil, nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+
+	wrappedLen := kek.NonceSize() + keySize + kek.Overhead()
+	if len(rest) < wrappedLen {
+		return "", nil, nil, ErrInvalidEnvelope
+	}
+
+	if dek, err = open(kek, rest[:wrappedLen], []byte(keyID)); err != nil {
+		return "", nil, nil, ErrInvalidEnvelope
+	}
+
+	return keyID, dek, rest[wrappedLen:], nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal appends the nonce and the sealed plaintext to dst
+func seal(aead cipher.AEAD, dst, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	dst = append(dst, nonce...)
+	return aead.Seal(dst, nonce, plaintext, aad), nil
+}
+
+// open opens the nonce prefixed sealed data
+func open(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrInvalidEnvelope
+	}
+
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], aad)
+}
+
+func decodeKey(v string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(key) != keySize {
+		return nil, fmt.Errorf("expected a %d byte key, got %d", keySize, len(key))
+	}
+
+	return key, nil
+}
@@ -0,0 +1,182 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, keySize))
+}
+
+func testKeyring(t *testing.T, primary string, ids ...string) *Keyring {
+	t.Helper()
+
+	// the key material follows the id so the same key is shared across keyrings
+	keys := ""
+	for i, id := range ids {
+		if i > 0 {
+			keys += ","
+		}
+		keys += fmt.Sprintf("%q:%q", id, testKey(id[len(id)-1]))
+	}
+
+	kr, err := ParseKeyring([]byte(fmt.Sprintf(`{"primary":%q,"keys":{%s},"blind_index_key":%q}`, primary, keys, testKey(0xff))))
+	require.NoError(t, err)
+	return kr
+}
+
+func TestParseKeyring(t *testing.T) {
+	testCases := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{
+			name: "valid keyring",
+			data: fmt.Sprintf(`{"primary":"k1","keys":{"k1":%q},"blind_index_key":%q}`, testKey(1), testKey(2)),
+		},
+		{
+			name:    "invalid json",
+			data:    `{"primary":`,
+			wantErr: true,
+		},
+		{
+			name:    "primary key missing",
+			data:    fmt.Sprintf(`{"primary":"k2","keys":{"k1":%q},"blind_index_key":%q}`, testKey(1), testKey(2)),
+			wantErr: true,
+		},
+		{
+			name:    "short key",
+			data:    fmt.Sprintf(`{"primary":"k1","keys":{"k1":"c2hvcnQ="},"blind_index_key":%q}`, testKey(2)),
+			wantErr: true,
+		},
+		{
+			name:    "blind index key missing",
+			data:    fmt.Sprintf(`{"primary":"k1","keys":{"k1":%q}}`, testKey(1)),
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseKeyring([]byte(tc.data))
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestLoadKeyringFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	data := fmt.Sprintf(`{"primary":"k1","keys":{"k1":%q},"blind_index_key":%q}`, testKey(1), testKey(2))
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	kr, err := LoadKeyringFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "k1", kr.PrimaryKeyID())
+
+	_, err = LoadKeyringFile(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	kr := testKeyring(t, "k1", "k1")
+	aad := []byte("accounts.document_number:1")
+
+	envelope, keyID, err := kr.Encrypt([]byte("12345678900"), aad)
+	require.NoError(t, err)
+	require.Equal(t, "k1", keyID)
+	require.NotContains(t, string(envelope), "12345678900")
+
+	plaintext, err := kr.Decrypt(envelope, aad)
+	require.NoError(t, err)
+	require.Equal(t, "12345678900", string(plaintext))
+
+	// the envelope can't be moved to another account
+	_, err = kr.Decrypt(envelope, []byte("accounts.document_number:2"))
+	require.ErrorIs(t, err, ErrInvalidEnvelope)
+
+	tampered := bytes.Clone(envelope)
+	tampered[len(tampered)-1] ^= 1
+	_, err = kr.Decrypt(tampered, aad)
+	require.ErrorIs(t, err, ErrInvalidEnvelope)
+
+	_, err = kr.Decrypt([]byte{envelopeVersion}, aad)
+	require.ErrorIs(t, err, ErrInvalidEnvelope)
+}
+
+func TestRotation(t *testing.T) {
+	aad := []byte("accounts.document_number:1")
+
+	old := testKeyring(t, "k1", "k1")
+	envelope, _, err := old.Encrypt([]byte("12345678900"), aad)
+	require.NoError(t, err)
+
+	// the rotated keyring reads the values of both keys and encrypts the new ones under k2
+	rotated := testKeyring(t, "k2", "k1", "k2")
+
+	plaintext, err := rotated.Decrypt(envelope, aad)
+	require.NoError(t, err)
+	require.Equal(t, "12345678900", string(plaintext))
+
+	rewrapped, keyID, err := rotated.Rewrap(envelope)
+	require.NoError(t, err)
+	require.Equal(t, "k2", keyID)
+
+	plaintext, err = rotated.Decrypt(rewrapped, aad)
+	require.NoError(t, err)
+	require.Equal(t, "12345678900", string(plaintext))
+
+	// rewrapping an envelope already under the primary key leaves it as is
+	again, _, err := rotated.Rewrap(rewrapped)
+	require.NoError(t, err)
+	require.Equal(t, rewrapped, again)
+
+	// once k1 is retired the old envelope can't be read anymore
+	retired := testKeyring(t, "k2", "k2")
+	_, err = retired.Decrypt(envelope, aad)
+	require.ErrorIs(t, err, ErrUnknownKey)
+
+	plaintext, err = retired.Decrypt(rewrapped, aad)
+	require.NoError(t, err)
+	require.Equal(t, "12345678900", string(plaintext))
+}
+
+func TestBlindIndex(t *testing.T) {
+	kr := testKeyring(t, "k1", "k1")
+	rotated := testKeyring(t, "k2", "k1", "k2")
+
+	require.Equal(t, kr.BlindIndex("12345678900"), kr.BlindIndex("12345678900"))
+	require.NotEqual(t, kr.BlindIndex("12345678900"), kr.BlindIndex("12345678901"))
+	// the blind index doesn't change with the encryption keys
+	require.Equal(t, kr.BlindIndex("12345678900"), rotated.BlindIndex("12345678900"))
+}
+
+func TestPrefixIndexes(t *testing.T) {
+	kr := testKeyring(t, "k1", "k1")
+
+	indexes := kr.PrefixIndexes("12345678", 4, 6)
+	require.Equal(t, [][]byte{kr.PrefixIndex("1234"), kr.PrefixIndex("12345"), kr.PrefixIndex("123456")}, indexes)
+
+	require.Empty(t, kr.PrefixIndexes("123", 4, 6))
+	require.NotEqual(t, kr.BlindIndex("1234"), kr.PrefixIndex("1234"))
+}
+
+func TestPseudonym(t *testing.T) {
+	kr := testKeyring(t, "k1", "k1")
+	rotated := testKeyring(t, "k2", "k1", "k2")
+
+	require.Equal(t, kr.Pseudonym("12345678900"), rotated.Pseudonym("12345678900"))
+	require.NotEqual(t, kr.Pseudonym("12345678900"), kr.Pseudonym("12345678901"))
+	require.NotEqual(t, kr.BlindIndex("12345678900"), kr.Pseudonym("12345678900"))
+}
@@ -0,0 +1,288 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/decode"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/etag"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/writer"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/sathishs-dev/pismo-transactions/pkg/service"
+)
+
+const (
+	defaultAccountsLimit = 100
+	maxAccountsLimit     = 1000
+)
+
+// UpdateAccountMetadata handler function merges the metadata sent into the account metadata, a null value removes
+// the key, and replaces the account tags when they are sent. The If-Match header has to carry the ETag of the
+// account, the update is refused with 412 when the account changed since so concurrent edits aren't overwritten
+func (h *handler) UpdateAccountMetadata() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accID, err := accountIDFromURL(r)
+		if err != nil {
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+			return
+		}
+
+		ifMatch, ok := parseIfMatch(r)
+		if !ok {
+			ifMatchRequiredWriter(w, r)
+			return
+		}
+
+		var req UpdateAccountMetadataReqPayload
+		if err := decode.JSON(r, &req); err != nil {
+			decodeErrorWriter(w, r, err)
+			return
+		}
+
+		acc, err := h.svc.UpdateAccountMetadata(r.Context(), accID, repository.AccountMetadataPatch{
+			Metadata: req.Metadata,
+			Tags:     req.Tags,
+			IfMatch:  ifMatch,
+		})
+		if err != nil {
+			serviceErrorWriter(w, r, err)
+			return
+		}
+
+		w.Header().Set("ETag", accountETag(acc))
+		if err := writer.WriteJSON(w, http.StatusOK, toAccountResPayload(acc)); err != nil {
+			log.Error().Err(err).Msg("failed to write")
+			return
+		}
+	}
+}
+
+// ListAccounts handler function returns a page of the accounts matching the filters in the sort order asked for,
+// eg: ?tag=vip&metadata[customer_id]=42&sort=-created_at, next_cursor is set while there are more accounts to page
+// through and total_count is only counted when asked for with count=true
+func (h *handler) ListAccounts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseAccountFilter(r)
+		if err != nil {
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+			return
+		}
+
+		count := false
+		if v := r.URL.Query().Get("count"); v != "" {
+			if count, err = strconv.ParseBool(v); err != nil {
+				errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "invalid count")
+				return
+			}
+		}
+
+		// one extra account tells whether there is a next page
+		filter.Limit++
+		accounts, err := h.repo.ListAccounts(r.Context(), filter)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to retrieve the accounts")
+			errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+			return
+		}
+
+		res := AccountsResPayload{Accounts: make([]GetAccountResPaylaod, 0, len(accounts))}
+		if len(accounts) == filter.Limit {
+			accounts = accounts[:len(accounts)-1]
+			last := accounts[len(accounts)-1]
+			res.NextCursor = encodeAccountCursor(filter.Sort, repository.AccountCursor{AccountID: last.AccountID, CreatedAt: last.CreatedAt})
+		}
+
+		for i := range accounts {
+			res.Accounts = append(res.Accounts, toAccountResPayload(&accounts[i]))
+		}
+
+		if count {
+			total, err := h.repo.CountAccounts(r.Context(), filter)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to count the accounts")
+				errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+				return
+			}
+			res.TotalCount = &total
+		}
+
+		if err := writer.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Error().Err(err).Msg("failed to write")
+			return
+		}
+	}
+}
+
+// parseAccountFilter reads the filter, sort, cursor and limit query params of the account listing
+func parseAccountFilter(r *http.Request) (repository.AccountFilter, error) {
+	q := r.URL.Query()
+
+	from, to, err := parsePeriod(r)
+	if err != nil {
+		return repository.AccountFilter{}, err
+	}
+
+	filter := repository.AccountFilter{
+		Tags:             q["tag"],
+		MetadataKeys:     q["metadata_key"],
+		Metadata:         repository.Metadata{},
+		DocumentNoPrefix: q.Get("document_number_prefix"),
+		From:             from,
+		To:               to,
+		Sort:             enums.SortAccountID,
+		Limit:            defaultAccountsLimit,
+	}
+
+	if n := utf8.RuneCountInString(filter.DocumentNoPrefix); n > 0 && (n < repository.MinDocumentPrefixLen || n > repository.MaxDocumentPrefixLen) {
+		return filter, fmt.Errorf("document_number_prefix must have %d to %d characters", repository.MinDocumentPrefixLen, repository.MaxDocumentPrefixLen)
+	}
+
+	if v := q.Get("status"); v != "" {
+		if filter.Status, err = enums.ParseAccountStatus(v); err != nil {
+			return filter, errors.New("invalid status")
+		}
+	}
+
+	if v := q.Get("sort"); v != "" {
+		if filter.Sort, err = enums.ParseAccountSort(v); err != nil {
+			return filter, errors.New("invalid sort")
+		}
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		if filter.After, err = decodeAccountCursor(filter.Sort, v); err != nil {
+			return filter, errors.New("invalid cursor")
+		}
+	}
+
+	if err := service.ValidateTags(filter.Tags); err != nil {
+		return filter, err
+	}
+
+	for _, k := range filter.MetadataKeys {
+		if !service.MetadataKeyPattern.MatchString(k) {
+			return filter, fmt.Errorf("invalid metadata key %q", k)
+		}
+	}
+
+	for param, values := range q {
+		k, ok := strings.CutPrefix(param, "metadata[")
+		if !ok {
+			continue
+		}
+
+		if k, ok = strings.CutSuffix(k, "]"); !ok || !service.MetadataKeyPattern.MatchString(k) || len(values) != 1 {
+			return filter, fmt.Errorf("invalid metadata filter %q", param)
+		}
+		filter.Metadata[k] = values[0]
+	}
+
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit <= 0 || filter.Limit > maxAccountsLimit {
+			return filter, errors.New("invalid limit")
+		}
+	}
+
+	return filter, nil
+}
+
+// accountCursor is the position of the last account of a page, it's tied to the sort it was taken in
+type accountCursor struct {
+	Sort      enums.AccountSort `json:"s"`
+	AccountID int               `json:"id"`
+	CreatedAt time.Time         `json:"t"`
+}
+
+// encodeAccountCursor returns the opaque cursor resuming the listing past the account
+func encodeAccountCursor(sort enums.AccountSort, c repository.AccountCursor) string {
+	data, _ := json.Marshal(accountCursor{Sort: sort, AccountID: c.AccountID, CreatedAt: c.CreatedAt})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeAccountCursor reads the opaque cursor, it's refused when taken in another sort
+func decodeAccountCursor(sort enums.AccountSort, v string) (*repository.AccountCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var c accountCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+
+	if c.Sort != sort || c.AccountID <= 0 {
+		return nil, errors.New("cursor doesn't match the sort")
+	}
+
+	return &repository.AccountCursor{AccountID: c.AccountID, CreatedAt: c.CreatedAt}, nil
+}
+
+func toAccountResPayload(acc *repository.Account) GetAccountResPaylaod {
+	res := GetAccountResPaylaod{
+		AccountID:      acc.AccountID,
+		DocumentNumber: acc.DocumentNo,
+		Status:         acc.Status,
+		Metadata:       acc.Metadata,
+		Tags:           acc.Tags,
+		CreatedAt:      acc.CreatedAt,
+		ErasedAt:       acc.ErasedAt,
+		Version:        acc.Version,
+	}
+
+	if res.Metadata == nil {
+		res.Metadata = map[string]string{}
+	}
+	if res.Tags == nil {
+		res.Tags = []string{}
+	}
+
+	return res
+}
+
+// accountETag is the strong entity tag of the account, it changes along with its version
+func accountETag(acc *repository.Account) string {
+	return etag.Strong(strconv.FormatInt(acc.Version, 10))
+}
+
+// parseIfMatch reads the versions of the If-Match header, it's false when the header is missing. * matches
+// whatever the version, and the tags that aren't account ETags match none
+func parseIfMatch(r *http.Request) (repository.IfMatch, bool) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return nil, false
+	}
+
+	ifMatch := repository.IfMatch{}
+	for _, t := range etag.Parse(header) {
+		if t == etag.Any {
+			return nil, true
+		}
+
+		v, ok := etag.Opaque(t)
+		if !ok {
+			continue
+		}
+		if version, err := strconv.ParseInt(v, 10, 64); err == nil {
+			ifMatch = append(ifMatch, version)
+		}
+	}
+
+	return ifMatch, true
+}
+
+// ifMatchRequiredWriter responds to a change of an account sent without the ETag it was based on
+func ifMatchRequiredWriter(w http.ResponseWriter, r *http.Request) {
+	errorWriter(w, r, http.StatusPreconditionRequired, problem.CodePreconditionRequired,
+		"If-Match is required, send the ETag of the account")
+}
@@ -0,0 +1,327 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/sathishs-dev/pismo-transactions/pkg/service"
+	"github.com/stretchr/testify/mock"
+)
+
+func (h *handlerTestSuite) TestUpdateAccountMetadata() {
+	segment := "gold"
+
+	tcs := []struct {
+		name               string
+		accID              string
+		reqBody            string
+		ifMatch            string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+	}{
+		{
+			name:    "Valid Update Account Metadata Request",
+			accID:   "1",
+			ifMatch: `"3"`,
+			reqBody: `{"metadata": {"segment": "gold", "legacy_id": null}, "tags": ["vip"]}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("UpdateAccountMetadata", mock.Anything, 1, repository.AccountMetadataPatch{
+					Metadata: map[string]*string{"segment": &segment, "legacy_id": nil},
+					Tags:     []string{"vip"},
+					IfMatch:  repository.IfMatch{3},
+				}).
+					Return(&repository.Account{
+						AccountID:  1,
+						DocumentNo: "1234567890",
+						Metadata:   repository.Metadata{"segment": "gold"},
+						Tags:       []string{"vip"},
+						Version:    4,
+					}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Invalid Update Account Metadata Request - Missing If-Match",
+			accID:              "1",
+			reqBody:            `{"tags": []}`,
+			expectedStatusCode: http.StatusPreconditionRequired,
+		},
+		{
+			name:    "Invalid Update Account Metadata Request - Account Modified",
+			accID:   "1",
+			reqBody: `{"tags": []}`,
+			ifMatch: `W/"3", "2"`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("UpdateAccountMetadata", mock.Anything, 1, mock.MatchedBy(func(p repository.AccountMetadataPatch) bool {
+					// weak tags never match
+					return slices.Equal(p.IfMatch, repository.IfMatch{2})
+				})).
+					Return(nil, repository.ErrVersionMismatch)
+			},
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:               "Invalid Update Account Metadata Request - Invalid Account ID",
+			accID:              "abc",
+			reqBody:            `{"tags": []}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Update Account Metadata Request - Empty Patch",
+			accID:              "1",
+			ifMatch:            `"3"`,
+			reqBody:            `{}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Update Account Metadata Request - Reserved Prefix",
+			accID:              "1",
+			ifMatch:            `"3"`,
+			reqBody:            `{"metadata": {"pismo_internal": "x"}}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Update Account Metadata Request - Value Too Long",
+			accID:              "1",
+			ifMatch:            `"3"`,
+			reqBody:            `{"metadata": {"segment": "` + strings.Repeat("x", service.MaxMetadataValueLen+1) + `"}}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Update Account Metadata Request - Too Many Tags",
+			accID:              "1",
+			ifMatch:            `"3"`,
+			reqBody:            fmt.Sprintf(`{"tags": ["t"%s]}`, strings.Repeat(`,"t"`, service.MaxAccountTags)),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:    "Invalid Update Account Metadata Request - Too Many Keys",
+			accID:   "1",
+			ifMatch: `"3"`,
+			reqBody: `{"metadata": {"segment": "gold"}}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("UpdateAccountMetadata", mock.Anything, 1, mock.Anything).
+					Return(nil, repository.ErrTooManyMetadataKeys)
+			},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:    "Invalid Update Account Metadata Request - Erased Account",
+			accID:   "1",
+			ifMatch: `"3"`,
+			reqBody: `{"tags": []}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("UpdateAccountMetadata", mock.Anything, 1, mock.Anything).
+					Return(nil, repository.ErrAccountErased)
+			},
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:    "Invalid Update Account Metadata Request - No Account Found",
+			accID:   "100",
+			ifMatch: `"3"`,
+			reqBody: `{"tags": []}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("UpdateAccountMetadata", mock.Anything, 100, mock.Anything).
+					Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:    "Invalid Update Account Metadata Request - Updating failed",
+			accID:   "1",
+			ifMatch: `"3"`,
+			reqBody: `{"tags": []}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("UpdateAccountMetadata", mock.Anything, 1, mock.Anything).
+					Return(nil, errors.New("err"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPatch, "/accounts/"+tc.accID+"/metadata", strings.NewReader(tc.reqBody))
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+
+			if tc.expectedStatusCode == http.StatusOK {
+				var res GetAccountResPaylaod
+				h.NoError(json.Unmarshal(h.recorder.Body.Bytes(), &res))
+				h.Equal(map[string]string{"segment": "gold"}, res.Metadata)
+				h.Equal([]string{"vip"}, res.Tags)
+				h.Equal(int64(4), res.Version)
+				h.Equal(`"4"`, h.recorder.Header().Get("ETag"))
+			}
+
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
+
+func (h *handlerTestSuite) TestListAccounts() {
+	created := time.Date(2024, 12, 5, 10, 0, 0, 0, time.UTC)
+	accounts := []repository.Account{
+		{AccountID: 3, DocumentNo: "1234567890", Status: enums.AccountActive, CreatedAt: created},
+		{AccountID: 2, DocumentNo: "1234567891", Status: enums.AccountActive, CreatedAt: created},
+	}
+	cursor := encodeAccountCursor(enums.SortAccountCreatedDesc, repository.AccountCursor{AccountID: 3, CreatedAt: created})
+	total := int64(7)
+
+	tcs := []struct {
+		name               string
+		query              string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+		expectedAccounts   int
+		expectedCursor     string
+		expectedTotal      *int64
+	}{
+		{
+			name:  "Valid List Accounts Request - Metadata And Tags",
+			query: "?tag=vip&tag=beta&metadata_key=segment&metadata[customer_id]=c-42&limit=10",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("ListAccounts", mock.Anything, repository.AccountFilter{
+					Tags:         []string{"vip", "beta"},
+					MetadataKeys: []string{"segment"},
+					Metadata:     repository.Metadata{"customer_id": "c-42"},
+					Sort:         enums.SortAccountID,
+					Limit:        11,
+				}).
+					Return(accounts[:1], nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedAccounts:   1,
+		},
+		{
+			name:  "Valid List Accounts Request - Next Page With Count",
+			query: "?document_number_prefix=1234&status=active&from=2024-12-01&to=2024-12-31&sort=-created_at&limit=1&count=true",
+			expectedMocks: func(h *handlerTestSuite) {
+				filter := repository.AccountFilter{
+					Metadata:         repository.Metadata{},
+					DocumentNoPrefix: "1234",
+					Status:           enums.AccountActive,
+					From:             time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+					To:               time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+					Sort:             enums.SortAccountCreatedDesc,
+					Limit:            2,
+				}
+				h.repo.On("ListAccounts", mock.Anything, filter).
+					Return(accounts, nil)
+				h.repo.On("CountAccounts", mock.Anything, filter).
+					Return(total, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedAccounts:   1,
+			expectedCursor:     cursor,
+			expectedTotal:      &total,
+		},
+		{
+			name:  "Valid List Accounts Request - Cursor",
+			query: "?sort=-created_at&cursor=" + cursor,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("ListAccounts", mock.Anything, repository.AccountFilter{
+					Metadata: repository.Metadata{},
+					Sort:     enums.SortAccountCreatedDesc,
+					After:    &repository.AccountCursor{AccountID: 3, CreatedAt: created},
+					Limit:    defaultAccountsLimit + 1,
+				}).
+					Return(accounts[1:], nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedAccounts:   1,
+		},
+		{
+			name:               "Invalid List Accounts Request - Cursor Of Another Sort",
+			query:              "?sort=account_id&cursor=" + cursor,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid List Accounts Request - Short Document Number Prefix",
+			query:              "?document_number_prefix=123",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid List Accounts Request - Invalid Status",
+			query:              "?status=frozen",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid List Accounts Request - Invalid Sort",
+			query:              "?sort=document_number",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid List Accounts Request - Invalid Metadata Filter",
+			query:              "?metadata[customer%20id]=c-42",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid List Accounts Request - Invalid Limit",
+			query:              "?limit=0",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Invalid List Accounts Request - Fetching DataStore failed",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("ListAccounts", mock.Anything, mock.Anything).
+					Return(nil, errors.New("err"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:  "Invalid List Accounts Request - Counting failed",
+			query: "?count=true",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("ListAccounts", mock.Anything, mock.Anything).
+					Return(nil, nil)
+				h.repo.On("CountAccounts", mock.Anything, mock.Anything).
+					Return(int64(0), errors.New("err"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/accounts"+tc.query, nil)
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+
+			if tc.expectedStatusCode == http.StatusOK {
+				var res AccountsResPayload
+				h.NoError(json.Unmarshal(h.recorder.Body.Bytes(), &res))
+				h.Len(res.Accounts, tc.expectedAccounts)
+				h.Equal(tc.expectedCursor, res.NextCursor)
+				h.Equal(tc.expectedTotal, res.TotalCount)
+			}
+
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/decode"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/writer"
+	"github.com/sathishs-dev/pismo-transactions/pkg/auth"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+)
+
+// maxAPIKeyNameLen is the size of api_keys.name
+const maxAPIKeyNameLen = 64
+
+// CreateAPIKey handler function grants an api key the given scopes, the key is generated and only returned in this
+// response
+func (h *handler) CreateAPIKey() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CreateAPIKeyReqPayload
+		if err := decode.JSON(r, &req); err != nil {
+			decodeErrorWriter(w, r, err)
+			return
+		}
+
+		scopes, errs := validateCreateAPIKeyReq(&req, time.Now())
+		if len(errs) > 0 {
+			fieldErrorWriter(w, r, errs)
+			return
+		}
+
+		key, err := auth.GenerateKey()
+		if err != nil {
+			log.Error().Err(err).Msg("failed to generate the api key")
+			errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+			return
+		}
+
+		k := repository.APIKey{
+			Name:      req.Name,
+			Prefix:    auth.DisplayPrefix(key),
+			Hash:      auth.HashKey(key),
+			Scopes:    scopes,
+			ExpiresAt: req.ExpiresAt,
+		}
+		if err := h.repo.CreateAPIKey(r.Context(), &k); err != nil {
+			log.Error().Err(err).Msg("failed to store the api key")
+			errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+			return
+		}
+
+		res := toAPIKeyResPayload(&k)
+		res.Key = key
+
+		if err := writer.WriteJSON(w, http.StatusCreated, res); err != nil {
+			log.Error().Err(err).Msg("failed to write")
+			return
+		}
+	}
+}
+
+// ListAPIKeys handler function returns every api key, the revoked ones included
+func (h *handler) ListAPIKeys() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keys, err := h.repo.ListAPIKeys(r.Context())
+		if err != nil {
+			log.Error().Err(err).Msg("failed to retrieve the api keys")
+			errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+			return
+		}
+
+		res := make([]APIKeyResPayload, 0, len(keys))
+		for i := range keys {
+			res = append(res, toAPIKeyResPayload(&keys[i]))
+		}
+
+		if err := writer.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Error().Err(err).Msg("failed to write")
+			return
+		}
+	}
+}
+
+// GetAPIKey handler function returns an api key, without the key itself
+func (h *handler) GetAPIKey() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keyID, err := apiKeyIDFromURL(r)
+		if err != nil {
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+			return
+		}
+
+		k, err := h.repo.GetAPIKey(r.Context(), keyID)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to retrieve the api key")
+			errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+			return
+		}
+
+		if k == nil {
+			errorWriter(w, r, http.StatusNotFound, problem.CodeAPIKeyNotFound, "api key not found")
+			return
+		}
+
+		if err := writer.WriteJSON(w, http.StatusOK, toAPIKeyResPayload(k)); err != nil {
+			log.Error().Err(err).Msg("failed to write")
+			return
+		}
+	}
+}
+
+// RevokeAPIKey handler function revokes an api key for good, the requests made with it are refused once the
+// replicas stop caching it
+func (h *handler) RevokeAPIKey() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keyID, err := apiKeyIDFromURL(r)
+		if err != nil {
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+			return
+		}
+
+		k, err := h.repo.RevokeAPIKey(r.Context(), keyID)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to revoke the api key")
+			errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+			return
+		}
+
+		if k == nil {
+			errorWriter(w, r, http.StatusNotFound, problem.CodeAPIKeyNotFound, "api key not found")
+			return
+		}
+
+		if err := writer.WriteJSON(w, http.StatusOK, toAPIKeyResPayload(k)); err != nil {
+			log.Error().Err(err).Msg("failed to write")
+			return
+		}
+	}
+}
+
+func validateCreateAPIKeyReq(req *CreateAPIKeyReqPayload, now time.Time) (scopes pq.StringArray, errs []problem.FieldError) {
+	if req.Name == "" || len(req.Name) > maxAPIKeyNameLen {
+		errs = append(errs, problem.FieldError{
+			In:      "body",
+			Name:    "name",
+			Message: fmt.Sprintf("must be 1 to %d characters", maxAPIKeyNameLen),
+		})
+	}
+
+	if len(req.Scopes) == 0 {
+		errs = append(errs, problem.FieldError{In: "body", Name: "scopes", Message: "is required"})
+	}
+
+	for i, v := range req.Scopes {
+		s, err := enums.ParseScope(v)
+		if err != nil {
+			errs = append(errs, problem.FieldError{In: "body", Name: fmt.Sprintf("scopes[%d]", i), Message: err.Error()})
+			continue
+		}
+		if !slices.Contains(scopes, string(s)) {
+			scopes = append(scopes, string(s))
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		errs = append(errs, problem.FieldError{In: "body", Name: "expires_at", Message: "must be in the future"})
+	}
+
+	return
+}
+
+// apiKeyIDFromURL reads and validates the keyId url param
+func apiKeyIDFromURL(r *http.Request) (int, error) {
+	keyID, err := strconv.Atoi(chi.URLParam(r, "keyId"))
+	if err != nil || keyID <= 0 {
+		return 0, errors.New("invalid keyId")
+	}
+
+	return keyID, nil
+}
+
+func toAPIKeyResPayload(k *repository.APIKey) APIKeyResPayload {
+	res := APIKeyResPayload{
+		KeyID:     k.KeyID,
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Scopes:    make([]enums.Scope, 0, len(k.Scopes)),
+		ExpiresAt: k.ExpiresAt,
+		RevokedAt: k.RevokedAt,
+		CreatedAt: k.CreatedAt,
+	}
+
+	for _, s := range k.Scopes {
+		res.Scopes = append(res.Scopes, enums.Scope(s))
+	}
+
+	return res
+}
@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sathishs-dev/pismo-transactions/pkg/auth"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+func (h *handlerTestSuite) TestCreateAPIKey() {
+	tcs := []struct {
+		name               string
+		reqBody            string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+	}{
+		{
+			name:    "Valid Create API Key Request",
+			reqBody: `{"name": "partner-a", "scopes": ["accounts:read", "transactions:write", "accounts:read"], "expires_at": "2999-01-01T00:00:00Z"}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("CreateAPIKey", mock.Anything, mock.MatchedBy(func(k *repository.APIKey) bool {
+					return k.Name == "partner-a" &&
+						strings.HasPrefix(k.Prefix, "pk_") &&
+						len(k.Hash) == 32 &&
+						len(k.Scopes) == 2 &&
+						k.ExpiresAt != nil
+				})).
+					Run(func(args mock.Arguments) { args.Get(1).(*repository.APIKey).KeyID = 1 }).
+					Return(nil)
+			},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "Invalid Create API Key Request - Invalid Payload",
+			reqBody:            `{"name": 1}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Create API Key Request - Missing Name",
+			reqBody:            `{"scopes": ["accounts:read"]}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Create API Key Request - Missing Scopes",
+			reqBody:            `{"name": "partner-a"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Create API Key Request - Unknown Scope",
+			reqBody:            `{"name": "partner-a", "scopes": ["accounts:delete"]}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Create API Key Request - Expired",
+			reqBody:            `{"name": "partner-a", "scopes": ["accounts:read"], "expires_at": "2024-01-01T00:00:00Z"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:    "Invalid Create API Key Request - Storing DataStore failed",
+			reqBody: `{"name": "partner-a", "scopes": ["accounts:read"]}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("CreateAPIKey", mock.Anything, mock.Anything).
+					Return(errors.New("err"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api-keys", strings.NewReader(tc.reqBody))
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+
+			if tc.expectedStatusCode == http.StatusCreated {
+				var res APIKeyResPayload
+				h.NoError(json.Unmarshal(h.recorder.Body.Bytes(), &res))
+
+				stored := h.repo.Calls[len(h.repo.Calls)-1].Arguments.Get(1).(*repository.APIKey)
+				h.Equal(stored.Hash, auth.HashKey(res.Key), "the key is only stored as its hash")
+				h.Equal(auth.DisplayPrefix(res.Key), res.Prefix)
+			}
+
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
+
+func (h *handlerTestSuite) TestGetAPIKey() {
+	tcs := []struct {
+		name               string
+		keyID              string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:  "Valid Get API Key Request",
+			keyID: "1",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAPIKey", mock.Anything, 1).
+					Return(&repository.APIKey{KeyID: 1, Name: "partner-a", Prefix: "pk_0123abcd", Hash: []byte("hidden"),
+						Scopes: pq.StringArray{"accounts:read"}}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"key_id": 1, "name": "partner-a", "prefix": "pk_0123abcd", "scopes": ["accounts:read"],
+				"created_at": "0001-01-01T00:00:00Z"}`,
+		},
+		{
+			name:               "Invalid Get API Key Request - Invalid ID",
+			keyID:              "-1",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:  "Invalid Get API Key Request - Not Found",
+			keyID: "2",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAPIKey", mock.Anything, 2).
+					Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:  "Invalid Get API Key Request - Fetching DataStore failed",
+			keyID: "3",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAPIKey", mock.Anything, 3).
+					Return(nil, errors.New("err"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api-keys/"+tc.keyID, nil)
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+			if tc.expectedBody != "" {
+				h.JSONEq(tc.expectedBody, h.recorder.Body.String())
+			}
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
+
+func (h *handlerTestSuite) TestListAPIKeys() {
+	h.recorder = httptest.NewRecorder()
+	h.repo.On("ListAPIKeys", mock.Anything).
+		Return([]repository.APIKey{{KeyID: 1, Name: "partner-a", Prefix: "pk_0123abcd", Scopes: pq.StringArray{"accounts:read"}}}, nil)
+
+	h.router.ServeHTTP(h.recorder, httptest.NewRequest(http.MethodGet, "/api-keys", nil))
+	h.Equal(http.StatusOK, h.recorder.Code)
+	h.JSONEq(`[{"key_id": 1, "name": "partner-a", "prefix": "pk_0123abcd", "scopes": ["accounts:read"],
+		"created_at": "0001-01-01T00:00:00Z"}]`, h.recorder.Body.String())
+	h.repo.ExpectedCalls = nil
+}
+
+func (h *handlerTestSuite) TestRevokeAPIKey() {
+	revokedAt := time.Date(2024, 12, 5, 10, 0, 0, 0, time.UTC)
+
+	tcs := []struct {
+		name               string
+		keyID              string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+	}{
+		{
+			name:  "Valid Revoke API Key Request",
+			keyID: "1",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("RevokeAPIKey", mock.Anything, 1).
+					Return(&repository.APIKey{KeyID: 1, RevokedAt: &revokedAt}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:  "Invalid Revoke API Key Request - Not Found",
+			keyID: "2",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("RevokeAPIKey", mock.Anything, 2).
+					Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:  "Invalid Revoke API Key Request - Revoking DataStore failed",
+			keyID: "3",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("RevokeAPIKey", mock.Anything, 3).
+					Return(nil, errors.New("err"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api-keys/"+tc.keyID+"/revoke", nil)
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/writer"
+	"github.com/sathishs-dev/pismo-transactions/pkg/audit"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+)
+
+const (
+	defaultAuditLogLimit = 100
+	maxAuditLogLimit     = 1000
+)
+
+// ListAuditLog handler function returns the audit entries matching the entity and actor filters, newest first,
+// next_cursor is set while there are older entries to page through
+func (h *handler) ListAuditLog() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		from, to, err := parsePeriod(r)
+		if err != nil {
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+			return
+		}
+
+		filter := repository.AuditFilter{
+			EntityType: q.Get("entity_type"),
+			EntityID:   q.Get("entity_id"),
+			ActorType:  audit.ActorType(q.Get("actor_type")),
+			ActorID:    q.Get("actor_id"),
+			From:       from,
+			To:         to,
+			Limit:      defaultAuditLogLimit,
+		}
+
+		if filter.EntityID != "" && filter.EntityType == "" {
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "entity_id requires entity_type")
+			return
+		}
+
+		if v := q.Get("limit"); v != "" {
+			if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit <= 0 || filter.Limit > maxAuditLogLimit {
+				errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "invalid limit")
+				return
+			}
+		}
+
+		if v := q.Get("cursor"); v != "" {
+			if filter.BeforeID, err = strconv.ParseInt(v, 10, 64); err != nil || filter.BeforeID <= 0 {
+				errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "invalid cursor")
+				return
+			}
+		}
+
+		// one extra entry tells whether there is a next page
+		filter.Limit++
+		entries, err := h.repo.ListAuditLog(r.Context(), filter)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to retrieve the audit log")
+			errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+			return
+		}
+
+		res := AuditLogResPayload{Entries: make([]AuditEntryResPayload, 0, len(entries))}
+		if len(entries) == filter.Limit {
+			entries = entries[:len(entries)-1]
+			res.NextCursor = entries[len(entries)-1].AuditID
+		}
+
+		for _, e := range entries {
+			res.Entries = append(res.Entries, AuditEntryResPayload{
+				AuditID:    e.AuditID,
+				OccurredAt: e.OccurredAt,
+				Actor:      AuditActorPayload{Type: e.ActorType, ID: e.ActorID},
+				RequestID:  e.RequestID,
+				EntityType: e.EntityType,
+				EntityID:   e.EntityID,
+				Action:     e.Action,
+				Before:     rawImage(e.Before),
+				After:      rawImage(e.After),
+			})
+		}
+
+		if err := writer.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Error().Err(err).Msg("failed to write")
+			return
+		}
+	}
+}
+
+// rawImage returns the stored json image, null when there is none
+func rawImage(img []byte) json.RawMessage {
+	if len(img) == 0 {
+		return json.RawMessage("null")
+	}
+
+	return json.RawMessage(img)
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/audit"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+func (h *handlerTestSuite) TestListAuditLog() {
+	entries := []repository.AuditEntry{
+		{AuditID: 9, ActorType: audit.ActorAnonymous, ActorID: "anonymous", EntityType: "account", EntityID: "1",
+			Action: enums.AuditCreate, After: []byte(`{"account_id":1,"version":1,"document_number_key_id":"k2"}`)},
+		{AuditID: 7, ActorType: audit.ActorAnonymous, ActorID: "anonymous", EntityType: "account", EntityID: "1",
+			Action: enums.AuditCreate},
+	}
+
+	tcs := []struct {
+		name               string
+		query              string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+		expectedEntries    int
+		expectedCursor     int64
+	}{
+		{
+			name:  "Valid List Audit Log Request - Last Page",
+			query: "?entity_type=account&entity_id=1&actor_type=anonymous&from=2024-12-01",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("ListAuditLog", mock.Anything, repository.AuditFilter{
+					EntityType: "account",
+					EntityID:   "1",
+					ActorType:  audit.ActorAnonymous,
+					From:       time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+					Limit:      defaultAuditLogLimit + 1,
+				}).
+					Return(entries, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedEntries:    2,
+		},
+		{
+			name:  "Valid List Audit Log Request - Next Page",
+			query: "?actor_id=anonymous&limit=1&cursor=10",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("ListAuditLog", mock.Anything, repository.AuditFilter{
+					ActorID:  "anonymous",
+					BeforeID: 10,
+					Limit:    2,
+				}).
+					Return(entries, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedEntries:    1,
+			expectedCursor:     9,
+		},
+		{
+			name:               "Invalid List Audit Log Request - Entity ID Without Type",
+			query:              "?entity_id=1",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid List Audit Log Request - Invalid Limit",
+			query:              "?limit=5000",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid List Audit Log Request - Invalid Cursor",
+			query:              "?cursor=abc",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid List Audit Log Request - Invalid Period",
+			query:              "?from=2024-12-05&to=2024-12-01",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Invalid List Audit Log Request - Fetching DataStore failed",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("ListAuditLog", mock.Anything, mock.Anything).
+					Return(nil, errors.New("err"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/audit-log"+tc.query, nil)
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+
+			if tc.expectedStatusCode == http.StatusOK {
+				var res AuditLogResPayload
+				h.NoError(json.Unmarshal(h.recorder.Body.Bytes(), &res))
+				h.Len(res.Entries, tc.expectedEntries)
+				h.Equal(tc.expectedCursor, res.NextCursor)
+				h.JSONEq(`null`, string(res.Entries[0].Before))
+			}
+
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
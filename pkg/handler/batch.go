@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/decode"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/writer"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/service"
+)
+
+// defaultMaxBatchSize is the number of transactions a batch can hold unless configured otherwise
+const defaultMaxBatchSize = 1000
+
+// CreateTransactionBatch handler function creates a batch of transactions validated like CreateTransaction in a
+// single call. In the atomic mode, the default, every transaction is created or none is and the failing ones are
+// answered as a problem listing their fields, eg: transactions[3].amount. In the partial mode the transactions that
+// can be created are and the outcome of every one of them is returned
+func (h *handler) CreateTransactionBatch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CreateTransactionBatchReqPayload
+		if err := decode.JSON(r, &req); err != nil {
+			decodeErrorWriter(w, r, err)
+			return
+		}
+
+		mode, ok := h.checkBatch(w, r, req.Mode, len(req.Transactions))
+		if !ok {
+			return
+		}
+
+		in := make([]service.CreateTransactionInput, len(req.Transactions))
+		for i, t := range req.Transactions {
+			in[i] = service.CreateTransactionInput{
+				AccountID:         t.AccountID,
+				OperationTypeID:   t.OperationTypeID,
+				Amount:            t.Amount,
+				ExternalReference: t.ExternalReference,
+			}
+		}
+
+		h.createTransactionBatch(w, r, mode, in, nil)
+	}
+}
+
+// CreateTransactionBatchV2 handler function is CreateTransactionBatch with the amounts sent as decimal strings, in the
+// atomic mode the amounts failing to parse are reported before the other failures like CreateTransactionV2 does
+func (h *handler) CreateTransactionBatchV2() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CreateTransactionBatchV2ReqPayload
+		if err := decode.JSON(r, &req); err != nil {
+			decodeErrorWriter(w, r, err)
+			return
+		}
+
+		mode, ok := h.checkBatch(w, r, req.Mode, len(req.Transactions))
+		if !ok {
+			return
+		}
+
+		var (
+			in     = make([]service.CreateTransactionInput, len(req.Transactions))
+			failed = map[int]error{}
+		)
+		for i, t := range req.Transactions {
+			amount, ok := parseAmount(t.Amount)
+			if !ok {
+				failed[i] = &service.Error{
+					Kind:    service.KindInvalid,
+					Code:    problem.CodeValidationFailed,
+					Message: "amount must be a decimal string with up to 2 decimal places",
+					Fields:  []problem.FieldError{{Name: "amount", Message: "must be a decimal string with up to 2 decimal places"}},
+				}
+				continue
+			}
+
+			in[i] = service.CreateTransactionInput{
+				AccountID:         t.AccountID,
+				OperationTypeID:   t.OperationTypeID,
+				Amount:            amount,
+				ExternalReference: t.ExternalReference,
+			}
+		}
+
+		h.createTransactionBatch(w, r, mode, in, failed)
+	}
+}
+
+// checkBatch validates the mode and size of a batch, it responds with the failure when they're invalid
+func (h *handler) checkBatch(w http.ResponseWriter, r *http.Request, m string, size int) (enums.BatchMode, bool) {
+	mode, err := enums.ParseBatchMode(m)
+	if err != nil {
+		fieldErrorWriter(w, r, []problem.FieldError{{In: "body", Name: "mode", Message: "must be atomic or partial"}})
+		return "", false
+	}
+
+	switch {
+	case size == 0:
+		fieldErrorWriter(w, r, []problem.FieldError{{In: "body", Name: "transactions", Message: "is required"}})
+		return "", false
+	case size > h.maxBatchSize:
+		fieldErrorWriter(w, r, []problem.FieldError{
+			{In: "body", Name: "transactions", Message: fmt.Sprintf("must have at most %d items", h.maxBatchSize)},
+		})
+		return "", false
+	}
+
+	return mode, true
+}
+
+// createTransactionBatch creates the transactions of the batch but the ones that already failed, failed holds the
+// failures of the transactions by their index in the batch
+func (h *handler) createTransactionBatch(w http.ResponseWriter, r *http.Request, mode enums.BatchMode, in []service.CreateTransactionInput, failed map[int]error) {
+	results := make([]service.TransactionResult, len(in))
+	for i, err := range failed {
+		results[i].Err = err
+	}
+
+	// an atomic batch with failures is rejected without calling the service
+	if mode != enums.BatchAtomic || len(failed) == 0 {
+		var (
+			pending = make([]service.CreateTransactionInput, 0, len(in))
+			indexes = make([]int, 0, len(in))
+		)
+		for i := range in {
+			if _, ok := failed[i]; !ok {
+				pending = append(pending, in[i])
+				indexes = append(indexes, i)
+			}
+		}
+
+		if len(pending) > 0 {
+			created, err := h.svc.CreateTransactions(r.Context(), pending, mode == enums.BatchAtomic)
+			if err != nil {
+				serviceErrorWriter(w, r, err)
+				return
+			}
+
+			for j, res := range created {
+				results[indexes[j]] = res
+			}
+		}
+	}
+
+	res := TransactionBatchResPayload{Mode: mode, Results: make([]TransactionBatchItemResPayload, 0, len(results))}
+	for i, result := range results {
+		item := TransactionBatchItemResPayload{Index: i, Status: enums.BatchItemCreated, TransactionID: result.TransactionID}
+		if result.Err != nil {
+			item = toBatchItemFailure(i, result.Err)
+			res.Failed++
+		} else {
+			res.Created++
+		}
+		res.Results = append(res.Results, item)
+	}
+
+	status := http.StatusOK
+	if mode == enums.BatchAtomic {
+		if res.Failed > 0 {
+			batchRejectedWriter(w, r, res)
+			return
+		}
+		status = http.StatusCreated
+	}
+
+	if err := writer.WriteJSON(w, status, res); err != nil {
+		log.Error().Err(err).Msg("failed to write")
+		return
+	}
+}
+
+// toBatchItemFailure reports the failure of a transaction of a batch, its fields are named after their path in the
+// body, eg: transactions[3].amount
+func toBatchItemFailure(index int, err error) TransactionBatchItemResPayload {
+	item := TransactionBatchItemResPayload{Index: index, Status: enums.BatchItemFailed}
+
+	var se *service.Error
+	if !errors.As(err, &se) {
+		item.Code, item.Detail = problem.CodeInternal, "please try again later."
+		return item
+	}
+
+	item.Code, item.Detail = se.Code, se.Message
+
+	prefix := fmt.Sprintf("transactions[%d]", index)
+	for _, f := range se.Fields {
+		item.Errors = append(item.Errors, problem.FieldError{In: "body", Name: prefix + "." + f.Name, Message: f.Message})
+	}
+	if len(item.Errors) == 0 {
+		item.Errors = []problem.FieldError{{In: "body", Name: prefix, Message: se.Message}}
+	}
+
+	return item
+}
+
+// batchRejectedWriter responds with the transactions failing an atomic batch, none of them was created
+func batchRejectedWriter(w http.ResponseWriter, r *http.Request, res TransactionBatchResPayload) {
+	var fields []problem.FieldError
+	for _, item := range res.Results {
+		fields = append(fields, item.Errors...)
+	}
+
+	detail := fmt.Sprintf("%d of %d transactions fail, none was created", res.Failed, len(res.Results))
+	problemWriter(w, problem.New(r, http.StatusBadRequest, problem.CodeBatchRejected, detail).WithErrors(fields...))
+}
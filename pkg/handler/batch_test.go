@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+func (h *handlerTestSuite) TestCreateTransactionBatch() {
+	tcs := []struct {
+		name               string
+		path               string
+		reqBody            string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name: "Valid Create Transaction Batch Request - Atomic",
+			path: "/transactions:batch",
+			reqBody: `{"transactions": [
+				{"account_id": 1, "operation_type_id": 1, "amount": -50.25, "external_reference": "NSU-1"},
+				{"account_id": 2, "operation_type_id": 4, "amount": 60}
+			]}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("CreateTransactions", mock.Anything, []repository.Transaction{
+					{AccountID: 1, OperationTypeID: 1, Amount: -50.25, ExternalReference: "NSU-1"},
+					{AccountID: 2, OperationTypeID: 4, Amount: 60},
+				}, true).
+					Return([]repository.TransactionResult{
+						{Transaction: repository.Transaction{TransactionID: 10}},
+						{Transaction: repository.Transaction{TransactionID: 11}},
+					}, nil)
+			},
+			expectedStatusCode: http.StatusCreated,
+			expectedBody: `{"mode": "atomic", "created": 2, "failed": 0, "results": [
+				{"index": 0, "status": "created", "transaction_id": 10},
+				{"index": 1, "status": "created", "transaction_id": 11}
+			]}`,
+		},
+		{
+			name: "Invalid Create Transaction Batch Request - Atomic Rejected Before Storing",
+			path: "/transactions:batch",
+			reqBody: `{"mode": "atomic", "transactions": [
+				{"account_id": 1, "operation_type_id": 1, "amount": -50.25},
+				{"account_id": 0, "operation_type_id": 4, "amount": -60}
+			]}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody: `{"type": "urn:pismo-transactions:problem:batch_rejected", "title": "Batch rejected", "status": 400,
+				"detail": "1 of 2 transactions fail, none was created", "instance": "/transactions:batch", "code": "batch_rejected",
+				"errors": [{"in": "body", "name": "transactions[1].account_id", "message": "must be a positive integer"}]}`,
+		},
+		{
+			name: "Invalid Create Transaction Batch Request - Atomic Rejected By Accounts",
+			path: "/transactions:batch",
+			reqBody: `{"transactions": [
+				{"account_id": 1, "operation_type_id": 1, "amount": -50.25},
+				{"account_id": 2, "operation_type_id": 4, "amount": 60, "external_reference": "NSU-1"}
+			]}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("CreateTransactions", mock.Anything, mock.Anything, true).
+					Return([]repository.TransactionResult{
+						{Err: repository.ErrAccountNotFound},
+						{Err: repository.ErrDuplicateExternalReference},
+					}, nil)
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody: `{"type": "urn:pismo-transactions:problem:batch_rejected", "title": "Batch rejected", "status": 400,
+				"detail": "2 of 2 transactions fail, none was created", "instance": "/transactions:batch", "code": "batch_rejected",
+				"errors": [
+					{"in": "body", "name": "transactions[0]", "message": "account not found"},
+					{"in": "body", "name": "transactions[1]", "message": "external_reference already associated with a transaction."}
+				]}`,
+		},
+		{
+			name: "Valid Create Transaction Batch Request - Partial",
+			path: "/transactions:batch",
+			reqBody: `{"mode": "partial", "transactions": [
+				{"account_id": 1, "operation_type_id": 1, "amount": -50.25},
+				{"account_id": 1, "operation_type_id": 9, "amount": 10},
+				{"account_id": 3, "operation_type_id": 4, "amount": 60}
+			]}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("CreateTransactions", mock.Anything, []repository.Transaction{
+					{AccountID: 1, OperationTypeID: 1, Amount: -50.25},
+					{AccountID: 3, OperationTypeID: 4, Amount: 60},
+				}, false).
+					Return([]repository.TransactionResult{
+						{Transaction: repository.Transaction{TransactionID: 10}},
+						{Err: repository.ErrAccountErased},
+					}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"mode": "partial", "created": 1, "failed": 2, "results": [
+				{"index": 0, "status": "created", "transaction_id": 10},
+				{"index": 1, "status": "failed", "code": "validation_failed", "detail": "operation_type_id is not a supported operation type",
+					"errors": [{"in": "body", "name": "transactions[1].operation_type_id", "message": "is not a supported operation type"}]},
+				{"index": 2, "status": "failed", "code": "account_erased", "detail": "account is erased",
+					"errors": [{"in": "body", "name": "transactions[2]", "message": "account is erased"}]}
+			]}`,
+		},
+		{
+			name: "Valid Create Transaction Batch Request - Partial Without Valid Transactions",
+			path: "/transactions:batch",
+			reqBody: `{"mode": "partial", "transactions": [
+				{"account_id": 1, "operation_type_id": 1, "amount": 0}
+			]}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"mode": "partial", "created": 0, "failed": 1, "results": [
+				{"index": 0, "status": "failed", "code": "validation_failed", "detail": "amount must not be zero",
+					"errors": [{"in": "body", "name": "transactions[0].amount", "message": "must not be zero"}]}
+			]}`,
+		},
+		{
+			name: "Valid Create Transaction Batch V2 Request - Partial",
+			path: "/v2/transactions:batch",
+			reqBody: `{"mode": "partial", "transactions": [
+				{"account_id": 1, "operation_type_id": 1, "amount": "-50.25"},
+				{"account_id": 1, "operation_type_id": 4, "amount": "6e1"}
+			]}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("CreateTransactions", mock.Anything, []repository.Transaction{
+					{AccountID: 1, OperationTypeID: 1, Amount: -50.25},
+				}, false).
+					Return([]repository.TransactionResult{
+						{Transaction: repository.Transaction{TransactionID: 10}},
+					}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"mode": "partial", "created": 1, "failed": 1, "results": [
+				{"index": 0, "status": "created", "transaction_id": 10},
+				{"index": 1, "status": "failed", "code": "validation_failed",
+					"detail": "amount must be a decimal string with up to 2 decimal places",
+					"errors": [{"in": "body", "name": "transactions[1].amount", "message": "must be a decimal string with up to 2 decimal places"}]}
+			]}`,
+		},
+		{
+			name: "Invalid Create Transaction Batch V2 Request - Atomic Amount",
+			path: "/v2/transactions:batch",
+			reqBody: `{"transactions": [
+				{"account_id": 1, "operation_type_id": 1, "amount": "-50.25"},
+				{"account_id": 1, "operation_type_id": 4, "amount": "60.001"}
+			]}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody: `{"type": "urn:pismo-transactions:problem:batch_rejected", "title": "Batch rejected", "status": 400,
+				"detail": "1 of 2 transactions fail, none was created", "instance": "/v2/transactions:batch", "code": "batch_rejected",
+				"errors": [{"in": "body", "name": "transactions[1].amount", "message": "must be a decimal string with up to 2 decimal places"}]}`,
+		},
+		{
+			name:               "Invalid Create Transaction Batch Request - Empty",
+			path:               "/transactions:batch",
+			reqBody:            `{"transactions": []}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Invalid Create Transaction Batch Request - Too Many Transactions",
+			path: "/transactions:batch",
+			reqBody: `{"transactions": [` + strings.Repeat(`{"account_id": 1, "operation_type_id": 4, "amount": 1},`, 3) +
+				`{"account_id": 1, "operation_type_id": 4, "amount": 1}]}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody: `{"type": "urn:pismo-transactions:problem:validation_failed", "title": "Validation failed", "status": 400,
+				"detail": "transactions must have at most 3 items", "instance": "/transactions:batch", "code": "validation_failed",
+				"errors": [{"in": "body", "name": "transactions", "message": "must have at most 3 items"}]}`,
+		},
+		{
+			name:               "Invalid Create Transaction Batch Request - Invalid Mode",
+			path:               "/transactions:batch",
+			reqBody:            `{"mode": "best_effort", "transactions": [{"account_id": 1, "operation_type_id": 4, "amount": 1}]}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:    "Invalid Create Transaction Batch Request - Storing failed",
+			path:    "/transactions:batch",
+			reqBody: `{"transactions": [{"account_id": 1, "operation_type_id": 4, "amount": 1}]}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("CreateTransactions", mock.Anything, mock.Anything, true).
+					Return(nil, errors.New("err"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.reqBody))
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+			if tc.expectedBody != "" {
+				h.JSONEq(tc.expectedBody, h.recorder.Body.String())
+			}
+			h.repo.AssertExpectations(t)
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/writer"
+	"github.com/sathishs-dev/pismo-transactions/pkg/notify"
+)
+
+const (
+	defaultChangesLimit = 100
+	maxChangesLimit     = 1000
+	// maxChangesTimeout keeps the long polls below the idle timeouts of the usual proxies
+	maxChangesTimeout = 60
+)
+
+// ListChanges handler function returns the changes to the accounts and transactions logged past the since sequence,
+// in the order of the sequence. next_since is the sequence to ask for the following changes with, so loaders resume
+// exactly where they stopped. With timeout=<seconds> the request is held until changes are logged or the timeout
+// elapses, an empty page is returned then.
+func (h *handler) ListChanges() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		var (
+			since   int64
+			limit   = defaultChangesLimit
+			timeout int
+			err     error
+		)
+
+		if v := q.Get("since"); v != "" {
+			if since, err = strconv.ParseInt(v, 10, 64); err != nil || since < 0 {
+				errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "invalid since")
+				return
+			}
+		}
+
+		if v := q.Get("limit"); v != "" {
+			if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > maxChangesLimit {
+				errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "invalid limit")
+				return
+			}
+		}
+
+		if v := q.Get("timeout"); v != "" {
+			if timeout, err = strconv.Atoi(v); err != nil || timeout < 0 || timeout > maxChangesTimeout {
+				errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "invalid timeout")
+				return
+			}
+		}
+
+		var (
+			changes <-chan notify.Notification
+			expired <-chan time.Time
+			poll    <-chan time.Time
+		)
+		if timeout > 0 {
+			// subscribed before the changes are first read so the ones logged in between aren't missed
+			if h.notifications != nil {
+				ch, cancel := h.notifications.Subscribe(changesBuffer)
+				defer cancel()
+				changes = ch
+			}
+
+			timer := time.NewTimer(time.Duration(timeout) * time.Second)
+			defer timer.Stop()
+			expired = timer.C
+
+			ticker := time.NewTicker(h.eventsPoll)
+			defer ticker.Stop()
+			poll = ticker.C
+		}
+
+		res := ChangesResPayload{Changes: []ChangeResPayload{}, NextSince: since}
+
+	wait:
+		for {
+			entries, err := h.repo.ListChangeLog(r.Context(), since, limit)
+			if err != nil {
+				if r.Context().Err() != nil {
+					return
+				}
+				log.Error().Err(err).Msg("failed to retrieve the change log")
+				errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+				return
+			}
+
+			if len(entries) > 0 {
+				for _, e := range entries {
+					res.Changes = append(res.Changes, ChangeResPayload{
+						Sequence:   e.Sequence,
+						EntityType: e.EntityType,
+						EntityID:   e.EntityID,
+						AccountID:  e.AccountID,
+						Operation:  e.Operation,
+						Data:       e.Data,
+						ChangedAt:  e.ChangedAt,
+					})
+				}
+				res.NextSince = entries[len(entries)-1].Sequence
+				break
+			}
+
+			if timeout == 0 {
+				break
+			}
+
+			select {
+			case <-r.Context().Done():
+				return
+			case <-h.shutdown:
+				break wait
+			case <-expired:
+				break wait
+			case _, ok := <-changes:
+				if !ok {
+					// the listener stopped, the long poll keeps polling
+					changes = nil
+				}
+			case <-poll:
+			}
+		}
+
+		if err := writer.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Error().Err(err).Msg("failed to write")
+			return
+		}
+	}
+}
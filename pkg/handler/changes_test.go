@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+func (h *handlerTestSuite) TestListChanges() {
+	at := time.Date(2024, 12, 5, 10, 32, 7, 0, time.UTC)
+	entries := []repository.ChangeLogEntry{
+		{
+			Sequence:   6,
+			EntityType: "account",
+			EntityID:   1,
+			AccountID:  1,
+			Operation:  "update",
+			Data:       []byte(`{"account_id": 1, "version": 2}`),
+			ChangedAt:  at,
+		},
+		{
+			Sequence:   8,
+			EntityType: "transaction",
+			EntityID:   3,
+			AccountID:  1,
+			Operation:  "create",
+			Data:       []byte(`{"transaction_id": 3, "account_id": 1, "amount": 10.5}`),
+			ChangedAt:  at,
+		},
+	}
+	expectedChanges := `{"changes": [
+		{"sequence": 6, "entity_type": "account", "entity_id": 1, "account_id": 1, "operation": "update",
+			"data": {"account_id": 1, "version": 2}, "changed_at": "2024-12-05T10:32:07Z"},
+		{"sequence": 8, "entity_type": "transaction", "entity_id": 3, "account_id": 1, "operation": "create",
+			"data": {"transaction_id": 3, "account_id": 1, "amount": 10.5}, "changed_at": "2024-12-05T10:32:07Z"}
+	], "next_since": 8}`
+
+	tcs := []struct {
+		name               string
+		query              string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:  "Valid List Changes Request",
+			query: "?since=5&limit=2",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("ListChangeLog", mock.Anything, int64(5), 2).
+					Return(entries, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       expectedChanges,
+		},
+		{
+			name:  "Valid List Changes Request - No Changes",
+			query: "?since=8",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("ListChangeLog", mock.Anything, int64(8), defaultChangesLimit).
+					Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"changes": [], "next_since": 8}`,
+		},
+		{
+			name:  "Valid List Changes Request - Long Poll",
+			query: "?since=5&timeout=10",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("ListChangeLog", mock.Anything, int64(5), defaultChangesLimit).
+					Return(nil, nil).Twice()
+				h.repo.On("ListChangeLog", mock.Anything, int64(5), defaultChangesLimit).
+					Return(entries, nil).Once()
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       expectedChanges,
+		},
+		{
+			name:               "Invalid List Changes Request - Invalid Since",
+			query:              "?since=-1",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid List Changes Request - Invalid Limit",
+			query:              "?limit=1001",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid List Changes Request - Invalid Timeout",
+			query:              "?timeout=61",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Invalid List Changes Request - Fetching DataStore failed",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("ListChangeLog", mock.Anything, int64(0), defaultChangesLimit).
+					Return(nil, errors.New("err"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/changes"+tc.query, nil)
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+			if tc.expectedBody != "" {
+				h.JSONEq(tc.expectedBody, h.recorder.Body.String())
+			}
+			h.repo.AssertExpectations(t)
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
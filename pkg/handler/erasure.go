@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/writer"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+)
+
+// EraseAccount handler function erases the personal data of an account for good while keeping its transactions,
+// with dry_run=true it only returns what would be erased. Unless it's a dry run the If-Match header has to carry
+// the ETag of the account
+func (h *handler) EraseAccount() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accID, err := accountIDFromURL(r)
+		if err != nil {
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+			return
+		}
+
+		dryRun := false
+		if v := r.URL.Query().Get("dry_run"); v != "" {
+			if dryRun, err = strconv.ParseBool(v); err != nil {
+				errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "invalid dry_run")
+				return
+			}
+		}
+
+		ifMatch, ok := parseIfMatch(r)
+		if !ok && !dryRun {
+			ifMatchRequiredWriter(w, r)
+			return
+		}
+
+		erasure, err := h.svc.EraseAccount(r.Context(), accID, dryRun, ifMatch)
+		if err != nil {
+			serviceErrorWriter(w, r, err)
+			return
+		}
+
+		if err := writer.WriteJSON(w, http.StatusOK, toAccountErasureResPayload(erasure)); err != nil {
+			log.Error().Err(err).Msg("failed to write")
+			return
+		}
+	}
+}
+
+func toAccountErasureResPayload(erasure *repository.AccountErasure) AccountErasureResPayload {
+	res := AccountErasureResPayload{
+		AccountID:            erasure.AccountID,
+		DryRun:               erasure.DryRun,
+		ErasedFields:         erasure.ErasedFields,
+		RetainedTransactions: erasure.RetainedTransactions,
+	}
+	if !erasure.DryRun {
+		res.ErasedAt = &erasure.ErasedAt
+	}
+
+	return res
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+func (h *handlerTestSuite) TestEraseAccount() {
+	erasedAt := time.Date(2024, 12, 6, 10, 0, 0, 0, time.UTC)
+
+	tcs := []struct {
+		name               string
+		path               string
+		ifMatch            string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+		expectedDryRun     bool
+	}{
+		{
+			name:    "Valid Erase Account Request",
+			path:    "/accounts/1/erasure",
+			ifMatch: `"2"`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("EraseAccount", mock.Anything, 1, false, repository.IfMatch{2}).
+					Return(&repository.AccountErasure{
+						AccountID:            1,
+						ErasedAt:             erasedAt,
+						ErasedFields:         []string{"document_number"},
+						RetainedTransactions: 3,
+					}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "Valid Erase Account Request - Dry Run",
+			path: "/accounts/1/erasure?dry_run=true",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("EraseAccount", mock.Anything, 1, true, repository.IfMatch(nil)).
+					Return(&repository.AccountErasure{
+						AccountID:            1,
+						DryRun:               true,
+						ErasedFields:         []string{"document_number"},
+						RetainedTransactions: 3,
+					}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedDryRun:     true,
+		},
+		{
+			name:    "Valid Erase Account Request - Any Version",
+			path:    "/accounts/1/erasure",
+			ifMatch: "*",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("EraseAccount", mock.Anything, 1, false, repository.IfMatch(nil)).
+					Return(&repository.AccountErasure{
+						AccountID:            1,
+						ErasedAt:             erasedAt,
+						ErasedFields:         []string{"document_number"},
+						RetainedTransactions: 3,
+					}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Invalid Erase Account Request - Missing If-Match",
+			path:               "/accounts/1/erasure",
+			expectedStatusCode: http.StatusPreconditionRequired,
+		},
+		{
+			name:    "Invalid Erase Account Request - Account Modified",
+			path:    "/accounts/1/erasure",
+			ifMatch: `"1"`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("EraseAccount", mock.Anything, 1, false, repository.IfMatch{1}).
+					Return(nil, repository.ErrVersionMismatch)
+			},
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:               "Invalid Erase Account Request - Invalid Dry Run",
+			path:               "/accounts/1/erasure?dry_run=maybe",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Erase Account Request - Invalid Account ID",
+			path:               "/accounts/abc/erasure",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:    "Invalid Erase Account Request - No Account Found",
+			path:    "/accounts/100/erasure",
+			ifMatch: `"2"`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("EraseAccount", mock.Anything, 100, false, repository.IfMatch{2}).
+					Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:    "Invalid Erase Account Request - Already Erased",
+			path:    "/accounts/1/erasure",
+			ifMatch: `"2"`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("EraseAccount", mock.Anything, 1, false, repository.IfMatch{2}).
+					Return(nil, repository.ErrAccountErased)
+			},
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name: "Invalid Erase Account Request - Balance Not Zero",
+			path: "/accounts/1/erasure?dry_run=true",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("EraseAccount", mock.Anything, 1, true, repository.IfMatch(nil)).
+					Return(nil, repository.ErrAccountBalanceNotZero)
+			},
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:    "Invalid Erase Account Request - Erasing failed",
+			path:    "/accounts/1/erasure",
+			ifMatch: `"2"`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("EraseAccount", mock.Anything, 1, false, repository.IfMatch{2}).
+					Return(nil, errors.New("err"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+
+			if tc.expectedStatusCode == http.StatusOK {
+				var res AccountErasureResPayload
+				h.NoError(json.Unmarshal(h.recorder.Body.Bytes(), &res))
+				h.Equal(tc.expectedDryRun, res.DryRun)
+				h.Equal([]string{"document_number"}, res.ErasedFields)
+				h.Equal(3, res.RetainedTransactions)
+				h.Equal(tc.expectedDryRun, res.ErasedAt == nil)
+			}
+
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
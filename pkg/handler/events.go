@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/notify"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+)
+
+const (
+	defaultHeartbeat  = 15 * time.Second
+	defaultEventsPoll = 5 * time.Second
+
+	// eventsBatchSize is the number of events read at once while a stream catches up
+	eventsBatchSize = 100
+	// eventsRetry is the time the clients are told to wait before reconnecting, in milliseconds
+	eventsRetry = 3000
+	// changesBuffer holds the notifications of the other accounts arriving while a stream is writing
+	changesBuffer = 64
+
+	eventTransactionCreated = "transaction.created"
+	eventBalanceChanged     = "balance.changed"
+	eventStatusChanged      = "status.changed"
+)
+
+// eventStream writes server-sent events, every write pushes the write deadline of the connection forward so the
+// write timeout of the server doesn't cut the stream as long as it's written to at least once per heartbeat
+type eventStream struct {
+	w       io.Writer
+	rc      *http.ResponseController
+	timeout time.Duration
+}
+
+func newEventStream(w http.ResponseWriter, heartbeat time.Duration) *eventStream {
+	return &eventStream{w: w, rc: http.NewResponseController(w), timeout: 2 * heartbeat}
+}
+
+// write sends the frame to the client right away
+func (s *eventStream) write(frame string) error {
+	// writers without deadlines have no write timeout to push forward
+	err := s.rc.SetWriteDeadline(time.Now().Add(s.timeout))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	if _, err := io.WriteString(s.w, frame); err != nil {
+		return err
+	}
+
+	return s.rc.Flush()
+}
+
+// event sends the event, data has to be single line json
+func (s *eventStream) event(id int64, name string, data []byte) error {
+	return s.write(fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", id, name, data))
+}
+
+// AccountEvents handler function streams the activity of the account as server-sent events, the transactions
+// created along with the balance they leave the account with and its status changes. Every event carries the id it
+// was persisted with, a client reconnecting with Last-Event-ID resumes right after the last event it got while a
+// stream without it starts with the events to come. Heartbeats are sent while the stream is idle so proxies keep it
+// open, and it ends when the client goes away or the server shuts down.
+func (h *handler) AccountEvents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accID, err := accountIDFromURL(r)
+		if err != nil {
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+			return
+		}
+
+		var (
+			after  int64
+			resume bool
+		)
+		if v := r.Header.Get("Last-Event-ID"); v != "" {
+			if after, err = strconv.ParseInt(v, 10, 64); err != nil || after < 0 {
+				errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "invalid Last-Event-ID")
+				return
+			}
+			resume = true
+		}
+
+		if _, err := h.svc.GetAccount(r.Context(), accID); err != nil {
+			serviceErrorWriter(w, r, err)
+			return
+		}
+
+		// subscribed before the events are first read so the changes made in between aren't missed
+		var changes <-chan notify.Notification
+		if h.notifications != nil {
+			ch, cancel := h.notifications.Subscribe(changesBuffer)
+			defer cancel()
+			changes = ch
+		}
+
+		if !resume {
+			if after, err = h.repo.LastAccountEventID(r.Context(), accID); err != nil {
+				log.Error().Err(err).Msg("failed to retrieve the last account event")
+				errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		// keeps reverse proxies from buffering the stream
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		s := newEventStream(w, h.heartbeat)
+		if err := s.write(fmt.Sprintf("retry: %d\n\n", eventsRetry)); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(h.heartbeat)
+		defer heartbeat.Stop()
+		poll := time.NewTicker(h.eventsPoll)
+		defer poll.Stop()
+
+		for pending := true; ; {
+			if pending {
+				if after, err = sendAccountEvents(r.Context(), h.repo, s, accID, after); err != nil {
+					if r.Context().Err() == nil {
+						log.Error().Err(err).Int("account_id", accID).Msg("failed to stream the account events")
+					}
+					return
+				}
+				pending = false
+			}
+
+			select {
+			case <-r.Context().Done():
+				return
+			case <-h.shutdown:
+				return
+			case n, ok := <-changes:
+				if !ok {
+					// the listener stopped, the stream keeps polling
+					changes = nil
+					continue
+				}
+				pending = n.AccountID == accID
+			case <-poll.C:
+				pending = true
+			case <-heartbeat.C:
+				if err := s.write(": heartbeat\n\n"); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// sendAccountEvents sends the events of the account past after until the stream is caught up, it returns the id of
+// the last event sent
+func sendAccountEvents(ctx context.Context, repo repository.PismoRepo, s *eventStream, accID int, after int64) (int64, error) {
+	for {
+		events, err := repo.ListAccountEvents(ctx, accID, after, eventsBatchSize)
+		if err != nil {
+			return after, err
+		}
+
+		for _, ev := range events {
+			if err := sendAccountEvent(s, accID, ev); err != nil {
+				return after, err
+			}
+			after = ev.EventID
+		}
+
+		if len(events) < eventsBatchSize {
+			return after, nil
+		}
+	}
+}
+
+// sendAccountEvent sends the stream events of the outbox event, they share its id so a stream resumed from it
+// doesn't get any of them again
+func sendAccountEvent(s *eventStream, accID int, ev repository.AccountEvent) error {
+	switch ev.EventType {
+	case enums.TransactionCreated:
+		if err := s.event(ev.EventID, eventTransactionCreated, ev.Payload); err != nil {
+			return err
+		}
+
+		if ev.Balance == nil {
+			return nil
+		}
+
+		data, err := json.Marshal(GetAccountBalanceResPayload{AccountID: accID, Balance: *ev.Balance, AsOf: ev.CreatedAt})
+		if err != nil {
+			return err
+		}
+		return s.event(ev.EventID, eventBalanceChanged, data)
+
+	case enums.AccountErased:
+		data, err := json.Marshal(AccountStatusEventPayload{AccountID: accID, Status: enums.AccountClosed, ChangedAt: ev.CreatedAt})
+		if err != nil {
+			return err
+		}
+		return s.event(ev.EventID, eventStatusChanged, data)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+func (h *handlerTestSuite) TestAccountEvents() {
+	var (
+		at      = time.Date(2024, 12, 5, 10, 32, 7, 0, time.UTC)
+		balance = 10.5
+	)
+
+	tcs := []struct {
+		name               string
+		lastEventID        string
+		expectedMocks      func(h *handlerTestSuite, cancel func())
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:        "Valid Account Events Request - Resume",
+			lastEventID: "4",
+			expectedMocks: func(h *handlerTestSuite, cancel func()) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(&repository.Account{AccountID: 1}, nil)
+				h.repo.On("ListAccountEvents", mock.Anything, 1, int64(4), eventsBatchSize).
+					Return([]repository.AccountEvent{
+						{
+							EventID:   5,
+							EventType: enums.TransactionCreated,
+							Payload:   []byte(`{"transaction_id": 3, "account_id": 1, "amount": 10.5}`),
+							CreatedAt: at,
+							Balance:   &balance,
+						},
+						{EventID: 7, EventType: enums.AccountErased, Payload: []byte(`{"account_id": 1}`), CreatedAt: at},
+					}, nil).Once()
+				// the stream ends once it's caught up
+				h.repo.On("ListAccountEvents", mock.Anything, 1, int64(7), eventsBatchSize).
+					Run(func(mock.Arguments) { cancel() }).
+					Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: "retry: 3000\n\n" +
+				"id: 5\nevent: transaction.created\ndata: {\"transaction_id\": 3, \"account_id\": 1, \"amount\": 10.5}\n\n" +
+				"id: 5\nevent: balance.changed\ndata: {\"account_id\":1,\"balance\":10.5,\"as_of\":\"2024-12-05T10:32:07Z\"}\n\n" +
+				"id: 7\nevent: status.changed\ndata: {\"account_id\":1,\"status\":\"closed\",\"changed_at\":\"2024-12-05T10:32:07Z\"}\n\n",
+		},
+		{
+			name: "Valid Account Events Request - Events To Come",
+			expectedMocks: func(h *handlerTestSuite, cancel func()) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(&repository.Account{AccountID: 1}, nil)
+				h.repo.On("LastAccountEventID", mock.Anything, 1).
+					Return(int64(9), nil)
+				h.repo.On("ListAccountEvents", mock.Anything, 1, int64(9), eventsBatchSize).
+					Run(func(mock.Arguments) { cancel() }).
+					Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "retry: 3000\n\n",
+		},
+		{
+			name:               "Invalid Account Events Request - Invalid Last Event ID",
+			lastEventID:        "-1",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Invalid Account Events Request - Account Not Found",
+			expectedMocks: func(h *handlerTestSuite, cancel func()) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/accounts/1/events", nil)
+			if tc.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tc.lastEventID)
+			}
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h, cancel)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+			if tc.expectedBody != "" {
+				h.Equal("text/event-stream", h.recorder.Header().Get("Content-Type"))
+				h.Equal(tc.expectedBody, h.recorder.Body.String())
+			}
+			h.repo.AssertExpectations(t)
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
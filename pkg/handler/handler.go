@@ -1,67 +1,142 @@
 package handler
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/decode"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/etag"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
 	"github.com/sathishs-dev/pismo-transactions/internal/meta/writer"
 	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/notify"
+	"github.com/sathishs-dev/pismo-transactions/pkg/reconcile"
 	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/sathishs-dev/pismo-transactions/pkg/service"
+	"github.com/sathishs-dev/pismo-transactions/pkg/statement"
 )
 
-type handler struct {
-	repo repository.PismoRepo
-}
+type (
+	handler struct {
+		repo          repository.PismoRepo
+		svc           service.Service
+		rpcMethods    map[string]rpcMethod
+		maxBatchSize  int
+		notifications *notify.Listener
+		heartbeat     time.Duration
+		eventsPoll    time.Duration
+		shutdown      <-chan struct{}
+	}
+
+	// Option - Used to configure the handler during initialization
+	Option func(*handler)
+)
 
 type Handler interface {
 	CreateAccount() http.HandlerFunc
 	GetAccount() http.HandlerFunc
+	ListAccounts() http.HandlerFunc
+	UpdateAccountMetadata() http.HandlerFunc
+	GetAccountBalance() http.HandlerFunc
+	AccountEvents() http.HandlerFunc
+	EraseAccount() http.HandlerFunc
 	CreateTransaction() http.HandlerFunc
+	CreateTransactionV2() http.HandlerFunc
+	CreateTransactionBatch() http.HandlerFunc
+	CreateTransactionBatchV2() http.HandlerFunc
+	ExportTransactions() http.HandlerFunc
+	CreateReconciliation() http.HandlerFunc
+	GetReconciliation() http.HandlerFunc
+	CreateWebhook() http.HandlerFunc
+	ListWebhooks() http.HandlerFunc
+	GetWebhook() http.HandlerFunc
+	DeleteWebhook() http.HandlerFunc
+	EnableWebhook() http.HandlerFunc
+	ListWebhookDeadLetters() http.HandlerFunc
+	ReplayWebhookDeadLetters() http.HandlerFunc
+	ListAuditLog() http.HandlerFunc
+	CreateAPIKey() http.HandlerFunc
+	ListAPIKeys() http.HandlerFunc
+	GetAPIKey() http.HandlerFunc
+	RevokeAPIKey() http.HandlerFunc
+	ListChanges() http.HandlerFunc
+	RPC() http.HandlerFunc
+}
+
+// WithMaxBatchSize - Will set the number of transactions a batch can hold
+func WithMaxBatchSize(n int) Option {
+	return func(h *handler) {
+		h.maxBatchSize = n
+	}
+}
+
+// WithNotifications - Will wake the account event streams and the change long polls up on the changes, they only
+// poll for them without it
+func WithNotifications(l *notify.Listener) Option {
+	return func(h *handler) {
+		h.notifications = l
+	}
 }
 
-func NewHandler(repo repository.PismoRepo) Handler {
-	return &handler{
-		repo,
+// WithHeartbeat - Will send a heartbeat on the idle event streams at the given interval
+func WithHeartbeat(d time.Duration) Option {
+	return func(h *handler) {
+		h.heartbeat = d
 	}
 }
 
+// WithEventsPollInterval - Will poll for the events of the streams, and for the changes of the long polls, at the
+// given interval
+func WithEventsPollInterval(d time.Duration) Option {
+	return func(h *handler) {
+		h.eventsPoll = d
+	}
+}
+
+// WithShutdown - Will end the event streams once done is closed, the clients resume them from another replica
+func WithShutdown(done <-chan struct{}) Option {
+	return func(h *handler) {
+		h.shutdown = done
+	}
+}
+
+func NewHandler(repo repository.PismoRepo, opts ...Option) Handler {
+	h := &handler{
+		repo:         repo,
+		svc:          service.New(repo),
+		maxBatchSize: defaultMaxBatchSize,
+		heartbeat:    defaultHeartbeat,
+		eventsPoll:   defaultEventsPoll,
+	}
+	for _, o := range opts {
+		o(h)
+	}
+	h.rpcMethods = h.newRPCMethods()
+
+	return h
+}
+
 // CreateAccount handler function handles account creation request
 func (h *handler) CreateAccount() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req CreateAccountReqPayload
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			log.Error().Err(err).Msg("failed to decode body")
-			errorWriter(w, http.StatusBadRequest, "failed to decode body")
-			return
-		}
-
-		if req.DocumentNumber == "" {
-			errorWriter(w, http.StatusBadRequest, "document_number required")
-			return
-		}
-
-		// check unique document_number
-		isExists, err := h.repo.GetAccountByDocumentNo(r.Context(), req.DocumentNumber)
-		if err != nil {
-			log.Error().Err(err).Msg("failed to retrive the account")
-			errorWriter(w, http.StatusInternalServerError, "please try again later.")
+		if err := decode.JSON(r, &req); err != nil {
+			decodeErrorWriter(w, r, err)
 			return
 		}
 
-		if isExists {
-			errorWriter(w, http.StatusConflict, "document_number already associated with an account.")
-			return
-		}
-
-		// create account
-		err = h.repo.CreateAccount(r.Context(), req.DocumentNumber)
+		_, err := h.svc.CreateAccount(r.Context(), service.CreateAccountInput{
+			DocumentNumber: req.DocumentNumber,
+			Metadata:       req.Metadata,
+			Tags:           req.Tags,
+		})
 		if err != nil {
-			log.Error().Err(err).Msg("failed to store the account")
-			errorWriter(w, http.StatusInternalServerError, "please try again later.")
+			serviceErrorWriter(w, r, err)
 			return
 		}
 
@@ -73,42 +148,66 @@ func (h *handler) CreateAccount() http.HandlerFunc {
 	}
 }
 
-// GetAccount handler function handles fetch account requests
+// GetAccount handler function handles fetch account requests, the response carries the ETag of the account and a
+// request whose If-None-Match has it is answered with 304 so polling clients don't download it again
 func (h *handler) GetAccount() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		accIdParam := chi.URLParam(r, "accountId")
-		if accIdParam == "" {
-			errorWriter(w, http.StatusBadRequest, "accountID required")
+		accID, err := accountIDFromURL(r)
+		if err != nil {
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
 			return
 		}
 
-		accID, err := strconv.Atoi(accIdParam)
+		account, err := h.svc.GetAccount(r.Context(), accID)
 		if err != nil {
-			log.Error().Err(err).Msg("failed to convert accountID")
-			errorWriter(w, http.StatusInternalServerError, "please try again later.")
+			serviceErrorWriter(w, r, err)
 			return
 		}
 
-		if accID <= 0 {
-			errorWriter(w, http.StatusBadRequest, "invalid accountId")
+		tag := accountETag(account)
+		w.Header().Set("ETag", tag)
+		// the document number is personal data, caches keep it private and check it's still current before using it
+		w.Header().Set("Cache-Control", "private, no-cache")
+		if etag.NoneMatch(r.Header.Get("If-None-Match"), tag) {
+			w.WriteHeader(http.StatusNotModified)
 			return
 		}
 
-		account, err := h.repo.GetAccountByAccountID(r.Context(), accID)
+		if err := writer.WriteJSON(w, http.StatusOK, toAccountResPayload(account)); err != nil {
+			log.Error().Err(err).Msg("failed to write")
+			return
+		}
+	}
+}
+
+// GetAccountBalance handler function returns the account balance as of the given time, defaults to now
+func (h *handler) GetAccountBalance() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accID, err := accountIDFromURL(r)
 		if err != nil {
-			log.Error().Err(err).Msg("failed to retrieve data from store")
-			errorWriter(w, http.StatusInternalServerError, "please try again later.")
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
 			return
 		}
 
-		if account == nil {
-			errorWriter(w, http.StatusBadRequest, "account not found")
+		asOf := time.Now()
+		if v := r.URL.Query().Get("as_of"); v != "" {
+			// a date covers the whole day, so the balance is the closing balance of that day
+			if asOf, err = parseTime(v, true); err != nil {
+				errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "invalid as_of")
+				return
+			}
+		}
+
+		balance, err := h.svc.GetAccountBalance(r.Context(), accID, asOf)
+		if err != nil {
+			serviceErrorWriter(w, r, err)
 			return
 		}
 
-		if err := writer.WriteJSON(w, http.StatusOK, GetAccountResPaylaod{
-			AccountID:      account.AccountID,
-			DocumentNumber: account.DocumentNo,
+		if err := writer.WriteJSON(w, http.StatusOK, GetAccountBalanceResPayload{
+			AccountID: accID,
+			Balance:   balance,
+			AsOf:      asOf,
 		}); err != nil {
 			log.Error().Err(err).Msg("failed to write")
 			return
@@ -120,92 +219,336 @@ func (h *handler) GetAccount() http.HandlerFunc {
 func (h *handler) CreateTransaction() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req CreateTransactionReqPayload
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			log.Error().Err(err).Msg("failed to decode body")
-			errorWriter(w, http.StatusBadRequest, "failed to decode body")
+		if err := decode.JSON(r, &req); err != nil {
+			decodeErrorWriter(w, r, err)
 			return
 		}
 
-		if errs := validateCreateTransactionReq(&req); len(errs) > 0 {
-			errorWriter(w, http.StatusBadRequest, strings.Join(errs, "/"))
+		err := h.svc.CreateTransaction(r.Context(), service.CreateTransactionInput{
+			AccountID:         req.AccountID,
+			OperationTypeID:   req.OperationTypeID,
+			Amount:            req.Amount,
+			ExternalReference: req.ExternalReference,
+		})
+		if err != nil {
+			serviceErrorWriter(w, r, err)
 			return
 		}
 
-		operationType, err := enums.ParseOperationType(req.OperationTypeID)
-		if err != nil {
-			errorWriter(w, http.StatusBadRequest, "invalid operation_type_id")
+		if err := writer.WriteJSON(w, http.StatusCreated, nil); err != nil {
+			log.Error().Err(err).Msg("failed to write")
 			return
 		}
+	}
+}
 
-		if req.Amount < 0 && !enums.AllowNegative(operationType) {
-			errorWriter(w, http.StatusBadRequest, "negative transactions not allowed for the operation_type_id")
+// ExportTransactions handler function streams the account transactions as a csv or ofx statement
+func (h *handler) ExportTransactions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accID, err := accountIDFromURL(r)
+		if err != nil {
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
 			return
 		}
 
-		if req.Amount > 0 && enums.AllowNegative(operationType) {
-			errorWriter(w, http.StatusBadRequest, "positive transactions not allowed for the operation_type_id")
+		format, err := statement.ParseFormat(r.URL.Query().Get("format"))
+		if err != nil {
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "invalid format, supported formats are csv/ofx/camt053/camt054")
 			return
 		}
 
-		// fetch account
-		acc, err := h.repo.GetAccountByAccountID(r.Context(), req.AccountID)
+		from, to, err := parsePeriod(r)
 		if err != nil {
-			log.Error().Err(err).Msg("failed to retrieve the account")
-			errorWriter(w, http.StatusInternalServerError, "please try again later.")
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
 			return
 		}
 
-		if acc == nil {
-			errorWriter(w, http.StatusBadRequest, "account not found")
+		acc, err := h.svc.GetAccount(r.Context(), accID)
+		if err != nil {
+			serviceErrorWriter(w, r, err)
 			return
 		}
 
-		// create transaction
-		err = h.repo.CreateTransaction(
+		opening := 0.0
+		if !from.IsZero() {
+			opening, err = h.repo.GetAccountBalance(r.Context(), acc.AccountID, from)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to retrieve the opening balance")
+				errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="account-%d-statement.%s"`, acc.AccountID, format.Extension()))
+		w.WriteHeader(http.StatusOK)
+
+		sw := statement.NewWriter(format, w, statement.Info{
+			AccountID:      acc.AccountID,
+			From:           from,
+			To:             to,
+			OpeningBalance: opening,
+		})
+
+		// the status is already sent, failures from here on can only be logged
+		err = h.repo.StreamTransactions(
 			r.Context(),
-			repository.Transaction{
-				AccountID:       acc.AccountID,
-				OperationTypeID: int(operationType),
-				Amount:          req.Amount,
+			repository.TransactionFilter{AccountID: acc.AccountID, From: from, To: to},
+			func(txn repository.Transaction) error {
+				return sw.Write(statement.Entry{
+					TransactionID: txn.TransactionID,
+					EventDate:     txn.EventDate,
+					OperationType: enums.OperationType(txn.OperationTypeID),
+					Memo:          txn.Description,
+					Amount:        txn.Amount,
+				})
 			},
 		)
 		if err != nil {
-			log.Error().Err(err).Msg("failed to store the transaction")
-			errorWriter(w, http.StatusInternalServerError, "please try again later.")
+			log.Error().Err(err).Msg("failed to stream the transactions")
 			return
 		}
 
-		if err := writer.WriteJSON(w, http.StatusCreated, nil); err != nil {
+		if err := sw.Close(); err != nil {
 			log.Error().Err(err).Msg("failed to write")
 			return
 		}
+	}
+}
+
+// CreateReconciliation handler function reconciles the settlement csv sent as the body against the transactions
+func (h *handler) CreateReconciliation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		mapping, err := reconcile.ParseMapping(q.Get("columns"))
+		if err != nil {
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+			return
+		}
+
+		if v := q.Get("date_layout"); v != "" {
+			mapping.DateLayout = v
+		}
+
+		if v := q.Get("delimiter"); v != "" {
+			if err := mapping.SetDelimiter(v); err != nil {
+				errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+				return
+			}
+		}
+
+		tolerance := reconcile.DefaultDateTolerance
+		if v := q.Get("tolerance"); v != "" {
+			if tolerance, err = time.ParseDuration(v); err != nil || tolerance <= 0 {
+				errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "invalid tolerance")
+				return
+			}
+		}
+
+		source := q.Get("source")
+		if source == "" {
+			source = "api"
+		}
 
+		rec, err := reconcile.New(h.repo).Run(r.Context(), r.Body, reconcile.Options{
+			Source:        source,
+			Mapping:       mapping,
+			DateTolerance: tolerance,
+		})
+		// the file is cut off by the size the route accepts
+		if tooLarge := decode.TooLarge(err); tooLarge != nil {
+			decodeErrorWriter(w, r, tooLarge)
+			return
+		}
+		if errors.Is(err, reconcile.ErrInvalidFile) {
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("failed to reconcile the settlement file")
+			errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+			return
+		}
+
+		if err := writer.WriteJSON(w, http.StatusCreated, toReconciliationResPayload(rec)); err != nil {
+			log.Error().Err(err).Msg("failed to write")
+			return
+		}
 	}
 }
 
-func validateCreateTransactionReq(req *CreateTransactionReqPayload) (errs []string) {
-	if req.AccountID <= 0 {
-		errs = append(errs, "invalid account_id")
+// GetReconciliation handler function returns a saved reconciliation report
+func (h *handler) GetReconciliation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recID, err := strconv.Atoi(chi.URLParam(r, "reconciliationId"))
+		if err != nil || recID <= 0 {
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "invalid reconciliationId")
+			return
+		}
+
+		rec, err := h.repo.GetReconciliation(r.Context(), recID)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to retrieve the reconciliation")
+			errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+			return
+		}
+
+		if rec == nil {
+			errorWriter(w, r, http.StatusNotFound, problem.CodeReconciliationNotFound, "reconciliation not found")
+			return
+		}
+
+		if err := writer.WriteJSON(w, http.StatusOK, toReconciliationResPayload(rec)); err != nil {
+			log.Error().Err(err).Msg("failed to write")
+			return
+		}
 	}
-	if req.Amount == 0 {
-		errs = append(errs, "invalid amount")
+}
+
+func toReconciliationResPayload(rec *repository.Reconciliation) ReconciliationResPayload {
+	res := ReconciliationResPayload{
+		ReconciliationID: rec.ReconciliationID,
+		Source:           rec.Source,
+		DateTolerance:    rec.DateTolerance.String(),
+		CreatedAt:        rec.CreatedAt,
+		Summary: ReconciliationSummaryPayload{
+			Matched:          rec.Matched,
+			MissingInLedger:  rec.MissingInLedger,
+			MissingInFile:    rec.MissingInFile,
+			AmountMismatches: rec.AmountMismatches,
+		},
+		Items: make([]ReconciliationItemPayload, 0, len(rec.Items)),
 	}
 
-	if req.OperationTypeID <= 0 {
-		errs = append(errs, "invalid operation_type_id")
+	for _, item := range rec.Items {
+		res.Items = append(res.Items, ReconciliationItemPayload{
+			Status:            item.Status,
+			Line:              item.Line,
+			ExternalReference: item.ExternalReference,
+			AccountID:         item.AccountID,
+			FileAmount:        item.FileAmount,
+			LedgerAmount:      item.LedgerAmount,
+			EventDate:         item.EventDate,
+			TransactionID:     item.TransactionID,
+		})
 	}
 
-	return
+	return res
 }
 
-// errorWriter writes error response to the caller
-func errorWriter(w http.ResponseWriter, status int, errMsg string) {
-	errResp := GenericErrRespPayload{
-		Message: errMsg,
+// accountIDFromURL reads and validates the accountId url param
+func accountIDFromURL(r *http.Request) (int, error) {
+	accIdParam := chi.URLParam(r, "accountId")
+	if accIdParam == "" {
+		return 0, errors.New("accountID required")
 	}
 
-	if err := writer.WriteJSON(w, status, errResp); err != nil {
-		log.Error().Err(err).Msg("failed writting to the client")
+	accID, err := strconv.Atoi(accIdParam)
+	if err != nil || accID <= 0 {
+		return 0, errors.New("invalid accountId")
+	}
+
+	return accID, nil
+}
+
+// parsePeriod reads the optional from and to query params, both accept RFC 3339 timestamps or dates,
+// a date passed as to covers the whole day
+func parsePeriod(r *http.Request) (from, to time.Time, err error) {
+	q := r.URL.Query()
+
+	if v := q.Get("from"); v != "" {
+		if from, err = parseTime(v, false); err != nil {
+			return from, to, errors.New("invalid from")
+		}
+	}
+
+	if v := q.Get("to"); v != "" {
+		if to, err = parseTime(v, true); err != nil {
+			return from, to, errors.New("invalid to")
+		}
+	}
+
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return from, to, errors.New("from must be before to")
+	}
+
+	return from, to, nil
+}
+
+func parseTime(v string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return t, err
+	}
+
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t, nil
+}
+
+// serviceErrorWriter responds with the failure of a service call, the fields failing validation are in the body
+// unless told otherwise
+func serviceErrorWriter(w http.ResponseWriter, r *http.Request, err error) {
+	var se *service.Error
+	if !errors.As(err, &se) {
+		log.Error().Err(err).Msg("request failed")
+		errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+		return
+	}
+
+	status := http.StatusBadRequest
+	switch se.Kind {
+	case service.KindNotFound:
+		status = http.StatusNotFound
+	case service.KindConflict:
+		status = http.StatusConflict
+	case service.KindPreconditionFailed:
+		status = http.StatusPreconditionFailed
+	}
+
+	fields := make([]problem.FieldError, 0, len(se.Fields))
+	for _, f := range se.Fields {
+		if f.In == "" {
+			f.In = "body"
+		}
+		fields = append(fields, f)
+	}
+
+	problemWriter(w, problem.New(r, status, se.Code, se.Message).WithErrors(fields...))
+}
+
+// decodeErrorWriter responds with the reason the body couldn't be decoded
+func decodeErrorWriter(w http.ResponseWriter, r *http.Request, err error) {
+	var de *decode.Error
+	if !errors.As(err, &de) {
+		log.Error().Err(err).Msg("failed to decode body")
+		errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidBody, "failed to decode body")
 		return
 	}
+
+	problemWriter(w, problem.New(r, de.Status, de.Code, de.Detail).WithErrors(de.Fields...))
+}
+
+// fieldErrorWriter responds with the fields failing validation, the detail is the first of them
+func fieldErrorWriter(w http.ResponseWriter, r *http.Request, errs []problem.FieldError) {
+	detail := errs[0].Name + " " + errs[0].Message
+	problemWriter(w, problem.New(r, http.StatusBadRequest, problem.CodeValidationFailed, detail).WithErrors(errs...))
+}
+
+// errorWriter writes error response to the caller
+func errorWriter(w http.ResponseWriter, r *http.Request, status int, code problem.Code, detail string) {
+	problemWriter(w, problem.New(r, status, code, detail))
+}
+
+func problemWriter(w http.ResponseWriter, p *problem.Problem) {
+	if err := p.Write(w); err != nil {
+		log.Error().Err(err).Msg("failed writting to the client")
+	}
 }
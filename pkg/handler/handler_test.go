@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/sathishs-dev/pismo-transactions/pkg/mocks"
@@ -31,11 +32,36 @@ func (h *handlerTestSuite) SetupTest() {
 	h.router = chi.NewRouter()
 	h.repo = new(mocks.PismoRepo)
 
-	handler := NewHandler(h.repo)
+	handler := NewHandler(h.repo, WithMaxBatchSize(3), WithEventsPollInterval(time.Millisecond))
 
 	h.router.Post("/accounts", handler.CreateAccount())
+	h.router.Get("/accounts", handler.ListAccounts())
 	h.router.Get("/accounts/{accountId}", handler.GetAccount())
+	h.router.Patch("/accounts/{accountId}/metadata", handler.UpdateAccountMetadata())
+	h.router.Get("/accounts/{accountId}/balance", handler.GetAccountBalance())
+	h.router.Get("/accounts/{accountId}/events", handler.AccountEvents())
+	h.router.Post("/accounts/{accountId}/erasure", handler.EraseAccount())
 	h.router.Post("/transactions", handler.CreateTransaction())
+	h.router.Post("/v2/transactions", handler.CreateTransactionV2())
+	h.router.Post("/transactions:batch", handler.CreateTransactionBatch())
+	h.router.Post("/v2/transactions:batch", handler.CreateTransactionBatchV2())
+	h.router.Get("/accounts/{accountId}/transactions/export", handler.ExportTransactions())
+	h.router.Post("/reconciliations", handler.CreateReconciliation())
+	h.router.Get("/reconciliations/{reconciliationId}", handler.GetReconciliation())
+	h.router.Post("/webhooks", handler.CreateWebhook())
+	h.router.Get("/webhooks", handler.ListWebhooks())
+	h.router.Get("/webhooks/{webhookId}", handler.GetWebhook())
+	h.router.Delete("/webhooks/{webhookId}", handler.DeleteWebhook())
+	h.router.Post("/webhooks/{webhookId}/enable", handler.EnableWebhook())
+	h.router.Get("/webhooks/{webhookId}/dead-letters", handler.ListWebhookDeadLetters())
+	h.router.Post("/webhooks/{webhookId}/dead-letters/replay", handler.ReplayWebhookDeadLetters())
+	h.router.Get("/audit-log", handler.ListAuditLog())
+	h.router.Post("/api-keys", handler.CreateAPIKey())
+	h.router.Get("/api-keys", handler.ListAPIKeys())
+	h.router.Get("/api-keys/{keyId}", handler.GetAPIKey())
+	h.router.Post("/api-keys/{keyId}/revoke", handler.RevokeAPIKey())
+	h.router.Get("/changes", handler.ListChanges())
+	h.router.Post("/rpc", handler.RPC())
 }
 
 func (h *handlerTestSuite) TestCreateAccount() {
@@ -51,11 +77,36 @@ func (h *handlerTestSuite) TestCreateAccount() {
 			expectedMocks: func(h *handlerTestSuite) {
 				h.repo.On("GetAccountByDocumentNo", mock.Anything, "1234567890").
 					Return(false, nil)
-				h.repo.On("CreateAccount", mock.Anything, "1234567890").
+				h.repo.On("CreateAccount", mock.Anything, &repository.Account{DocumentNo: "1234567890"}).
 					Return(nil)
 			},
 			expectedStatusCode: http.StatusCreated,
 		},
+		{
+			name:    "Valid Create Account Request - Metadata And Tags",
+			reqBody: `{"document_number": "1234567890", "metadata": {"customer_id": "c-42"}, "tags": ["vip"]}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByDocumentNo", mock.Anything, "1234567890").
+					Return(false, nil)
+				h.repo.On("CreateAccount", mock.Anything, &repository.Account{
+					DocumentNo: "1234567890",
+					Metadata:   map[string]string{"customer_id": "c-42"},
+					Tags:       []string{"vip"},
+				}).
+					Return(nil)
+			},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "Invalid Create Account Request - Reserved Metadata Key",
+			reqBody:            `{"document_number": "1234567890", "metadata": {"document_number": "1234567890"}}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Create Account Request - Invalid Tag",
+			reqBody:            `{"document_number": "1234567890", "tags": ["not a tag"]}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
 		{
 			name:               "Invalid Create Account Request - Empty Payload",
 			reqBody:            ``,
@@ -91,7 +142,7 @@ func (h *handlerTestSuite) TestCreateAccount() {
 			expectedMocks: func(h *handlerTestSuite) {
 				h.repo.On("GetAccountByDocumentNo", mock.Anything, "1234567890").
 					Return(false, nil)
-				h.repo.On("CreateAccount", mock.Anything, "1234567890").
+				h.repo.On("CreateAccount", mock.Anything, &repository.Account{DocumentNo: "1234567890"}).
 					Return(errors.New("err"))
 			},
 		},
@@ -118,8 +169,10 @@ func (h *handlerTestSuite) TestGetAccount() {
 	tcs := []struct {
 		name               string
 		accID              int
+		ifNoneMatch        string
 		expectedMocks      func(h *handlerTestSuite)
 		expectedStatusCode int
+		expectedETag       string
 	}{
 		{
 			name:  "Valid Get Account Request",
@@ -129,9 +182,33 @@ func (h *handlerTestSuite) TestGetAccount() {
 					Return(&repository.Account{
 						AccountID:  1,
 						DocumentNo: "1234567890",
+						Version:    3,
 					}, nil)
 			},
 			expectedStatusCode: http.StatusOK,
+			expectedETag:       `"3"`,
+		},
+		{
+			name:        "Valid Get Account Request - Not Modified",
+			accID:       1,
+			ifNoneMatch: `"2", W/"3"`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(&repository.Account{AccountID: 1, DocumentNo: "1234567890", Version: 3}, nil)
+			},
+			expectedStatusCode: http.StatusNotModified,
+			expectedETag:       `"3"`,
+		},
+		{
+			name:        "Valid Get Account Request - Modified",
+			accID:       1,
+			ifNoneMatch: `"2"`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(&repository.Account{AccountID: 1, DocumentNo: "1234567890", Version: 3}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       `"3"`,
 		},
 		{
 			name:               "Invalid Get Account Request - Empty AccountId",
@@ -140,7 +217,7 @@ func (h *handlerTestSuite) TestGetAccount() {
 		{
 			name:               "Invalid Get Account Request - No Account Found",
 			accID:              100,
-			expectedStatusCode: http.StatusBadRequest,
+			expectedStatusCode: http.StatusNotFound,
 			expectedMocks: func(h *handlerTestSuite) {
 				h.repo.On("GetAccountByAccountID", mock.Anything, 100).
 					Return(nil, nil)
@@ -166,6 +243,9 @@ func (h *handlerTestSuite) TestGetAccount() {
 		h.T().Run(tc.name, func(t *testing.T) {
 			h.recorder = httptest.NewRecorder()
 			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d", tc.accID), nil)
+			if tc.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
 
 			if tc.expectedMocks != nil {
 				tc.expectedMocks(h)
@@ -173,18 +253,97 @@ func (h *handlerTestSuite) TestGetAccount() {
 
 			h.router.ServeHTTP(h.recorder, req)
 			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+			h.Equal(tc.expectedETag, h.recorder.Header().Get("ETag"))
+			if tc.expectedStatusCode == http.StatusNotModified {
+				h.Empty(h.recorder.Body.Bytes())
+			}
 			h.repo.ExpectedCalls = nil
 		})
 	}
 
 }
 
+func (h *handlerTestSuite) TestGetAccountBalance() {
+	tcs := []struct {
+		name               string
+		query              string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:  "Valid Get Balance Request - As Of Date",
+			query: "?as_of=2024-12-05",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(&repository.Account{AccountID: 1, DocumentNo: "1234567890"}, nil)
+				h.repo.On("GetAccountBalance", mock.Anything, 1, time.Date(2024, 12, 6, 0, 0, 0, 0, time.UTC)).
+					Return(-150.5, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"account_id":1,"balance":-150.5,"as_of":"2024-12-06T00:00:00Z"}`,
+		},
+		{
+			name: "Valid Get Balance Request - Now",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(&repository.Account{AccountID: 1, DocumentNo: "1234567890"}, nil)
+				h.repo.On("GetAccountBalance", mock.Anything, 1, mock.Anything).
+					Return(10.0, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Invalid Get Balance Request - Invalid As Of",
+			query:              "?as_of=yesterday",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Invalid Get Balance Request - No Account Found",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Invalid Get Balance Request - Balance Fails",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(&repository.Account{AccountID: 1, DocumentNo: "1234567890"}, nil)
+				h.repo.On("GetAccountBalance", mock.Anything, 1, mock.Anything).
+					Return(0.0, errors.New("err"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/accounts/1/balance"+tc.query, nil)
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+			if tc.expectedBody != "" {
+				h.JSONEq(tc.expectedBody, h.recorder.Body.String())
+			}
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
+
 func (h *handlerTestSuite) TestCreateTransaction() {
 	tcs := []struct {
 		name               string
 		reqBody            string
 		expectedMocks      func(h *handlerTestSuite)
 		expectedStatusCode int
+		expectedBody       string
 	}{
 		{
 			name:    "Valid Create Transaction Request",
@@ -201,15 +360,102 @@ func (h *handlerTestSuite) TestCreateTransaction() {
 			},
 			expectedStatusCode: http.StatusCreated,
 		},
+		{
+			name:    "Valid Create Transaction Request - External Reference",
+			reqBody: `{"account_id": 1, "operation_type_id": 1, "amount": -500.00, "external_reference": "NSU-1"}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(&repository.Account{
+						AccountID:  1,
+						DocumentNo: "1234567890",
+					}, nil)
+				h.repo.On("CreateTransaction", mock.Anything,
+					repository.Transaction{AccountID: 1, OperationTypeID: 1, Amount: -500.00, ExternalReference: "NSU-1"},
+				).Return(nil)
+			},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:    "Invalid Create Transaction Request - Duplicate External Reference",
+			reqBody: `{"account_id": 1, "operation_type_id": 1, "amount": -500.00, "external_reference": "NSU-1"}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(&repository.Account{
+						AccountID:  1,
+						DocumentNo: "1234567890",
+					}, nil)
+				h.repo.On("CreateTransaction", mock.Anything,
+					repository.Transaction{AccountID: 1, OperationTypeID: 1, Amount: -500.00, ExternalReference: "NSU-1"},
+				).Return(repository.ErrDuplicateExternalReference)
+			},
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:    "Invalid Create Transaction Request - Erased Account",
+			reqBody: `{"account_id": 1, "operation_type_id": 1, "amount": -500.00}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				erasedAt := time.Date(2024, 12, 6, 0, 0, 0, 0, time.UTC)
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(&repository.Account{
+						AccountID: 1,
+						ErasedAt:  &erasedAt,
+					}, nil)
+			},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:    "Invalid Create Transaction Request - Account Erased Meanwhile",
+			reqBody: `{"account_id": 1, "operation_type_id": 1, "amount": -500.00}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(&repository.Account{
+						AccountID:  1,
+						DocumentNo: "1234567890",
+					}, nil)
+				h.repo.On("CreateTransaction", mock.Anything,
+					repository.Transaction{AccountID: 1, OperationTypeID: 1, Amount: -500.00},
+				).Return(repository.ErrAccountErased)
+			},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Create Transaction Request - External Reference Too Long",
+			reqBody:            `{"account_id": 1, "operation_type_id": 1, "amount": -500.00, "external_reference": "` + strings.Repeat("x", 65) + `"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Create Transaction Request - Unknown Field",
+			reqBody:            `{"account_id": 1, "operation_type_id": 1, "amount": -500.00, "amout": -500.00}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody: `{"type": "urn:pismo-transactions:problem:validation_failed", "title": "Validation failed", "status": 400,
+				"detail": "amout is not a known field", "instance": "/transactions", "code": "validation_failed",
+				"errors": [{"in": "body", "name": "amout", "message": "is not a known field"}]}`,
+		},
+		{
+			name:               "Invalid Create Transaction Request - Trailing Data",
+			reqBody:            `{"account_id": 1, "operation_type_id": 1, "amount": -500.00}]`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody: `{"type": "urn:pismo-transactions:problem:invalid_body", "title": "Invalid body", "status": 400,
+				"detail": "body must contain a single json value", "instance": "/transactions", "code": "invalid_body"}`,
+		},
 		{
 			name:               "Invalid Create Transaction Request - Empty Payload",
 			reqBody:            `{}`,
 			expectedStatusCode: http.StatusBadRequest,
+			expectedBody: `{"type": "urn:pismo-transactions:problem:validation_failed", "title": "Validation failed", "status": 400,
+				"detail": "account_id must be a positive integer", "instance": "/transactions", "code": "validation_failed",
+				"errors": [
+					{"in": "body", "name": "account_id", "message": "must be a positive integer"},
+					{"in": "body", "name": "amount", "message": "must not be zero"},
+					{"in": "body", "name": "operation_type_id", "message": "must be a positive integer"}
+				]}`,
 		},
 		{
 			name:               "Invalid Create Transaction Request - Invalid Account",
 			reqBody:            `{"account_id": 2000, "operation_type_id": 1, "amount": -500.00}`,
 			expectedStatusCode: http.StatusBadRequest,
+			expectedBody: `{"type": "urn:pismo-transactions:problem:account_not_found", "title": "Account not found", "status": 400,
+				"detail": "account not found", "instance": "/transactions", "code": "account_not_found"}`,
 			expectedMocks: func(h *handlerTestSuite) {
 				h.repo.On("GetAccountByAccountID", mock.Anything, 2000).
 					Return(nil, nil)
@@ -239,6 +485,8 @@ func (h *handlerTestSuite) TestCreateTransaction() {
 			name:               "Invalid Create Transaction Request - Get Account Fails",
 			reqBody:            `{"account_id": 1, "operation_type_id": 4, "amount": 1000.00}`,
 			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody: `{"type": "urn:pismo-transactions:problem:internal_error", "title": "Internal error", "status": 500,
+				"detail": "please try again later.", "instance": "/transactions", "code": "internal_error"}`,
 			expectedMocks: func(h *handlerTestSuite) {
 				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
 					Return(nil, errors.New("err"))
@@ -272,8 +520,311 @@ func (h *handlerTestSuite) TestCreateTransaction() {
 
 			h.router.ServeHTTP(h.recorder, req)
 			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+			if tc.expectedBody != "" {
+				h.Equal("application/problem+json", h.recorder.Header().Get("Content-Type"))
+				h.JSONEq(tc.expectedBody, h.recorder.Body.String())
+			}
 			h.repo.ExpectedCalls = nil
 		})
 	}
 
 }
+
+func (h *handlerTestSuite) TestExportTransactions() {
+	txns := []repository.Transaction{
+		{
+			TransactionID:   1,
+			AccountID:       1,
+			OperationTypeID: 1,
+			Description:     "Normal Purchase",
+			Amount:          -50,
+			EventDate:       time.Date(2024, 12, 5, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			TransactionID:   2,
+			AccountID:       1,
+			OperationTypeID: 4,
+			Description:     "Credit Voucher",
+			Amount:          60,
+			EventDate:       time.Date(2024, 12, 6, 8, 0, 0, 0, time.UTC),
+		},
+	}
+
+	streamTxns := func(args mock.Arguments) {
+		fn := args.Get(2).(func(repository.Transaction) error)
+		for _, txn := range txns {
+			h.NoError(fn(txn))
+		}
+	}
+
+	tcs := []struct {
+		name                string
+		query               string
+		expectedMocks       func(h *handlerTestSuite)
+		expectedStatusCode  int
+		expectedContentType string
+		expectedBody        []string
+	}{
+		{
+			name:  "Valid Export Request - CSV",
+			query: "?format=csv&from=2024-12-01&to=2024-12-31",
+			expectedMocks: func(h *handlerTestSuite) {
+				from := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+				to := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(&repository.Account{AccountID: 1, DocumentNo: "1234567890"}, nil)
+				h.repo.On("GetAccountBalance", mock.Anything, 1, from).
+					Return(100.0, nil)
+				h.repo.On("StreamTransactions", mock.Anything,
+					repository.TransactionFilter{AccountID: 1, From: from, To: to}, mock.Anything,
+				).Run(streamTxns).Return(nil)
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody: []string{
+				"transaction_id,event_date,description,amount,balance\r\n",
+				"1,2024-12-05T12:30:00Z,Normal Purchase,-50.00,50.00\r\n",
+				"2,2024-12-06T08:00:00Z,Credit Voucher,60.00,110.00\r\n",
+			},
+		},
+		{
+			name:  "Valid Export Request - OFX",
+			query: "?format=ofx",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(&repository.Account{AccountID: 1, DocumentNo: "1234567890"}, nil)
+				h.repo.On("StreamTransactions", mock.Anything,
+					repository.TransactionFilter{AccountID: 1}, mock.Anything,
+				).Run(streamTxns).Return(nil)
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "application/x-ofx",
+			expectedBody: []string{
+				"<MEMO>Normal Purchase</MEMO>",
+				"<MEMO>Credit Voucher</MEMO>",
+				"<LEDGERBAL><BALAMT>10.00</BALAMT>",
+			},
+		},
+		{
+			name:  "Valid Export Request - camt.053",
+			query: "?format=camt053",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(&repository.Account{AccountID: 1, DocumentNo: "1234567890"}, nil)
+				h.repo.On("StreamTransactions", mock.Anything,
+					repository.TransactionFilter{AccountID: 1}, mock.Anything,
+				).Run(streamTxns).Return(nil)
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "application/xml",
+			expectedBody: []string{
+				`<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">`,
+				"<CdtDbtInd>DBIT</CdtDbtInd>",
+				"<Cd>CLBD</Cd>",
+			},
+		},
+		{
+			name:               "Invalid Export Request - Invalid Format",
+			query:              "?format=pdf",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Export Request - Invalid Period",
+			query:              "?format=csv&from=yesterday",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Export Request - From After To",
+			query:              "?format=csv&from=2025-01-01&to=2024-12-01",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:  "Invalid Export Request - No Account Found",
+			query: "?format=csv",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:  "Invalid Export Request - Opening Balance Fails",
+			query: "?format=csv&from=2024-12-01",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(&repository.Account{AccountID: 1, DocumentNo: "1234567890"}, nil)
+				h.repo.On("GetAccountBalance", mock.Anything, 1, mock.Anything).
+					Return(0.0, errors.New("err"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/accounts/1/transactions/export"+tc.query, nil)
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+			if tc.expectedContentType != "" {
+				h.Equal(tc.expectedContentType, h.recorder.Header().Get("Content-Type"))
+			}
+			for _, part := range tc.expectedBody {
+				h.Contains(h.recorder.Body.String(), part)
+			}
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
+
+func (h *handlerTestSuite) TestCreateReconciliation() {
+	tcs := []struct {
+		name               string
+		query              string
+		reqBody            string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+		expectedBody       []string
+	}{
+		{
+			name:    "Valid Create Reconciliation Request",
+			query:   "?columns=reference=nsu&tolerance=2h&source=cielo.csv",
+			reqBody: "nsu,account_id,amount,date\nA1,1,10,2024-12-05T10:00:00Z\n",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("StreamTransactions", mock.Anything, mock.Anything, mock.Anything).
+					Run(func(args mock.Arguments) {
+						fn := args.Get(2).(func(repository.Transaction) error)
+						h.NoError(fn(repository.Transaction{
+							TransactionID:     9,
+							AccountID:         1,
+							Amount:            -10,
+							EventDate:         time.Date(2024, 12, 5, 11, 0, 0, 0, time.UTC),
+							ExternalReference: "A1",
+						}))
+					}).Return(nil)
+				h.repo.On("SaveReconciliation", mock.Anything, mock.Anything).
+					Run(func(args mock.Arguments) {
+						args.Get(1).(*repository.Reconciliation).ReconciliationID = 3
+					}).Return(nil)
+			},
+			expectedStatusCode: http.StatusCreated,
+			expectedBody: []string{
+				`"reconciliation_id":3`,
+				`"source":"cielo.csv"`,
+				`"date_tolerance":"2h0m0s"`,
+				`"matched":1`,
+				`"transaction_id":9`,
+			},
+		},
+		{
+			name:               "Invalid Create Reconciliation Request - Invalid Mapping",
+			query:              "?columns=merchant=name",
+			reqBody:            "reference,account_id,amount,date\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Create Reconciliation Request - Invalid Tolerance",
+			query:              "?tolerance=1day",
+			reqBody:            "reference,account_id,amount,date\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Create Reconciliation Request - Invalid File",
+			reqBody:            "reference,account_id,amount,date\nA1,1,ten,2024-12-05\n",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       []string{"line 2: invalid amount"},
+		},
+		{
+			name:    "Invalid Create Reconciliation Request - Save Fails",
+			reqBody: "reference,account_id,amount,date\nA1,1,10,2024-12-05\n",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("StreamTransactions", mock.Anything, mock.Anything, mock.Anything).
+					Return(nil)
+				h.repo.On("SaveReconciliation", mock.Anything, mock.Anything).
+					Return(errors.New("err"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/reconciliations"+tc.query, strings.NewReader(tc.reqBody))
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+			for _, part := range tc.expectedBody {
+				h.Contains(h.recorder.Body.String(), part)
+			}
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
+
+func (h *handlerTestSuite) TestGetReconciliation() {
+	tcs := []struct {
+		name               string
+		recID              string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+	}{
+		{
+			name:  "Valid Get Reconciliation Request",
+			recID: "3",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetReconciliation", mock.Anything, 3).
+					Return(&repository.Reconciliation{ReconciliationID: 3, Matched: 1}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Invalid Get Reconciliation Request - Invalid ID",
+			recID:              "abc",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:  "Invalid Get Reconciliation Request - Not Found",
+			recID: "4",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetReconciliation", mock.Anything, 4).
+					Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:  "Invalid Get Reconciliation Request - Fetching DataStore failed",
+			recID: "5",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetReconciliation", mock.Anything, 5).
+					Return(nil, errors.New("err"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/reconciliations/"+tc.recID, nil)
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
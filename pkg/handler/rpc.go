@@ -0,0 +1,360 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/decode"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/writer"
+	"github.com/sathishs-dev/pismo-transactions/pkg/auth"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/sathishs-dev/pismo-transactions/pkg/service"
+)
+
+const (
+	rpcVersion = "2.0"
+
+	// maxRPCBatch is the number of calls accepted in a batch request
+	maxRPCBatch = 100
+)
+
+// JSON-RPC 2.0 error codes, the server defined ones mirror the 403, 404 and 409 of the rest api
+const (
+	RPCParseError     = -32700
+	RPCInvalidRequest = -32600
+	RPCMethodNotFound = -32601
+	RPCInvalidParams  = -32602
+	RPCInternalError  = -32603
+	RPCForbidden      = -32003
+	RPCNotFound       = -32004
+	RPCConflict       = -32009
+)
+
+// rpcMethod calls the service with the params of a call and returns its result
+type rpcMethod func(ctx context.Context, params json.RawMessage) (any, error)
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+// newRPCMethods returns the methods served by RPC, they call the same service as the rest handlers
+func (h *handler) newRPCMethods() map[string]rpcMethod {
+	return map[string]rpcMethod{
+		"accounts.create": rpcMethodOf(func(ctx context.Context, p CreateAccountReqPayload) (any, error) {
+			acc, err := h.svc.CreateAccount(ctx, service.CreateAccountInput{
+				DocumentNumber: p.DocumentNumber,
+				Metadata:       p.Metadata,
+				Tags:           p.Tags,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			return CreateAccountResPayload{AccountID: acc.AccountID}, nil
+		}),
+
+		"accounts.get": rpcMethodOf(func(ctx context.Context, p RPCAccountReqPayload) (any, error) {
+			acc, err := h.svc.GetAccount(ctx, p.AccountID)
+			if err != nil {
+				return nil, err
+			}
+
+			return toAccountResPayload(acc), nil
+		}),
+
+		"accounts.getBalance": rpcMethodOf(func(ctx context.Context, p RPCAccountBalanceReqPayload) (any, error) {
+			asOf, err := p.asOf()
+			if err != nil {
+				return nil, err
+			}
+
+			balance, err := h.svc.GetAccountBalance(ctx, p.AccountID, asOf)
+			if err != nil {
+				return nil, err
+			}
+
+			return GetAccountBalanceResPayload{AccountID: p.AccountID, Balance: balance, AsOf: asOf}, nil
+		}),
+
+		"accounts.updateMetadata": rpcMethodOf(func(ctx context.Context, p RPCUpdateAccountMetadataReqPayload) (any, error) {
+			acc, err := h.svc.UpdateAccountMetadata(ctx, p.AccountID, repository.AccountMetadataPatch{
+				Metadata: p.Metadata,
+				Tags:     p.Tags,
+				IfMatch:  ifVersion(p.IfVersion),
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			return toAccountResPayload(acc), nil
+		}),
+
+		"accounts.erase": rpcMethodOf(func(ctx context.Context, p RPCEraseAccountReqPayload) (any, error) {
+			erasure, err := h.svc.EraseAccount(ctx, p.AccountID, p.DryRun, ifVersion(p.IfVersion))
+			if err != nil {
+				return nil, err
+			}
+
+			return toAccountErasureResPayload(erasure), nil
+		}),
+
+		"transactions.create": rpcMethodOf(func(ctx context.Context, p CreateTransactionReqPayload) (any, error) {
+			return nil, h.svc.CreateTransaction(ctx, service.CreateTransactionInput{
+				AccountID:         p.AccountID,
+				OperationTypeID:   p.OperationTypeID,
+				Amount:            p.Amount,
+				ExternalReference: p.ExternalReference,
+			})
+		}),
+	}
+}
+
+// rpcScopes are the scopes the methods require, like their rest routes do
+var rpcScopes = map[string]enums.Scope{
+	"accounts.create":         enums.ScopeAccountsWrite,
+	"accounts.get":            enums.ScopeAccountsRead,
+	"accounts.getBalance":     enums.ScopeAccountsRead,
+	"accounts.updateMetadata": enums.ScopeAccountsWrite,
+	"accounts.erase":          enums.ScopeAccountsWrite,
+	"transactions.create":     enums.ScopeTransactionsWrite,
+}
+
+// rpcMethodOf decodes the params of a call before calling fn, params are passed by name only
+func rpcMethodOf[P any](fn func(ctx context.Context, params P) (any, error)) rpcMethod {
+	return func(ctx context.Context, raw json.RawMessage) (any, error) {
+		var params P
+
+		raw = bytes.TrimSpace(raw)
+		if len(raw) > 0 && !bytes.Equal(raw, []byte("null")) {
+			if raw[0] != '{' {
+				return nil, &RPCError{Code: RPCInvalidParams, Message: "params must be an object"}
+			}
+
+			if err := decode.Unmarshal(raw, &params); err != nil {
+				return nil, rpcParamsError(err)
+			}
+		}
+
+		return fn(ctx, params)
+	}
+}
+
+// RPC handler function serves JSON-RPC 2.0 calls, a batch is answered with the responses of its calls that
+// aren't notifications and a request made only of notifications gets no content
+func (h *handler) RPC() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// a body larger than the route accepts is refused before being parsed
+		body, err := decode.Read(r)
+		if err != nil {
+			decodeErrorWriter(w, r, err)
+			return
+		}
+
+		body = bytes.TrimSpace(body)
+		if !json.Valid(body) {
+			writeRPC(w, rpcErrorResponse(nil, &RPCError{Code: RPCParseError, Message: "parse error"}))
+			return
+		}
+
+		if body[0] != '[' {
+			res := h.callRPC(r.Context(), body)
+			if res == nil {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			writeRPC(w, res)
+			return
+		}
+
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			writeRPC(w, rpcErrorResponse(nil, &RPCError{Code: RPCParseError, Message: "parse error"}))
+			return
+		}
+
+		switch {
+		case len(batch) == 0:
+			writeRPC(w, rpcErrorResponse(nil, &RPCError{Code: RPCInvalidRequest, Message: "empty batch"}))
+			return
+		case len(batch) > maxRPCBatch:
+			writeRPC(w, rpcErrorResponse(nil, &RPCError{Code: RPCInvalidRequest, Message: "batch too large"}))
+			return
+		}
+
+		responses := make([]*RPCResponse, 0, len(batch))
+		for _, raw := range batch {
+			if res := h.callRPC(r.Context(), raw); res != nil {
+				responses = append(responses, res)
+			}
+		}
+
+		if len(responses) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeRPC(w, responses)
+	}
+}
+
+// callRPC runs a single call, it returns nil for notifications
+func (h *handler) callRPC(ctx context.Context, raw json.RawMessage) *RPCResponse {
+	var req RPCRequest
+	if err := json.Unmarshal(raw, &req); err != nil || req.JSONRPC != rpcVersion || req.Method == "" || !validRPCID(req.ID) {
+		return rpcErrorResponse(nil, &RPCError{Code: RPCInvalidRequest, Message: "invalid request"})
+	}
+
+	var (
+		result any
+		err    error
+	)
+	method, ok := h.rpcMethods[req.Method]
+	switch {
+	case !ok:
+		err = &RPCError{Code: RPCMethodNotFound, Message: "method not found"}
+	case !auth.Allowed(ctx, rpcScopes[req.Method]):
+		err = &RPCError{
+			Code:    RPCForbidden,
+			Message: fmt.Sprintf("the %s scope is required", rpcScopes[req.Method]),
+			Data:    RPCErrorData{Code: problem.CodeInsufficientScope},
+		}
+	default:
+		result, err = method(ctx, req.Params)
+	}
+
+	// notifications get no response, not even for their failures, toRPCError still logs the internal ones
+	if req.ID == nil {
+		if err != nil {
+			toRPCError(err)
+		}
+		return nil
+	}
+
+	if err != nil {
+		return rpcErrorResponse(req.ID, toRPCError(err))
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode the result")
+		return rpcErrorResponse(req.ID, &RPCError{Code: RPCInternalError, Message: "please try again later."})
+	}
+
+	return &RPCResponse{JSONRPC: rpcVersion, Result: data, ID: req.ID}
+}
+
+// toRPCError maps the failure of a call to its error object, internal failures are only logged
+func toRPCError(err error) *RPCError {
+	var re *RPCError
+	if errors.As(err, &re) {
+		return re
+	}
+
+	var se *service.Error
+	if !errors.As(err, &se) {
+		log.Error().Err(err).Msg("rpc call failed")
+		return &RPCError{Code: RPCInternalError, Message: "please try again later."}
+	}
+
+	// the data carries the same error code and fields as the problem details of the rest api
+	data := RPCErrorData{Code: se.Code}
+	for _, f := range se.Fields {
+		if f.In == "" {
+			f.In = "params"
+		}
+		data.Errors = append(data.Errors, f)
+	}
+
+	switch se.Kind {
+	case service.KindNotFound:
+		return &RPCError{Code: RPCNotFound, Message: se.Message, Data: data}
+	case service.KindConflict, service.KindPreconditionFailed:
+		return &RPCError{Code: RPCConflict, Message: se.Message, Data: data}
+	default:
+		return &RPCError{Code: RPCInvalidParams, Message: se.Message, Data: data}
+	}
+}
+
+// ifVersion conditions a change on the version of the account, nil applies it whatever the version
+func ifVersion(v *int64) repository.IfMatch {
+	if v == nil {
+		return nil
+	}
+
+	return repository.IfMatch{*v}
+}
+
+// rpcParamsError reports the params failing to decode like the rest api reports the body fields
+func rpcParamsError(err error) *RPCError {
+	var de *decode.Error
+	if !errors.As(err, &de) {
+		return &RPCError{Code: RPCInvalidParams, Message: "failed to decode params"}
+	}
+
+	// the params are the body of the call
+	data := RPCErrorData{Code: de.Code}
+	for _, f := range de.Fields {
+		f.In = "params"
+		if f.Name == "body" {
+			f.Name = "params"
+		}
+		data.Errors = append(data.Errors, f)
+	}
+
+	msg := de.Detail
+	if rest, ok := strings.CutPrefix(msg, "body "); ok {
+		msg = "params " + rest
+	}
+
+	return &RPCError{Code: RPCInvalidParams, Message: msg, Data: data}
+}
+
+// validRPCID accepts the ids allowed by the spec, a string, a number or null, a missing id is a notification
+func validRPCID(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+
+	switch id[0] {
+	case '{', '[', 't', 'f':
+		return false
+	}
+
+	return true
+}
+
+func rpcErrorResponse(id json.RawMessage, rpcErr *RPCError) *RPCResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+
+	return &RPCResponse{JSONRPC: rpcVersion, Error: rpcErr, ID: id}
+}
+
+func writeRPC(w http.ResponseWriter, res any) {
+	if err := writer.WriteJSON(w, http.StatusOK, res); err != nil {
+		log.Error().Err(err).Msg("failed to write")
+	}
+}
+
+// asOf returns the time the balance is asked for, a date is the closing balance of that day, defaults to now
+func (p RPCAccountBalanceReqPayload) asOf() (time.Time, error) {
+	if p.AsOf == "" {
+		return time.Now(), nil
+	}
+
+	asOf, err := parseTime(p.AsOf, true)
+	if err != nil {
+		return asOf, &RPCError{Code: RPCInvalidParams, Message: "invalid as_of"}
+	}
+
+	return asOf, nil
+}
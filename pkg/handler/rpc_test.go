@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/sathishs-dev/pismo-transactions/pkg/auth"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+func (h *handlerTestSuite) TestRPC() {
+	tcs := []struct {
+		name               string
+		reqBody            string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:    "Valid RPC Request - Get Account",
+			reqBody: `{"jsonrpc": "2.0", "method": "accounts.get", "params": {"account_id": 1}, "id": 1}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(&repository.Account{AccountID: 1, DocumentNo: "1234567890", Status: "active", Version: 2}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"jsonrpc": "2.0", "id": 1, "result": {"account_id": 1, "document_number": "1234567890",
+				"status": "active", "metadata": {}, "tags": [], "created_at": "0001-01-01T00:00:00Z", "version": 2}}`,
+		},
+		{
+			name:    "Valid RPC Request - Create Account With Null ID",
+			reqBody: `{"jsonrpc": "2.0", "method": "accounts.create", "params": {"document_number": "1234567890"}, "id": null}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByDocumentNo", mock.Anything, "1234567890").
+					Return(false, nil)
+				h.repo.On("CreateAccount", mock.Anything, &repository.Account{DocumentNo: "1234567890"}).
+					Run(func(args mock.Arguments) { args.Get(1).(*repository.Account).AccountID = 7 }).
+					Return(nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"jsonrpc": "2.0", "id": null, "result": {"account_id": 7}}`,
+		},
+		{
+			name: "Valid RPC Request - Batch",
+			reqBody: `[
+				{"jsonrpc": "2.0", "method": "transactions.create", "params": {"account_id": 1, "operation_type_id": 4, "amount": 10}, "id": "a"},
+				{"jsonrpc": "2.0", "method": "transactions.create", "params": {"account_id": 0, "operation_type_id": 4, "amount": 0}, "id": "b"},
+				{"jsonrpc": "2.0", "method": "transactions.create", "params": {"account_id": 1, "operation_type_id": 4, "amount": 5}},
+				{"jsonrpc": "2.0", "method": "accounts.get", "params": {"account_id": 2}, "id": "c"},
+				{"jsonrpc": "2.0", "method": "accounts.delete", "id": "d"},
+				{"jsonrpc": "1.0", "method": "accounts.get", "id": "e"},
+				1
+			]`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(&repository.Account{AccountID: 1}, nil)
+				h.repo.On("GetAccountByAccountID", mock.Anything, 2).
+					Return(nil, nil)
+				h.repo.On("CreateTransaction", mock.Anything, mock.Anything).
+					Return(nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `[
+				{"jsonrpc": "2.0", "id": "a", "result": null},
+				{"jsonrpc": "2.0", "id": "b", "error": {"code": -32602, "message": "account_id must be a positive integer",
+					"data": {"code": "validation_failed", "errors": [
+						{"in": "params", "name": "account_id", "message": "must be a positive integer"},
+						{"in": "params", "name": "amount", "message": "must not be zero"}
+					]}}},
+				{"jsonrpc": "2.0", "id": "c", "error": {"code": -32004, "message": "account not found",
+					"data": {"code": "account_not_found"}}},
+				{"jsonrpc": "2.0", "id": "d", "error": {"code": -32601, "message": "method not found"}},
+				{"jsonrpc": "2.0", "id": null, "error": {"code": -32600, "message": "invalid request"}},
+				{"jsonrpc": "2.0", "id": null, "error": {"code": -32600, "message": "invalid request"}}
+			]`,
+		},
+		{
+			name:    "Valid RPC Request - Notifications Only",
+			reqBody: `[{"jsonrpc": "2.0", "method": "accounts.erase", "params": {"account_id": 1}}]`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("EraseAccount", mock.Anything, 1, false, repository.IfMatch(nil)).
+					Return(nil, repository.ErrAccountBalanceNotZero)
+			},
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:    "Invalid RPC Request - Conflict",
+			reqBody: `{"jsonrpc": "2.0", "method": "accounts.erase", "params": {"account_id": 1, "dry_run": true}, "id": 1}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("EraseAccount", mock.Anything, 1, true, repository.IfMatch(nil)).
+					Return(nil, repository.ErrAccountErased)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"jsonrpc": "2.0", "id": 1, "error": {"code": -32009, "message": "account already erased",
+				"data": {"code": "account_erased"}}}`,
+		},
+		{
+			name: "Invalid RPC Request - Precondition Failed",
+			reqBody: `{"jsonrpc": "2.0", "method": "accounts.updateMetadata",
+				"params": {"account_id": 1, "tags": ["vip"], "if_version": 2}, "id": 1}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("UpdateAccountMetadata", mock.Anything, 1, repository.AccountMetadataPatch{
+					Tags:    []string{"vip"},
+					IfMatch: repository.IfMatch{2},
+				}).
+					Return(nil, repository.ErrVersionMismatch)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"jsonrpc": "2.0", "id": 1, "error": {"code": -32009, "message": "account was modified, fetch it again",
+				"data": {"code": "precondition_failed"}}}`,
+		},
+		{
+			name:               "Invalid RPC Request - Unknown Param",
+			reqBody:            `{"jsonrpc": "2.0", "method": "accounts.get", "params": {"accountId": 1}, "id": 1}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"jsonrpc": "2.0", "id": 1, "error": {"code": -32602, "message": "accountId is not a known field",
+				"data": {"code": "validation_failed", "errors": [{"in": "params", "name": "accountId", "message": "is not a known field"}]}}}`,
+		},
+		{
+			name:               "Invalid RPC Request - Param Type Mismatch",
+			reqBody:            `{"jsonrpc": "2.0", "method": "accounts.get", "params": {"account_id": "1"}, "id": 1}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"jsonrpc": "2.0", "id": 1, "error": {"code": -32602, "message": "account_id must be an integer",
+				"data": {"code": "validation_failed", "errors": [{"in": "params", "name": "account_id", "message": "must be an integer"}]}}}`,
+		},
+		{
+			name:               "Invalid RPC Request - Positional Params",
+			reqBody:            `{"jsonrpc": "2.0", "method": "accounts.get", "params": [1], "id": 1}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"jsonrpc": "2.0", "id": 1, "error": {"code": -32602, "message": "params must be an object"}}`,
+		},
+		{
+			name:               "Invalid RPC Request - Invalid As Of",
+			reqBody:            `{"jsonrpc": "2.0", "method": "accounts.getBalance", "params": {"account_id": 1, "as_of": "yesterday"}, "id": 1}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"jsonrpc": "2.0", "id": 1, "error": {"code": -32602, "message": "invalid as_of"}}`,
+		},
+		{
+			name:               "Invalid RPC Request - Parse Error",
+			reqBody:            `{"jsonrpc": "2.0", "method"`,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"jsonrpc": "2.0", "id": null, "error": {"code": -32700, "message": "parse error"}}`,
+		},
+		{
+			name:               "Invalid RPC Request - Empty Batch",
+			reqBody:            `[]`,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"jsonrpc": "2.0", "id": null, "error": {"code": -32600, "message": "empty batch"}}`,
+		},
+		{
+			name:    "Invalid RPC Request - Fetching DataStore failed",
+			reqBody: `{"jsonrpc": "2.0", "method": "accounts.get", "params": {"account_id": 1}, "id": 1}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(nil, errors.New("err"))
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"jsonrpc": "2.0", "id": 1, "error": {"code": -32603, "message": "please try again later."}}`,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(tc.reqBody))
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+			if tc.expectedBody != "" {
+				h.JSONEq(tc.expectedBody, h.recorder.Body.String())
+			} else {
+				h.Empty(h.recorder.Body.String())
+			}
+			h.repo.AssertExpectations(t)
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
+
+func (h *handlerTestSuite) TestRPCScopes() {
+	authn := auth.New(h.repo)
+	h.repo.On("GetAPIKeyByHash", mock.Anything, auth.HashKey("pk_reader")).
+		Return(&repository.APIKey{KeyID: 1, Scopes: pq.StringArray{"accounts:read"}}, nil)
+	h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+		Return(&repository.Account{AccountID: 1, DocumentNo: "1234567890", Status: "active"}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(`[
+		{"jsonrpc": "2.0", "method": "accounts.get", "params": {"account_id": 1}, "id": 1},
+		{"jsonrpc": "2.0", "method": "transactions.create", "params": {"account_id": 1, "operation_type_id": 4, "amount": 10}, "id": 2}
+	]`))
+	req.Header.Set(auth.Header, "pk_reader")
+
+	authn.Middleware(h.router).ServeHTTP(h.recorder, req)
+	h.Equal(http.StatusOK, h.recorder.Code)
+	h.JSONEq(`[
+		{"jsonrpc": "2.0", "id": 1, "result": {"account_id": 1, "document_number": "1234567890", "status": "active",
+			"metadata": {}, "tags": [], "created_at": "0001-01-01T00:00:00Z", "version": 0}},
+		{"jsonrpc": "2.0", "id": 2, "error": {"code": -32003, "message": "the transactions:write scope is required",
+			"data": {"code": "insufficient_scope"}}}
+	]`, h.recorder.Body.String())
+	h.repo.AssertExpectations(h.T())
+}
@@ -1,22 +1,274 @@
 package handler
 
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/pkg/audit"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+)
+
 type (
 	CreateAccountReqPayload struct {
-		DocumentNumber string `json:"document_number"`
+		DocumentNumber string            `json:"document_number"`
+		Metadata       map[string]string `json:"metadata,omitempty"`
+		Tags           []string          `json:"tags,omitempty"`
 	}
 
 	GetAccountResPaylaod struct {
-		AccountID      int    `json:"account_id"`
-		DocumentNumber string `json:"document_number"`
+		AccountID      int                 `json:"account_id"`
+		DocumentNumber string              `json:"document_number"`
+		Status         enums.AccountStatus `json:"status"`
+		Metadata       map[string]string   `json:"metadata"`
+		Tags           []string            `json:"tags"`
+		CreatedAt      time.Time           `json:"created_at"`
+		ErasedAt       *time.Time          `json:"erased_at,omitempty"`
+		Version        int64               `json:"version"`
+	}
+
+	AccountsResPayload struct {
+		Accounts   []GetAccountResPaylaod `json:"accounts"`
+		NextCursor string                 `json:"next_cursor,omitempty"`
+		TotalCount *int64                 `json:"total_count,omitempty"`
+	}
+
+	UpdateAccountMetadataReqPayload struct {
+		Metadata map[string]*string `json:"metadata"`
+		Tags     []string           `json:"tags"`
+	}
+
+	AccountErasureResPayload struct {
+		AccountID            int        `json:"account_id"`
+		DryRun               bool       `json:"dry_run"`
+		ErasedAt             *time.Time `json:"erased_at,omitempty"`
+		ErasedFields         []string   `json:"erased_fields"`
+		RetainedTransactions int        `json:"retained_transactions"`
+	}
+
+	GetAccountBalanceResPayload struct {
+		AccountID int       `json:"account_id"`
+		Balance   float64   `json:"balance"`
+		AsOf      time.Time `json:"as_of"`
+	}
+
+	AccountStatusEventPayload struct {
+		AccountID int                 `json:"account_id"`
+		Status    enums.AccountStatus `json:"status"`
+		ChangedAt time.Time           `json:"changed_at"`
 	}
 
 	CreateTransactionReqPayload struct {
-		AccountID       int     `json:"account_id"`
-		OperationTypeID int     `json:"operation_type_id"`
-		Amount          float64 `json:"amount"`
+		AccountID         int     `json:"account_id"`
+		OperationTypeID   int     `json:"operation_type_id"`
+		Amount            float64 `json:"amount"`
+		ExternalReference string  `json:"external_reference,omitempty"`
+	}
+
+	CreateTransactionV2ReqPayload struct {
+		AccountID         int    `json:"account_id"`
+		OperationTypeID   int    `json:"operation_type_id"`
+		Amount            string `json:"amount"`
+		ExternalReference string `json:"external_reference,omitempty"`
+	}
+
+	CreateTransactionBatchReqPayload struct {
+		Mode         string                        `json:"mode"`
+		Transactions []CreateTransactionReqPayload `json:"transactions"`
+	}
+
+	CreateTransactionBatchV2ReqPayload struct {
+		Mode         string                          `json:"mode"`
+		Transactions []CreateTransactionV2ReqPayload `json:"transactions"`
+	}
+
+	TransactionBatchResPayload struct {
+		Mode    enums.BatchMode                  `json:"mode"`
+		Created int                              `json:"created"`
+		Failed  int                              `json:"failed"`
+		Results []TransactionBatchItemResPayload `json:"results"`
+	}
+
+	// TransactionBatchItemResPayload is the outcome of a transaction of a batch, the code, detail and errors of a
+	// failed one are the ones its problem details would have
+	TransactionBatchItemResPayload struct {
+		Index         int                   `json:"index"`
+		Status        enums.BatchItemStatus `json:"status"`
+		TransactionID int                   `json:"transaction_id,omitempty"`
+		Code          problem.Code          `json:"code,omitempty"`
+		Detail        string                `json:"detail,omitempty"`
+		Errors        []problem.FieldError  `json:"errors,omitempty"`
+	}
+
+	ReconciliationResPayload struct {
+		ReconciliationID int                          `json:"reconciliation_id"`
+		Source           string                       `json:"source"`
+		DateTolerance    string                       `json:"date_tolerance"`
+		CreatedAt        time.Time                    `json:"created_at"`
+		Summary          ReconciliationSummaryPayload `json:"summary"`
+		Items            []ReconciliationItemPayload  `json:"items"`
+	}
+
+	ReconciliationSummaryPayload struct {
+		Matched          int `json:"matched"`
+		MissingInLedger  int `json:"missing_in_ledger"`
+		MissingInFile    int `json:"missing_in_file"`
+		AmountMismatches int `json:"amount_mismatches"`
+	}
+
+	ReconciliationItemPayload struct {
+		Status            enums.ReconciliationStatus `json:"status"`
+		Line              int                        `json:"line,omitempty"`
+		ExternalReference string                     `json:"external_reference,omitempty"`
+		AccountID         int                        `json:"account_id,omitempty"`
+		FileAmount        *float64                   `json:"file_amount,omitempty"`
+		LedgerAmount      *float64                   `json:"ledger_amount,omitempty"`
+		EventDate         time.Time                  `json:"event_date"`
+		TransactionID     int                        `json:"transaction_id,omitempty"`
+	}
+
+	CreateWebhookReqPayload struct {
+		URL        string   `json:"url"`
+		Secret     string   `json:"secret,omitempty"`
+		EventTypes []string `json:"event_types"`
+	}
+
+	WebhookResPayload struct {
+		WebhookID           int               `json:"webhook_id"`
+		URL                 string            `json:"url"`
+		Secret              string            `json:"secret,omitempty"`
+		EventTypes          []enums.EventType `json:"event_types"`
+		Enabled             bool              `json:"enabled"`
+		ConsecutiveFailures int               `json:"consecutive_failures"`
+		DisabledAt          *time.Time        `json:"disabled_at,omitempty"`
+		CreatedAt           time.Time         `json:"created_at"`
+	}
+
+	CreateAPIKeyReqPayload struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at,omitempty"`
 	}
 
-	GenericErrRespPayload struct {
+	APIKeyResPayload struct {
+		KeyID     int           `json:"key_id"`
+		Name      string        `json:"name"`
+		Prefix    string        `json:"prefix"`
+		Key       string        `json:"key,omitempty"`
+		Scopes    []enums.Scope `json:"scopes"`
+		ExpiresAt *time.Time    `json:"expires_at,omitempty"`
+		RevokedAt *time.Time    `json:"revoked_at,omitempty"`
+		CreatedAt time.Time     `json:"created_at"`
+	}
+
+	WebhookDeliveryResPayload struct {
+		DeliveryID     int64                `json:"delivery_id"`
+		EventID        int64                `json:"event_id"`
+		EventType      enums.EventType      `json:"event_type"`
+		Status         enums.DeliveryStatus `json:"status"`
+		Attempts       int                  `json:"attempts"`
+		LastStatusCode *int                 `json:"last_status_code,omitempty"`
+		LastError      *string              `json:"last_error,omitempty"`
+		CreatedAt      time.Time            `json:"created_at"`
+	}
+
+	ReplayWebhookDeliveriesReqPayload struct {
+		DeliveryIDs []int64 `json:"delivery_ids"`
+	}
+
+	ReplayWebhookDeliveriesResPayload struct {
+		Replayed int64 `json:"replayed"`
+	}
+
+	AuditLogResPayload struct {
+		Entries    []AuditEntryResPayload `json:"entries"`
+		NextCursor int64                  `json:"next_cursor,omitempty"`
+	}
+
+	AuditEntryResPayload struct {
+		AuditID    int64             `json:"audit_id"`
+		OccurredAt time.Time         `json:"occurred_at"`
+		Actor      AuditActorPayload `json:"actor"`
+		RequestID  string            `json:"request_id,omitempty"`
+		EntityType string            `json:"entity_type"`
+		EntityID   string            `json:"entity_id"`
+		Action     enums.AuditAction `json:"action"`
+		Before     json.RawMessage   `json:"before"`
+		After      json.RawMessage   `json:"after"`
+	}
+
+	AuditActorPayload struct {
+		Type audit.ActorType `json:"type"`
+		ID   string          `json:"id"`
+	}
+
+	ChangesResPayload struct {
+		Changes   []ChangeResPayload `json:"changes"`
+		NextSince int64              `json:"next_since"`
+	}
+
+	ChangeResPayload struct {
+		Sequence   int64           `json:"sequence"`
+		EntityType string          `json:"entity_type"`
+		EntityID   int             `json:"entity_id"`
+		AccountID  int             `json:"account_id"`
+		Operation  string          `json:"operation"`
+		Data       json.RawMessage `json:"data"`
+		ChangedAt  time.Time       `json:"changed_at"`
+	}
+
+	CreateAccountResPayload struct {
+		AccountID int `json:"account_id"`
+	}
+
+	RPCRequest struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params,omitempty"`
+		// ID is nil for notifications, a null id is kept as the null literal
+		ID json.RawMessage `json:"id,omitempty"`
+	}
+
+	RPCResponse struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Result  json.RawMessage `json:"result,omitempty"`
+		Error   *RPCError       `json:"error,omitempty"`
+		ID      json.RawMessage `json:"id"`
+	}
+
+	RPCError struct {
+		Code    int    `json:"code"`
 		Message string `json:"message"`
+		Data    any    `json:"data,omitempty"`
+	}
+
+	// RPCErrorData is the data of the errors returned by the service
+	RPCErrorData struct {
+		Code   problem.Code         `json:"code"`
+		Errors []problem.FieldError `json:"errors,omitempty"`
+	}
+
+	RPCAccountReqPayload struct {
+		AccountID int `json:"account_id"`
+	}
+
+	RPCAccountBalanceReqPayload struct {
+		AccountID int    `json:"account_id"`
+		AsOf      string `json:"as_of,omitempty"`
+	}
+
+	// RPCUpdateAccountMetadataReqPayload is the If-Match of the rest api in if_version, the version the account
+	// has to be at for the update to apply, it applies whatever the version when it's missing
+	RPCUpdateAccountMetadataReqPayload struct {
+		AccountID int                `json:"account_id"`
+		Metadata  map[string]*string `json:"metadata"`
+		Tags      []string           `json:"tags"`
+		IfVersion *int64             `json:"if_version"`
+	}
+
+	RPCEraseAccountReqPayload struct {
+		AccountID int    `json:"account_id"`
+		DryRun    bool   `json:"dry_run"`
+		IfVersion *int64 `json:"if_version"`
 	}
 )
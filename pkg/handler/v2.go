@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/decode"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/writer"
+	"github.com/sathishs-dev/pismo-transactions/pkg/service"
+)
+
+// amountPattern is the decimal amounts accepted by the v2 payloads, up to 15 significant digits so the amount
+// round trips through a float64 unchanged
+var amountPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]{0,12})(\.[0-9]{1,2})?$`)
+
+// CreateTransactionV2 handler function handles the v2 transaction creation request, it only differs from the v1 one
+// by its amount sent as a decimal string
+func (h *handler) CreateTransactionV2() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CreateTransactionV2ReqPayload
+		if err := decode.JSON(r, &req); err != nil {
+			decodeErrorWriter(w, r, err)
+			return
+		}
+
+		amount, ok := parseAmount(req.Amount)
+		if !ok {
+			fieldErrorWriter(w, r, []problem.FieldError{
+				{In: "body", Name: "amount", Message: "must be a decimal string with up to 2 decimal places"},
+			})
+			return
+		}
+
+		err := h.svc.CreateTransaction(r.Context(), service.CreateTransactionInput{
+			AccountID:         req.AccountID,
+			OperationTypeID:   req.OperationTypeID,
+			Amount:            amount,
+			ExternalReference: req.ExternalReference,
+		})
+		if err != nil {
+			serviceErrorWriter(w, r, err)
+			return
+		}
+
+		if err := writer.WriteJSON(w, http.StatusCreated, nil); err != nil {
+			log.Error().Err(err).Msg("failed to write")
+			return
+		}
+	}
+}
+
+// parseAmount parses a decimal string amount, eg: -50.25
+func parseAmount(s string) (float64, bool) {
+	if !amountPattern.MatchString(s) {
+		return 0, false
+	}
+
+	amount, err := strconv.ParseFloat(s, 64)
+	return amount, err == nil
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+func (h *handlerTestSuite) TestCreateTransactionV2() {
+	tcs := []struct {
+		name               string
+		reqBody            string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:    "Valid Create Transaction V2 Request",
+			reqBody: `{"account_id": 1, "operation_type_id": 1, "amount": "-500.25", "external_reference": "NSU-1"}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(&repository.Account{AccountID: 1}, nil)
+				h.repo.On("CreateTransaction", mock.Anything,
+					repository.Transaction{AccountID: 1, OperationTypeID: 1, Amount: -500.25, ExternalReference: "NSU-1"},
+				).Return(nil)
+			},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:    "Valid Create Transaction V2 Request - Integer Amount",
+			reqBody: `{"account_id": 1, "operation_type_id": 4, "amount": "60"}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetAccountByAccountID", mock.Anything, 1).
+					Return(&repository.Account{AccountID: 1}, nil)
+				h.repo.On("CreateTransaction", mock.Anything,
+					repository.Transaction{AccountID: 1, OperationTypeID: 4, Amount: 60},
+				).Return(nil)
+			},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "Invalid Create Transaction V2 Request - Number Amount",
+			reqBody:            `{"account_id": 1, "operation_type_id": 4, "amount": 60}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody: `{"type": "urn:pismo-transactions:problem:validation_failed", "title": "Validation failed", "status": 400,
+				"detail": "amount must be a string", "instance": "/v2/transactions", "code": "validation_failed",
+				"errors": [{"in": "body", "name": "amount", "message": "must be a string"}]}`,
+		},
+		{
+			name:               "Invalid Create Transaction V2 Request - Too Many Decimal Places",
+			reqBody:            `{"account_id": 1, "operation_type_id": 4, "amount": "60.001"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody: `{"type": "urn:pismo-transactions:problem:validation_failed", "title": "Validation failed", "status": 400,
+				"detail": "amount must be a decimal string with up to 2 decimal places", "instance": "/v2/transactions",
+				"code": "validation_failed", "errors": [
+					{"in": "body", "name": "amount", "message": "must be a decimal string with up to 2 decimal places"}
+				]}`,
+		},
+		{
+			name:               "Invalid Create Transaction V2 Request - Exponent",
+			reqBody:            `{"account_id": 1, "operation_type_id": 4, "amount": "6e1"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody: `{"type": "urn:pismo-transactions:problem:validation_failed", "title": "Validation failed", "status": 400,
+				"detail": "amount must be a decimal string with up to 2 decimal places", "instance": "/v2/transactions",
+				"code": "validation_failed", "errors": [
+					{"in": "body", "name": "amount", "message": "must be a decimal string with up to 2 decimal places"}
+				]}`,
+		},
+		{
+			name:               "Invalid Create Transaction V2 Request - Unsupported transaction for Operation Type",
+			reqBody:            `{"account_id": 1, "operation_type_id": 4, "amount": "-60"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody: `{"type": "urn:pismo-transactions:problem:validation_failed", "title": "Validation failed", "status": 400,
+				"detail": "amount must be positive for the operation_type_id", "instance": "/v2/transactions",
+				"code": "validation_failed", "errors": [
+					{"in": "body", "name": "amount", "message": "must be positive for the operation_type_id"}
+				]}`,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/v2/transactions", strings.NewReader(tc.reqBody))
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+			if tc.expectedBody != "" {
+				h.JSONEq(tc.expectedBody, h.recorder.Body.String())
+			}
+			h.repo.AssertExpectations(t)
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
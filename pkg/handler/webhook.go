@@ -0,0 +1,344 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/decode"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/writer"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+)
+
+const (
+	// minWebhookSecretLen keeps partner chosen secrets from being guessable
+	minWebhookSecretLen = 16
+	// maxWebhookSecretLen is the size of webhooks.secret
+	maxWebhookSecretLen = 128
+)
+
+// CreateWebhook handler function registers a webhook, the secret is generated unless one is sent and it's only
+// returned in this response
+func (h *handler) CreateWebhook() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CreateWebhookReqPayload
+		if err := decode.JSON(r, &req); err != nil {
+			decodeErrorWriter(w, r, err)
+			return
+		}
+
+		eventTypes, errs := validateCreateWebhookReq(&req)
+		if len(errs) > 0 {
+			fieldErrorWriter(w, r, errs)
+			return
+		}
+
+		if req.Secret == "" {
+			secret, err := generateWebhookSecret()
+			if err != nil {
+				log.Error().Err(err).Msg("failed to generate the webhook secret")
+				errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+				return
+			}
+			req.Secret = secret
+		}
+
+		wh := repository.Webhook{
+			URL:        req.URL,
+			Secret:     req.Secret,
+			EventTypes: eventTypes,
+		}
+		if err := h.repo.CreateWebhook(r.Context(), &wh); err != nil {
+			log.Error().Err(err).Msg("failed to store the webhook")
+			errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+			return
+		}
+
+		res := toWebhookResPayload(&wh)
+		res.Secret = wh.Secret
+
+		if err := writer.WriteJSON(w, http.StatusCreated, res); err != nil {
+			log.Error().Err(err).Msg("failed to write")
+			return
+		}
+	}
+}
+
+// ListWebhooks handler function returns every registered webhook
+func (h *handler) ListWebhooks() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		webhooks, err := h.repo.ListWebhooks(r.Context())
+		if err != nil {
+			log.Error().Err(err).Msg("failed to retrieve the webhooks")
+			errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+			return
+		}
+
+		res := make([]WebhookResPayload, 0, len(webhooks))
+		for i := range webhooks {
+			res = append(res, toWebhookResPayload(&webhooks[i]))
+		}
+
+		if err := writer.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Error().Err(err).Msg("failed to write")
+			return
+		}
+	}
+}
+
+// GetWebhook handler function returns a registered webhook
+func (h *handler) GetWebhook() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		whID, err := webhookIDFromURL(r)
+		if err != nil {
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+			return
+		}
+
+		wh, err := h.repo.GetWebhook(r.Context(), whID)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to retrieve the webhook")
+			errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+			return
+		}
+
+		if wh == nil {
+			errorWriter(w, r, http.StatusNotFound, problem.CodeWebhookNotFound, "webhook not found")
+			return
+		}
+
+		if err := writer.WriteJSON(w, http.StatusOK, toWebhookResPayload(wh)); err != nil {
+			log.Error().Err(err).Msg("failed to write")
+			return
+		}
+	}
+}
+
+// DeleteWebhook handler function removes a webhook along with its pending deliveries and dead letters
+func (h *handler) DeleteWebhook() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		whID, err := webhookIDFromURL(r)
+		if err != nil {
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+			return
+		}
+
+		deleted, err := h.repo.DeleteWebhook(r.Context(), whID)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to delete the webhook")
+			errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+			return
+		}
+
+		if !deleted {
+			errorWriter(w, r, http.StatusNotFound, problem.CodeWebhookNotFound, "webhook not found")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// EnableWebhook handler function enables a webhook disabled after failing repeatedly, its pending deliveries
+// are resumed
+func (h *handler) EnableWebhook() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		whID, err := webhookIDFromURL(r)
+		if err != nil {
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+			return
+		}
+
+		wh, err := h.repo.EnableWebhook(r.Context(), whID)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to enable the webhook")
+			errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+			return
+		}
+
+		if wh == nil {
+			errorWriter(w, r, http.StatusNotFound, problem.CodeWebhookNotFound, "webhook not found")
+			return
+		}
+
+		if err := writer.WriteJSON(w, http.StatusOK, toWebhookResPayload(wh)); err != nil {
+			log.Error().Err(err).Msg("failed to write")
+			return
+		}
+	}
+}
+
+// ListWebhookDeadLetters handler function returns the deliveries of a webhook that ran out of attempts
+func (h *handler) ListWebhookDeadLetters() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		whID, err := webhookIDFromURL(r)
+		if err != nil {
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+			return
+		}
+
+		wh, err := h.repo.GetWebhook(r.Context(), whID)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to retrieve the webhook")
+			errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+			return
+		}
+
+		if wh == nil {
+			errorWriter(w, r, http.StatusNotFound, problem.CodeWebhookNotFound, "webhook not found")
+			return
+		}
+
+		deliveries, err := h.repo.ListDeadWebhookDeliveries(r.Context(), whID)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to retrieve the dead letters")
+			errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+			return
+		}
+
+		res := make([]WebhookDeliveryResPayload, 0, len(deliveries))
+		for _, d := range deliveries {
+			res = append(res, WebhookDeliveryResPayload{
+				DeliveryID:     d.DeliveryID,
+				EventID:        d.Event.EventID,
+				EventType:      d.Event.EventType,
+				Status:         d.Status,
+				Attempts:       d.Attempts,
+				LastStatusCode: d.LastStatusCode,
+				LastError:      d.LastError,
+				CreatedAt:      d.CreatedAt,
+			})
+		}
+
+		if err := writer.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Error().Err(err).Msg("failed to write")
+			return
+		}
+	}
+}
+
+// ReplayWebhookDeadLetters handler function queues the given dead letters of a webhook for delivery again,
+// every dead letter is replayed when the body is empty
+func (h *handler) ReplayWebhookDeadLetters() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		whID, err := webhookIDFromURL(r)
+		if err != nil {
+			errorWriter(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+			return
+		}
+
+		var req ReplayWebhookDeliveriesReqPayload
+		if err := decode.JSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+			decodeErrorWriter(w, r, err)
+			return
+		}
+
+		if slices.ContainsFunc(req.DeliveryIDs, func(id int64) bool { return id <= 0 }) {
+			fieldErrorWriter(w, r, []problem.FieldError{{In: "body", Name: "delivery_ids", Message: "must be positive integers"}})
+			return
+		}
+
+		wh, err := h.repo.GetWebhook(r.Context(), whID)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to retrieve the webhook")
+			errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+			return
+		}
+
+		if wh == nil {
+			errorWriter(w, r, http.StatusNotFound, problem.CodeWebhookNotFound, "webhook not found")
+			return
+		}
+
+		count, err := h.repo.ReplayWebhookDeliveries(r.Context(), whID, req.DeliveryIDs)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to replay the dead letters")
+			errorWriter(w, r, http.StatusInternalServerError, problem.CodeInternal, "please try again later.")
+			return
+		}
+
+		if err := writer.WriteJSON(w, http.StatusOK, ReplayWebhookDeliveriesResPayload{Replayed: count}); err != nil {
+			log.Error().Err(err).Msg("failed to write")
+			return
+		}
+	}
+}
+
+func validateCreateWebhookReq(req *CreateWebhookReqPayload) (eventTypes []string, errs []problem.FieldError) {
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, problem.FieldError{In: "body", Name: "url", Message: "must be an http or https url"})
+	}
+
+	if req.Secret != "" && (len(req.Secret) < minWebhookSecretLen || len(req.Secret) > maxWebhookSecretLen) {
+		errs = append(errs, problem.FieldError{
+			In:      "body",
+			Name:    "secret",
+			Message: fmt.Sprintf("must be %d to %d characters", minWebhookSecretLen, maxWebhookSecretLen),
+		})
+	}
+
+	if len(req.EventTypes) == 0 {
+		errs = append(errs, problem.FieldError{In: "body", Name: "event_types", Message: "is required"})
+	}
+
+	for i, v := range req.EventTypes {
+		et, err := enums.ParseEventType(v)
+		if err != nil {
+			errs = append(errs, problem.FieldError{In: "body", Name: fmt.Sprintf("event_types[%d]", i), Message: err.Error()})
+			continue
+		}
+		if !slices.Contains(eventTypes, string(et)) {
+			eventTypes = append(eventTypes, string(et))
+		}
+	}
+
+	return
+}
+
+// generateWebhookSecret returns a random secret for signing the deliveries of a webhook
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// webhookIDFromURL reads and validates the webhookId url param
+func webhookIDFromURL(r *http.Request) (int, error) {
+	whID, err := strconv.Atoi(chi.URLParam(r, "webhookId"))
+	if err != nil || whID <= 0 {
+		return 0, errors.New("invalid webhookId")
+	}
+
+	return whID, nil
+}
+
+func toWebhookResPayload(wh *repository.Webhook) WebhookResPayload {
+	res := WebhookResPayload{
+		WebhookID:           wh.WebhookID,
+		URL:                 wh.URL,
+		EventTypes:          make([]enums.EventType, 0, len(wh.EventTypes)),
+		Enabled:             wh.Enabled,
+		ConsecutiveFailures: wh.ConsecutiveFailures,
+		DisabledAt:          wh.DisabledAt,
+		CreatedAt:           wh.CreatedAt,
+	}
+
+	for _, et := range wh.EventTypes {
+		res.EventTypes = append(res.EventTypes, enums.EventType(et))
+	}
+
+	return res
+}
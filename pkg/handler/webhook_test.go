@@ -0,0 +1,400 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+func (h *handlerTestSuite) TestCreateWebhook() {
+	tcs := []struct {
+		name               string
+		reqBody            string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+		expectedSecret     string
+	}{
+		{
+			name:    "Valid Create Webhook Request - Generated Secret",
+			reqBody: `{"url": "https://partner.example.com/hooks", "event_types": ["transaction.created", "transaction.created"]}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("CreateWebhook", mock.Anything, mock.MatchedBy(func(wh *repository.Webhook) bool {
+					return wh.URL == "https://partner.example.com/hooks" &&
+						strings.HasPrefix(wh.Secret, "whsec_") &&
+						len(wh.EventTypes) == 1
+				})).
+					Run(func(args mock.Arguments) { args.Get(1).(*repository.Webhook).WebhookID = 1 }).
+					Return(nil)
+			},
+			expectedStatusCode: http.StatusCreated,
+			expectedSecret:     "whsec_",
+		},
+		{
+			name:    "Valid Create Webhook Request - Partner Secret",
+			reqBody: `{"url": "http://partner.local/hooks", "secret": "0123456789abcdef", "event_types": ["account.created"]}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("CreateWebhook", mock.Anything, &repository.Webhook{
+					URL:        "http://partner.local/hooks",
+					Secret:     "0123456789abcdef",
+					EventTypes: pq.StringArray{"account.created"},
+				}).
+					Return(nil)
+			},
+			expectedStatusCode: http.StatusCreated,
+			expectedSecret:     "0123456789abcdef",
+		},
+		{
+			name:               "Invalid Create Webhook Request - Invalid Payload",
+			reqBody:            `{"url": 1}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Create Webhook Request - Invalid URL",
+			reqBody:            `{"url": "ftp://partner.local/hooks", "event_types": ["account.created"]}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Create Webhook Request - Short Secret",
+			reqBody:            `{"url": "https://partner.local/hooks", "secret": "abc", "event_types": ["account.created"]}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Create Webhook Request - Missing Event Types",
+			reqBody:            `{"url": "https://partner.local/hooks"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Create Webhook Request - Unknown Event Type",
+			reqBody:            `{"url": "https://partner.local/hooks", "event_types": ["account.deleted"]}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:    "Invalid Create Webhook Request - Storing DataStore failed",
+			reqBody: `{"url": "https://partner.local/hooks", "event_types": ["account.created"]}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("CreateWebhook", mock.Anything, mock.Anything).
+					Return(errors.New("err"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(tc.reqBody))
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+
+			if tc.expectedSecret != "" {
+				var res WebhookResPayload
+				h.NoError(json.Unmarshal(h.recorder.Body.Bytes(), &res))
+				h.True(strings.HasPrefix(res.Secret, tc.expectedSecret), res.Secret)
+			}
+
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
+
+func (h *handlerTestSuite) TestGetWebhook() {
+	tcs := []struct {
+		name               string
+		webhookID          string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+	}{
+		{
+			name:      "Valid Get Webhook Request",
+			webhookID: "1",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetWebhook", mock.Anything, 1).
+					Return(&repository.Webhook{WebhookID: 1, Secret: "whsec_hidden", EventTypes: pq.StringArray{"account.created"}}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Invalid Get Webhook Request - Invalid ID",
+			webhookID:          "-1",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:      "Invalid Get Webhook Request - Not Found",
+			webhookID: "2",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetWebhook", mock.Anything, 2).
+					Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:      "Invalid Get Webhook Request - Fetching DataStore failed",
+			webhookID: "3",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetWebhook", mock.Anything, 3).
+					Return(nil, errors.New("err"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/webhooks/"+tc.webhookID, nil)
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+			h.NotContains(h.recorder.Body.String(), "whsec_hidden")
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
+
+func (h *handlerTestSuite) TestDeleteWebhook() {
+	tcs := []struct {
+		name               string
+		webhookID          string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+	}{
+		{
+			name:      "Valid Delete Webhook Request",
+			webhookID: "1",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("DeleteWebhook", mock.Anything, 1).
+					Return(true, nil)
+			},
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:      "Invalid Delete Webhook Request - Not Found",
+			webhookID: "2",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("DeleteWebhook", mock.Anything, 2).
+					Return(false, nil)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:      "Invalid Delete Webhook Request - Deleting DataStore failed",
+			webhookID: "3",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("DeleteWebhook", mock.Anything, 3).
+					Return(false, errors.New("err"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodDelete, "/webhooks/"+tc.webhookID, nil)
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
+
+func (h *handlerTestSuite) TestEnableWebhook() {
+	tcs := []struct {
+		name               string
+		webhookID          string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+	}{
+		{
+			name:      "Valid Enable Webhook Request",
+			webhookID: "1",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("EnableWebhook", mock.Anything, 1).
+					Return(&repository.Webhook{WebhookID: 1, Enabled: true}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:      "Invalid Enable Webhook Request - Not Found",
+			webhookID: "2",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("EnableWebhook", mock.Anything, 2).
+					Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/"+tc.webhookID+"/enable", nil)
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
+
+func (h *handlerTestSuite) TestListWebhookDeadLetters() {
+	tcs := []struct {
+		name               string
+		webhookID          string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+	}{
+		{
+			name:      "Valid List Dead Letters Request",
+			webhookID: "1",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetWebhook", mock.Anything, 1).
+					Return(&repository.Webhook{WebhookID: 1}, nil)
+				h.repo.On("ListDeadWebhookDeliveries", mock.Anything, 1).
+					Return([]repository.WebhookDelivery{{
+						DeliveryID: 5,
+						Status:     enums.DeliveryDead,
+						Attempts:   10,
+						Event:      repository.OutboxEvent{EventID: 9, EventType: enums.TransactionCreated},
+					}}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:      "Invalid List Dead Letters Request - Not Found",
+			webhookID: "2",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetWebhook", mock.Anything, 2).
+					Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:      "Invalid List Dead Letters Request - Fetching DataStore failed",
+			webhookID: "3",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetWebhook", mock.Anything, 3).
+					Return(&repository.Webhook{WebhookID: 3}, nil)
+				h.repo.On("ListDeadWebhookDeliveries", mock.Anything, 3).
+					Return(nil, errors.New("err"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/webhooks/"+tc.webhookID+"/dead-letters", nil)
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
+
+func (h *handlerTestSuite) TestReplayWebhookDeadLetters() {
+	tcs := []struct {
+		name               string
+		webhookID          string
+		reqBody            string
+		expectedMocks      func(h *handlerTestSuite)
+		expectedStatusCode int
+	}{
+		{
+			name:      "Valid Replay Request - Every Dead Letter",
+			webhookID: "1",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetWebhook", mock.Anything, 1).
+					Return(&repository.Webhook{WebhookID: 1}, nil)
+				h.repo.On("ReplayWebhookDeliveries", mock.Anything, 1, []int64(nil)).
+					Return(int64(4), nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:      "Valid Replay Request - Given Dead Letters",
+			webhookID: "1",
+			reqBody:   `{"delivery_ids": [5, 6]}`,
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetWebhook", mock.Anything, 1).
+					Return(&repository.Webhook{WebhookID: 1}, nil)
+				h.repo.On("ReplayWebhookDeliveries", mock.Anything, 1, []int64{5, 6}).
+					Return(int64(2), nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Invalid Replay Request - Invalid Delivery IDs",
+			webhookID:          "1",
+			reqBody:            `{"delivery_ids": [0]}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:      "Invalid Replay Request - Not Found",
+			webhookID: "2",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetWebhook", mock.Anything, 2).
+					Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:      "Invalid Replay Request - Replaying DataStore failed",
+			webhookID: "3",
+			expectedMocks: func(h *handlerTestSuite) {
+				h.repo.On("GetWebhook", mock.Anything, 3).
+					Return(&repository.Webhook{WebhookID: 3}, nil)
+				h.repo.On("ReplayWebhookDeliveries", mock.Anything, 3, []int64(nil)).
+					Return(int64(0), errors.New("err"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tcs {
+		h.T().Run(tc.name, func(t *testing.T) {
+			h.recorder = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/"+tc.webhookID+"/dead-letters/replay", strings.NewReader(tc.reqBody))
+
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(h)
+			}
+
+			h.router.ServeHTTP(h.recorder, req)
+			h.Equal(tc.expectedStatusCode, h.recorder.Code)
+			h.repo.ExpectedCalls = nil
+		})
+	}
+}
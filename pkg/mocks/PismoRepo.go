@@ -5,8 +5,12 @@ package mocks
 import (
 	context "context"
 
-	repository "github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	enums "github.com/sathishs-dev/pismo-transactions/pkg/enums"
 	mock "github.com/stretchr/testify/mock"
+
+	repository "github.com/sathishs-dev/pismo-transactions/pkg/repository"
+
+	time "time"
 )
 
 // PismoRepo is an autogenerated mock type for the PismoRepo type
@@ -14,17 +18,123 @@ type PismoRepo struct {
 	mock.Mock
 }
 
-// CreateAccount provides a mock function with given fields: ctx, document_number
-func (_m *PismoRepo) CreateAccount(ctx context.Context, document_number string) error {
-	ret := _m.Called(ctx, document_number)
+// ClaimOutboxEvents provides a mock function with given fields: ctx, limit, lease
+func (_m *PismoRepo) ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]repository.OutboxEvent, error) {
+	ret := _m.Called(ctx, limit, lease)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClaimOutboxEvents")
+	}
+
+	var r0 []repository.OutboxEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Duration) ([]repository.OutboxEvent, error)); ok {
+		return rf(ctx, limit, lease)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Duration) []repository.OutboxEvent); ok {
+		r0 = rf(ctx, limit, lease)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.OutboxEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, time.Duration) error); ok {
+		r1 = rf(ctx, limit, lease)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClaimWebhookDeliveries provides a mock function with given fields: ctx, limit, lease
+func (_m *PismoRepo) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]repository.WebhookDelivery, error) {
+	ret := _m.Called(ctx, limit, lease)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClaimWebhookDeliveries")
+	}
+
+	var r0 []repository.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Duration) ([]repository.WebhookDelivery, error)); ok {
+		return rf(ctx, limit, lease)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Duration) []repository.WebhookDelivery); ok {
+		r0 = rf(ctx, limit, lease)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.WebhookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, time.Duration) error); ok {
+		r1 = rf(ctx, limit, lease)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountAccounts provides a mock function with given fields: ctx, filter
+func (_m *PismoRepo) CountAccounts(ctx context.Context, filter repository.AccountFilter) (int64, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountAccounts")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.AccountFilter) (int64, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, repository.AccountFilter) int64); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, repository.AccountFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateAPIKey provides a mock function with given fields: ctx, key
+func (_m *PismoRepo) CreateAPIKey(ctx context.Context, key *repository.APIKey) error {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateAPIKey")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *repository.APIKey) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateAccount provides a mock function with given fields: ctx, acc
+func (_m *PismoRepo) CreateAccount(ctx context.Context, acc *repository.Account) error {
+	ret := _m.Called(ctx, acc)
 
 	if len(ret) == 0 {
 		panic("no return value specified for CreateAccount")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, document_number)
+	if rf, ok := ret.Get(0).(func(context.Context, *repository.Account) error); ok {
+		r0 = rf(ctx, acc)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -32,6 +142,34 @@ func (_m *PismoRepo) CreateAccount(ctx context.Context, document_number string)
 	return r0
 }
 
+// CreateBalanceSnapshots provides a mock function with given fields: ctx, at
+func (_m *PismoRepo) CreateBalanceSnapshots(ctx context.Context, at time.Time) (int64, error) {
+	ret := _m.Called(ctx, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateBalanceSnapshots")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return rf(ctx, at)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, at)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, at)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateTransaction provides a mock function with given fields: ctx, txn
 func (_m *PismoRepo) CreateTransaction(ctx context.Context, txn repository.Transaction) error {
 	ret := _m.Called(ctx, txn)
@@ -50,29 +188,29 @@ func (_m *PismoRepo) CreateTransaction(ctx context.Context, txn repository.Trans
 	return r0
 }
 
-// GetAccountByAccountID provides a mock function with given fields: ctx, account_id
-func (_m *PismoRepo) GetAccountByAccountID(ctx context.Context, account_id int) (*repository.Account, error) {
-	ret := _m.Called(ctx, account_id)
+// CreateTransactions provides a mock function with given fields: ctx, txns, atomic
+func (_m *PismoRepo) CreateTransactions(ctx context.Context, txns []repository.Transaction, atomic bool) ([]repository.TransactionResult, error) {
+	ret := _m.Called(ctx, txns, atomic)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetAccountByAccountID")
+		panic("no return value specified for CreateTransactions")
 	}
 
-	var r0 *repository.Account
+	var r0 []repository.TransactionResult
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int) (*repository.Account, error)); ok {
-		return rf(ctx, account_id)
+	if rf, ok := ret.Get(0).(func(context.Context, []repository.Transaction, bool) ([]repository.TransactionResult, error)); ok {
+		return rf(ctx, txns, atomic)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int) *repository.Account); ok {
-		r0 = rf(ctx, account_id)
+	if rf, ok := ret.Get(0).(func(context.Context, []repository.Transaction, bool) []repository.TransactionResult); ok {
+		r0 = rf(ctx, txns, atomic)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*repository.Account)
+			r0 = ret.Get(0).([]repository.TransactionResult)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
-		r1 = rf(ctx, account_id)
+	if rf, ok := ret.Get(1).(func(context.Context, []repository.Transaction, bool) error); ok {
+		r1 = rf(ctx, txns, atomic)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -80,27 +218,856 @@ func (_m *PismoRepo) GetAccountByAccountID(ctx context.Context, account_id int)
 	return r0, r1
 }
 
-// GetAccountByDocumentNo provides a mock function with given fields: ctx, document_number
-func (_m *PismoRepo) GetAccountByDocumentNo(ctx context.Context, document_number string) (bool, error) {
-	ret := _m.Called(ctx, document_number)
+// CreateWebhook provides a mock function with given fields: ctx, wh
+func (_m *PismoRepo) CreateWebhook(ctx context.Context, wh *repository.Webhook) error {
+	ret := _m.Called(ctx, wh)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetAccountByDocumentNo")
+		panic("no return value specified for CreateWebhook")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *repository.Webhook) error); ok {
+		r0 = rf(ctx, wh)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteIdleRateLimitBuckets provides a mock function with given fields: ctx, since
+func (_m *PismoRepo) DeleteIdleRateLimitBuckets(ctx context.Context, since time.Time) (int64, error) {
+	ret := _m.Called(ctx, since)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteIdleRateLimitBuckets")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return rf(ctx, since)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, since)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, since)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteWebhook provides a mock function with given fields: ctx, webhook_id
+func (_m *PismoRepo) DeleteWebhook(ctx context.Context, webhook_id int) (bool, error) {
+	ret := _m.Called(ctx, webhook_id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteWebhook")
 	}
 
 	var r0 bool
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
-		return rf(ctx, document_number)
+	if rf, ok := ret.Get(0).(func(context.Context, int) (bool, error)); ok {
+		return rf(ctx, webhook_id)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
-		r0 = rf(ctx, document_number)
+	if rf, ok := ret.Get(0).(func(context.Context, int) bool); ok {
+		r0 = rf(ctx, webhook_id)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, document_number)
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, webhook_id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// EnableWebhook provides a mock function with given fields: ctx, webhook_id
+func (_m *PismoRepo) EnableWebhook(ctx context.Context, webhook_id int) (*repository.Webhook, error) {
+	ret := _m.Called(ctx, webhook_id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnableWebhook")
+	}
+
+	var r0 *repository.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*repository.Webhook, error)); ok {
+		return rf(ctx, webhook_id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *repository.Webhook); ok {
+		r0 = rf(ctx, webhook_id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Webhook)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, webhook_id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// EnqueueWebhookDeliveries provides a mock function with given fields: ctx, event_id, event_type
+func (_m *PismoRepo) EnqueueWebhookDeliveries(ctx context.Context, event_id int64, event_type enums.EventType) (int64, error) {
+	ret := _m.Called(ctx, event_id, event_type)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnqueueWebhookDeliveries")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, enums.EventType) (int64, error)); ok {
+		return rf(ctx, event_id, event_type)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, enums.EventType) int64); ok {
+		r0 = rf(ctx, event_id, event_type)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, enums.EventType) error); ok {
+		r1 = rf(ctx, event_id, event_type)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// EraseAccount provides a mock function with given fields: ctx, account_id, dry_run, if_match
+func (_m *PismoRepo) EraseAccount(ctx context.Context, account_id int, dry_run bool, if_match repository.IfMatch) (*repository.AccountErasure, error) {
+	ret := _m.Called(ctx, account_id, dry_run, if_match)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EraseAccount")
+	}
+
+	var r0 *repository.AccountErasure
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool, repository.IfMatch) (*repository.AccountErasure, error)); ok {
+		return rf(ctx, account_id, dry_run, if_match)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool, repository.IfMatch) *repository.AccountErasure); ok {
+		r0 = rf(ctx, account_id, dry_run, if_match)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.AccountErasure)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, bool, repository.IfMatch) error); ok {
+		r1 = rf(ctx, account_id, dry_run, if_match)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAPIKey provides a mock function with given fields: ctx, key_id
+func (_m *PismoRepo) GetAPIKey(ctx context.Context, key_id int) (*repository.APIKey, error) {
+	ret := _m.Called(ctx, key_id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAPIKey")
+	}
+
+	var r0 *repository.APIKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*repository.APIKey, error)); ok {
+		return rf(ctx, key_id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *repository.APIKey); ok {
+		r0 = rf(ctx, key_id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.APIKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, key_id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAPIKeyByHash provides a mock function with given fields: ctx, hash
+func (_m *PismoRepo) GetAPIKeyByHash(ctx context.Context, hash []byte) (*repository.APIKey, error) {
+	ret := _m.Called(ctx, hash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAPIKeyByHash")
+	}
+
+	var r0 *repository.APIKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []byte) (*repository.APIKey, error)); ok {
+		return rf(ctx, hash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []byte) *repository.APIKey); ok {
+		r0 = rf(ctx, hash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.APIKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []byte) error); ok {
+		r1 = rf(ctx, hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAccountBalance provides a mock function with given fields: ctx, account_id, before
+func (_m *PismoRepo) GetAccountBalance(ctx context.Context, account_id int, before time.Time) (float64, error) {
+	ret := _m.Called(ctx, account_id, before)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAccountBalance")
+	}
+
+	var r0 float64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) (float64, error)); ok {
+		return rf(ctx, account_id, before)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) float64); ok {
+		r0 = rf(ctx, account_id, before)
+	} else {
+		r0 = ret.Get(0).(float64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, time.Time) error); ok {
+		r1 = rf(ctx, account_id, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAccountByAccountID provides a mock function with given fields: ctx, account_id
+func (_m *PismoRepo) GetAccountByAccountID(ctx context.Context, account_id int) (*repository.Account, error) {
+	ret := _m.Called(ctx, account_id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAccountByAccountID")
+	}
+
+	var r0 *repository.Account
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*repository.Account, error)); ok {
+		return rf(ctx, account_id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *repository.Account); ok {
+		r0 = rf(ctx, account_id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Account)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, account_id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAccountByDocumentNo provides a mock function with given fields: ctx, document_number
+func (_m *PismoRepo) GetAccountByDocumentNo(ctx context.Context, document_number string) (bool, error) {
+	ret := _m.Called(ctx, document_number)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAccountByDocumentNo")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(ctx, document_number)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, document_number)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, document_number)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetChangeCursor provides a mock function with given fields: ctx
+func (_m *PismoRepo) GetChangeCursor(ctx context.Context) (int64, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetChangeCursor")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (int64, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetReconciliation provides a mock function with given fields: ctx, reconciliation_id
+func (_m *PismoRepo) GetReconciliation(ctx context.Context, reconciliation_id int) (*repository.Reconciliation, error) {
+	ret := _m.Called(ctx, reconciliation_id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetReconciliation")
+	}
+
+	var r0 *repository.Reconciliation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*repository.Reconciliation, error)); ok {
+		return rf(ctx, reconciliation_id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *repository.Reconciliation); ok {
+		r0 = rf(ctx, reconciliation_id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Reconciliation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, reconciliation_id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWebhook provides a mock function with given fields: ctx, webhook_id
+func (_m *PismoRepo) GetWebhook(ctx context.Context, webhook_id int) (*repository.Webhook, error) {
+	ret := _m.Called(ctx, webhook_id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWebhook")
+	}
+
+	var r0 *repository.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*repository.Webhook, error)); ok {
+		return rf(ctx, webhook_id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *repository.Webhook); ok {
+		r0 = rf(ctx, webhook_id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Webhook)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, webhook_id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LastAccountEventID provides a mock function with given fields: ctx, account_id
+func (_m *PismoRepo) LastAccountEventID(ctx context.Context, account_id int) (int64, error) {
+	ret := _m.Called(ctx, account_id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LastAccountEventID")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (int64, error)); ok {
+		return rf(ctx, account_id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) int64); ok {
+		r0 = rf(ctx, account_id)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, account_id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAPIKeys provides a mock function with given fields: ctx
+func (_m *PismoRepo) ListAPIKeys(ctx context.Context) ([]repository.APIKey, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAPIKeys")
+	}
+
+	var r0 []repository.APIKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]repository.APIKey, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []repository.APIKey); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.APIKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAccountEvents provides a mock function with given fields: ctx, account_id, after, limit
+func (_m *PismoRepo) ListAccountEvents(ctx context.Context, account_id int, after int64, limit int) ([]repository.AccountEvent, error) {
+	ret := _m.Called(ctx, account_id, after, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAccountEvents")
+	}
+
+	var r0 []repository.AccountEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64, int) ([]repository.AccountEvent, error)); ok {
+		return rf(ctx, account_id, after, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64, int) []repository.AccountEvent); ok {
+		r0 = rf(ctx, account_id, after, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.AccountEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int64, int) error); ok {
+		r1 = rf(ctx, account_id, after, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAccounts provides a mock function with given fields: ctx, filter
+func (_m *PismoRepo) ListAccounts(ctx context.Context, filter repository.AccountFilter) ([]repository.Account, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAccounts")
+	}
+
+	var r0 []repository.Account
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.AccountFilter) ([]repository.Account, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, repository.AccountFilter) []repository.Account); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.Account)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, repository.AccountFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAuditLog provides a mock function with given fields: ctx, filter
+func (_m *PismoRepo) ListAuditLog(ctx context.Context, filter repository.AuditFilter) ([]repository.AuditEntry, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAuditLog")
+	}
+
+	var r0 []repository.AuditEntry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.AuditFilter) ([]repository.AuditEntry, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, repository.AuditFilter) []repository.AuditEntry); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.AuditEntry)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, repository.AuditFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListChangeLog provides a mock function with given fields: ctx, since, limit
+func (_m *PismoRepo) ListChangeLog(ctx context.Context, since int64, limit int) ([]repository.ChangeLogEntry, error) {
+	ret := _m.Called(ctx, since, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListChangeLog")
+	}
+
+	var r0 []repository.ChangeLogEntry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) ([]repository.ChangeLogEntry, error)); ok {
+		return rf(ctx, since, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) []repository.ChangeLogEntry); ok {
+		r0 = rf(ctx, since, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.ChangeLogEntry)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int) error); ok {
+		r1 = rf(ctx, since, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListDeadWebhookDeliveries provides a mock function with given fields: ctx, webhook_id
+func (_m *PismoRepo) ListDeadWebhookDeliveries(ctx context.Context, webhook_id int) ([]repository.WebhookDelivery, error) {
+	ret := _m.Called(ctx, webhook_id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDeadWebhookDeliveries")
+	}
+
+	var r0 []repository.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]repository.WebhookDelivery, error)); ok {
+		return rf(ctx, webhook_id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []repository.WebhookDelivery); ok {
+		r0 = rf(ctx, webhook_id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.WebhookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, webhook_id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListWebhooks provides a mock function with given fields: ctx
+func (_m *PismoRepo) ListWebhooks(ctx context.Context) ([]repository.Webhook, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListWebhooks")
+	}
+
+	var r0 []repository.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]repository.Webhook, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []repository.Webhook); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.Webhook)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecordOutboxDeliveries provides a mock function with given fields: ctx, eventIDs, deliveries
+func (_m *PismoRepo) RecordOutboxDeliveries(ctx context.Context, eventIDs []int64, deliveries []repository.OutboxDelivery) error {
+	ret := _m.Called(ctx, eventIDs, deliveries)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordOutboxDeliveries")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int64, []repository.OutboxDelivery) error); ok {
+		r0 = rf(ctx, eventIDs, deliveries)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RecordWebhookAttempt provides a mock function with given fields: ctx, attempt
+func (_m *PismoRepo) RecordWebhookAttempt(ctx context.Context, attempt repository.WebhookAttempt) error {
+	ret := _m.Called(ctx, attempt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordWebhookAttempt")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.WebhookAttempt) error); ok {
+		r0 = rf(ctx, attempt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReplayWebhookDeliveries provides a mock function with given fields: ctx, webhook_id, delivery_ids
+func (_m *PismoRepo) ReplayWebhookDeliveries(ctx context.Context, webhook_id int, delivery_ids []int64) (int64, error) {
+	ret := _m.Called(ctx, webhook_id, delivery_ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReplayWebhookDeliveries")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, []int64) (int64, error)); ok {
+		return rf(ctx, webhook_id, delivery_ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, []int64) int64); ok {
+		r0 = rf(ctx, webhook_id, delivery_ids)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, []int64) error); ok {
+		r1 = rf(ctx, webhook_id, delivery_ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeAPIKey provides a mock function with given fields: ctx, key_id
+func (_m *PismoRepo) RevokeAPIKey(ctx context.Context, key_id int) (*repository.APIKey, error) {
+	ret := _m.Called(ctx, key_id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeAPIKey")
+	}
+
+	var r0 *repository.APIKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*repository.APIKey, error)); ok {
+		return rf(ctx, key_id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *repository.APIKey); ok {
+		r0 = rf(ctx, key_id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.APIKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, key_id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RotateDocumentNumbers provides a mock function with given fields: ctx, limit
+func (_m *PismoRepo) RotateDocumentNumbers(ctx context.Context, limit int) (int, error) {
+	ret := _m.Called(ctx, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RotateDocumentNumbers")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (int, error)); ok {
+		return rf(ctx, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) int); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveReconciliation provides a mock function with given fields: ctx, rec
+func (_m *PismoRepo) SaveReconciliation(ctx context.Context, rec *repository.Reconciliation) error {
+	ret := _m.Called(ctx, rec)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveReconciliation")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *repository.Reconciliation) error); ok {
+		r0 = rf(ctx, rec)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// StreamTransactions provides a mock function with given fields: ctx, filter, fn
+func (_m *PismoRepo) StreamTransactions(ctx context.Context, filter repository.TransactionFilter, fn func(repository.Transaction) error) error {
+	ret := _m.Called(ctx, filter, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StreamTransactions")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.TransactionFilter, func(repository.Transaction) error) error); ok {
+		r0 = rf(ctx, filter, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// TakeRateLimitToken provides a mock function with given fields: ctx, key, rate, burst
+func (_m *PismoRepo) TakeRateLimitToken(ctx context.Context, key string, rate float64, burst int) (float64, bool, error) {
+	ret := _m.Called(ctx, key, rate, burst)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TakeRateLimitToken")
+	}
+
+	var r0 float64
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, float64, int) (float64, bool, error)); ok {
+		return rf(ctx, key, rate, burst)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, float64, int) float64); ok {
+		r0 = rf(ctx, key, rate, burst)
+	} else {
+		r0 = ret.Get(0).(float64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, float64, int) bool); ok {
+		r1 = rf(ctx, key, rate, burst)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, float64, int) error); ok {
+		r2 = rf(ctx, key, rate, burst)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// UpdateAccountMetadata provides a mock function with given fields: ctx, account_id, patch
+func (_m *PismoRepo) UpdateAccountMetadata(ctx context.Context, account_id int, patch repository.AccountMetadataPatch) (*repository.Account, error) {
+	ret := _m.Called(ctx, account_id, patch)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateAccountMetadata")
+	}
+
+	var r0 *repository.Account
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, repository.AccountMetadataPatch) (*repository.Account, error)); ok {
+		return rf(ctx, account_id, patch)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, repository.AccountMetadataPatch) *repository.Account); ok {
+		r0 = rf(ctx, account_id, patch)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Account)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, repository.AccountMetadataPatch) error); ok {
+		r1 = rf(ctx, account_id, patch)
 	} else {
 		r1 = ret.Error(1)
 	}
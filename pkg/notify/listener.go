@@ -0,0 +1,267 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+)
+
+const (
+	// Channel is the postgres channel the accounts and transactions triggers notify on
+	Channel = "pismo_changes"
+
+	DefaultMinReconnect = time.Second
+	DefaultMaxReconnect = time.Minute
+	DefaultPingInterval = 90 * time.Second
+
+	catchUpBatchSize = 500
+	// maxSeen bounds the notifications remembered between two catch ups, the ones past it are dispatched again
+	maxSeen = 100000
+)
+
+type (
+	// Notification is an account or transaction inserted by any of the service replicas
+	Notification struct {
+		Table     string `json:"table"`
+		ID        int    `json:"id"`
+		AccountID int    `json:"account_id"`
+	}
+
+	// Listener listens for the change notifications and fans them out to the in-process subscribers. The changes
+	// missed while the connection was lost are caught up from the change log once it's re-established, and at every
+	// ping interval for the ones committed late, a notification can be received more than once.
+	Listener struct {
+		dsn          string
+		repo         repository.PismoRepo
+		minReconnect time.Duration
+		maxReconnect time.Duration
+		pingInterval time.Duration
+
+		mu          sync.Mutex
+		subscribers map[chan Notification]struct{}
+		closed      bool
+
+		// cursor is the sequence of the change log caught up to, seen the notifications dispatched since
+		cursor int64
+		seen   map[Notification]struct{}
+		synced bool
+		stale  bool
+	}
+
+	// Option - Used to configure the listener during initialization
+	Option func(*Listener)
+)
+
+// WithReconnect - Will wait min before reconnecting, doubling on every failed attempt up to max
+func WithReconnect(min, max time.Duration) Option {
+	return func(l *Listener) {
+		l.minReconnect = min
+		l.maxReconnect = max
+	}
+}
+
+// WithPingInterval - Will check the connection is alive at the given interval while it's idle
+func WithPingInterval(d time.Duration) Option {
+	return func(l *Listener) {
+		l.pingInterval = d
+	}
+}
+
+// NewListener configures and returns the change notifications Listener, dsn is used for its dedicated connection
+func NewListener(dsn string, repo repository.PismoRepo, opts ...Option) *Listener {
+	l := &Listener{
+		dsn:          dsn,
+		repo:         repo,
+		minReconnect: DefaultMinReconnect,
+		maxReconnect: DefaultMaxReconnect,
+		pingInterval: DefaultPingInterval,
+		subscribers:  map[chan Notification]struct{}{},
+		seen:         map[Notification]struct{}{},
+	}
+
+	for _, o := range opts {
+		o(l)
+	}
+
+	return l
+}
+
+// Subscribe returns a channel receiving every notification from now on along with the function cancelling the
+// subscription. Notifications are dropped for subscribers whose buffer is full, so they must keep up, and the
+// channel is closed once the subscription is cancelled or the listener stops.
+func (l *Listener) Subscribe(buffer int) (<-chan Notification, func()) {
+	ch := make(chan Notification, buffer)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	l.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if _, ok := l.subscribers[ch]; ok {
+			delete(l.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Start listens for notifications until the context is cancelled
+func (l *Listener) Start(ctx context.Context) {
+	pl := pq.NewListener(l.dsn, l.minReconnect, l.maxReconnect, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventDisconnected:
+			log.Warn().Err(err).Msg("lost the change notifications connection")
+		case pq.ListenerEventConnectionAttemptFailed:
+			log.Warn().Err(err).Msg("failed to connect for change notifications")
+		case pq.ListenerEventReconnected:
+			log.Info().Msg("change notifications connection re-established")
+		}
+	})
+
+	go func() {
+		<-ctx.Done()
+		_ = pl.Close()
+	}()
+
+	// blocks until the connection is established or the listener is closed
+	if err := pl.Listen(Channel); err != nil {
+		if ctx.Err() == nil {
+			log.Error().Err(err).Msg("failed to listen for change notifications")
+		}
+		l.closeSubscribers()
+		return
+	}
+
+	l.run(ctx, pl.Notify, pl.Ping)
+}
+
+// run dispatches the notifications until the channel is closed, a nil notification signals a reconnection
+func (l *Listener) run(ctx context.Context, notifications <-chan *pq.Notification, ping func() error) {
+	defer l.closeSubscribers()
+
+	l.sync(ctx)
+
+	ticker := time.NewTicker(l.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n, ok := <-notifications:
+			if !ok {
+				return
+			}
+
+			if n == nil {
+				l.sync(ctx)
+				continue
+			}
+
+			var msg Notification
+			if err := json.Unmarshal([]byte(n.Extra), &msg); err != nil {
+				log.Error().Err(err).Str("payload", n.Extra).Msg("failed to decode change notification")
+				continue
+			}
+			l.dispatch(msg)
+		case <-ticker.C:
+			if err := ping(); err != nil {
+				log.Warn().Err(err).Msg("failed to ping the change notifications connection")
+			}
+
+			l.sync(ctx)
+		}
+	}
+}
+
+// sync takes the current position on the first connection and catches up with the settled changes of the change
+// log past it on the following calls, the ones already dispatched are left out. A change committing after a later
+// one isn't settled until then, so it's never skipped over. It's retried on the next tick when it fails.
+func (l *Listener) sync(ctx context.Context) {
+	if !l.synced {
+		cursor, err := l.repo.GetChangeCursor(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to read the change cursor")
+			l.stale = true
+			return
+		}
+
+		l.cursor, l.synced, l.stale = cursor, true, false
+		return
+	}
+
+	for {
+		entries, err := l.repo.ListChangeLog(ctx, l.cursor, catchUpBatchSize)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to catch up with the missed changes")
+			l.stale = true
+			return
+		}
+
+		for _, e := range entries {
+			l.cursor = e.Sequence
+
+			// only the inserts are notified
+			n, ok := Notification{ID: e.EntityID, AccountID: e.AccountID}, e.Operation == "create"
+			switch e.EntityType {
+			case "account":
+				n.Table = "accounts"
+			case "transaction":
+				n.Table = "transactions"
+			default:
+				ok = false
+			}
+
+			if _, seen := l.seen[n]; ok && !seen {
+				l.dispatch(n)
+			}
+		}
+
+		if len(entries) < catchUpBatchSize {
+			clear(l.seen)
+			l.stale = false
+			return
+		}
+	}
+}
+
+// dispatch remembers the notification until the next catch up and hands it over to every subscriber with room for it
+func (l *Listener) dispatch(n Notification) {
+	if len(l.seen) < maxSeen {
+		l.seen[n] = struct{}{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for ch := range l.subscribers {
+		select {
+		case ch <- n:
+		default:
+			log.Warn().Str("table", n.Table).Int("id", n.ID).Msg("change notification dropped for a slow subscriber")
+		}
+	}
+}
+
+func (l *Listener) closeSubscribers() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for ch := range l.subscribers {
+		delete(l.subscribers, ch)
+		close(ch)
+	}
+	l.closed = true
+}
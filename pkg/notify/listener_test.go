@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sathishs-dev/pismo-transactions/pkg/mocks"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, ch <-chan Notification) Notification {
+	t.Helper()
+
+	select {
+	case n := <-ch:
+		return n
+	case <-time.After(time.Second):
+		t.Fatal("notification not received")
+	}
+	return Notification{}
+}
+
+func TestListenerRun(t *testing.T) {
+	repo := mocks.NewPismoRepo(t)
+	l := NewListener("", repo)
+
+	first, _ := l.Subscribe(10)
+	second, cancelSecond := l.Subscribe(10)
+
+	repo.On("GetChangeCursor", mock.Anything).Return(int64(20), nil).Once()
+	// the reconnection catches up with the change log past the cursor, the changes already notified and the updates
+	// are left out
+	repo.On("ListChangeLog", mock.Anything, int64(20), catchUpBatchSize).
+		Return([]repository.ChangeLogEntry{
+			{Sequence: 21, EntityType: "account", EntityID: 4, AccountID: 4, Operation: "create"},
+			{Sequence: 22, EntityType: "account", EntityID: 4, AccountID: 4, Operation: "update"},
+			{Sequence: 23, EntityType: "transaction", EntityID: 11, AccountID: 4, Operation: "create"},
+		}, nil).Once()
+
+	notifications := make(chan *pq.Notification)
+	done := make(chan struct{})
+	go func() {
+		l.run(context.Background(), notifications, func() error { return nil })
+		close(done)
+	}()
+
+	notifications <- &pq.Notification{Channel: Channel, Extra: `{"table":"accounts","id":4,"account_id":4}`}
+	require.Equal(t, Notification{Table: "accounts", ID: 4, AccountID: 4}, receive(t, first))
+	require.Equal(t, Notification{Table: "accounts", ID: 4, AccountID: 4}, receive(t, second))
+
+	cancelSecond()
+	_, ok := <-second
+	require.False(t, ok)
+
+	notifications <- nil
+	require.Equal(t, Notification{Table: "transactions", ID: 11, AccountID: 4}, receive(t, first))
+	require.Empty(t, first)
+
+	close(notifications)
+	<-done
+
+	_, ok = <-first
+	require.False(t, ok, "subscribers are closed once the listener stops")
+
+	late, _ := l.Subscribe(1)
+	_, ok = <-late
+	require.False(t, ok)
+}
+
+func TestListenerSyncRetry(t *testing.T) {
+	repo := mocks.NewPismoRepo(t)
+	l := NewListener("", repo)
+
+	repo.On("GetChangeCursor", mock.Anything).Return(int64(0), errors.New("err")).Once()
+	l.sync(context.Background())
+	require.True(t, l.stale)
+	require.False(t, l.synced)
+
+	repo.On("GetChangeCursor", mock.Anything).Return(int64(1), nil).Once()
+	l.sync(context.Background())
+	require.False(t, l.stale)
+	require.Equal(t, int64(1), l.cursor)
+
+	repo.On("ListChangeLog", mock.Anything, int64(1), catchUpBatchSize).Return(nil, errors.New("err")).Once()
+	l.sync(context.Background())
+	require.True(t, l.stale)
+
+	// the cursor only moves past the settled changes, a change committing late is read once it's settled
+	repo.On("ListChangeLog", mock.Anything, int64(1), catchUpBatchSize).Return(nil, nil).Once()
+	l.sync(context.Background())
+	require.False(t, l.stale)
+	require.Equal(t, int64(1), l.cursor)
+
+	repo.On("ListChangeLog", mock.Anything, int64(1), catchUpBatchSize).
+		Return([]repository.ChangeLogEntry{{Sequence: 2, EntityType: "transaction", EntityID: 5, AccountID: 1, Operation: "create"}}, nil).Once()
+	ch, _ := l.Subscribe(1)
+	l.sync(context.Background())
+	require.Equal(t, Notification{Table: "transactions", ID: 5, AccountID: 1}, receive(t, ch))
+	require.Equal(t, int64(2), l.cursor)
+}
+
+func TestListenerDropsForSlowSubscribers(t *testing.T) {
+	l := NewListener("", mocks.NewPismoRepo(t))
+	ch, _ := l.Subscribe(1)
+
+	l.dispatch(Notification{Table: "transactions", ID: 1})
+	l.dispatch(Notification{Table: "transactions", ID: 2})
+
+	require.Equal(t, 1, receive(t, ch).ID)
+	require.Empty(t, ch)
+}
@@ -0,0 +1,271 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/decode"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+)
+
+const jsonMediaType = "application/json"
+
+// route is a path of the document, the names of the path parameters are kept with their braces in its segments
+type route struct {
+	segments []string
+	static   int
+	item     *PathItem
+}
+
+func newRoute(pattern string, item *PathItem) route {
+	rt := route{segments: splitPath(pattern), item: item}
+	for _, seg := range rt.segments {
+		if !isParam(seg) {
+			rt.static++
+		}
+	}
+
+	return rt
+}
+
+// match returns the path parameters of the path when it matches the route
+func (rt route) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(rt.segments) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, seg := range rt.segments {
+		switch {
+		case isParam(seg):
+			if segments[i] == "" {
+				return nil, false
+			}
+			params[seg[1:len(seg)-1]] = segments[i]
+		case seg != segments[i]:
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// Middleware validates the parameters and the json body of the requests against the operation described for them,
+// requests to operations the document doesn't describe are left for the router to answer
+func (d *Document) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		item, pathParams := d.find(r.URL.Path)
+		if item == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		op := item.operation(r.Method)
+		if op == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var errs []problem.FieldError
+		for _, p := range slices.Concat(item.Parameters, op.Parameters) {
+			errs = append(errs, validateParam(p, r, pathParams)...)
+		}
+
+		if op.RequestBody != nil {
+			bodyErrs, err := validateBody(op.RequestBody, r)
+			if tooLarge := decode.TooLarge(err); tooLarge != nil {
+				_ = problem.Error(w, r, tooLarge.Status, tooLarge.Code, tooLarge.Detail)
+				return
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to read body")
+				writeErrors(w, r, []problem.FieldError{{In: "body", Name: "body", Message: "could not be read"}})
+				return
+			}
+			errs = append(errs, bodyErrs...)
+		}
+
+		if len(errs) > 0 {
+			writeErrors(w, r, errs)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// find returns the path item matching the path, static segments take precedence over parameters like chi does
+func (d *Document) find(path string) (*PathItem, map[string]string) {
+	segments := splitPath(path)
+
+	var (
+		best   *route
+		params map[string]string
+	)
+	for i := range d.routes {
+		rt := &d.routes[i]
+		p, ok := rt.match(segments)
+		if ok && (best == nil || rt.static > best.static) {
+			best, params = rt, p
+		}
+	}
+
+	if best == nil {
+		return nil, nil
+	}
+
+	return best.item, params
+}
+
+func validateParam(p *Parameter, r *http.Request, pathParams map[string]string) []problem.FieldError {
+	var (
+		value any
+		set   bool
+	)
+
+	switch p.In {
+	case "path":
+		var v string
+		v, set = pathParams[p.Name]
+		value = p.Schema.parse(v)
+
+	case "header":
+		if v := r.Header.Get(p.Name); v != "" {
+			value, set = p.Schema.parse(v), true
+		}
+
+	case "query":
+		value, set = queryValue(p, r)
+	}
+
+	if !set {
+		if p.Required {
+			return []problem.FieldError{{In: p.In, Name: p.Name, Message: "is required"}}
+		}
+		return nil
+	}
+
+	var errs []problem.FieldError
+	for _, v := range p.Schema.validate(p.Name, value) {
+		errs = append(errs, problem.FieldError{In: p.In, Name: v.name, Message: v.message})
+	}
+
+	return errs
+}
+
+// queryValue reads a query parameter in the form style, or the deepObject style eg: metadata[customer_id]=42
+func queryValue(p *Parameter, r *http.Request) (any, bool) {
+	q := r.URL.Query()
+
+	if p.Style == "deepObject" {
+		obj := map[string]any{}
+		for param, values := range q {
+			k, ok := strings.CutPrefix(param, p.Name+"[")
+			if !ok {
+				continue
+			}
+			k = strings.TrimSuffix(k, "]")
+
+			v := values[0]
+			if p.Schema.AdditionalProperties != nil {
+				obj[k] = p.Schema.AdditionalProperties.parse(v)
+			} else {
+				obj[k] = v
+			}
+		}
+
+		return obj, len(obj) > 0
+	}
+
+	values, ok := q[p.Name]
+	if !ok || len(values) == 0 {
+		return nil, false
+	}
+
+	if slices.Contains(p.Schema.Type, "array") {
+		arr := make([]any, 0, len(values))
+		for _, v := range values {
+			if p.Schema.Items != nil {
+				arr = append(arr, p.Schema.Items.parse(v))
+			} else {
+				arr = append(arr, v)
+			}
+		}
+		return arr, true
+	}
+
+	// the handlers read the first value only
+	if values[0] == "" {
+		return nil, false
+	}
+
+	return p.Schema.parse(values[0]), true
+}
+
+// validateBody validates json bodies against their schema, other media types are read by the handlers as streams
+// and left to them, the body is replaced so the handlers can read it again
+func validateBody(rb *RequestBody, r *http.Request) ([]problem.FieldError, error) {
+	mt, ok := rb.Content[jsonMediaType]
+	if !ok || mt.Schema == nil {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if rb.Required {
+			return []problem.FieldError{{In: "body", Name: "body", Message: "is required"}}, nil
+		}
+		return nil, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return []problem.FieldError{{In: "body", Name: "body", Message: "must be valid json"}}, nil
+	}
+
+	var errs []problem.FieldError
+	for _, vi := range mt.Schema.validate("", v) {
+		name := vi.name
+		if name == "" {
+			name = "body"
+		}
+		errs = append(errs, problem.FieldError{In: "body", Name: name, Message: vi.message})
+	}
+
+	return errs, nil
+}
+
+// writeErrors responds with the problem listing the errors, the detail is the first of them so callers reading
+// only the detail still get the reason
+func writeErrors(w http.ResponseWriter, r *http.Request, errs []problem.FieldError) {
+	detail := errs[0].Name + " " + errs[0].Message
+	err := problem.New(r, http.StatusBadRequest, problem.CodeValidationFailed, detail).WithErrors(errs...).Write(w)
+	if err != nil {
+		log.Error().Err(err).Msg("failed writting to the client")
+	}
+}
+
+func isParam(seg string) bool {
+	return strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}")
+}
+
+func splitPath(path string) []string {
+	if path != "/" {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
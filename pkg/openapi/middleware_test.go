@@ -0,0 +1,250 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	d, err := Load()
+	require.NoError(t, err)
+	require.NotNil(t, d.Operation(http.MethodGet, "/accounts/{accountId}"))
+	require.Nil(t, d.Operation(http.MethodPut, "/accounts/{accountId}"))
+
+	_, err = Parse([]byte(`{"openapi":"3.1.0","paths":{"/a":{"get":{"parameters":[{"$ref":"#/components/parameters/Missing"}]}}}}`))
+	require.Error(t, err)
+
+	require.Same(t, d.Paths["/accounts"], d.Paths["/v1/accounts"])
+	require.NotSame(t, d.Paths["/v1/transactions"], d.Paths["/v2/transactions"])
+
+	_, err = Parse([]byte(`{"openapi":"3.1.0","paths":{"/a":{"$ref":"#/components/pathItems/Missing"}}}`))
+	require.Error(t, err)
+
+	_, err = Parse([]byte(`{"openapi":"3.0.3","paths":{}}`))
+	require.Error(t, err)
+}
+
+// TestProblemCodes keeps the error codes described in the document in sync with the catalog
+func TestProblemCodes(t *testing.T) {
+	d, err := Load()
+	require.NoError(t, err)
+
+	var described []string
+	for _, c := range d.Components.Schemas["Problem"].Properties["code"].Enum {
+		described = append(described, c.(string))
+	}
+
+	var codes []string
+	for c := range problem.Codes() {
+		codes = append(codes, string(c))
+	}
+
+	require.ElementsMatch(t, codes, described)
+}
+
+func TestMiddleware(t *testing.T) {
+	d, err := Load()
+	require.NoError(t, err)
+
+	tcs := []struct {
+		name           string
+		method         string
+		target         string
+		body           string
+		expectedErrors []problem.FieldError
+	}{
+		{
+			name:   "Test Valid Body",
+			method: http.MethodPost,
+			target: "/accounts",
+			body:   `{"document_number":"12345678900","metadata":{"customer_id":"c-42"},"tags":["vip"]}`,
+		},
+		{
+			name:   "Test Missing Required Field",
+			method: http.MethodPost,
+			target: "/accounts",
+			body:   `{"tags":["vip"]}`,
+			expectedErrors: []problem.FieldError{
+				{In: "body", Name: "document_number", Message: "is required"},
+			},
+		},
+		{
+			name:   "Test Invalid Nested Fields",
+			method: http.MethodPost,
+			target: "/accounts",
+			body:   `{"document_number":"123","metadata":{"customer id":"c-42"},"tags":["vip","no space"]}`,
+			expectedErrors: []problem.FieldError{
+				{In: "body", Name: "metadata.customer id", Message: "key must match ^[A-Za-z0-9_.-]+$"},
+				{In: "body", Name: "tags[1]", Message: "must match ^[A-Za-z0-9_.:-]+$"},
+			},
+		},
+		{
+			name:   "Test Wrong Types",
+			method: http.MethodPost,
+			target: "/transactions",
+			body:   `{"account_id":1.5,"operation_type_id":4,"amount":"10"}`,
+			expectedErrors: []problem.FieldError{
+				{In: "body", Name: "account_id", Message: "must be an integer"},
+				{In: "body", Name: "amount", Message: "must be a number"},
+			},
+		},
+		{
+			name:   "Test Valid V2 String Amount",
+			method: http.MethodPost,
+			target: "/v2/transactions",
+			body:   `{"account_id":1,"operation_type_id":4,"amount":"10.50"}`,
+		},
+		{
+			name:   "Test Invalid V2 Amount",
+			method: http.MethodPost,
+			target: "/v2/transactions",
+			body:   `{"account_id":1,"operation_type_id":4,"amount":10.5}`,
+			expectedErrors: []problem.FieldError{
+				{In: "body", Name: "amount", Message: "must be a string"},
+			},
+		},
+		{
+			name:   "Test Invalid Enum",
+			method: http.MethodPost,
+			target: "/transactions",
+			body:   `{"account_id":1,"operation_type_id":9,"amount":10}`,
+			expectedErrors: []problem.FieldError{
+				{In: "body", Name: "operation_type_id", Message: "must be one of 1, 2, 3, 4"},
+			},
+		},
+		{
+			name:   "Test Nullable Field",
+			method: http.MethodPatch,
+			target: "/accounts/1/metadata",
+			body:   `{"metadata":{"segment":null}}`,
+		},
+		{
+			name:   "Test Invalid JSON",
+			method: http.MethodPost,
+			target: "/accounts",
+			body:   `{"document_number":`,
+			expectedErrors: []problem.FieldError{
+				{In: "body", Name: "body", Message: "must be valid json"},
+			},
+		},
+		{
+			name:   "Test Missing Required Body",
+			method: http.MethodPost,
+			target: "/webhooks",
+			expectedErrors: []problem.FieldError{
+				{In: "body", Name: "body", Message: "is required"},
+			},
+		},
+		{
+			name:   "Test Optional Body",
+			method: http.MethodPost,
+			target: "/webhooks/1/dead-letters/replay",
+		},
+		{
+			name:   "Test Non JSON Body",
+			method: http.MethodPost,
+			target: "/reconciliations?source=bank",
+			body:   "reference,amount,date\n",
+		},
+		{
+			name:   "Test Invalid Path Param",
+			method: http.MethodGet,
+			target: "/accounts/abc",
+			expectedErrors: []problem.FieldError{
+				{In: "path", Name: "accountId", Message: "must be an integer"},
+			},
+		},
+		{
+			name:   "Test Invalid Query Params",
+			method: http.MethodGet,
+			target: "/accounts?limit=5000&status=open&count=maybe&from=yesterday",
+			expectedErrors: []problem.FieldError{
+				{In: "query", Name: "status", Message: "must be one of active, closed"},
+				{In: "query", Name: "from", Message: "must be an RFC 3339 timestamp or a YYYY-MM-DD date"},
+				{In: "query", Name: "limit", Message: "must be less than or equal to 1000"},
+				{In: "query", Name: "count", Message: "must be a boolean"},
+			},
+		},
+		{
+			name:   "Test Valid Query Params",
+			method: http.MethodGet,
+			target: "/accounts?tag=vip&tag=gold&metadata[customer_id]=42&from=2024-12-01&to=2024-12-05T10:00:00Z&count=true",
+		},
+		{
+			name:   "Test Invalid Array Query Param",
+			method: http.MethodGet,
+			target: "/accounts?tag=vip&tag=a%20b",
+			expectedErrors: []problem.FieldError{
+				{In: "query", Name: "tag[1]", Message: "must match ^[A-Za-z0-9_.:-]+$"},
+			},
+		},
+		{
+			name:   "Test Missing Required Query Param",
+			method: http.MethodGet,
+			target: "/accounts/1/transactions/export",
+			expectedErrors: []problem.FieldError{
+				{In: "query", Name: "format", Message: "is required"},
+			},
+		},
+		{
+			name:   "Test Undescribed Route",
+			method: http.MethodGet,
+			target: "/unknown/route",
+		},
+		{
+			name:   "Test Undescribed Method",
+			method: http.MethodPut,
+			target: "/accounts/abc",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotBody string
+			called := false
+			h := d.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				called = true
+				b, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				gotBody = string(b)
+			}))
+
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if len(tc.expectedErrors) == 0 {
+				require.True(t, called)
+				require.Equal(t, tc.body, gotBody)
+				return
+			}
+
+			require.False(t, called)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+
+			require.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+
+			var res problem.Problem
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			require.Equal(t, problem.CodeValidationFailed, res.Code)
+			require.Equal(t, tc.expectedErrors, res.Errors)
+			require.Equal(t, tc.expectedErrors[0].Name+" "+tc.expectedErrors[0].Message, res.Detail)
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler()(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.JSONEq(t, string(Raw()), rec.Body.String())
+}
@@ -0,0 +1,242 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// document is the OpenAPI description of every route the web server registers, it's the contract the
+// middleware validates the requests against
+//
+//go:embed openapi.json
+var document []byte
+
+type (
+	// Document is the part of an OpenAPI 3.1 document needed to validate requests
+	Document struct {
+		OpenAPI    string               `json:"openapi"`
+		Paths      map[string]*PathItem `json:"paths"`
+		Components Components           `json:"components"`
+
+		routes    []route
+		resolving map[*Schema]bool
+	}
+
+	Components struct {
+		Schemas    map[string]*Schema    `json:"schemas"`
+		Parameters map[string]*Parameter `json:"parameters"`
+		PathItems  map[string]*PathItem  `json:"pathItems"`
+	}
+
+	// PathItem holds the operations of a path, its parameters are shared by every operation, a path item
+	// referencing one of the components is shared by every path pointing to it
+	PathItem struct {
+		Ref        string       `json:"$ref"`
+		Parameters []*Parameter `json:"parameters"`
+		Get        *Operation   `json:"get"`
+		Post       *Operation   `json:"post"`
+		Put        *Operation   `json:"put"`
+		Patch      *Operation   `json:"patch"`
+		Delete     *Operation   `json:"delete"`
+	}
+
+	Operation struct {
+		OperationID string       `json:"operationId"`
+		Parameters  []*Parameter `json:"parameters"`
+		RequestBody *RequestBody `json:"requestBody"`
+	}
+
+	Parameter struct {
+		Ref      string  `json:"$ref"`
+		Name     string  `json:"name"`
+		In       string  `json:"in"`
+		Required bool    `json:"required"`
+		Style    string  `json:"style"`
+		Schema   *Schema `json:"schema"`
+	}
+
+	RequestBody struct {
+		Required bool                  `json:"required"`
+		Content  map[string]*MediaType `json:"content"`
+	}
+
+	MediaType struct {
+		Schema *Schema `json:"schema"`
+	}
+)
+
+// Raw returns the embedded document as it's served
+func Raw() []byte {
+	return document
+}
+
+// Handler serves the embedded document
+func Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(document)
+	}
+}
+
+// Load parses the embedded document and resolves its references
+func Load() (*Document, error) {
+	return Parse(document)
+}
+
+// Parse parses an OpenAPI document, every $ref has to point to the components of the same document
+func Parse(data []byte) (*Document, error) {
+	var d Document
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("failed to parse the openapi document: %w", err)
+	}
+
+	if !strings.HasPrefix(d.OpenAPI, "3.1") {
+		return nil, fmt.Errorf("unsupported openapi version %q", d.OpenAPI)
+	}
+
+	if err := d.resolve(); err != nil {
+		return nil, err
+	}
+
+	for pattern, item := range d.Paths {
+		d.routes = append(d.routes, newRoute(pattern, item))
+	}
+
+	return &d, nil
+}
+
+// Operation returns the operation described for the method and path pattern, eg: GET /accounts/{accountId}
+func (d *Document) Operation(method, pattern string) *Operation {
+	item, ok := d.Paths[pattern]
+	if !ok {
+		return nil
+	}
+
+	return item.operation(method)
+}
+
+// Operations calls fn with the method and path pattern of every operation in the document
+func (d *Document) Operations(fn func(method, pattern string)) {
+	for pattern, item := range d.Paths {
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			if item.operation(method) != nil {
+				fn(method, pattern)
+			}
+		}
+	}
+}
+
+func (p *PathItem) operation(method string) *Operation {
+	switch method {
+	case http.MethodGet:
+		return p.Get
+	case http.MethodPost:
+		return p.Post
+	case http.MethodPut:
+		return p.Put
+	case http.MethodPatch:
+		return p.Patch
+	case http.MethodDelete:
+		return p.Delete
+	}
+
+	return nil
+}
+
+// resolve replaces the references with the components they point to and compiles the schemas
+func (d *Document) resolve() error {
+	d.resolving = map[*Schema]bool{}
+
+	for name, s := range d.Components.Schemas {
+		if err := d.resolveSchema(s); err != nil {
+			return fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+
+	resolveParams := func(params []*Parameter) error {
+		for i, p := range params {
+			if p.Ref != "" {
+				name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/")
+				if !ok || d.Components.Parameters[name] == nil {
+					return fmt.Errorf("unknown reference %q", p.Ref)
+				}
+				params[i] = d.Components.Parameters[name]
+				continue
+			}
+
+			if p.Schema == nil {
+				return fmt.Errorf("parameter %s has no schema", p.Name)
+			}
+
+			if err := d.resolveSchema(p.Schema); err != nil {
+				return fmt.Errorf("parameter %s: %w", p.Name, err)
+			}
+		}
+
+		return nil
+	}
+
+	if err := resolveParams(componentParams(d.Components.Parameters)); err != nil {
+		return err
+	}
+
+	for pattern, item := range d.Paths {
+		if item.Ref != "" {
+			name, ok := strings.CutPrefix(item.Ref, "#/components/pathItems/")
+			if !ok || d.Components.PathItems[name] == nil {
+				return fmt.Errorf("path %s: unknown reference %q", pattern, item.Ref)
+			}
+			d.Paths[pattern] = d.Components.PathItems[name]
+		}
+	}
+
+	resolved := map[*PathItem]bool{}
+	for pattern, item := range d.Paths {
+		if resolved[item] {
+			continue
+		}
+		resolved[item] = true
+
+		if err := resolveParams(item.Parameters); err != nil {
+			return fmt.Errorf("path %s: %w", pattern, err)
+		}
+
+		for _, op := range []*Operation{item.Get, item.Post, item.Put, item.Patch, item.Delete} {
+			if op == nil {
+				continue
+			}
+
+			if err := resolveParams(op.Parameters); err != nil {
+				return fmt.Errorf("operation %s: %w", op.OperationID, err)
+			}
+
+			if op.RequestBody == nil {
+				continue
+			}
+
+			for _, mt := range op.RequestBody.Content {
+				if mt.Schema == nil {
+					continue
+				}
+				if err := d.resolveSchema(mt.Schema); err != nil {
+					return fmt.Errorf("operation %s: %w", op.OperationID, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func componentParams(params map[string]*Parameter) []*Parameter {
+	res := make([]*Parameter, 0, len(params))
+	for _, p := range params {
+		res = append(res, p)
+	}
+
+	return res
+}
@@ -0,0 +1,372 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type (
+	// Schema is the subset of JSON Schema the document uses, values are expected to be decoded with
+	// json.Decoder.UseNumber so integers are told apart from numbers
+	Schema struct {
+		Ref                  string             `json:"$ref"`
+		Type                 schemaTypes        `json:"type"`
+		Format               string             `json:"format"`
+		Enum                 []any              `json:"enum"`
+		Pattern              string             `json:"pattern"`
+		MinLength            *int               `json:"minLength"`
+		MaxLength            *int               `json:"maxLength"`
+		Minimum              *float64           `json:"minimum"`
+		Maximum              *float64           `json:"maximum"`
+		MinItems             *int               `json:"minItems"`
+		MaxItems             *int               `json:"maxItems"`
+		Items                *Schema            `json:"items"`
+		Required             []string           `json:"required"`
+		Properties           map[string]*Schema `json:"properties"`
+		AdditionalProperties *Schema            `json:"additionalProperties"`
+		PropertyNames        *Schema            `json:"propertyNames"`
+		MinProperties        *int               `json:"minProperties"`
+		MaxProperties        *int               `json:"maxProperties"`
+		AnyOf                []*Schema          `json:"anyOf"`
+
+		// deny is set for the false schema, eg: "additionalProperties": false
+		deny    bool
+		pattern *regexp.Regexp
+	}
+
+	// schemaTypes is the type keyword, a single type or a list of them
+	schemaTypes []string
+
+	// violation is a value failing its schema, name is the path to the value
+	violation struct {
+		name    string
+		message string
+	}
+)
+
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	switch string(bytes.TrimSpace(data)) {
+	case "true":
+		*s = Schema{}
+		return nil
+	case "false":
+		*s = Schema{deny: true}
+		return nil
+	}
+
+	type plain Schema
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = schemaTypes{one}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+
+	*t = many
+	return nil
+}
+
+// resolveSchema resolves the references of the schema and its subschemas and compiles its patterns
+func (d *Document) resolveSchema(s *Schema) error {
+	if s == nil {
+		return nil
+	}
+
+	if s.Ref != "" {
+		name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+		target := d.Components.Schemas[name]
+		if !ok || target == nil {
+			return fmt.Errorf("unknown reference %q", s.Ref)
+		}
+
+		if d.resolving[target] {
+			return fmt.Errorf("circular reference %q", s.Ref)
+		}
+
+		d.resolving[target] = true
+		err := d.resolveSchema(target)
+		delete(d.resolving, target)
+		if err != nil {
+			return err
+		}
+
+		// siblings of $ref only annotate the schema, the referenced schema does the validation
+		*s = *target
+		return nil
+	}
+
+	if s.Pattern != "" && s.pattern == nil {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+
+	subs := []*Schema{s.Items, s.AdditionalProperties, s.PropertyNames}
+	subs = append(subs, s.AnyOf...)
+	for _, p := range s.Properties {
+		subs = append(subs, p)
+	}
+
+	for _, sub := range subs {
+		if err := d.resolveSchema(sub); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validate returns every violation of the value, name is the path of the value eg: tags[1]
+func (s *Schema) validate(name string, v any) []violation {
+	if s.deny {
+		return []violation{{name, "is not allowed"}}
+	}
+
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(t string) bool { return isType(t, v) }) {
+		return []violation{{name, "must be " + typeNames(s.Type)}}
+	}
+
+	var vs []violation
+	fail := func(format string, args ...any) {
+		vs = append(vs, violation{name, fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return equal(e, v) }) {
+		fail("must be one of %s", enumNames(s.Enum))
+	}
+
+	switch v := v.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("must have at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("must have at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("must match %s", s.Pattern)
+		}
+		if msg := checkFormat(s.Format, v); msg != "" {
+			fail("%s", msg)
+		}
+
+	case json.Number:
+		f, _ := v.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			fail("must be greater than or equal to %v", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			fail("must be less than or equal to %v", *s.Maximum)
+		}
+
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				vs = append(vs, s.Items.validate(fmt.Sprintf("%s[%d]", name, i), item)...)
+			}
+		}
+
+	case map[string]any:
+		if s.MinProperties != nil && len(v) < *s.MinProperties {
+			fail("must have at least %d fields", *s.MinProperties)
+		}
+		if s.MaxProperties != nil && len(v) > *s.MaxProperties {
+			fail("must have at most %d fields", *s.MaxProperties)
+		}
+
+		for _, field := range s.Required {
+			if _, ok := v[field]; !ok {
+				vs = append(vs, violation{join(name, field), "is required"})
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if s.PropertyNames != nil {
+				for _, kv := range s.PropertyNames.validate(join(name, k), k) {
+					vs = append(vs, violation{kv.name, "key " + kv.message})
+				}
+			}
+
+			if p, ok := s.Properties[k]; ok {
+				vs = append(vs, p.validate(join(name, k), v[k])...)
+			} else if s.AdditionalProperties != nil {
+				vs = append(vs, s.AdditionalProperties.validate(join(name, k), v[k])...)
+			}
+		}
+	}
+
+	if len(s.AnyOf) > 0 {
+		var msgs []string
+		for _, sub := range s.AnyOf {
+			svs := sub.validate(name, v)
+			if len(svs) == 0 {
+				msgs = nil
+				break
+			}
+			msgs = append(msgs, svs[0].message)
+		}
+
+		if len(msgs) > 0 {
+			for i := 1; i < len(msgs); i++ {
+				msgs[i] = strings.TrimPrefix(msgs[i], "must be ")
+			}
+			fail("%s", strings.Join(msgs, " or "))
+		}
+	}
+
+	return vs
+}
+
+// parse converts a parameter value to the type of the schema, values that don't convert are returned as is
+// so validate reports them
+func (s *Schema) parse(v string) any {
+	for _, t := range s.Type {
+		switch t {
+		case "integer", "number":
+			if _, err := strconv.ParseFloat(v, 64); err == nil {
+				return json.Number(v)
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b
+			}
+		case "string":
+			return v
+		}
+	}
+
+	return v
+}
+
+func isType(t string, v any) bool {
+	switch t {
+	case "null":
+		return v == nil
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(json.Number)
+		return ok
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	}
+
+	return false
+}
+
+var typeArticles = map[string]string{
+	"null":    "null",
+	"boolean": "a boolean",
+	"string":  "a string",
+	"number":  "a number",
+	"integer": "an integer",
+	"array":   "an array",
+	"object":  "an object",
+}
+
+func typeNames(types []string) string {
+	names := make([]string, 0, len(types))
+	for _, t := range types {
+		names = append(names, typeArticles[t])
+	}
+
+	return strings.Join(names, " or ")
+}
+
+func enumNames(enum []any) string {
+	names := make([]string, 0, len(enum))
+	for _, e := range enum {
+		names = append(names, fmt.Sprint(e))
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// equal compares an enum value of the document with a request value, numbers of the document are float64
+func equal(e, v any) bool {
+	if n, ok := v.(json.Number); ok {
+		f, err := n.Float64()
+		if err != nil {
+			return false
+		}
+		v = f
+	}
+
+	return e == v
+}
+
+// checkFormat returns why the value doesn't have the format, formats it doesn't know are only annotations
+func checkFormat(format, v string) string {
+	switch format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, v); err != nil {
+			return "must be an RFC 3339 timestamp"
+		}
+	case "date":
+		if _, err := time.Parse(time.DateOnly, v); err != nil {
+			return "must be a YYYY-MM-DD date"
+		}
+	case "uri":
+		if u, err := url.Parse(v); err != nil || !u.IsAbs() {
+			return "must be an absolute URI"
+		}
+	}
+
+	return ""
+}
+
+func join(name, field string) string {
+	if name == "" {
+		return field
+	}
+
+	return name + "." + field
+}
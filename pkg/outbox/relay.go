@@ -0,0 +1,138 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+)
+
+const (
+	DefaultInterval  = time.Second
+	DefaultBatchSize = 100
+	// DefaultLease is how long a claimed batch is kept from the other replicas, long enough to publish a whole batch
+	DefaultLease = 5 * time.Minute
+)
+
+type (
+	// Relay publishes the pending outbox events to the sink, events are delivered at least once and in order per account
+	Relay struct {
+		repo      repository.PismoRepo
+		sink      Sink
+		interval  time.Duration
+		batchSize int
+		lease     time.Duration
+	}
+
+	// Option - Used to configure the relay during initialization
+	Option func(*Relay)
+)
+
+// WithInterval - Will poll the outbox at the given interval while it's drained
+func WithInterval(d time.Duration) Option {
+	return func(r *Relay) {
+		r.interval = d
+	}
+}
+
+// WithBatchSize - Will read up to n events from the outbox at a time
+func WithBatchSize(n int) Option {
+	return func(r *Relay) {
+		r.batchSize = n
+	}
+}
+
+// WithLease - Will keep the claimed events from the other replicas for the given duration, the events of a replica
+// dying mid batch are published again once it's over
+func WithLease(d time.Duration) Option {
+	return func(r *Relay) {
+		r.lease = d
+	}
+}
+
+// NewRelay configures and returns the outbox Relay
+func NewRelay(repo repository.PismoRepo, sink Sink, opts ...Option) *Relay {
+	r := &Relay{
+		repo:      repo,
+		sink:      sink,
+		interval:  DefaultInterval,
+		batchSize: DefaultBatchSize,
+		lease:     DefaultLease,
+	}
+
+	for _, o := range opts {
+		o(r)
+	}
+
+	return r
+}
+
+// Start relays the events until the context is cancelled, full batches are followed right away by the next one
+func (r *Relay) Start(ctx context.Context) {
+	for {
+		count, err := r.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("failed to relay outbox events")
+		}
+
+		if err == nil && count >= r.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+// RunOnce relays a single batch and returns the number of events claimed from the outbox, no db connection is held
+// while the events are published
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	events, err := r.repo.ClaimOutboxEvents(ctx, r.batchSize, r.lease)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	deliveries := r.deliver(ctx, events)
+
+	eventIDs := make([]int64, len(events))
+	for i, e := range events {
+		eventIDs[i] = e.EventID
+	}
+
+	// the outcome is recorded even when the relay is stopped mid batch, the published events would be published again
+	// once the lease is over otherwise
+	if err := r.repo.RecordOutboxDeliveries(context.WithoutCancel(ctx), eventIDs, deliveries); err != nil {
+		return 0, err
+	}
+
+	return len(events), nil
+}
+
+// deliver publishes the events in order, once an event of an account fails the following events of that account
+// are held back so they aren't published ahead of it
+func (r *Relay) deliver(ctx context.Context, events []repository.OutboxEvent) []repository.OutboxDelivery {
+	var (
+		deliveries []repository.OutboxDelivery
+		blocked    = map[int]bool{}
+	)
+
+	for _, e := range events {
+		if blocked[e.AccountID] {
+			continue
+		}
+
+		err := r.sink.Publish(ctx, NewMessage(e))
+		if err != nil {
+			blocked[e.AccountID] = true
+			log.Warn().Err(err).Int64("event_id", e.EventID).Int("attempts", e.Attempts+1).Msg("failed to publish event")
+		}
+
+		deliveries = append(deliveries, repository.OutboxDelivery{EventID: e.EventID, Err: err})
+	}
+
+	return deliveries
+}
@@ -0,0 +1,80 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/mocks"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type stubSink struct {
+	failing   map[int64]bool
+	published []int64
+}
+
+func (s *stubSink) Publish(_ context.Context, msg Message) error {
+	if s.failing[msg.EventID] {
+		return errors.New("sink unavailable")
+	}
+	s.published = append(s.published, msg.EventID)
+	return nil
+}
+
+func TestRelayRunOnce(t *testing.T) {
+	events := []repository.OutboxEvent{
+		{EventID: 1, EventType: enums.AccountCreated, AccountID: 1, Payload: []byte(`{}`)},
+		{EventID: 2, EventType: enums.TransactionCreated, AccountID: 2, Payload: []byte(`{}`)},
+		{EventID: 3, EventType: enums.TransactionCreated, AccountID: 1, Payload: []byte(`{}`)},
+		{EventID: 4, EventType: enums.TransactionCreated, AccountID: 2, Payload: []byte(`{}`)},
+		{EventID: 5, EventType: enums.TransactionCreated, AccountID: 3, Payload: []byte(`{}`)},
+	}
+
+	repo := mocks.NewPismoRepo(t)
+	sink := &stubSink{failing: map[int64]bool{2: true}}
+	r := NewRelay(repo, sink, WithBatchSize(10))
+
+	var deliveries []repository.OutboxDelivery
+	repo.On("ClaimOutboxEvents", mock.Anything, 10, DefaultLease).Return(events, nil).Once()
+	// every claimed event is released, the held back ones included
+	repo.On("RecordOutboxDeliveries", mock.Anything, []int64{1, 2, 3, 4, 5}, mock.Anything).
+		Run(func(args mock.Arguments) {
+			deliveries = args.Get(2).([]repository.OutboxDelivery)
+		}).
+		Return(nil).Once()
+
+	count, err := r.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 5, count)
+
+	// event 4 is held back behind the failed event 2 of the same account
+	require.Equal(t, []int64{1, 3, 5}, sink.published)
+	require.Len(t, deliveries, 4)
+	require.Equal(t, int64(2), deliveries[1].EventID)
+	require.Error(t, deliveries[1].Err)
+	for _, d := range []repository.OutboxDelivery{deliveries[0], deliveries[2], deliveries[3]} {
+		require.NoError(t, d.Err)
+	}
+}
+
+func TestRelayStartStopsWithContext(t *testing.T) {
+	repo := mocks.NewPismoRepo(t)
+	r := NewRelay(repo, &stubSink{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	repo.On("ClaimOutboxEvents", mock.Anything, DefaultBatchSize, DefaultLease).
+		Run(func(mock.Arguments) { cancel() }).
+		Return(nil, nil).Once()
+
+	done := make(chan struct{})
+	go func() {
+		r.Start(ctx)
+		close(done)
+	}()
+
+	<-done
+}
@@ -0,0 +1,169 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+)
+
+type (
+	// Message is the wire format of a published domain event
+	Message struct {
+		EventID       int64           `json:"event_id"`
+		EventType     enums.EventType `json:"event_type"`
+		AggregateType string          `json:"aggregate_type"`
+		AggregateID   int             `json:"aggregate_id"`
+		AccountID     int             `json:"account_id"`
+		OccurredAt    time.Time       `json:"occurred_at"`
+		Data          json.RawMessage `json:"data"`
+	}
+
+	// Sink publishes the messages somewhere downstream services can read them from,
+	// a message is only considered published once Publish returns without an error
+	Sink interface {
+		Publish(ctx context.Context, msg Message) error
+	}
+
+	// fileSink appends the messages as NDJSON to a file
+	fileSink struct {
+		mu   sync.Mutex
+		file *os.File
+	}
+
+	// httpSink POSTs every message as JSON to an url
+	httpSink struct {
+		url    string
+		client *http.Client
+	}
+
+	// fanout publishes every message to all of its sinks
+	fanout []Sink
+)
+
+// NewMessage converts the outbox event into its wire format
+func NewMessage(e repository.OutboxEvent) Message {
+	return Message{
+		EventID:       e.EventID,
+		EventType:     e.EventType,
+		AggregateType: e.AggregateType,
+		AggregateID:   e.AggregateID,
+		AccountID:     e.AccountID,
+		OccurredAt:    e.CreatedAt,
+		Data:          json.RawMessage(e.Payload),
+	}
+}
+
+// NewSink returns the sink for the given uri, file:///path/to/events.ndjson appends to a file
+// and http(s)://host/path POSTs to the url
+func NewSink(uri string) (Sink, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sink %q: %w", uri, err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		return NewFileSink(u.Path)
+	case "http", "https":
+		return NewHTTPSink(uri, 10*time.Second), nil
+	}
+
+	return nil, fmt.Errorf("unsupported sink %q, expected a file or http(s) url", uri)
+}
+
+// NewFileSink opens the file in append mode, creating it when needed
+func NewFileSink(path string) (Sink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sink file: %w", err)
+	}
+
+	return &fileSink{file: f}, nil
+}
+
+// Publish writes the message as a single line and syncs the file so the line survives a crash
+func (f *fileSink) Publish(_ context.Context, msg Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, err := f.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	return f.file.Sync()
+}
+
+// NewHTTPSink returns a sink posting to the url with the given request timeout
+func NewHTTPSink(url string, timeout time.Duration) Sink {
+	return &httpSink{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Publish POSTs the message, any 2xx response acknowledges it
+func (h *httpSink) Publish(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("sink responded with status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// Fanout returns a sink publishing to all the given sinks, a message failing on any of them is published to all of
+// them again on the retry so the sinks must tolerate duplicates
+func Fanout(sinks ...Sink) Sink {
+	if len(sinks) == 1 {
+		return sinks[0]
+	}
+
+	return fanout(sinks)
+}
+
+// Publish publishes the message to every sink and joins their errors
+func (f fanout) Publish(ctx context.Context, msg Message) error {
+	var errs []error
+	for _, s := range f {
+		if err := s.Publish(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
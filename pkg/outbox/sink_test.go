@@ -0,0 +1,106 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSink(t *testing.T) {
+	dir := t.TempDir()
+
+	tcs := []struct {
+		name        string
+		uri         string
+		expectedErr bool
+	}{
+		{name: "Test File Sink", uri: "file://" + filepath.Join(dir, "events.ndjson")},
+		{name: "Test HTTP Sink", uri: "http://localhost:9000/events"},
+		{name: "Test HTTPS Sink", uri: "https://events.example.com/pismo"},
+		{name: "Test Unsupported Scheme", uri: "kafka://localhost:9092/events", expectedErr: true},
+		{name: "Test Missing Dir", uri: "file://" + filepath.Join(dir, "missing", "events.ndjson"), expectedErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewSink(tc.uri)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+
+	sink, err := NewFileSink(path)
+	require.NoError(t, err)
+
+	for i := int64(1); i <= 2; i++ {
+		require.NoError(t, sink.Publish(context.Background(), Message{
+			EventID:   i,
+			EventType: enums.TransactionCreated,
+			Data:      json.RawMessage(`{"amount":-50}`),
+		}))
+	}
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var lines []Message
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg Message
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &msg))
+		lines = append(lines, msg)
+	}
+
+	require.Len(t, lines, 2)
+	require.Equal(t, int64(2), lines[1].EventID)
+	require.JSONEq(t, `{"amount":-50}`, string(lines[1].Data))
+}
+
+func TestHTTPSink(t *testing.T) {
+	var received Message
+	status := http.StatusAccepted
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink := NewHTTPSink(srv.URL, time.Second)
+
+	msg := Message{EventID: 7, EventType: enums.AccountCreated, AccountID: 3, Data: json.RawMessage(`{"account_id":3}`)}
+	require.NoError(t, sink.Publish(context.Background(), msg))
+	require.Equal(t, int64(7), received.EventID)
+
+	status = http.StatusServiceUnavailable
+	require.Error(t, sink.Publish(context.Background(), msg))
+}
+
+func TestFanout(t *testing.T) {
+	first, second := &stubSink{}, &stubSink{failing: map[int64]bool{2: true}}
+	sink := Fanout(first, second)
+
+	require.NoError(t, sink.Publish(context.Background(), Message{EventID: 1}))
+	require.Error(t, sink.Publish(context.Background(), Message{EventID: 2}))
+
+	require.Equal(t, []int64{1, 2}, first.published)
+	require.Equal(t, []int64{1}, second.published)
+}
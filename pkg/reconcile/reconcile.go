@@ -0,0 +1,240 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+)
+
+// DefaultDateTolerance is how far apart the settlement date and the transaction event_date can be
+const DefaultDateTolerance = 24 * time.Hour
+
+var (
+	// ErrInvalidFile wraps every error caused by the content of the settlement file
+	ErrInvalidFile = errors.New("invalid settlement file")
+	// ErrNoLines is returned for settlement files without any line
+	ErrNoLines = errors.New("settlement file has no lines")
+)
+
+type Reconciler struct {
+	repo repository.PismoRepo
+}
+
+// Options configures a reconciliation run
+type Options struct {
+	Source        string
+	Mapping       Mapping
+	DateTolerance time.Duration
+}
+
+// New configures and returns the Reconciler
+func New(repo repository.PismoRepo) *Reconciler {
+	return &Reconciler{
+		repo,
+	}
+}
+
+// Run parses the settlement file, matches it against the transactions of the period it covers and saves the outcome,
+// the period covered is from the earliest to the latest settlement date widened by the date tolerance
+func (r *Reconciler) Run(ctx context.Context, file io.Reader, opts Options) (*repository.Reconciliation, error) {
+	if opts.DateTolerance <= 0 {
+		opts.DateTolerance = DefaultDateTolerance
+	}
+
+	lines, err := ParseFile(file, opts.Mapping)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, ErrNoLines)
+	}
+
+	from, to := lines[0].Date, lines[0].Date
+	for _, l := range lines[1:] {
+		if l.Date.Before(from) {
+			from = l.Date
+		}
+		if l.Date.After(to) {
+			to = l.Date
+		}
+	}
+
+	var ledger []repository.Transaction
+	err = r.repo.StreamTransactions(ctx,
+		repository.TransactionFilter{
+			From: from.Add(-opts.DateTolerance),
+			// the filter upper bound is exclusive
+			To: to.Add(opts.DateTolerance).Add(time.Nanosecond),
+		},
+		func(txn repository.Transaction) error {
+			ledger = append(ledger, txn)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := Match(lines, ledger, opts.DateTolerance)
+	rec.Source = opts.Source
+
+	if err := r.repo.SaveReconciliation(ctx, rec); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+type amountKey struct {
+	accountID int
+	cents     int64
+}
+
+// Match matches the settlement lines against the ledger transactions.
+//
+// A line is matched with the transaction sharing its external reference when the account agrees and the dates are
+// within the tolerance, differing amounts are reported as amount mismatches. Lines without a usable reference fall
+// back to the closest transaction of the same account and amount within the tolerance. Amounts are compared by
+// their absolute value since processors report purchases unsigned.
+func Match(lines []Line, ledger []repository.Transaction, tolerance time.Duration) *repository.Reconciliation {
+	rec := &repository.Reconciliation{DateTolerance: tolerance}
+
+	used := make([]bool, len(ledger))
+	byRef := map[string]int{}
+	byAmount := map[amountKey][]int{}
+	for i, txn := range ledger {
+		if txn.ExternalReference != "" {
+			byRef[txn.ExternalReference] = i
+		}
+		key := amountKey{txn.AccountID, cents(txn.Amount)}
+		byAmount[key] = append(byAmount[key], i)
+	}
+
+	sameAccount := func(l Line, txn repository.Transaction) bool {
+		return l.AccountID == 0 || l.AccountID == txn.AccountID
+	}
+
+	for _, l := range lines {
+		item := repository.ReconciliationItem{
+			Line:              l.Number,
+			ExternalReference: l.Reference,
+			AccountID:         l.AccountID,
+			FileAmount:        amount(l.Amount),
+			EventDate:         l.Date,
+		}
+
+		match := -1
+		if i, ok := byRef[l.Reference]; ok && l.Reference != "" && !used[i] &&
+			sameAccount(l, ledger[i]) && within(l.Date, ledger[i].EventDate, tolerance) {
+			match = i
+		}
+
+		if match < 0 {
+			match = closest(l, ledger, used, candidates(l, byAmount, ledger), tolerance)
+		}
+
+		if match < 0 {
+			item.Status = enums.MissingInLedger
+			rec.MissingInLedger++
+			rec.Items = append(rec.Items, item)
+			continue
+		}
+
+		used[match] = true
+		txn := ledger[match]
+
+		item.TransactionID = txn.TransactionID
+		item.AccountID = txn.AccountID
+		item.LedgerAmount = amount(txn.Amount)
+		if cents(txn.Amount) == cents(l.Amount) {
+			item.Status = enums.Matched
+			rec.Matched++
+		} else {
+			item.Status = enums.AmountMismatch
+			rec.AmountMismatches++
+		}
+
+		rec.Items = append(rec.Items, item)
+	}
+
+	for i, txn := range ledger {
+		if used[i] {
+			continue
+		}
+
+		rec.MissingInFile++
+		rec.Items = append(rec.Items, repository.ReconciliationItem{
+			Status:            enums.MissingInFile,
+			ExternalReference: txn.ExternalReference,
+			AccountID:         txn.AccountID,
+			LedgerAmount:      amount(txn.Amount),
+			EventDate:         txn.EventDate,
+			TransactionID:     txn.TransactionID,
+		})
+	}
+
+	return rec
+}
+
+// candidates returns the ledger transactions with the same amount as the line
+func candidates(l Line, byAmount map[amountKey][]int, ledger []repository.Transaction) []int {
+	if l.AccountID != 0 {
+		return byAmount[amountKey{l.AccountID, cents(l.Amount)}]
+	}
+
+	var idx []int
+	for i, txn := range ledger {
+		if cents(txn.Amount) == cents(l.Amount) {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// closest picks the unused candidate nearest to the line date, transactions carrying a different reference are skipped
+func closest(l Line, ledger []repository.Transaction, used []bool, idx []int, tolerance time.Duration) int {
+	best, bestDiff := -1, time.Duration(math.MaxInt64)
+
+	for _, i := range idx {
+		txn := ledger[i]
+		if used[i] || !within(l.Date, txn.EventDate, tolerance) {
+			continue
+		}
+		if l.Reference != "" && txn.ExternalReference != "" && l.Reference != txn.ExternalReference {
+			continue
+		}
+
+		if diff := absDuration(l.Date.Sub(txn.EventDate)); diff < bestDiff {
+			best, bestDiff = i, diff
+		}
+	}
+
+	return best
+}
+
+func within(a, b time.Time, tolerance time.Duration) bool {
+	return absDuration(a.Sub(b)) <= tolerance
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// cents converts the absolute amount to cents to compare amounts without floating point noise
+func cents(f float64) int64 {
+	return int64(math.Round(math.Abs(f) * 100))
+}
+
+func amount(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,218 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/mocks"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func day(d, h int) time.Time {
+	return time.Date(2024, 12, d, h, 0, 0, 0, time.UTC)
+}
+
+func TestParseMapping(t *testing.T) {
+	tcs := []struct {
+		name            string
+		columns         string
+		expectedMapping Mapping
+		expectErr       bool
+	}{
+		{
+			name:            "Test ParseMapping_Default",
+			expectedMapping: DefaultMapping(),
+		},
+		{
+			name:    "Test ParseMapping_Override",
+			columns: "reference=nsu, amount=gross_value,account_id=",
+			expectedMapping: Mapping{
+				Reference:  "nsu",
+				Amount:     "gross_value",
+				Date:       ColumnDate,
+				DateLayout: time.DateOnly,
+				Delimiter:  ',',
+			},
+		},
+		{
+			name:      "Test ParseMapping_UnknownField",
+			columns:   "merchant=name",
+			expectErr: true,
+		},
+		{
+			name:      "Test ParseMapping_MissingRequiredColumn",
+			columns:   "amount=",
+			expectErr: true,
+		},
+		{
+			name:      "Test ParseMapping_InvalidPair",
+			columns:   "amount",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := ParseMapping(tc.columns)
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedMapping, m)
+		})
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	m, err := ParseMapping("reference=nsu,account_id=acct,amount=value,date=settled_at")
+	require.NoError(t, err)
+	require.NoError(t, m.SetDelimiter(";"))
+
+	lines, err := ParseFile(strings.NewReader(
+		"nsu;acct;value;settled_at;merchant\n"+
+			"A1;1;50.10;2024-12-05;shop\n"+
+			"A2;2;-20;2024-12-06T10:00:00Z;shop\n",
+	), m)
+	require.NoError(t, err)
+	require.Equal(t, []Line{
+		{Number: 2, Reference: "A1", AccountID: 1, Amount: 50.10, Date: day(5, 0)},
+		{Number: 3, Reference: "A2", AccountID: 2, Amount: -20, Date: day(6, 10)},
+	}, lines)
+
+	tcs := []struct {
+		name        string
+		file        string
+		expectedErr string
+	}{
+		{
+			name:        "Test ParseFile_Empty",
+			expectedErr: "settlement file is empty",
+		},
+		{
+			name:        "Test ParseFile_MissingColumn",
+			file:        "nsu;acct;value\n",
+			expectedErr: `column "settled_at" not found in header`,
+		},
+		{
+			name:        "Test ParseFile_InvalidAmount",
+			file:        "nsu;acct;value;settled_at\nA1;1;ten;2024-12-05\n",
+			expectedErr: `line 2: invalid amount "ten"`,
+		},
+		{
+			name:        "Test ParseFile_InvalidDate",
+			file:        "nsu;acct;value;settled_at\nA1;1;10;05/12/2024\n",
+			expectedErr: `line 2: invalid date "05/12/2024"`,
+		},
+		{
+			name:        "Test ParseFile_InvalidAccount",
+			file:        "nsu;acct;value;settled_at\nA1;x;10;2024-12-05\n",
+			expectedErr: `line 2: invalid account_id "x"`,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseFile(strings.NewReader(tc.file), m)
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	ledger := []repository.Transaction{
+		{TransactionID: 1, AccountID: 1, Amount: -50.10, EventDate: day(5, 12), ExternalReference: "A1"},
+		{TransactionID: 2, AccountID: 1, Amount: -20, EventDate: day(5, 13), ExternalReference: "A2"},
+		{TransactionID: 3, AccountID: 2, Amount: -30, EventDate: day(6, 9)},
+		{TransactionID: 4, AccountID: 2, Amount: 15, EventDate: day(6, 10)},
+		{TransactionID: 5, AccountID: 3, Amount: -99, EventDate: day(5, 1), ExternalReference: "A5"},
+	}
+
+	lines := []Line{
+		// matched by reference, amounts are compared unsigned
+		{Number: 2, Reference: "A1", AccountID: 1, Amount: 50.10, Date: day(5, 0)},
+		// same reference with a different amount
+		{Number: 3, Reference: "A2", AccountID: 1, Amount: 25, Date: day(5, 0)},
+		// no reference, matched by account, amount and date
+		{Number: 4, AccountID: 2, Amount: 30, Date: day(6, 0)},
+		// unknown to the ledger
+		{Number: 5, Reference: "A9", AccountID: 2, Amount: 70, Date: day(6, 0)},
+		// reference exists but the date is out of tolerance
+		{Number: 6, Reference: "A5", AccountID: 3, Amount: 99, Date: day(9, 0)},
+	}
+
+	rec := Match(lines, ledger, 24*time.Hour)
+
+	require.Equal(t, 2, rec.Matched)
+	require.Equal(t, 1, rec.AmountMismatches)
+	require.Equal(t, 2, rec.MissingInLedger)
+	require.Equal(t, 2, rec.MissingInFile)
+
+	statuses := map[int]enums.ReconciliationStatus{}
+	txns := map[int]int{}
+	for _, item := range rec.Items {
+		if item.Line != 0 {
+			statuses[item.Line] = item.Status
+			txns[item.Line] = item.TransactionID
+		} else {
+			require.Equal(t, enums.MissingInFile, item.Status)
+			require.Contains(t, []int{4, 5}, item.TransactionID)
+		}
+	}
+
+	require.Equal(t, map[int]enums.ReconciliationStatus{
+		2: enums.Matched,
+		3: enums.AmountMismatch,
+		4: enums.Matched,
+		5: enums.MissingInLedger,
+		6: enums.MissingInLedger,
+	}, statuses)
+	require.Equal(t, 1, txns[2])
+	require.Equal(t, 2, txns[3])
+	require.Equal(t, 3, txns[4])
+}
+
+func TestReconcilerRun(t *testing.T) {
+	repo := mocks.NewPismoRepo(t)
+
+	repo.On("StreamTransactions", mock.Anything,
+		repository.TransactionFilter{From: day(4, 0), To: day(7, 0).Add(time.Nanosecond)},
+		mock.Anything,
+	).Run(func(args mock.Arguments) {
+		fn := args.Get(2).(func(repository.Transaction) error)
+		require.NoError(t, fn(repository.Transaction{TransactionID: 1, AccountID: 1, Amount: -10, EventDate: day(5, 8), ExternalReference: "A1"}))
+	}).Return(nil)
+
+	repo.On("SaveReconciliation", mock.Anything, mock.MatchedBy(func(rec *repository.Reconciliation) bool {
+		return rec.Source == "settlement.csv" && rec.Matched == 1 && rec.MissingInLedger == 1
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*repository.Reconciliation).ReconciliationID = 7
+	}).Return(nil)
+
+	rec, err := New(repo).Run(context.Background(), strings.NewReader(
+		"reference,account_id,amount,date\nA1,1,10,2024-12-05\nA2,1,20,2024-12-06\n",
+	), Options{Source: "settlement.csv", Mapping: DefaultMapping()})
+	require.NoError(t, err)
+	require.Equal(t, 7, rec.ReconciliationID)
+	require.Equal(t, DefaultDateTolerance, rec.DateTolerance)
+}
+
+func TestReconcilerRunInvalidFile(t *testing.T) {
+	repo := mocks.NewPismoRepo(t)
+
+	_, err := New(repo).Run(context.Background(), strings.NewReader("reference,account_id,amount,date\n"),
+		Options{Mapping: DefaultMapping()})
+	require.True(t, errors.Is(err, ErrInvalidFile))
+	require.True(t, errors.Is(err, ErrNoLines))
+
+	_, err = New(repo).Run(context.Background(), strings.NewReader("reference,amount\n"),
+		Options{Mapping: DefaultMapping()})
+	require.True(t, errors.Is(err, ErrInvalidFile))
+}
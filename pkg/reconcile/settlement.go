@@ -0,0 +1,220 @@
+package reconcile
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	ColumnReference = "reference"
+	ColumnAccountID = "account_id"
+	ColumnAmount    = "amount"
+	ColumnDate      = "date"
+)
+
+type (
+	// Mapping tells which header of the settlement file holds each field, the account column is optional
+	Mapping struct {
+		Reference  string
+		AccountID  string
+		Amount     string
+		Date       string
+		DateLayout string
+		Delimiter  rune
+	}
+
+	// Line is a single settlement line, Number is the line number in the file
+	Line struct {
+		Number    int
+		Reference string
+		AccountID int
+		Amount    float64
+		Date      time.Time
+	}
+
+	// LineError is returned when a settlement line can't be parsed
+	LineError struct {
+		Line int
+		Err  error
+	}
+)
+
+func (l *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", l.Line, l.Err)
+}
+
+func (l *LineError) Unwrap() error {
+	return l.Err
+}
+
+// DefaultMapping returns the mapping of a file using the field names as headers
+func DefaultMapping() Mapping {
+	return Mapping{
+		Reference:  ColumnReference,
+		AccountID:  ColumnAccountID,
+		Amount:     ColumnAmount,
+		Date:       ColumnDate,
+		DateLayout: time.DateOnly,
+		Delimiter:  ',',
+	}
+}
+
+// ParseMapping overrides the default mapping with a comma separated list of field=header pairs,
+// eg: reference=nsu,account_id=,amount=gross_value, an empty header drops the optional account column
+func ParseMapping(s string) (Mapping, error) {
+	m := DefaultMapping()
+	if strings.TrimSpace(s) == "" {
+		return m, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		field, header, ok := strings.Cut(pair, "=")
+		if !ok {
+			return m, fmt.Errorf("invalid column mapping %q, expected field=header", pair)
+		}
+
+		header = strings.TrimSpace(header)
+		switch strings.TrimSpace(field) {
+		case ColumnReference:
+			m.Reference = header
+		case ColumnAccountID:
+			m.AccountID = header
+		case ColumnAmount:
+			m.Amount = header
+		case ColumnDate:
+			m.Date = header
+		default:
+			return m, fmt.Errorf("unknown field %q in column mapping", field)
+		}
+	}
+
+	return m, m.validate()
+}
+
+// SetDelimiter sets the column delimiter, it has to be a single character
+func (m *Mapping) SetDelimiter(s string) error {
+	if utf8.RuneCountInString(s) != 1 {
+		return errors.New("delimiter must be a single character")
+	}
+
+	m.Delimiter, _ = utf8.DecodeRuneInString(s)
+	return nil
+}
+
+func (m Mapping) validate() error {
+	if m.Reference == "" || m.Amount == "" || m.Date == "" {
+		return errors.New("reference, amount and date columns are required")
+	}
+	return nil
+}
+
+// ParseFile reads the settlement csv, the first row has to be the header
+func ParseFile(r io.Reader, m Mapping) ([]Line, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	if m.DateLayout == "" {
+		m.DateLayout = time.DateOnly
+	}
+
+	cr := csv.NewReader(r)
+	if m.Delimiter != 0 {
+		cr.Comma = m.Delimiter
+	}
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("settlement file is empty")
+		}
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, h := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))] = i
+	}
+
+	index := func(name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+		i, ok := columns[name]
+		if !ok {
+			return 0, fmt.Errorf("column %q not found in header", name)
+		}
+		return i, nil
+	}
+
+	refIdx, err := index(m.Reference)
+	if err != nil {
+		return nil, err
+	}
+	accIdx, err := index(m.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	amountIdx, err := index(m.Amount)
+	if err != nil {
+		return nil, err
+	}
+	dateIdx, err := index(m.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []Line
+	for number := 2; ; number++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, &LineError{Line: number, Err: err}
+		}
+
+		line := Line{
+			Number:    number,
+			Reference: strings.TrimSpace(record[refIdx]),
+		}
+
+		if line.Amount, err = strconv.ParseFloat(strings.TrimSpace(record[amountIdx]), 64); err != nil {
+			return nil, &LineError{Line: number, Err: fmt.Errorf("invalid amount %q", record[amountIdx])}
+		}
+
+		if line.Date, err = parseDate(strings.TrimSpace(record[dateIdx]), m.DateLayout); err != nil {
+			return nil, &LineError{Line: number, Err: fmt.Errorf("invalid date %q", record[dateIdx])}
+		}
+
+		if accIdx >= 0 {
+			if line.AccountID, err = strconv.Atoi(strings.TrimSpace(record[accIdx])); err != nil || line.AccountID <= 0 {
+				return nil, &LineError{Line: number, Err: fmt.Errorf("invalid account_id %q", record[accIdx])}
+			}
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// parseDate parses the date with the configured layout and falls back to RFC 3339
+func parseDate(v, layout string) (time.Time, error) {
+	t, err := time.Parse(layout, v)
+	if err == nil {
+		return t, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+
+	return t, err
+}
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+)
+
+// MaxMetadataKeys is the number of metadata keys an account can hold
+const MaxMetadataKeys = 50
+
+// accountColumns are the columns scanned into an accountRow
+const accountColumns = "account_id, document_number, document_number_enc, status, metadata, tags, created_at, erased_at, version"
+
+// ErrTooManyMetadataKeys is returned when a metadata patch leaves the account with more than MaxMetadataKeys keys
+var ErrTooManyMetadataKeys = fmt.Errorf("accounts can't hold more than %d metadata keys", MaxMetadataKeys)
+
+// Metadata is the key/value data clients attach to an account, stored as a jsonb object
+type Metadata map[string]string
+
+// Value implements driver.Valuer, nil is stored as an empty object
+func (m Metadata) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+
+	data, err := json.Marshal(map[string]string(m))
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner
+func (m *Metadata) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*m = Metadata{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported metadata type %T", src)
+	}
+
+	return json.Unmarshal(data, (*map[string]string)(m))
+}
+
+// UpdateAccountMetadata applies the patch to the metadata and tags of the account along with its audit entry and
+// returns the updated account at its next version, a nil account is returned when it doesn't exist
+func (p *pismoRepo) UpdateAccountMetadata(ctx context.Context, accID int, patch AccountMetadataPatch) (acc *Account, err error) {
+	err = p.withTx(ctx, func(tx *sqlx.Tx) error {
+		var row accountRow
+		err := tx.GetContext(ctx, &row, "SELECT "+accountColumns+" FROM accounts WHERE account_id = $1 FOR UPDATE", accID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return fmt.Errorf("failed to query account: %w", err)
+		}
+
+		if row.ErasedAt != nil {
+			return ErrAccountErased
+		}
+
+		if !patch.IfMatch.Allows(row.Version) {
+			return ErrVersionMismatch
+		}
+
+		before := newAccountImage(row.AccountID, row.Version, "")
+
+		metadata := maps.Clone(row.Metadata)
+		if metadata == nil {
+			metadata = Metadata{}
+		}
+		for k, v := range patch.Metadata {
+			if v == nil {
+				delete(metadata, k)
+				continue
+			}
+			metadata[k] = *v
+		}
+
+		if len(metadata) > MaxMetadataKeys {
+			return ErrTooManyMetadataKeys
+		}
+
+		tags := row.Tags
+		if patch.Tags != nil {
+			tags = normalizeTags(patch.Tags)
+		}
+
+		err = tx.GetContext(ctx,
+			&row.Version,
+			"UPDATE accounts SET metadata = $2, tags = $3, version = version + 1 WHERE account_id = $1 RETURNING version",
+			accID,
+			metadata,
+			tags,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update account metadata: %w", err)
+		}
+
+		row.Metadata, row.Tags = metadata, tags
+
+		if acc, err = p.decryptAccount(row); err != nil {
+			return err
+		}
+
+		return insertAuditEntry(ctx, tx, aggregateAccount, accID, enums.AuditUpdate, before,
+			newAccountImage(row.AccountID, row.Version, ""),
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return acc, nil
+}
+
+// accountFilters are the conditions of AccountFilter, the tags and metadata filters are served by the gin indexes and
+// the encrypted document numbers are matched through the blind indexes of their prefixes
+const accountFilters = `tags @> $1 AND metadata ?& $2 AND metadata @> $3
+	AND ($4 = '' OR status = $4)
+	AND ($5::TIMESTAMPTZ IS NULL OR created_at >= $5)
+	AND ($6::TIMESTAMPTZ IS NULL OR created_at < $6)
+	AND ($7 = '' OR starts_with(document_number, $7) OR document_number_prefixes @> ARRAY[$8::BYTEA])`
+
+// accountSorts are the ORDER BY of every sort along with the condition resuming past the cursor in that order, $10
+// is the account id of the cursor and $11 its creation time
+var accountSorts = map[enums.AccountSort]struct{ orderBy, after string }{
+	enums.SortAccountID:          {"account_id", "account_id > $10"},
+	enums.SortAccountIDDesc:      {"account_id DESC", "account_id < $10"},
+	enums.SortAccountCreated:     {"created_at, account_id", "(created_at, account_id) > ($11, $10)"},
+	enums.SortAccountCreatedDesc: {"created_at DESC, account_id DESC", "(created_at, account_id) < ($11, $10)"},
+}
+
+// ListAccounts retrieves up to filter.Limit accounts matching the filter in the filter.Sort order, account_id by default
+func (p *pismoRepo) ListAccounts(ctx context.Context, filter AccountFilter) (accounts []Account, err error) {
+	sort, ok := accountSorts[filter.Sort]
+	if !ok {
+		sort = accountSorts[enums.SortAccountID]
+	}
+
+	where := accountFilters
+	args := append(p.accountFilterArgs(filter), filter.Limit)
+	if filter.After != nil {
+		// postgres can't tell the type of an unused parameter, so the creation time is only sent when it's used
+		where += " AND " + sort.after
+		args = append(args, filter.After.AccountID)
+		if filter.Sort == enums.SortAccountCreated || filter.Sort == enums.SortAccountCreatedDesc {
+			args = append(args, filter.After.CreatedAt)
+		}
+	}
+
+	var rows []accountRow
+	err = p.db.SelectContext(ctx,
+		&rows,
+		`SELECT `+accountColumns+`
+		FROM accounts
+		WHERE `+where+`
+		ORDER BY `+sort.orderBy+`
+		LIMIT $9
+		`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query accounts: %w", err)
+	}
+
+	accounts = make([]Account, 0, len(rows))
+	for _, row := range rows {
+		acc, err := p.decryptAccount(row)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, *acc)
+	}
+
+	return accounts, nil
+}
+
+// CountAccounts returns the number of accounts matching the filter, its sort, cursor and limit are ignored
+func (p *pismoRepo) CountAccounts(ctx context.Context, filter AccountFilter) (count int64, err error) {
+	err = p.db.GetContext(ctx, &count, "SELECT COUNT(*) FROM accounts WHERE "+accountFilters, p.accountFilterArgs(filter)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count accounts: %w", err)
+	}
+
+	return count, nil
+}
+
+// accountFilterArgs returns the arguments of accountFilters
+func (p *pismoRepo) accountFilterArgs(filter AccountFilter) []any {
+	// a nil time or []byte would be sent as a zero time or an empty string instead of null
+	var from, to, prefix any
+	if !filter.From.IsZero() {
+		from = filter.From
+	}
+	if !filter.To.IsZero() {
+		to = filter.To
+	}
+	if filter.DocumentNoPrefix != "" && p.keyring != nil {
+		prefix = p.keyring.PrefixIndex(filter.DocumentNoPrefix)
+	}
+
+	// a nil array would be sent as null, making the whole condition null, an empty one matches every account
+	return []any{
+		append(pq.StringArray{}, filter.Tags...),
+		append(pq.StringArray{}, filter.MetadataKeys...),
+		filter.Metadata,
+		filter.Status,
+		from,
+		to,
+		filter.DocumentNoPrefix,
+		prefix,
+	}
+}
+
+// normalizeTags returns the tags sorted without duplicates, no tags is an empty array since a nil one is sent as null
+// and the column isn't nullable
+func normalizeTags(tags []string) pq.StringArray {
+	tags = append(pq.StringArray{}, tags...)
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
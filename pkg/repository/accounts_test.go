@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	require.Equal(t, pq.StringArray{"gold", "vip"}, normalizeTags([]string{"vip", "gold", "vip"}))
+
+	// the column isn't nullable
+	v, err := normalizeTags(nil).Value()
+	require.NoError(t, err)
+	require.Equal(t, "{}", v)
+}
+
+func TestCreateAccountWithoutTags(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	acc := &Account{DocumentNo: testDocumentNo(t)}
+	require.NoError(t, repo.CreateAccount(ctx, acc))
+
+	got, err := repo.GetAccountByAccountID(ctx, acc.AccountID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	require.Empty(t, got.Tags)
+	require.Empty(t, got.Metadata)
+}
+
+func TestAccountFilterArgsWithoutFilters(t *testing.T) {
+	args := (&pismoRepo{}).accountFilterArgs(AccountFilter{})
+
+	// null arrays would make the tags and metadata keys conditions null
+	for _, arg := range args[:2] {
+		v, err := arg.(pq.StringArray).Value()
+		require.NoError(t, err)
+		require.Equal(t, "{}", v)
+	}
+}
+
+func TestListAccountsWithoutFilters(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	acc := &Account{DocumentNo: testDocumentNo(t), Tags: []string{"vip"}}
+	require.NoError(t, repo.CreateAccount(ctx, acc))
+
+	accounts, err := repo.ListAccounts(ctx, AccountFilter{After: &AccountCursor{AccountID: acc.AccountID - 1}, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	require.Equal(t, acc.AccountID, accounts[0].AccountID)
+}
+
+func TestCountAccountsWithoutFilters(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	before, err := repo.CountAccounts(ctx, AccountFilter{})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.CreateAccount(ctx, &Account{DocumentNo: testDocumentNo(t)}))
+
+	after, err := repo.CountAccounts(ctx, AccountFilter{})
+	require.NoError(t, err)
+	require.Equal(t, before+1, after)
+}
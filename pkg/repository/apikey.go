@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+)
+
+const apiKeyColumns = `key_id, name, prefix, key_hash, scopes, expires_at, revoked_at, created_at`
+
+// CreateAPIKey stores the api key and fills in its generated fields
+func (p *pismoRepo) CreateAPIKey(ctx context.Context, key *APIKey) (err error) {
+	return p.withTx(ctx, func(tx *sqlx.Tx) error {
+		err := tx.GetContext(ctx,
+			key,
+			`INSERT INTO api_keys
+				(name, prefix, key_hash, scopes, expires_at)
+			VALUES
+				($1, $2, $3, $4, $5)
+			RETURNING `+apiKeyColumns,
+			key.Name,
+			key.Prefix,
+			key.Hash,
+			key.Scopes,
+			key.ExpiresAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert api key: %w", err)
+		}
+
+		return insertAuditEntry(ctx, tx, entityAPIKey, key.KeyID, enums.AuditCreate, nil, newAPIKeyImage(key))
+	})
+}
+
+// GetAPIKey retrieves the api key for the given key_id, returns nil when it doesn't exist
+func (p *pismoRepo) GetAPIKey(ctx context.Context, keyID int) (key *APIKey, err error) {
+	return p.getAPIKey(ctx, "key_id = $1", keyID)
+}
+
+// GetAPIKeyByHash retrieves the api key with the given hash, returns nil when it doesn't exist
+func (p *pismoRepo) GetAPIKeyByHash(ctx context.Context, hash []byte) (key *APIKey, err error) {
+	return p.getAPIKey(ctx, "key_hash = $1", hash)
+}
+
+func (p *pismoRepo) getAPIKey(ctx context.Context, where string, arg any) (*APIKey, error) {
+	var key APIKey
+	err := p.db.GetContext(ctx, &key, "SELECT "+apiKeyColumns+" FROM api_keys WHERE "+where, arg)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query api key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// ListAPIKeys retrieves every api key, the revoked ones included
+func (p *pismoRepo) ListAPIKeys(ctx context.Context) (keys []APIKey, err error) {
+	err = p.db.SelectContext(ctx, &keys, "SELECT "+apiKeyColumns+" FROM api_keys ORDER BY key_id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey revokes the api key for good, revoking a revoked key leaves it as is. Returns nil when it doesn't
+// exist.
+func (p *pismoRepo) RevokeAPIKey(ctx context.Context, keyID int) (key *APIKey, err error) {
+	err = p.withTx(ctx, func(tx *sqlx.Tx) error {
+		var before, after APIKey
+		err := tx.GetContext(ctx, &before, "SELECT "+apiKeyColumns+" FROM api_keys WHERE key_id = $1 FOR UPDATE", keyID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return fmt.Errorf("failed to query api key: %w", err)
+		}
+
+		if before.RevokedAt != nil {
+			key = &before
+			return nil
+		}
+
+		err = tx.GetContext(ctx,
+			&after,
+			"UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP WHERE key_id = $1 RETURNING "+apiKeyColumns,
+			keyID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to revoke api key: %w", err)
+		}
+
+		key = &after
+		return insertAuditEntry(ctx, tx, entityAPIKey, keyID, enums.AuditRevoke, newAPIKeyImage(&before), newAPIKeyImage(&after))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/requestid"
+	"github.com/sathishs-dev/pismo-transactions/pkg/audit"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+)
+
+type (
+	// accountImage is the audited state of an account. The append-only audit log is out of the erasure's reach, so
+	// it holds no personal data: the document number is only referred to by the key it's encrypted with, when it's
+	// created, and the metadata and tags are left out.
+	accountImage struct {
+		AccountID           int    `json:"account_id"`
+		Version             int64  `json:"version"`
+		DocumentNumberKeyID string `json:"document_number_key_id,omitempty"`
+	}
+
+	reconciliationImage struct {
+		ReconciliationID int    `json:"reconciliation_id"`
+		Source           string `json:"source"`
+		DateTolerance    string `json:"date_tolerance"`
+		Matched          int    `json:"matched"`
+		MissingInLedger  int    `json:"missing_in_ledger"`
+		MissingInFile    int    `json:"missing_in_file"`
+		AmountMismatches int    `json:"amount_mismatches"`
+	}
+
+	// webhookImage is the audited state of a webhook, without its secret
+	webhookImage struct {
+		WebhookID           int      `json:"webhook_id"`
+		URL                 string   `json:"url"`
+		EventTypes          []string `json:"event_types"`
+		Enabled             bool     `json:"enabled"`
+		ConsecutiveFailures int      `json:"consecutive_failures"`
+	}
+
+	// apiKeyImage is the audited state of an api key, without its hash
+	apiKeyImage struct {
+		KeyID     int        `json:"key_id"`
+		Name      string     `json:"name"`
+		Prefix    string     `json:"prefix"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at,omitempty"`
+		RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	}
+
+	webhookReplayImage struct {
+		DeliveryIDs []int64 `json:"delivery_ids,omitempty"`
+		Replayed    int64   `json:"replayed"`
+	}
+)
+
+func newAccountImage(accID int, version int64, keyID string) accountImage {
+	return accountImage{AccountID: accID, Version: version, DocumentNumberKeyID: keyID}
+}
+
+func newWebhookImage(wh *Webhook) webhookImage {
+	return webhookImage{
+		WebhookID:           wh.WebhookID,
+		URL:                 wh.URL,
+		EventTypes:          wh.EventTypes,
+		Enabled:             wh.Enabled,
+		ConsecutiveFailures: wh.ConsecutiveFailures,
+	}
+}
+
+func newAPIKeyImage(key *APIKey) apiKeyImage {
+	return apiKeyImage{
+		KeyID:     key.KeyID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		ExpiresAt: key.ExpiresAt,
+		RevokedAt: key.RevokedAt,
+	}
+}
+
+// insertAuditEntry records the change in the audit log as part of the given db transaction, the actor and
+// request id are taken from the context, a nil before or after image is stored as null
+func insertAuditEntry(ctx context.Context, tx *sqlx.Tx, entityType string, entityID any, action enums.AuditAction, before, after any) error {
+	// a nil []byte would be sent as an empty string instead of null
+	images := make([]any, 2)
+	for i, img := range []any{before, after} {
+		if img == nil {
+			continue
+		}
+
+		data, err := json.Marshal(img)
+		if err != nil {
+			return fmt.Errorf("failed to encode the %s audit image: %w", entityType, err)
+		}
+		images[i] = data
+	}
+
+	actor := audit.ActorFromContext(ctx)
+
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO audit_log
+			(actor_type, actor_id, request_id, entity_type, entity_id, action, before_image, after_image)
+		VALUES
+			($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8)
+		`,
+		actor.Type,
+		actor.ID,
+		requestid.FromContext(ctx),
+		entityType,
+		fmt.Sprint(entityID),
+		action,
+		images[0],
+		images[1],
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert %s audit entry: %w", entityType, err)
+	}
+
+	return nil
+}
+
+// insertAuditCreations records the creation of the entities in the audit log with a single statement as part of
+// the given db transaction, the after images are in the order of the entity ids
+func insertAuditCreations(ctx context.Context, tx *sqlx.Tx, entityType string, entityIDs []string, afters []any) error {
+	images := make(pq.StringArray, len(afters))
+	for i, img := range afters {
+		data, err := json.Marshal(img)
+		if err != nil {
+			return fmt.Errorf("failed to encode the %s audit image: %w", entityType, err)
+		}
+		images[i] = string(data)
+	}
+
+	actor := audit.ActorFromContext(ctx)
+
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO audit_log
+			(actor_type, actor_id, request_id, entity_type, entity_id, action, after_image)
+		SELECT
+			$1, $2, NULLIF($3, ''), $4, e.entity_id, $5, e.after_image
+		FROM unnest($6::VARCHAR[], $7::JSONB[]) WITH ORDINALITY AS e(entity_id, after_image, ord)
+		ORDER BY e.ord
+		`,
+		actor.Type,
+		actor.ID,
+		requestid.FromContext(ctx),
+		entityType,
+		enums.AuditCreate,
+		pq.StringArray(entityIDs),
+		images,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert %s audit entries: %w", entityType, err)
+	}
+
+	return nil
+}
+
+// ListAuditLog retrieves the audit entries matching the filter, newest first
+func (p *pismoRepo) ListAuditLog(ctx context.Context, filter AuditFilter) (entries []AuditEntry, err error) {
+	var from, to any
+	if !filter.From.IsZero() {
+		from = filter.From
+	}
+	if !filter.To.IsZero() {
+		to = filter.To
+	}
+
+	err = p.db.SelectContext(ctx,
+		&entries,
+		`SELECT
+			audit_id, occurred_at, actor_type, actor_id, COALESCE(request_id, '') AS request_id,
+			entity_type, entity_id, action, before_image, after_image
+		FROM audit_log
+		WHERE ($1 = '' OR entity_type = $1)
+			AND ($2 = '' OR entity_id = $2)
+			AND ($3 = '' OR actor_type = $3)
+			AND ($4 = '' OR actor_id = $4)
+			AND ($5::TIMESTAMPTZ IS NULL OR occurred_at >= $5)
+			AND ($6::TIMESTAMPTZ IS NULL OR occurred_at < $6)
+			AND ($7::BIGINT = 0 OR audit_id < $7)
+		ORDER BY audit_id DESC
+		LIMIT $8
+		`,
+		filter.EntityType,
+		filter.EntityID,
+		filter.ActorType,
+		filter.ActorID,
+		from,
+		to,
+		filter.BeforeID,
+		filter.Limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+
+	return entries, nil
+}
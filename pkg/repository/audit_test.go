@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountImageLeavesPersonalDataOut(t *testing.T) {
+	data, err := json.Marshal(newAccountImage(1, 2, "k2"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"account_id":1,"version":2,"document_number_key_id":"k2"}`, string(data))
+
+	// in plaintext there's no key to refer to
+	data, err = json.Marshal(newAccountImage(1, 1, ""))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"account_id":1,"version":1}`, string(data))
+}
@@ -0,0 +1,268 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+)
+
+// ErrAccountNotFound is returned for the transactions of a batch whose account doesn't exist
+var ErrAccountNotFound = errors.New("account not found")
+
+// errBatchRejected rolls back an atomic batch once one of its transactions failed
+var errBatchRejected = errors.New("batch rejected")
+
+// TransactionResult is the outcome of a transaction of a batch, its id and event date are set once it's created
+// and Err is set when it failed
+type TransactionResult struct {
+	Transaction
+	Err error
+}
+
+// CreateTransactions creates the transactions of a batch with a single multi-row insert along with their
+// transaction.created events and audit entries, the results are in the order of the transactions. A transaction
+// fails with ErrAccountNotFound, ErrAccountErased or ErrDuplicateExternalReference, a reference repeated in the
+// batch is only created for its first transaction. When atomic, none is created unless every one of them is and
+// the ones that didn't fail are left without an id. The accounts are share locked so they can't be erased meanwhile.
+func (p *pismoRepo) CreateTransactions(ctx context.Context, txns []Transaction, atomic bool) (results []TransactionResult, err error) {
+	err = p.withTx(ctx, func(tx *sqlx.Tx) error {
+		results = make([]TransactionResult, len(txns))
+		for i, txn := range txns {
+			results[i] = TransactionResult{Transaction: txn}
+		}
+
+		erased, err := p.lockBatchAccounts(ctx, tx, txns)
+		if err != nil {
+			return err
+		}
+
+		existing, err := existingExternalReferences(ctx, tx, txns)
+		if err != nil {
+			return err
+		}
+
+		pending := make([]int, 0, len(txns))
+		for i, txn := range txns {
+			isErased, found := erased[txn.AccountID]
+			switch {
+			case !found:
+				results[i].Err = ErrAccountNotFound
+			case isErased:
+				results[i].Err = ErrAccountErased
+			case txn.ExternalReference != "" && existing[txn.ExternalReference]:
+				results[i].Err = ErrDuplicateExternalReference
+			default:
+				pending = append(pending, i)
+				if txn.ExternalReference != "" {
+					existing[txn.ExternalReference] = true
+				}
+			}
+		}
+
+		if len(pending) == 0 || (atomic && len(pending) < len(txns)) {
+			return errBatchRejected
+		}
+
+		created, err := insertTransactions(ctx, tx, results, pending)
+		if err != nil {
+			return err
+		}
+
+		if len(created) < len(pending) && atomic {
+			return errBatchRejected
+		}
+
+		return insertTransactionsCreated(ctx, tx, results, created)
+	})
+	if errors.Is(err, errBatchRejected) {
+		for i := range results {
+			results[i].TransactionID = 0
+			results[i].EventDate = time.Time{}
+		}
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// lockBatchAccounts share locks the accounts of the batch and tells which ones are erased, the missing ones and the
+// ones the owner of the context doesn't have are left out. They are locked in order so concurrent batches don't deadlock
+func (p *pismoRepo) lockBatchAccounts(ctx context.Context, tx *sqlx.Tx, txns []Transaction) (map[int]bool, error) {
+	accIDs := make(pq.Int64Array, 0, len(txns))
+	for _, txn := range txns {
+		accIDs = append(accIDs, int64(txn.AccountID))
+	}
+
+	owned, args := p.ownerCondition(ctx, 2)
+
+	var accounts []struct {
+		AccountID int  `db:"account_id"`
+		Erased    bool `db:"erased"`
+	}
+	err := tx.SelectContext(ctx,
+		&accounts,
+		`SELECT account_id, erased_at IS NOT NULL AS erased
+		FROM accounts
+		WHERE account_id = ANY($1)`+owned+`
+		ORDER BY account_id
+		FOR SHARE
+		`,
+		append([]any{accIDs}, args...)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock accounts: %w", err)
+	}
+
+	erased := make(map[int]bool, len(accounts))
+	for _, acc := range accounts {
+		erased[acc.AccountID] = acc.Erased
+	}
+
+	return erased, nil
+}
+
+// existingExternalReferences returns the external references of the batch already associated with a transaction
+func existingExternalReferences(ctx context.Context, tx *sqlx.Tx, txns []Transaction) (map[string]bool, error) {
+	refs := make(pq.StringArray, 0, len(txns))
+	for _, txn := range txns {
+		if txn.ExternalReference != "" {
+			refs = append(refs, txn.ExternalReference)
+		}
+	}
+
+	existing := make(map[string]bool, len(refs))
+	if len(refs) == 0 {
+		return existing, nil
+	}
+
+	var found []string
+	err := tx.SelectContext(ctx, &found, "SELECT external_reference FROM transactions WHERE external_reference = ANY($1)", refs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query external references: %w", err)
+	}
+
+	for _, ref := range found {
+		existing[ref] = true
+	}
+
+	return existing, nil
+}
+
+// insertTransactions inserts the pending transactions of the batch in a single statement and returns the ones
+// created. Their ids are taken upfront so each returned row is told apart, the ones whose external reference was
+// taken meanwhile by a concurrent request are skipped and fail with ErrDuplicateExternalReference
+func insertTransactions(ctx context.Context, tx *sqlx.Tx, results []TransactionResult, pending []int) (created []int, err error) {
+	var ids []int
+	err = tx.SelectContext(ctx,
+		&ids,
+		"SELECT nextval(pg_get_serial_sequence('transactions', 'transaction_id')) FROM generate_series(1, $1)",
+		len(pending),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate transaction ids: %w", err)
+	}
+
+	var (
+		txnIDs         = make(pq.Int64Array, len(pending))
+		accIDs         = make(pq.Int64Array, len(pending))
+		operationTypes = make(pq.Int64Array, len(pending))
+		amounts        = make(pq.Float64Array, len(pending))
+		refs           = make(pq.StringArray, len(pending))
+		byID           = make(map[int]int, len(pending))
+	)
+	for j, i := range pending {
+		results[i].TransactionID = ids[j]
+		byID[ids[j]] = i
+
+		txnIDs[j] = int64(ids[j])
+		accIDs[j] = int64(results[i].AccountID)
+		operationTypes[j] = int64(results[i].OperationTypeID)
+		amounts[j] = results[i].Amount
+		refs[j] = results[i].ExternalReference
+	}
+
+	var rows []struct {
+		TransactionID int       `db:"transaction_id"`
+		EventDate     time.Time `db:"event_date"`
+	}
+	err = tx.SelectContext(ctx,
+		&rows,
+		`INSERT INTO transactions
+			(transaction_id, account_id, operation_type_id, amount, external_reference)
+		SELECT
+			t.transaction_id, t.account_id, t.operation_type_id, t.amount, NULLIF(t.external_reference, '')
+		FROM unnest($1::INT[], $2::INT[], $3::INT[], $4::DECIMAL[], $5::VARCHAR[])
+			WITH ORDINALITY AS t(transaction_id, account_id, operation_type_id, amount, external_reference, ord)
+		ORDER BY t.ord
+		ON CONFLICT (external_reference) WHERE external_reference IS NOT NULL DO NOTHING
+		RETURNING transaction_id, event_date
+		`,
+		txnIDs,
+		accIDs,
+		operationTypes,
+		amounts,
+		refs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction records: %w", err)
+	}
+
+	inserted := make(map[int]bool, len(rows))
+	for _, row := range rows {
+		i := byID[row.TransactionID]
+		results[i].EventDate = row.EventDate
+		inserted[row.TransactionID] = true
+	}
+
+	created = make([]int, 0, len(rows))
+	for _, i := range pending {
+		if !inserted[results[i].TransactionID] {
+			results[i].TransactionID = 0
+			results[i].Err = ErrDuplicateExternalReference
+			continue
+		}
+		created = append(created, i)
+	}
+
+	return created, nil
+}
+
+// insertTransactionsCreated writes the transaction.created events and audit entries of the created transactions
+func insertTransactionsCreated(ctx context.Context, tx *sqlx.Tx, results []TransactionResult, created []int) error {
+	var (
+		txnIDs   = make([]int, 0, len(created))
+		accIDs   = make([]int, 0, len(created))
+		entities = make([]string, 0, len(created))
+		payloads = make([]any, 0, len(created))
+	)
+	for _, i := range created {
+		txn := results[i].Transaction
+		txnIDs = append(txnIDs, txn.TransactionID)
+		accIDs = append(accIDs, txn.AccountID)
+		entities = append(entities, strconv.Itoa(txn.TransactionID))
+		payloads = append(payloads, transactionCreatedPayload{
+			TransactionID:     txn.TransactionID,
+			AccountID:         txn.AccountID,
+			OperationTypeID:   txn.OperationTypeID,
+			Amount:            txn.Amount,
+			EventDate:         txn.EventDate,
+			ExternalReference: txn.ExternalReference,
+		})
+	}
+
+	err := insertOutboxEvents(ctx, tx, enums.TransactionCreated, aggregateTransaction, txnIDs, accIDs, payloads)
+	if err != nil {
+		return err
+	}
+
+	return insertAuditCreations(ctx, tx, aggregateTransaction, entities, payloads)
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+)
+
+// GetChangeCursor returns the latest sequence of the change log every change up to is settled, the changes past it
+// are read with ListChangeLog
+func (p *pismoRepo) GetChangeCursor(ctx context.Context) (sequence int64, err error) {
+	err = p.db.GetContext(ctx,
+		&sequence,
+		"SELECT COALESCE(MAX(o.sequence), 0) FROM change_log o WHERE "+settledChanges,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query change cursor: %w", err)
+	}
+
+	return sequence, nil
+}
+
+// ListChangeLog returns up to limit changes of the change log past the since sequence, in the order of the sequence.
+// A change is held back while a transaction that could have logged an earlier one is in flight, so a loader resuming
+// from the last sequence it got never skips over a change.
+func (p *pismoRepo) ListChangeLog(ctx context.Context, since int64, limit int) (entries []ChangeLogEntry, err error) {
+	err = p.db.SelectContext(ctx,
+		&entries,
+		`SELECT
+			o.sequence, o.entity_type, o.entity_id, o.account_id, o.operation, o.data, o.changed_at
+		FROM change_log o
+		WHERE o.sequence > $1 AND `+settledChanges+`
+		ORDER BY o.sequence
+		LIMIT $2
+		`,
+		since,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the change log: %w", err)
+	}
+
+	return entries, nil
+}
+
+// settledChanges filters the change log down to the changes every earlier change is committed before, they make up
+// a prefix of the log
+const settledChanges = "o.next_xact_id <= pg_snapshot_xmin(pg_current_snapshot())"
+
+// settledEvents filters the outbox events down to the ones written by transactions older than any transaction in
+// flight, an event committing after a later one was read would be skipped over otherwise
+const settledEvents = "o.xact_id < pg_snapshot_xmin(pg_current_snapshot())"
+
+// LastAccountEventID returns the id of the latest event of the account, 0 when it has none
+func (p *pismoRepo) LastAccountEventID(ctx context.Context, accID int) (eventID int64, err error) {
+	err = p.db.GetContext(ctx,
+		&eventID,
+		"SELECT COALESCE(MAX(o.event_id), 0) FROM outbox o WHERE o.account_id = $1 AND "+settledEvents,
+		accID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query the last account event: %w", err)
+	}
+
+	return eventID, nil
+}
+
+// ListAccountEvents returns up to limit transaction.created and account.erased events of the account past the
+// event id after, in the order they were written. The transaction.created events carry the balance of the account
+// right after their transaction, it starts from the latest balance snapshot taken up to the transaction and only sums
+// up the transactions since then.
+func (p *pismoRepo) ListAccountEvents(ctx context.Context, accID int, after int64, limit int) (events []AccountEvent, err error) {
+	err = p.db.SelectContext(ctx,
+		&events,
+		`SELECT
+			o.event_id, o.event_type, o.payload, o.created_at,
+			CASE WHEN o.event_type = $4 THEN COALESCE(s.balance, 0) + COALESCE((
+				SELECT SUM(t.amount) FROM transactions t
+				WHERE t.account_id = o.account_id
+					AND t.event_date >= COALESCE(s.snapshot_at, '-infinity')
+					AND t.transaction_id <= o.aggregate_id
+			), 0) END AS balance
+		FROM outbox o
+		LEFT JOIN transactions e ON o.event_type = $4 AND e.transaction_id = o.aggregate_id
+		LEFT JOIN LATERAL (
+			SELECT bs.snapshot_at, bs.balance FROM balance_snapshots bs
+			WHERE bs.account_id = o.account_id AND bs.snapshot_at <= e.event_date
+			ORDER BY bs.snapshot_at DESC
+			LIMIT 1
+		) s ON true
+		WHERE o.account_id = $1 AND o.event_id > $2 AND o.event_type IN ($4, $5) AND `+settledEvents+`
+		ORDER BY o.event_id
+		LIMIT $3
+		`,
+		accID,
+		after,
+		limit,
+		enums.TransactionCreated,
+		enums.AccountErased,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query account events: %w", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeLogLeavesPersonalDataOut(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	var since int64
+	require.NoError(t, repo.db.GetContext(ctx, &since, "SELECT COALESCE(MAX(sequence), 0) FROM change_log"))
+
+	acc := &Account{DocumentNo: testDocumentNo(t), Metadata: Metadata{"email": "jane@example.com"}, Tags: []string{"vip"}}
+	require.NoError(t, repo.CreateAccount(ctx, acc))
+
+	entries, err := repo.ListChangeLog(ctx, since, 100)
+	require.NoError(t, err)
+
+	var logged bool
+	for _, e := range entries {
+		if e.EntityType != "account" || e.EntityID != acc.AccountID {
+			continue
+		}
+		logged = true
+
+		var data map[string]any
+		require.NoError(t, json.Unmarshal(e.Data, &data))
+		require.Equal(t, float64(acc.AccountID), data["account_id"])
+		for _, column := range []string{"document_number", "document_number_hash", "metadata", "tags"} {
+			require.NotContains(t, data, column)
+		}
+	}
+	require.True(t, logged)
+}
+
+func TestListAccountEventsBalances(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	acc := &Account{DocumentNo: testDocumentNo(t)}
+	require.NoError(t, repo.CreateAccount(ctx, acc))
+
+	require.NoError(t, repo.CreateTransaction(ctx, Transaction{AccountID: acc.AccountID, OperationTypeID: int(enums.NormalPurchase), Amount: -50}))
+	require.NoError(t, repo.CreateTransaction(ctx, Transaction{AccountID: acc.AccountID, OperationTypeID: int(enums.CreditVoucher), Amount: 100}))
+
+	// the snapshot is taken on the clock of the db the transactions are dated with
+	var snapshotAt time.Time
+	require.NoError(t, repo.db.GetContext(ctx, &snapshotAt, "SELECT CURRENT_TIMESTAMP"))
+	_, err := repo.CreateBalanceSnapshots(ctx, snapshotAt)
+	require.NoError(t, err)
+	// the balances after the snapshot start from it, the transactions before it aren't summed up again
+	_, err = repo.db.ExecContext(ctx,
+		"UPDATE balance_snapshots SET balance = 1000 WHERE account_id = $1 AND snapshot_at = $2",
+		acc.AccountID,
+		snapshotAt,
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.CreateTransaction(ctx, Transaction{AccountID: acc.AccountID, OperationTypeID: int(enums.CreditVoucher), Amount: 20}))
+
+	events, err := repo.ListAccountEvents(ctx, acc.AccountID, 0, 10)
+	require.NoError(t, err)
+
+	var balances []float64
+	for _, e := range events {
+		require.NotNil(t, e.Balance)
+		balances = append(balances, *e.Balance)
+	}
+	require.Equal(t, []float64{-50, 50, 1020}, balances)
+}
+
+func TestGetChangeCursor(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	acc := &Account{DocumentNo: testDocumentNo(t)}
+	require.NoError(t, repo.CreateAccount(ctx, acc))
+
+	var sequence int64
+	require.NoError(t, repo.db.GetContext(ctx,
+		&sequence,
+		"SELECT MAX(sequence) FROM change_log WHERE entity_type = 'account' AND entity_id = $1",
+		acc.AccountID,
+	))
+
+	// the committed change is settled, the cursor is past it
+	cursor, err := repo.GetChangeCursor(ctx)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, cursor, sequence)
+}
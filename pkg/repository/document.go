@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+)
+
+const (
+	// MinDocumentPrefixLen and MaxDocumentPrefixLen bound the document number prefixes that can be looked up
+	MinDocumentPrefixLen = 4
+	MaxDocumentPrefixLen = 32
+)
+
+// ErrNoKeyring is returned when reading or rotating an encrypted document number without a keyring configured
+var ErrNoKeyring = errors.New("document number is encrypted but no keyring is configured")
+
+// accountRow is the stored account, the document number is either in plaintext or in the encrypted envelope
+// until every row is encrypted
+type accountRow struct {
+	AccountID     int                 `db:"account_id"`
+	DocumentNo    sql.NullString      `db:"document_number"`
+	DocumentNoEnc []byte              `db:"document_number_enc"`
+	Status        enums.AccountStatus `db:"status"`
+	Metadata      Metadata            `db:"metadata"`
+	Tags          pq.StringArray      `db:"tags"`
+	CreatedAt     time.Time           `db:"created_at"`
+	ErasedAt      *time.Time          `db:"erased_at"`
+	Version       int64               `db:"version"`
+
+	// NoPrefixes is set for the rows encrypted before the prefixes were indexed, only read by the rotation
+	NoPrefixes bool `db:"no_prefixes"`
+}
+
+// documentNumberAAD binds the encrypted document number to its account so it can't be copied over to another one
+func documentNumberAAD(accID int) []byte {
+	return []byte("accounts.document_number:" + strconv.Itoa(accID))
+}
+
+// documentNumberPrefixes returns the blind indexes of the document number prefixes that can be looked up, an empty
+// array rather than null for the document numbers too short to have any so they aren't indexed again
+func (p *pismoRepo) documentNumberPrefixes(docNo string) pq.ByteaArray {
+	prefixes := p.keyring.PrefixIndexes(docNo, MinDocumentPrefixLen, MaxDocumentPrefixLen)
+	if prefixes == nil {
+		prefixes = [][]byte{}
+	}
+
+	return prefixes
+}
+
+// decryptAccount returns the account with its document number in plaintext
+func (p *pismoRepo) decryptAccount(row accountRow) (*Account, error) {
+	acc := Account{
+		AccountID:  row.AccountID,
+		DocumentNo: row.DocumentNo.String,
+		Status:     row.Status,
+		Metadata:   row.Metadata,
+		Tags:       row.Tags,
+		CreatedAt:  row.CreatedAt,
+		ErasedAt:   row.ErasedAt,
+		Version:    row.Version,
+	}
+	if row.DocumentNoEnc == nil {
+		return &acc, nil
+	}
+
+	if p.keyring == nil {
+		return nil, ErrNoKeyring
+	}
+
+	docNo, err := p.keyring.Decrypt(row.DocumentNoEnc, documentNumberAAD(row.AccountID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt document number of account %d: %w", row.AccountID, err)
+	}
+	acc.DocumentNo = string(docNo)
+
+	return &acc, nil
+}
+
+// insertAccount inserts the account with its document number encrypted when there's a keyring, the account id is
+// taken upfront since the envelope is bound to it. keyID is the key it's encrypted with, empty in plaintext.
+func (p *pismoRepo) insertAccount(ctx context.Context, tx *sqlx.Tx, acc *Account) (accID int, keyID string, err error) {
+	if p.keyring == nil {
+		err = tx.GetContext(ctx,
+			&accID,
+			"INSERT INTO accounts (document_number, metadata, tags) VALUES ($1, $2, $3) RETURNING account_id",
+			acc.DocumentNo,
+			acc.Metadata,
+			acc.Tags,
+		)
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to insert account: %w", err)
+		}
+
+		return accID, "", nil
+	}
+
+	err = tx.GetContext(ctx, &accID, "SELECT nextval(pg_get_serial_sequence('accounts', 'account_id'))")
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to allocate account id: %w", err)
+	}
+
+	envelope, keyID, err := p.keyring.Encrypt([]byte(acc.DocumentNo), documentNumberAAD(accID))
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to encrypt document number: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO accounts
+			(account_id, document_number_enc, document_number_key_id, document_number_hash, document_number_prefixes, metadata, tags)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7)
+		`,
+		accID,
+		envelope,
+		keyID,
+		p.keyring.BlindIndex(acc.DocumentNo),
+		p.documentNumberPrefixes(acc.DocumentNo),
+		acc.Metadata,
+		acc.Tags,
+	)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to insert account: %w", err)
+	}
+
+	return accID, keyID, nil
+}
+
+// RotateDocumentNumbers encrypts up to limit plaintext document numbers and re-wraps the ones encrypted under an
+// older key with the primary key, the prefixes of the ones encrypted before they were indexed are indexed along the
+// way. It returns the number of accounts updated, erased accounts are skipped. Their version isn't bumped since
+// they read the same.
+func (p *pismoRepo) RotateDocumentNumbers(ctx context.Context, limit int) (count int, err error) {
+	if p.keyring == nil {
+		return 0, ErrNoKeyring
+	}
+
+	primary := p.keyring.PrimaryKeyID()
+
+	err = p.withTx(ctx, func(tx *sqlx.Tx) error {
+		var rows []accountRow
+		err := tx.SelectContext(ctx,
+			&rows,
+			`SELECT account_id, document_number, document_number_enc, document_number_prefixes IS NULL AS no_prefixes
+			FROM accounts
+			WHERE erased_at IS NULL
+				AND (document_number_enc IS NULL OR document_number_key_id <> $1 OR document_number_prefixes IS NULL)
+			ORDER BY account_id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+			`,
+			primary,
+			limit,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to query accounts to rotate: %w", err)
+		}
+
+		for _, row := range rows {
+			if err := p.rotateDocumentNumber(ctx, tx, row); err != nil {
+				return err
+			}
+		}
+
+		count = len(rows)
+		return nil
+	})
+
+	return count, err
+}
+
+// rotateDocumentNumber encrypts the plaintext document number of the account or re-wraps its envelope
+func (p *pismoRepo) rotateDocumentNumber(ctx context.Context, tx *sqlx.Tx, row accountRow) error {
+	if row.DocumentNoEnc != nil {
+		envelope, keyID, err := p.keyring.Rewrap(row.DocumentNoEnc)
+		if err != nil {
+			return fmt.Errorf("failed to rewrap document number of account %d: %w", row.AccountID, err)
+		}
+
+		var prefixes any
+		if row.NoPrefixes {
+			acc, err := p.decryptAccount(row)
+			if err != nil {
+				return err
+			}
+			prefixes = p.documentNumberPrefixes(acc.DocumentNo)
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE accounts SET
+				document_number_enc = $2,
+				document_number_key_id = $3,
+				document_number_prefixes = COALESCE($4, document_number_prefixes)
+			WHERE account_id = $1
+			`,
+			row.AccountID,
+			envelope,
+			keyID,
+			prefixes,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update account %d: %w", row.AccountID, err)
+		}
+
+		return nil
+	}
+
+	envelope, keyID, err := p.keyring.Encrypt([]byte(row.DocumentNo.String), documentNumberAAD(row.AccountID))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt document number of account %d: %w", row.AccountID, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE accounts SET
+			document_number = NULL,
+			document_number_enc = $2,
+			document_number_key_id = $3,
+			document_number_hash = $4,
+			document_number_prefixes = $5
+		WHERE account_id = $1
+		`,
+		row.AccountID,
+		envelope,
+		keyID,
+		p.keyring.BlindIndex(row.DocumentNo.String),
+		p.documentNumberPrefixes(row.DocumentNo.String),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update account %d: %w", row.AccountID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+)
+
+// ErrAccountBalanceNotZero is returned when erasing an account with money left on it
+var ErrAccountBalanceNotZero = errors.New("account balance is not zero")
+
+// erasedAccountFields are the personal data fields cleared from an erased account
+var erasedAccountFields = []string{"document_number", "metadata", "tags"}
+
+type (
+	accountErasedPayload struct {
+		AccountID int       `json:"account_id"`
+		ErasedAt  time.Time `json:"erased_at"`
+	}
+
+	// accountErasureImage is the audited outcome of an erasure, it holds no personal data by design
+	accountErasureImage struct {
+		AccountID            int       `json:"account_id"`
+		ErasedAt             time.Time `json:"erased_at"`
+		ErasedFields         []string  `json:"erased_fields"`
+		RetainedTransactions int       `json:"retained_transactions"`
+	}
+)
+
+// EraseAccount irreversibly clears the personal data of the account, which is kept as a tombstone holding its
+// transactions for retention, along with its account.erased event and audit entry. It's refused while the balance
+// isn't zero, the ledger has no disputes yet so that's the only check. A dry run goes through the same checks and
+// returns what would change without changing it. A nil erasure is returned when the account doesn't exist.
+func (p *pismoRepo) EraseAccount(ctx context.Context, accID int, dryRun bool, ifMatch IfMatch) (erasure *AccountErasure, err error) {
+	err = p.withTx(ctx, func(tx *sqlx.Tx) error {
+		// locked so no transaction is created for it meanwhile
+		var acc struct {
+			ErasedAt *time.Time `db:"erased_at"`
+			Version  int64      `db:"version"`
+		}
+		err := tx.GetContext(ctx, &acc, "SELECT erased_at, version FROM accounts WHERE account_id = $1 FOR UPDATE", accID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return fmt.Errorf("failed to query account: %w", err)
+		}
+
+		if acc.ErasedAt != nil {
+			return ErrAccountErased
+		}
+
+		if !ifMatch.Allows(acc.Version) {
+			return ErrVersionMismatch
+		}
+
+		var (
+			settled      bool
+			transactions int
+		)
+		err = tx.QueryRowxContext(ctx,
+			"SELECT COALESCE(SUM(amount), 0) = 0, COUNT(*) FROM transactions WHERE account_id = $1",
+			accID,
+		).Scan(&settled, &transactions)
+		if err != nil {
+			return fmt.Errorf("failed to query account balance: %w", err)
+		}
+
+		if !settled {
+			return ErrAccountBalanceNotZero
+		}
+
+		erasure = &AccountErasure{
+			AccountID:            accID,
+			DryRun:               dryRun,
+			ErasedFields:         erasedAccountFields,
+			RetainedTransactions: transactions,
+		}
+		if dryRun {
+			return nil
+		}
+
+		err = tx.GetContext(ctx,
+			&erasure.ErasedAt,
+			`UPDATE accounts SET
+				document_number = NULL,
+				document_number_enc = NULL,
+				document_number_key_id = NULL,
+				document_number_hash = NULL,
+				document_number_prefixes = NULL,
+				status = 'closed',
+				metadata = '{}',
+				tags = '{}',
+				erased_at = CURRENT_TIMESTAMP,
+				version = version + 1
+			WHERE account_id = $1
+			RETURNING erased_at
+			`,
+			accID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to erase account: %w", err)
+		}
+
+		err = insertOutboxEvent(ctx, tx, enums.AccountErased, aggregateAccount, accID, accID,
+			accountErasedPayload{AccountID: accID, ErasedAt: erasure.ErasedAt},
+		)
+		if err != nil {
+			return err
+		}
+
+		return insertAuditEntry(ctx, tx, aggregateAccount, accID, enums.AuditErase, nil, accountErasureImage{
+			AccountID:            accID,
+			ErasedAt:             erasure.ErasedAt,
+			ErasedFields:         erasure.ErasedFields,
+			RetainedTransactions: erasure.RetainedTransactions,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return erasure, nil
+}
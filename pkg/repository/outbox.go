@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+)
+
+const (
+	aggregateAccount     = "account"
+	aggregateTransaction = "transaction"
+
+	// entities only found in the audit log
+	entityReconciliation = "reconciliation"
+	entityWebhook        = "webhook"
+	entityAPIKey         = "api_key"
+
+	// outboxRelayLock is the advisory lock key that keeps a single replica claiming events at a time,
+	// the events of an account are only published in order when a single relay is at work
+	outboxRelayLock = "pismo_outbox_relay"
+)
+
+type (
+	accountCreatedPayload struct {
+		AccountID int `json:"account_id"`
+	}
+
+	transactionCreatedPayload struct {
+		TransactionID     int       `json:"transaction_id"`
+		AccountID         int       `json:"account_id"`
+		OperationTypeID   int       `json:"operation_type_id"`
+		Amount            float64   `json:"amount"`
+		EventDate         time.Time `json:"event_date"`
+		ExternalReference string    `json:"external_reference,omitempty"`
+	}
+)
+
+// withTx runs fn inside a db transaction, it's committed when fn succeeds and rolled back otherwise
+func (p *pismoRepo) withTx(ctx context.Context, fn func(tx *sqlx.Tx) error) (err error) {
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// insertOutboxEvent writes the domain event into the outbox as part of the given db transaction
+func insertOutboxEvent(ctx context.Context, tx *sqlx.Tx, eventType enums.EventType, aggregateType string, aggregateID, accID int, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO outbox
+			(event_type, aggregate_type, aggregate_id, account_id, payload)
+		VALUES
+			($1, $2, $3, $4, $5)
+		`,
+		eventType,
+		aggregateType,
+		aggregateID,
+		accID,
+		data,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert %s event: %w", eventType, err)
+	}
+
+	return nil
+}
+
+// insertOutboxEvents writes the domain events of the same type into the outbox with a single statement as part of
+// the given db transaction, they get their event ids in the order given
+func insertOutboxEvents(ctx context.Context, tx *sqlx.Tx, eventType enums.EventType, aggregateType string, aggregateIDs, accIDs []int, payloads []any) error {
+	var (
+		ids  = make(pq.Int64Array, len(aggregateIDs))
+		accs = make(pq.Int64Array, len(accIDs))
+		data = make(pq.StringArray, len(payloads))
+	)
+	for i := range payloads {
+		b, err := json.Marshal(payloads[i])
+		if err != nil {
+			return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+		}
+		ids[i], accs[i], data[i] = int64(aggregateIDs[i]), int64(accIDs[i]), string(b)
+	}
+
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO outbox
+			(event_type, aggregate_type, aggregate_id, account_id, payload)
+		SELECT
+			$1, $2, e.aggregate_id, e.account_id, e.payload
+		FROM unnest($3::INT[], $4::INT[], $5::JSONB[]) WITH ORDINALITY AS e(aggregate_id, account_id, payload, ord)
+		ORDER BY e.ord
+		`,
+		eventType,
+		aggregateType,
+		ids,
+		accs,
+		data,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert %s events: %w", eventType, err)
+	}
+
+	return nil
+}
+
+// ClaimOutboxEvents claims the oldest pending events for the lease, the claimed events are published outside of any
+// db transaction and recorded with RecordOutboxDeliveries. Only the settled events are claimed, so an event committing
+// after a later one of its account was published isn't published out of order, and nothing is claimed while another
+// claim is live, which keeps a single relay at work across the replicas.
+func (p *pismoRepo) ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) (events []OutboxEvent, err error) {
+	err = p.withTx(ctx, func(tx *sqlx.Tx) error {
+		var locked bool
+		if err := tx.GetContext(ctx, &locked, "SELECT pg_try_advisory_xact_lock(hashtext($1))", outboxRelayLock); err != nil {
+			return fmt.Errorf("failed to acquire the relay lock: %w", err)
+		}
+
+		if !locked {
+			return nil
+		}
+
+		err := tx.SelectContext(ctx,
+			&events,
+			`WITH due AS (
+				SELECT o.event_id
+				FROM outbox o
+				WHERE o.published_at IS NULL AND `+settledEvents+`
+					AND NOT EXISTS (
+						SELECT 1 FROM outbox c WHERE c.published_at IS NULL AND c.claimed_until > CURRENT_TIMESTAMP
+					)
+				ORDER BY o.event_id
+				LIMIT $1
+			), claimed AS (
+				UPDATE outbox o
+				SET claimed_until = CURRENT_TIMESTAMP + make_interval(secs => $2)
+				FROM due
+				WHERE o.event_id = due.event_id
+				RETURNING o.event_id, o.event_type, o.aggregate_type, o.aggregate_id, o.account_id, o.payload, o.created_at, o.attempts
+			)
+			SELECT * FROM claimed ORDER BY event_id
+			`,
+			limit,
+			lease.Seconds(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to claim pending events: %w", err)
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// RecordOutboxDeliveries records the outcome of each delivery and releases the claim of the events, published events
+// are never claimed again and the failed or held back ones are claimed again by the next call
+func (p *pismoRepo) RecordOutboxDeliveries(ctx context.Context, eventIDs []int64, deliveries []OutboxDelivery) (err error) {
+	return p.withTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, "UPDATE outbox SET claimed_until = NULL WHERE event_id = ANY($1)", pq.Int64Array(eventIDs))
+		if err != nil {
+			return fmt.Errorf("failed to release the claimed events: %w", err)
+		}
+
+		for _, d := range deliveries {
+			if d.Err == nil {
+				_, err = tx.ExecContext(ctx,
+					"UPDATE outbox SET published_at = CURRENT_TIMESTAMP, attempts = attempts + 1, last_error = NULL WHERE event_id = $1",
+					d.EventID,
+				)
+			} else {
+				_, err = tx.ExecContext(ctx,
+					"UPDATE outbox SET attempts = attempts + 1, last_error = $2 WHERE event_id = $1",
+					d.EventID,
+					d.Err.Error(),
+				)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to record the delivery of event %d: %w", d.EventID, err)
+			}
+		}
+
+		return nil
+	})
+}
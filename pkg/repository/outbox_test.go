@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimOutboxEvents(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	acc := &Account{DocumentNo: testDocumentNo(t)}
+	require.NoError(t, repo.CreateAccount(ctx, acc))
+
+	claim := func() []int64 {
+		events, err := repo.ClaimOutboxEvents(ctx, 100000, time.Minute)
+		require.NoError(t, err)
+
+		var ids []int64
+		for _, e := range events {
+			ids = append(ids, e.EventID)
+		}
+		return ids
+	}
+	created := func(ids []int64) bool {
+		lastID, err := repo.LastAccountEventID(ctx, acc.AccountID)
+		require.NoError(t, err)
+		return slices.Contains(ids, lastID)
+	}
+
+	ids := claim()
+	require.True(t, created(ids))
+
+	// nothing is claimed while the claim is live, a released one is claimed again
+	require.Empty(t, claim())
+	require.NoError(t, repo.RecordOutboxDeliveries(ctx, ids, nil))
+	ids = claim()
+	require.True(t, created(ids))
+
+	deliveries := make([]OutboxDelivery, len(ids))
+	for i, id := range ids {
+		deliveries[i] = OutboxDelivery{EventID: id}
+	}
+	require.NoError(t, repo.RecordOutboxDeliveries(ctx, ids, deliveries))
+	ids = claim()
+	require.False(t, created(ids))
+	require.NoError(t, repo.RecordOutboxDeliveries(ctx, ids, nil))
+}
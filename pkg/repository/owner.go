@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+)
+
+type ownerCtxKey struct{}
+
+// WithOwner returns a copy of the context restricted to the accounts of the document number, the accounts of anyone
+// else are then looked up as if they didn't exist
+func WithOwner(ctx context.Context, docNo string) context.Context {
+	return context.WithValue(ctx, ownerCtxKey{}, docNo)
+}
+
+// OwnerFromContext returns the document number the context is restricted to, false when it isn't restricted
+func OwnerFromContext(ctx context.Context) (string, bool) {
+	docNo, ok := ctx.Value(ownerCtxKey{}).(string)
+	return docNo, ok
+}
+
+// ownerCondition returns the condition restricting the accounts to the owner of the context along with its args,
+// numbered from n, it's empty when the context isn't restricted. Encrypted document numbers are matched by their
+// blind index and the erased accounts, which have neither, belong to no one.
+func (p *pismoRepo) ownerCondition(ctx context.Context, n int) (string, []any) {
+	docNo, ok := OwnerFromContext(ctx)
+	if !ok {
+		return "", nil
+	}
+
+	// a nil []byte would be sent as an empty bytea instead of null
+	var hash any
+	if p.keyring != nil {
+		hash = p.keyring.BlindIndex(docNo)
+	}
+
+	return fmt.Sprintf(" AND (document_number = $%d OR document_number_hash = $%d)", n, n+1), []any{docNo, hash}
+}
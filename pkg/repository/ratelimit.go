@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// refilledTokens is the tokens of a bucket refilled at the rate ( $2 ) up to the burst ( $3 ) since it was last taken
+// from, the time of the take is the one of the row proposed for insertion
+const refilledTokens = "LEAST($3, b.tokens + EXTRACT(EPOCH FROM EXCLUDED.updated_at - b.updated_at) * $2)"
+
+// TakeRateLimitToken takes a token from the bucket of the key when it has one, the bucket is refilled at rate tokens
+// per second up to burst tokens since it was last taken from and created full. It's a single statement so the
+// replicas sharing the bucket never take the same token.
+func (p *pismoRepo) TakeRateLimitToken(ctx context.Context, key string, rate float64, burst int) (tokens float64, ok bool, err error) {
+	row := p.db.QueryRowxContext(ctx,
+		`INSERT INTO rate_limit_buckets AS b
+			(key, tokens, allowed, updated_at)
+		VALUES
+			($1, $3 - 1, TRUE, clock_timestamp())
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = CASE WHEN `+refilledTokens+` >= 1 THEN `+refilledTokens+` - 1 ELSE `+refilledTokens+` END,
+			allowed = `+refilledTokens+` >= 1,
+			updated_at = EXCLUDED.updated_at
+		RETURNING tokens, allowed
+		`,
+		key,
+		rate,
+		burst,
+	)
+	if err := row.Scan(&tokens, &ok); err != nil {
+		return 0, false, fmt.Errorf("failed to take a rate limit token: %w", err)
+	}
+
+	return tokens, ok, nil
+}
+
+// DeleteIdleRateLimitBuckets deletes the buckets not taken from since the given time, they are full again unless
+// their limit takes longer than that to refill them
+func (p *pismoRepo) DeleteIdleRateLimitBuckets(ctx context.Context, since time.Time) (count int64, err error) {
+	res, err := p.db.ExecContext(ctx, "DELETE FROM rate_limit_buckets WHERE updated_at < $1", since)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete the idle rate limit buckets: %w", err)
+	}
+
+	return res.RowsAffected()
+}
@@ -6,37 +6,114 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/fieldcrypt"
+)
+
+// uniqueViolation is the postgres error code raised on unique constraint violations
+const uniqueViolation = "23505"
+
+var (
+	// ErrDuplicateExternalReference is returned when a transaction reuses an existing external_reference
+	ErrDuplicateExternalReference = errors.New("external_reference already exists")
+	// ErrAccountErased is returned when creating a transaction for or erasing an account already erased
+	ErrAccountErased = errors.New("account is erased")
+	// ErrVersionMismatch is returned when changing an account that isn't at any of the versions the change is
+	// conditioned on
+	ErrVersionMismatch = errors.New("account version doesn't match")
 )
 
 type (
 	pismoRepo struct {
-		db *sqlx.DB
+		db      *sqlx.DB
+		keyring *fieldcrypt.Keyring
 	}
 
+	// Option - Used to configure the repository during initialization
+	Option func(*pismoRepo)
+
 	PismoRepo interface {
 		GetAccountByDocumentNo(ctx context.Context, document_number string) (isExists bool, err error)
-		CreateAccount(ctx context.Context, document_number string) (err error)
+		CreateAccount(ctx context.Context, acc *Account) (err error)
 		GetAccountByAccountID(ctx context.Context, account_id int) (account *Account, err error)
+		UpdateAccountMetadata(ctx context.Context, account_id int, patch AccountMetadataPatch) (account *Account, err error)
+		ListAccounts(ctx context.Context, filter AccountFilter) (accounts []Account, err error)
+		CountAccounts(ctx context.Context, filter AccountFilter) (count int64, err error)
 		CreateTransaction(ctx context.Context, txn Transaction) (err error)
+		CreateTransactions(ctx context.Context, txns []Transaction, atomic bool) (results []TransactionResult, err error)
+		GetAccountBalance(ctx context.Context, account_id int, before time.Time) (balance float64, err error)
+		CreateBalanceSnapshots(ctx context.Context, at time.Time) (count int64, err error)
+		StreamTransactions(ctx context.Context, filter TransactionFilter, fn func(txn Transaction) error) (err error)
+		SaveReconciliation(ctx context.Context, rec *Reconciliation) (err error)
+		GetReconciliation(ctx context.Context, reconciliation_id int) (rec *Reconciliation, err error)
+		ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) (events []OutboxEvent, err error)
+		RecordOutboxDeliveries(ctx context.Context, eventIDs []int64, deliveries []OutboxDelivery) (err error)
+		CreateWebhook(ctx context.Context, wh *Webhook) (err error)
+		GetWebhook(ctx context.Context, webhook_id int) (wh *Webhook, err error)
+		ListWebhooks(ctx context.Context) (webhooks []Webhook, err error)
+		DeleteWebhook(ctx context.Context, webhook_id int) (deleted bool, err error)
+		EnableWebhook(ctx context.Context, webhook_id int) (wh *Webhook, err error)
+		EnqueueWebhookDeliveries(ctx context.Context, event_id int64, event_type enums.EventType) (count int64, err error)
+		ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) (deliveries []WebhookDelivery, err error)
+		RecordWebhookAttempt(ctx context.Context, attempt WebhookAttempt) (err error)
+		ListDeadWebhookDeliveries(ctx context.Context, webhook_id int) (deliveries []WebhookDelivery, err error)
+		ReplayWebhookDeliveries(ctx context.Context, webhook_id int, delivery_ids []int64) (count int64, err error)
+		GetChangeCursor(ctx context.Context) (sequence int64, err error)
+		ListChangeLog(ctx context.Context, since int64, limit int) (entries []ChangeLogEntry, err error)
+		LastAccountEventID(ctx context.Context, account_id int) (event_id int64, err error)
+		ListAccountEvents(ctx context.Context, account_id int, after int64, limit int) (events []AccountEvent, err error)
+		ListAuditLog(ctx context.Context, filter AuditFilter) (entries []AuditEntry, err error)
+		RotateDocumentNumbers(ctx context.Context, limit int) (count int, err error)
+		EraseAccount(ctx context.Context, account_id int, dry_run bool, if_match IfMatch) (erasure *AccountErasure, err error)
+		CreateAPIKey(ctx context.Context, key *APIKey) (err error)
+		GetAPIKey(ctx context.Context, key_id int) (key *APIKey, err error)
+		GetAPIKeyByHash(ctx context.Context, hash []byte) (key *APIKey, err error)
+		ListAPIKeys(ctx context.Context) (keys []APIKey, err error)
+		RevokeAPIKey(ctx context.Context, key_id int) (key *APIKey, err error)
+		TakeRateLimitToken(ctx context.Context, key string, rate float64, burst int) (tokens float64, ok bool, err error)
+		DeleteIdleRateLimitBuckets(ctx context.Context, since time.Time) (count int64, err error)
 	}
 )
 
+// WithKeyring - Will encrypt the document numbers with the keyring, they are stored in plaintext without it
+func WithKeyring(kr *fieldcrypt.Keyring) Option {
+	return func(p *pismoRepo) {
+		p.keyring = kr
+	}
+}
+
 // NewPismoRepo configures and returns the object for PismoRepo
-func NewPismoRepo(db *sqlx.DB) PismoRepo {
-	return &pismoRepo{
-		db,
+func NewPismoRepo(db *sqlx.DB, opts ...Option) PismoRepo {
+	p := &pismoRepo{
+		db: db,
+	}
+
+	for _, o := range opts {
+		o(p)
 	}
+
+	return p
 }
 
-// GetAccountByDocumentNo retrives the account for given document_number, if account exists it will return true
+// GetAccountByDocumentNo retrives the account for given document_number, if account exists it will return true,
+// encrypted document numbers are looked up by their blind index
 func (p *pismoRepo) GetAccountByDocumentNo(ctx context.Context, docNo string) (isExists bool, err error) {
+	// a nil []byte would be sent as an empty bytea instead of null
+	var hash any
+	if p.keyring != nil {
+		hash = p.keyring.BlindIndex(docNo)
+	}
+
 	err = p.db.GetContext(
 		ctx,
 		&isExists,
-		"SELECT EXISTS ( SELECT 1 FROM accounts WHERE document_number = $1 limit 1)",
+		"SELECT EXISTS ( SELECT 1 FROM accounts WHERE document_number = $1 OR document_number_hash = $2 limit 1)",
 		docNo,
+		hash,
 	)
 	if err != nil {
 		return false, fmt.Errorf("failed to query account: %w", err)
@@ -45,53 +122,328 @@ func (p *pismoRepo) GetAccountByDocumentNo(ctx context.Context, docNo string) (i
 	return
 }
 
-// CreateAccount creates new account record in accounts table
-func (p *pismoRepo) CreateAccount(ctx context.Context, docNo string) (err error) {
-	_, err = p.db.ExecContext(
-		ctx,
-		"INSERT INTO accounts (document_number) VALUES ($1)",
-		docNo,
-	)
+// CreateAccount creates new account record in accounts table along with its account.created event and audit entry,
+// the generated id is set on acc
+func (p *pismoRepo) CreateAccount(ctx context.Context, acc *Account) (err error) {
+	acc.Tags = normalizeTags(acc.Tags)
 
-	if err != nil {
-		return fmt.Errorf("failed to insert account: %w", err)
-	}
+	return p.withTx(ctx, func(tx *sqlx.Tx) error {
+		accID, keyID, err := p.insertAccount(ctx, tx, acc)
+		if err != nil {
+			return err
+		}
+		acc.AccountID, acc.Version = accID, 1
 
-	return
+		err = insertOutboxEvent(ctx, tx, enums.AccountCreated, aggregateAccount, accID, accID,
+			accountCreatedPayload{AccountID: accID},
+		)
+		if err != nil {
+			return err
+		}
+
+		return insertAuditEntry(ctx, tx, aggregateAccount, accID, enums.AuditCreate, nil, newAccountImage(accID, acc.Version, keyID))
+	})
 }
 
-// GetAccountByAccountID retrives account for given account_id
+// GetAccountByAccountID retrives account for given account_id, nil when the context is restricted to an owner the
+// account doesn't belong to
 func (p *pismoRepo) GetAccountByAccountID(ctx context.Context, accID int) (*Account, error) {
-	var acc Account
+	owned, args := p.ownerCondition(ctx, 2)
+
+	var row accountRow
 	err := p.db.GetContext(
 		ctx,
-		&acc,
-		"SELECT account_id, document_number FROM accounts WHERE account_id = $1",
-		accID,
+		&row,
+		"SELECT "+accountColumns+" FROM accounts WHERE account_id = $1"+owned,
+		append([]any{accID}, args...)...,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
-		return &acc, fmt.Errorf("failed to query account: %w", err)
+		return nil, fmt.Errorf("failed to query account: %w", err)
 	}
 
-	return &acc, nil
+	return p.decryptAccount(row)
 }
 
-// CreateTransaction creates new record for in transactions table
+// CreateTransaction creates new record for in transactions table along with its transaction.created event and audit entry,
+// the account is share locked so it can't be erased meanwhile
 func (p *pismoRepo) CreateTransaction(ctx context.Context, txn Transaction) (err error) {
-	_, err = p.db.NamedExecContext(ctx,
-		`INSERT INTO transactions 
-			(account_id, operation_type_id, amount) 
-		VALUES 
-			(:account_id, :operation_type_id, :amount)
+	return p.withTx(ctx, func(tx *sqlx.Tx) error {
+		rows, err := sqlx.NamedQueryContext(ctx, tx,
+			`INSERT INTO transactions 
+				(account_id, operation_type_id, amount, external_reference) 
+			SELECT
+				account_id, CAST(:operation_type_id AS INT), CAST(:amount AS DECIMAL), NULLIF(CAST(:external_reference AS VARCHAR), '')
+			FROM accounts
+			WHERE account_id = :account_id AND erased_at IS NULL
+			FOR SHARE
+			RETURNING transaction_id, event_date
+			`,
+			txn,
+		)
+		if err == nil {
+			if rows.Next() {
+				err = rows.Scan(&txn.TransactionID, &txn.EventDate)
+			} else if err = rows.Err(); err == nil {
+				err = ErrAccountErased
+			}
+			if err == nil {
+				err = rows.Err()
+			}
+			rows.Close()
+		}
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrDuplicateExternalReference
+			}
+			if errors.Is(err, ErrAccountErased) {
+				return err
+			}
+			return fmt.Errorf("failed to create transaction record: %w", err)
+		}
+
+		created := transactionCreatedPayload{
+			TransactionID:     txn.TransactionID,
+			AccountID:         txn.AccountID,
+			OperationTypeID:   txn.OperationTypeID,
+			Amount:            txn.Amount,
+			EventDate:         txn.EventDate,
+			ExternalReference: txn.ExternalReference,
+		}
+
+		err = insertOutboxEvent(ctx, tx, enums.TransactionCreated, aggregateTransaction, txn.TransactionID, txn.AccountID, created)
+		if err != nil {
+			return err
+		}
+
+		return insertAuditEntry(ctx, tx, aggregateTransaction, txn.TransactionID, enums.AuditCreate, nil, created)
+	})
+}
+
+// GetAccountBalance returns the balance of the account made of every transaction that happened before the given time,
+// it starts from the latest balance snapshot taken up to that time and only sums up the transactions since then
+func (p *pismoRepo) GetAccountBalance(ctx context.Context, accID int, before time.Time) (balance float64, err error) {
+	err = p.db.GetContext(
+		ctx,
+		&balance,
+		`WITH snapshot AS (
+			SELECT snapshot_at, balance FROM balance_snapshots
+			WHERE account_id = $1 AND snapshot_at <= $2
+			ORDER BY snapshot_at DESC
+			LIMIT 1
+		)
+		SELECT
+			COALESCE((SELECT balance FROM snapshot), 0) + COALESCE(SUM(t.amount), 0)
+		FROM transactions t
+		WHERE t.account_id = $1
+			AND t.event_date >= COALESCE((SELECT snapshot_at FROM snapshot), '-infinity')
+			AND t.event_date < $2
 		`,
-		txn,
+		accID,
+		before,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create transaction record: %w", err)
+		return 0, fmt.Errorf("failed to query account balance: %w", err)
 	}
 
 	return
 }
+
+// CreateBalanceSnapshots writes the closing balance of every account at the given time, each balance is carried over
+// from the previous snapshot, running it again for the same time recalculates the snapshots
+func (p *pismoRepo) CreateBalanceSnapshots(ctx context.Context, at time.Time) (count int64, err error) {
+	res, err := p.db.ExecContext(
+		ctx,
+		`INSERT INTO balance_snapshots (account_id, snapshot_at, balance)
+		SELECT
+			a.account_id,
+			$1,
+			COALESCE(s.balance, 0) + COALESCE((
+				SELECT SUM(t.amount) FROM transactions t
+				WHERE t.account_id = a.account_id
+					AND t.event_date >= COALESCE(s.snapshot_at, '-infinity')
+					AND t.event_date < $1
+			), 0)
+		FROM accounts a
+		LEFT JOIN LATERAL (
+			SELECT snapshot_at, balance FROM balance_snapshots bs
+			WHERE bs.account_id = a.account_id AND bs.snapshot_at < $1
+			ORDER BY bs.snapshot_at DESC
+			LIMIT 1
+		) s ON true
+		ON CONFLICT (account_id, snapshot_at) DO UPDATE SET balance = EXCLUDED.balance, created_at = CURRENT_TIMESTAMP
+		`,
+		at,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create balance snapshots: %w", err)
+	}
+
+	if count, err = res.RowsAffected(); err != nil {
+		return 0, fmt.Errorf("failed to read created snapshots: %w", err)
+	}
+
+	return
+}
+
+// StreamTransactions reads the transactions matching the filter in chronological order and passes them one by one to fn,
+// the rows are not buffered so the caller can write them out as they arrive
+func (p *pismoRepo) StreamTransactions(ctx context.Context, filter TransactionFilter, fn func(txn Transaction) error) (err error) {
+	to := filter.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	rows, err := p.db.QueryxContext(ctx,
+		`SELECT
+			t.transaction_id, t.account_id, t.operation_type_id, o.description, t.amount, t.event_date,
+			COALESCE(t.external_reference, '') AS external_reference
+		FROM transactions t
+		JOIN operation_types o ON o.operation_type_id = t.operation_type_id
+		WHERE ($1 = 0 OR t.account_id = $1) AND t.event_date >= $2 AND t.event_date < $3
+		ORDER BY t.event_date, t.transaction_id
+		`,
+		filter.AccountID,
+		filter.From,
+		to,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var txn Transaction
+		if err := rows.StructScan(&txn); err != nil {
+			return fmt.Errorf("failed to scan transaction: %w", err)
+		}
+
+		if err := fn(txn); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate transactions: %w", err)
+	}
+
+	return
+}
+
+// SaveReconciliation stores the reconciliation along with its items and sets the generated id on rec
+func (p *pismoRepo) SaveReconciliation(ctx context.Context, rec *Reconciliation) (err error) {
+	return p.withTx(ctx, func(tx *sqlx.Tx) error {
+		err := tx.QueryRowxContext(ctx,
+			`INSERT INTO reconciliations
+				(source, date_tolerance, matched, missing_in_ledger, missing_in_file, amount_mismatches)
+			VALUES
+				($1, make_interval(secs => $2), $3, $4, $5, $6)
+			RETURNING reconciliation_id, created_at
+			`,
+			rec.Source,
+			rec.DateTolerance.Seconds(),
+			rec.Matched,
+			rec.MissingInLedger,
+			rec.MissingInFile,
+			rec.AmountMismatches,
+		).Scan(&rec.ReconciliationID, &rec.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert reconciliation: %w", err)
+		}
+
+		for _, item := range rec.Items {
+			_, err = tx.ExecContext(ctx,
+				`INSERT INTO reconciliation_items
+					(reconciliation_id, status, line, external_reference, account_id, file_amount, ledger_amount, event_date, transaction_id)
+				VALUES
+					($1, $2, NULLIF($3, 0), NULLIF($4, ''), NULLIF($5, 0), $6, $7, $8, NULLIF($9, 0))
+				`,
+				rec.ReconciliationID,
+				item.Status,
+				item.Line,
+				item.ExternalReference,
+				item.AccountID,
+				item.FileAmount,
+				item.LedgerAmount,
+				item.EventDate,
+				item.TransactionID,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert reconciliation item: %w", err)
+			}
+		}
+
+		return insertAuditEntry(ctx, tx, entityReconciliation, rec.ReconciliationID, enums.AuditCreate, nil,
+			reconciliationImage{
+				ReconciliationID: rec.ReconciliationID,
+				Source:           rec.Source,
+				DateTolerance:    rec.DateTolerance.String(),
+				Matched:          rec.Matched,
+				MissingInLedger:  rec.MissingInLedger,
+				MissingInFile:    rec.MissingInFile,
+				AmountMismatches: rec.AmountMismatches,
+			},
+		)
+	})
+}
+
+// GetReconciliation retrieves the reconciliation along with its items for given reconciliation_id
+func (p *pismoRepo) GetReconciliation(ctx context.Context, recID int) (*Reconciliation, error) {
+	var (
+		rec       Reconciliation
+		tolerance float64
+	)
+
+	err := p.db.QueryRowxContext(ctx,
+		`SELECT
+			reconciliation_id, source, EXTRACT(EPOCH FROM date_tolerance), matched,
+			missing_in_ledger, missing_in_file, amount_mismatches, created_at
+		FROM reconciliations
+		WHERE reconciliation_id = $1
+		`,
+		recID,
+	).Scan(
+		&rec.ReconciliationID,
+		&rec.Source,
+		&tolerance,
+		&rec.Matched,
+		&rec.MissingInLedger,
+		&rec.MissingInFile,
+		&rec.AmountMismatches,
+		&rec.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query reconciliation: %w", err)
+	}
+
+	rec.DateTolerance = time.Duration(tolerance * float64(time.Second))
+
+	err = p.db.SelectContext(ctx,
+		&rec.Items,
+		`SELECT
+			status, COALESCE(line, 0) AS line, COALESCE(external_reference, '') AS external_reference,
+			COALESCE(account_id, 0) AS account_id, file_amount, ledger_amount, event_date,
+			COALESCE(transaction_id, 0) AS transaction_id
+		FROM reconciliation_items
+		WHERE reconciliation_id = $1
+		ORDER BY reconciliation_item_id
+		`,
+		recID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reconciliation items: %w", err)
+	}
+
+	return &rec, nil
+}
+
+// isUniqueViolation reports whether the error is a postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// testDSNEnv is the database the repository tests run against, they are skipped without one
+const testDSNEnv = "PISMO_TEST_DB_DSN"
+
+// newTestRepo returns a repository on the test database migrated to the latest schema. The database is shared by
+// the tests, so they only look at the rows they create.
+func newTestRepo(t *testing.T, opts ...Option) *pismoRepo {
+	t.Helper()
+
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s isn't set", testDSNEnv)
+	}
+
+	m, err := migrate.New("file://../../schema/migrations", dsn)
+	require.NoError(t, err)
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		require.NoError(t, err)
+	}
+	_, _ = m.Close()
+
+	db, err := sqlx.Connect("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	return NewPismoRepo(db, opts...).(*pismoRepo)
+}
+
+// testDocumentNo returns a document number no other test uses
+func testDocumentNo(t *testing.T) string {
+	t.Helper()
+
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+
+	return "test-" + hex.EncodeToString(b)
+}
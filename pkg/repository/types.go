@@ -1,15 +1,229 @@
 package repository
 
-import "time"
+import (
+	"slices"
+	"time"
 
+	"github.com/lib/pq"
+	"github.com/sathishs-dev/pismo-transactions/pkg/audit"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+)
+
+// Account is an account with its document number in plaintext, ErasedAt is set once its personal data is erased.
+// Version is bumped on every change to the account
 type Account struct {
-	AccountID  int    `db:"account_id"`
-	DocumentNo string `db:"document_number"`
+	AccountID  int                 `db:"account_id"`
+	DocumentNo string              `db:"document_number"`
+	Status     enums.AccountStatus `db:"status"`
+	Metadata   Metadata            `db:"metadata"`
+	Tags       pq.StringArray      `db:"tags"`
+	CreatedAt  time.Time           `db:"created_at"`
+	ErasedAt   *time.Time          `db:"erased_at"`
+	Version    int64               `db:"version"`
+}
+
+// IfMatch is the versions an account has to be at for a change to apply, nil applies it whatever the version
+type IfMatch []int64
+
+// AccountMetadataPatch changes the metadata and tags of an account, a nil metadata value removes the key and nil
+// Tags leaves the tags as they are
+type AccountMetadataPatch struct {
+	Metadata map[string]*string
+	Tags     []string
+	IfMatch  IfMatch
+}
+
+// AccountFilter narrows down the accounts to the ones having every tag, every metadata key and every metadata value,
+// the document number prefix, the status and created within [From, To), the zero values match every account.
+// After resumes the listing past the given account in the Sort order.
+type AccountFilter struct {
+	Tags             []string
+	MetadataKeys     []string
+	Metadata         Metadata
+	DocumentNoPrefix string
+	Status           enums.AccountStatus
+	From             time.Time
+	To               time.Time
+	Sort             enums.AccountSort
+	After            *AccountCursor
+	Limit            int
+}
+
+// AccountCursor is the position of an account in the listing
+type AccountCursor struct {
+	AccountID int
+	CreatedAt time.Time
 }
 
 type Transaction struct {
-	AccountID       int       `db:"account_id"`
-	OperationTypeID int       `db:"operation_type_id"`
-	Amount          float64   `db:"amount"`
-	EventDate       time.Time `db:"event_date"`
+	TransactionID     int       `db:"transaction_id"`
+	AccountID         int       `db:"account_id"`
+	OperationTypeID   int       `db:"operation_type_id"`
+	Description       string    `db:"description"`
+	Amount            float64   `db:"amount"`
+	EventDate         time.Time `db:"event_date"`
+	ExternalReference string    `db:"external_reference"`
+}
+
+// TransactionFilter narrows down the transactions of an account to [From, To), AccountID 0 covers every account
+type TransactionFilter struct {
+	AccountID int
+	From      time.Time
+	To        time.Time
+}
+
+// Reconciliation is the saved outcome of matching a settlement file against the transactions
+type Reconciliation struct {
+	ReconciliationID int           `db:"reconciliation_id"`
+	Source           string        `db:"source"`
+	DateTolerance    time.Duration `db:"-"`
+	Matched          int           `db:"matched"`
+	MissingInLedger  int           `db:"missing_in_ledger"`
+	MissingInFile    int           `db:"missing_in_file"`
+	AmountMismatches int           `db:"amount_mismatches"`
+	CreatedAt        time.Time     `db:"created_at"`
+	Items            []ReconciliationItem
+}
+
+// ReconciliationItem is a single settlement line or ledger transaction of a reconciliation,
+// Line is 0 for transactions missing in the file and TransactionID is 0 for lines missing in the ledger
+type ReconciliationItem struct {
+	Status            enums.ReconciliationStatus `db:"status"`
+	Line              int                        `db:"line"`
+	ExternalReference string                     `db:"external_reference"`
+	AccountID         int                        `db:"account_id"`
+	FileAmount        *float64                   `db:"file_amount"`
+	LedgerAmount      *float64                   `db:"ledger_amount"`
+	EventDate         time.Time                  `db:"event_date"`
+	TransactionID     int                        `db:"transaction_id"`
+}
+
+// OutboxEvent is a domain event written along with the change that caused it, AccountID is the ordering key
+type OutboxEvent struct {
+	EventID       int64           `db:"event_id"`
+	EventType     enums.EventType `db:"event_type"`
+	AggregateType string          `db:"aggregate_type"`
+	AggregateID   int             `db:"aggregate_id"`
+	AccountID     int             `db:"account_id"`
+	Payload       []byte          `db:"payload"`
+	CreatedAt     time.Time       `db:"created_at"`
+	Attempts      int             `db:"attempts"`
+}
+
+// AccountEvent is an outbox event of an account as streamed to its clients, Balance is the balance of the account
+// right after the transaction of a transaction.created event and nil for the other events
+type AccountEvent struct {
+	EventID   int64           `db:"event_id"`
+	EventType enums.EventType `db:"event_type"`
+	Payload   []byte          `db:"payload"`
+	CreatedAt time.Time       `db:"created_at"`
+	Balance   *float64        `db:"balance"`
+}
+
+// OutboxDelivery is the outcome of publishing an outbox event, Err is nil once the event is published
+type OutboxDelivery struct {
+	EventID int64
+	Err     error
+}
+
+// Webhook is an endpoint registered by a partner to receive the events of the given types
+type Webhook struct {
+	WebhookID           int            `db:"webhook_id"`
+	URL                 string         `db:"url"`
+	Secret              string         `db:"secret"`
+	EventTypes          pq.StringArray `db:"event_types"`
+	Enabled             bool           `db:"enabled"`
+	ConsecutiveFailures int            `db:"consecutive_failures"`
+	DisabledAt          *time.Time     `db:"disabled_at"`
+	CreatedAt           time.Time      `db:"created_at"`
+}
+
+// APIKey is a key granted to a partner, Hash is the sha-256 of the key which is only known to the partner
+type APIKey struct {
+	KeyID     int            `db:"key_id"`
+	Name      string         `db:"name"`
+	Prefix    string         `db:"prefix"`
+	Hash      []byte         `db:"key_hash"`
+	Scopes    pq.StringArray `db:"scopes"`
+	ExpiresAt *time.Time     `db:"expires_at"`
+	RevokedAt *time.Time     `db:"revoked_at"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+// WebhookDelivery is an event queued for a webhook, URL and Secret are only set on claimed deliveries
+type WebhookDelivery struct {
+	DeliveryID     int64                `db:"delivery_id"`
+	WebhookID      int                  `db:"webhook_id"`
+	URL            string               `db:"url"`
+	Secret         string               `db:"secret"`
+	Status         enums.DeliveryStatus `db:"status"`
+	Attempts       int                  `db:"attempts"`
+	LastStatusCode *int                 `db:"last_status_code"`
+	LastError      *string              `db:"last_error"`
+	CreatedAt      time.Time            `db:"created_at"`
+	Event          OutboxEvent          `db:"event"`
+}
+
+// WebhookAttempt is the outcome of posting a delivery to its webhook, Err is nil once the endpoint acknowledged it.
+// A failed delivery is retried at RetryAt or moved to the dead letters when RetryAt is zero, and the webhook is
+// disabled after DisableAfter consecutive failures, 0 never disables it.
+type WebhookAttempt struct {
+	DeliveryID   int64
+	WebhookID    int
+	StatusCode   int
+	Err          error
+	RetryAt      time.Time
+	DisableAfter int
+}
+
+// ChangeLogEntry is a change to an account or transaction in the change log, Data is the row as changed without
+// its document number, metadata and tags, and Sequence its position in the log
+type ChangeLogEntry struct {
+	Sequence   int64     `db:"sequence"`
+	EntityType string    `db:"entity_type"`
+	EntityID   int       `db:"entity_id"`
+	AccountID  int       `db:"account_id"`
+	Operation  string    `db:"operation"`
+	Data       []byte    `db:"data"`
+	ChangedAt  time.Time `db:"changed_at"`
+}
+
+// AuditEntry is a change recorded in the append-only audit log, Before is nil for creations and After for deletions
+type AuditEntry struct {
+	AuditID    int64             `db:"audit_id"`
+	OccurredAt time.Time         `db:"occurred_at"`
+	ActorType  audit.ActorType   `db:"actor_type"`
+	ActorID    string            `db:"actor_id"`
+	RequestID  string            `db:"request_id"`
+	EntityType string            `db:"entity_type"`
+	EntityID   string            `db:"entity_id"`
+	Action     enums.AuditAction `db:"action"`
+	Before     []byte            `db:"before_image"`
+	After      []byte            `db:"after_image"`
+}
+
+// AuditFilter narrows down the audit log, empty fields match every entry and BeforeID pages through older entries
+type AuditFilter struct {
+	EntityType string
+	EntityID   string
+	ActorType  audit.ActorType
+	ActorID    string
+	From       time.Time
+	To         time.Time
+	BeforeID   int64
+	Limit      int
+}
+
+// AccountErasure is the outcome of erasing the personal data of an account, ErasedAt is zero for dry runs
+type AccountErasure struct {
+	AccountID            int
+	DryRun               bool
+	ErasedAt             time.Time
+	ErasedFields         []string
+	RetainedTransactions int
+}
+
+// Allows reports whether a change applies to the account at version v
+func (m IfMatch) Allows(v int64) bool {
+	return m == nil || slices.Contains(m, v)
 }
@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+)
+
+const webhookColumns = `webhook_id, url, secret, event_types, enabled, consecutive_failures, disabled_at, created_at`
+
+// deliveryEventColumns selects the outbox event of a delivery into WebhookDelivery.Event
+const deliveryEventColumns = `o.event_id AS "event.event_id",
+	o.event_type AS "event.event_type",
+	o.aggregate_type AS "event.aggregate_type",
+	o.aggregate_id AS "event.aggregate_id",
+	o.account_id AS "event.account_id",
+	o.payload AS "event.payload",
+	o.created_at AS "event.created_at"`
+
+// CreateWebhook registers the webhook and fills in its generated fields
+func (p *pismoRepo) CreateWebhook(ctx context.Context, wh *Webhook) (err error) {
+	return p.withTx(ctx, func(tx *sqlx.Tx) error {
+		err := tx.GetContext(ctx,
+			wh,
+			`INSERT INTO webhooks
+				(url, secret, event_types)
+			VALUES
+				($1, $2, $3)
+			RETURNING `+webhookColumns,
+			wh.URL,
+			wh.Secret,
+			wh.EventTypes,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert webhook: %w", err)
+		}
+
+		return insertAuditEntry(ctx, tx, entityWebhook, wh.WebhookID, enums.AuditCreate, nil, newWebhookImage(wh))
+	})
+}
+
+// GetWebhook retrieves the webhook for the given webhook_id, returns nil when it doesn't exist
+func (p *pismoRepo) GetWebhook(ctx context.Context, webhookID int) (wh *Webhook, err error) {
+	var w Webhook
+	err = p.db.GetContext(ctx, &w, "SELECT "+webhookColumns+" FROM webhooks WHERE webhook_id = $1", webhookID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query webhook: %w", err)
+	}
+
+	return &w, nil
+}
+
+// ListWebhooks retrieves every registered webhook
+func (p *pismoRepo) ListWebhooks(ctx context.Context) (webhooks []Webhook, err error) {
+	err = p.db.SelectContext(ctx, &webhooks, "SELECT "+webhookColumns+" FROM webhooks ORDER BY webhook_id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook removes the webhook along with its deliveries, returns false when it doesn't exist
+func (p *pismoRepo) DeleteWebhook(ctx context.Context, webhookID int) (deleted bool, err error) {
+	err = p.withTx(ctx, func(tx *sqlx.Tx) error {
+		var wh Webhook
+		err := tx.GetContext(ctx, &wh, "DELETE FROM webhooks WHERE webhook_id = $1 RETURNING "+webhookColumns, webhookID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return fmt.Errorf("failed to delete webhook: %w", err)
+		}
+
+		deleted = true
+		return insertAuditEntry(ctx, tx, entityWebhook, webhookID, enums.AuditDelete, newWebhookImage(&wh), nil)
+	})
+
+	return deleted, err
+}
+
+// EnableWebhook enables the webhook again and resets its failure count, returns nil when it doesn't exist
+func (p *pismoRepo) EnableWebhook(ctx context.Context, webhookID int) (wh *Webhook, err error) {
+	err = p.withTx(ctx, func(tx *sqlx.Tx) error {
+		var before, after Webhook
+		err := tx.GetContext(ctx, &before, "SELECT "+webhookColumns+" FROM webhooks WHERE webhook_id = $1 FOR UPDATE", webhookID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return fmt.Errorf("failed to query webhook: %w", err)
+		}
+
+		err = tx.GetContext(ctx,
+			&after,
+			`UPDATE webhooks
+			SET enabled = TRUE, consecutive_failures = 0, disabled_at = NULL
+			WHERE webhook_id = $1
+			RETURNING `+webhookColumns,
+			webhookID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to enable webhook: %w", err)
+		}
+
+		wh = &after
+		return insertAuditEntry(ctx, tx, entityWebhook, webhookID, enums.AuditEnable, newWebhookImage(&before), newWebhookImage(&after))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return wh, nil
+}
+
+// EnqueueWebhookDeliveries queues the event for every enabled webhook subscribed to its type, it's safe to call
+// more than once for the same event
+func (p *pismoRepo) EnqueueWebhookDeliveries(ctx context.Context, eventID int64, eventType enums.EventType) (count int64, err error) {
+	res, err := p.db.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event_id)
+		SELECT webhook_id, $1 FROM webhooks WHERE enabled AND $2 = ANY(event_types)
+		ON CONFLICT (webhook_id, event_id) DO NOTHING
+		`,
+		eventID,
+		eventType,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+
+	if count, err = res.RowsAffected(); err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+
+	return count, nil
+}
+
+// ClaimWebhookDeliveries returns up to limit deliveries due for an attempt on enabled webhooks, the claimed
+// deliveries aren't handed over again, to this or any other replica, until the lease expires
+func (p *pismoRepo) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) (deliveries []WebhookDelivery, err error) {
+	err = p.db.SelectContext(ctx,
+		&deliveries,
+		`WITH due AS (
+			SELECT d.delivery_id
+			FROM webhook_deliveries d
+			JOIN webhooks w ON w.webhook_id = d.webhook_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= CURRENT_TIMESTAMP AND w.enabled
+			ORDER BY d.next_attempt_at, d.delivery_id
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		), claimed AS (
+			UPDATE webhook_deliveries d
+			SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+			FROM due
+			WHERE d.delivery_id = due.delivery_id
+			RETURNING d.delivery_id, d.webhook_id, d.event_id, d.status, d.attempts, d.last_status_code, d.last_error, d.created_at
+		)
+		SELECT
+			c.delivery_id, c.webhook_id, w.url, w.secret, c.status, c.attempts, c.last_status_code, c.last_error, c.created_at,
+			`+deliveryEventColumns+`
+		FROM claimed c
+		JOIN webhooks w ON w.webhook_id = c.webhook_id
+		JOIN outbox o ON o.event_id = c.event_id
+		ORDER BY c.delivery_id
+		`,
+		limit,
+		lease.Seconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// RecordWebhookAttempt records the outcome of a delivery attempt on the delivery and its webhook
+func (p *pismoRepo) RecordWebhookAttempt(ctx context.Context, attempt WebhookAttempt) (err error) {
+	return p.withTx(ctx, func(tx *sqlx.Tx) error {
+		if attempt.Err == nil {
+			_, err := tx.ExecContext(ctx,
+				`UPDATE webhook_deliveries
+				SET status = $2, attempts = attempts + 1, last_status_code = $3, last_error = NULL, delivered_at = CURRENT_TIMESTAMP
+				WHERE delivery_id = $1
+				`,
+				attempt.DeliveryID,
+				enums.DeliveryDelivered,
+				attempt.StatusCode,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to update webhook delivery: %w", err)
+			}
+
+			_, err = tx.ExecContext(ctx, "UPDATE webhooks SET consecutive_failures = 0 WHERE webhook_id = $1", attempt.WebhookID)
+			if err != nil {
+				return fmt.Errorf("failed to update webhook: %w", err)
+			}
+
+			return nil
+		}
+
+		status, retryAt := enums.DeliveryPending, any(attempt.RetryAt)
+		if attempt.RetryAt.IsZero() {
+			status, retryAt = enums.DeliveryDead, nil
+		}
+
+		_, err := tx.ExecContext(ctx,
+			`UPDATE webhook_deliveries
+			SET status = $2, attempts = attempts + 1, last_status_code = NULLIF($3, 0), last_error = $4,
+				next_attempt_at = COALESCE($5, next_attempt_at)
+			WHERE delivery_id = $1
+			`,
+			attempt.DeliveryID,
+			status,
+			attempt.StatusCode,
+			attempt.Err.Error(),
+			retryAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update webhook delivery: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE webhooks
+			SET consecutive_failures = consecutive_failures + 1,
+				enabled = enabled AND ($2 = 0 OR consecutive_failures + 1 < $2),
+				disabled_at = CASE
+					WHEN enabled AND $2 > 0 AND consecutive_failures + 1 >= $2 THEN CURRENT_TIMESTAMP
+					ELSE disabled_at
+				END
+			WHERE webhook_id = $1
+			`,
+			attempt.WebhookID,
+			attempt.DisableAfter,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update webhook: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ListDeadWebhookDeliveries retrieves the dead letters of the webhook
+func (p *pismoRepo) ListDeadWebhookDeliveries(ctx context.Context, webhookID int) (deliveries []WebhookDelivery, err error) {
+	err = p.db.SelectContext(ctx,
+		&deliveries,
+		`SELECT
+			d.delivery_id, d.webhook_id, d.status, d.attempts, d.last_status_code, d.last_error, d.created_at,
+			`+deliveryEventColumns+`
+		FROM webhook_deliveries d
+		JOIN outbox o ON o.event_id = d.event_id
+		WHERE d.webhook_id = $1 AND d.status = $2
+		ORDER BY d.delivery_id
+		`,
+		webhookID,
+		enums.DeliveryDead,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// ReplayWebhookDeliveries queues the given dead letters of the webhook for delivery again with a fresh set of
+// attempts, every dead letter of the webhook is replayed when delivery_ids is empty
+func (p *pismoRepo) ReplayWebhookDeliveries(ctx context.Context, webhookID int, deliveryIDs []int64) (count int64, err error) {
+	err = p.withTx(ctx, func(tx *sqlx.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE webhook_deliveries
+			SET status = $2, attempts = 0, next_attempt_at = CURRENT_TIMESTAMP
+			WHERE webhook_id = $1 AND status = $3
+				AND (COALESCE(cardinality($4::BIGINT[]), 0) = 0 OR delivery_id = ANY($4::BIGINT[]))
+			`,
+			webhookID,
+			enums.DeliveryPending,
+			enums.DeliveryDead,
+			pq.Array(deliveryIDs),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to replay webhook deliveries: %w", err)
+		}
+
+		if count, err = res.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to replay webhook deliveries: %w", err)
+		}
+
+		return insertAuditEntry(ctx, tx, entityWebhook, webhookID, enums.AuditReplay, nil,
+			webhookReplayImage{DeliveryIDs: deliveryIDs, Replayed: count},
+		)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
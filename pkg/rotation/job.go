@@ -0,0 +1,90 @@
+package rotation
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+)
+
+const (
+	DefaultInterval  = time.Minute
+	DefaultBatchSize = 100
+)
+
+type (
+	// Job encrypts the document numbers still stored in plaintext and moves the ones encrypted under an older key
+	// to the primary key of the keyring, it keeps running so rotating the keyring again is picked up
+	Job struct {
+		repo      repository.PismoRepo
+		interval  time.Duration
+		batchSize int
+	}
+
+	// Option - Used to configure the job during initialization
+	Option func(*Job)
+)
+
+// WithInterval - Will check for accounts left to rotate at the given interval once they are all rotated
+func WithInterval(d time.Duration) Option {
+	return func(j *Job) {
+		j.interval = d
+	}
+}
+
+// WithBatchSize - Will rotate up to n accounts per db transaction
+func WithBatchSize(n int) Option {
+	return func(j *Job) {
+		j.batchSize = n
+	}
+}
+
+// NewJob configures and returns the key rotation Job
+func NewJob(repo repository.PismoRepo, opts ...Option) *Job {
+	j := &Job{
+		repo:      repo,
+		interval:  DefaultInterval,
+		batchSize: DefaultBatchSize,
+	}
+
+	for _, o := range opts {
+		o(j)
+	}
+
+	return j
+}
+
+// Start rotates the accounts until the context is cancelled, full batches are followed right away by the next one
+func (j *Job) Start(ctx context.Context) {
+	for {
+		count, err := j.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("failed to rotate document numbers")
+		}
+
+		if err == nil && count >= j.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(j.interval):
+		}
+	}
+}
+
+// RunOnce rotates a single batch of accounts and returns the number of accounts rotated
+func (j *Job) RunOnce(ctx context.Context) (int, error) {
+	count, err := j.repo.RotateDocumentNumbers(ctx, j.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	if count > 0 {
+		log.Info().Int("accounts", count).Msg("document numbers rotated")
+	}
+
+	return count, nil
+}
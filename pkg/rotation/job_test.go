@@ -0,0 +1,46 @@
+package rotation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobRunOnce(t *testing.T) {
+	repo := mocks.NewPismoRepo(t)
+	j := NewJob(repo, WithBatchSize(10))
+
+	repo.On("RotateDocumentNumbers", mock.Anything, 10).Return(4, nil).Once()
+
+	count, err := j.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 4, count)
+
+	repo.On("RotateDocumentNumbers", mock.Anything, 10).Return(0, errors.New("db down")).Once()
+
+	_, err = j.RunOnce(context.Background())
+	require.Error(t, err)
+}
+
+func TestJobStartDrainsFullBatches(t *testing.T) {
+	repo := mocks.NewPismoRepo(t)
+	j := NewJob(repo, WithBatchSize(2))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	repo.On("RotateDocumentNumbers", mock.Anything, 2).Return(2, nil).Twice()
+	repo.On("RotateDocumentNumbers", mock.Anything, 2).
+		Run(func(mock.Arguments) { cancel() }).
+		Return(1, nil).Once()
+
+	done := make(chan struct{})
+	go func() {
+		j.Start(ctx)
+		close(done)
+	}()
+
+	<-done
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+)
+
+// limits of the account metadata and tags
+const (
+	MaxMetadataKeyLen   = 40
+	MaxMetadataValueLen = 500
+	MaxAccountTags      = 20
+	MaxTagLen           = 64
+
+	// reservedMetadataPrefix is kept for the keys the service may add itself
+	reservedMetadataPrefix = "pismo_"
+)
+
+var (
+	// MetadataKeyPattern is the characters allowed in metadata keys
+	MetadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	tagPattern         = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+
+	// reservedMetadataKeys are account fields, the document number in particular is only stored encrypted
+	reservedMetadataKeys = []string{"account_id", "document_number"}
+)
+
+// CreateAccountInput is a new account, metadata and tags are optional
+type CreateAccountInput struct {
+	DocumentNumber string            `json:"document_number"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	Tags           []string          `json:"tags,omitempty"`
+}
+
+// CreateAccount creates an account unless its document number is already associated with one
+func (s *service) CreateAccount(ctx context.Context, in CreateAccountInput) (*repository.Account, error) {
+	if in.DocumentNumber == "" {
+		return nil, invalidFields(problem.FieldError{Name: "document_number", Message: "is required"})
+	}
+
+	if err := validateMetadata(in.Metadata); err != nil {
+		return nil, invalid(problem.CodeValidationFailed, "%s", err)
+	}
+
+	if err := ValidateTags(in.Tags); err != nil {
+		return nil, invalid(problem.CodeValidationFailed, "%s", err)
+	}
+
+	// check unique document_number
+	isExists, err := s.repo.GetAccountByDocumentNo(ctx, in.DocumentNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve the account: %w", err)
+	}
+
+	if isExists {
+		return nil, conflict(problem.CodeDuplicateDocumentNumber, "document_number already associated with an account.")
+	}
+
+	acc := &repository.Account{
+		DocumentNo: in.DocumentNumber,
+		Metadata:   in.Metadata,
+		Tags:       in.Tags,
+	}
+	if err := s.repo.CreateAccount(ctx, acc); err != nil {
+		return nil, fmt.Errorf("failed to store the account: %w", err)
+	}
+
+	return acc, nil
+}
+
+// GetAccount returns the account
+func (s *service) GetAccount(ctx context.Context, accID int) (*repository.Account, error) {
+	if accID <= 0 {
+		return nil, invalid(problem.CodeInvalidRequest, "invalid accountId")
+	}
+
+	acc, err := s.repo.GetAccountByAccountID(ctx, accID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve the account: %w", err)
+	}
+
+	if acc == nil {
+		return nil, notFound(problem.CodeAccountNotFound, "account not found")
+	}
+
+	return acc, nil
+}
+
+// GetAccountBalance returns the balance of the account as of the given time
+func (s *service) GetAccountBalance(ctx context.Context, accID int, asOf time.Time) (float64, error) {
+	acc, err := s.GetAccount(ctx, accID)
+	if err != nil {
+		return 0, err
+	}
+
+	balance, err := s.repo.GetAccountBalance(ctx, acc.AccountID, asOf)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve the balance: %w", err)
+	}
+
+	return balance, nil
+}
+
+// UpdateAccountMetadata merges the metadata of the patch into the account metadata, a nil value removes the key,
+// and replaces the account tags when the patch has them
+func (s *service) UpdateAccountMetadata(ctx context.Context, accID int, patch repository.AccountMetadataPatch) (*repository.Account, error) {
+	if accID <= 0 {
+		return nil, invalid(problem.CodeInvalidRequest, "invalid accountId")
+	}
+
+	if patch.Metadata == nil && patch.Tags == nil {
+		return nil, invalid(problem.CodeValidationFailed, "metadata or tags required")
+	}
+
+	for k, v := range patch.Metadata {
+		if err := validateMetadataKey(k); err != nil {
+			return nil, invalid(problem.CodeValidationFailed, "%s", err)
+		}
+		if v != nil && len(*v) > MaxMetadataValueLen {
+			return nil, invalid(problem.CodeValidationFailed, "metadata value of %q is too long", k)
+		}
+	}
+
+	if err := ValidateTags(patch.Tags); err != nil {
+		return nil, invalid(problem.CodeValidationFailed, "%s", err)
+	}
+
+	acc, err := s.repo.UpdateAccountMetadata(ctx, accID, patch)
+	switch {
+	case errors.Is(err, repository.ErrTooManyMetadataKeys):
+		return nil, invalid(problem.CodeValidationFailed, "too many metadata keys")
+	case errors.Is(err, repository.ErrAccountErased):
+		return nil, conflict(problem.CodeAccountErased, "account is erased")
+	case errors.Is(err, repository.ErrVersionMismatch):
+		return nil, preconditionFailed(problem.CodePreconditionFailed, "account was modified, fetch it again")
+	case err != nil:
+		return nil, fmt.Errorf("failed to update the account metadata: %w", err)
+	}
+
+	if acc == nil {
+		return nil, notFound(problem.CodeAccountNotFound, "account not found")
+	}
+
+	return acc, nil
+}
+
+// EraseAccount erases the personal data of an account for good while keeping its transactions, a dry run only
+// returns what would be erased
+func (s *service) EraseAccount(ctx context.Context, accID int, dryRun bool, ifMatch repository.IfMatch) (*repository.AccountErasure, error) {
+	if accID <= 0 {
+		return nil, invalid(problem.CodeInvalidRequest, "invalid accountId")
+	}
+
+	erasure, err := s.repo.EraseAccount(ctx, accID, dryRun, ifMatch)
+	switch {
+	case errors.Is(err, repository.ErrAccountErased):
+		return nil, conflict(problem.CodeAccountErased, "account already erased")
+	case errors.Is(err, repository.ErrAccountBalanceNotZero):
+		return nil, conflict(problem.CodeAccountBalanceNotZero, "account balance must be zero to erase it")
+	case errors.Is(err, repository.ErrVersionMismatch):
+		return nil, preconditionFailed(problem.CodePreconditionFailed, "account was modified, fetch it again")
+	case err != nil:
+		return nil, fmt.Errorf("failed to erase the account: %w", err)
+	}
+
+	if erasure == nil {
+		return nil, notFound(problem.CodeAccountNotFound, "account not found")
+	}
+
+	return erasure, nil
+}
+
+// validateMetadata validates the metadata sent along with a new account
+func validateMetadata(metadata map[string]string) error {
+	if len(metadata) > repository.MaxMetadataKeys {
+		return errors.New("too many metadata keys")
+	}
+
+	for k, v := range metadata {
+		if err := validateMetadataKey(k); err != nil {
+			return err
+		}
+		if len(v) > MaxMetadataValueLen {
+			return fmt.Errorf("metadata value of %q is too long", k)
+		}
+	}
+
+	return nil
+}
+
+func validateMetadataKey(k string) error {
+	if len(k) > MaxMetadataKeyLen || !MetadataKeyPattern.MatchString(k) {
+		return fmt.Errorf("invalid metadata key %q", k)
+	}
+
+	if strings.HasPrefix(k, reservedMetadataPrefix) || slices.Contains(reservedMetadataKeys, k) {
+		return fmt.Errorf("metadata key %q is reserved", k)
+	}
+
+	return nil
+}
+
+// ValidateTags validates the tags of an account, or the tags an account listing is filtered by
+func ValidateTags(tags []string) error {
+	if len(tags) > MaxAccountTags {
+		return errors.New("too many tags")
+	}
+
+	for _, t := range tags {
+		if len(t) > MaxTagLen || !tagPattern.MatchString(t) {
+			return fmt.Errorf("invalid tag %q", t)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,365 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/pkg/mocks"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAccount(t *testing.T) {
+	tooManyKeys := map[string]string{}
+	for i := 0; i <= repository.MaxMetadataKeys; i++ {
+		tooManyKeys[fmt.Sprintf("key_%d", i)] = "v"
+	}
+
+	tcs := []struct {
+		name          string
+		in            CreateAccountInput
+		expectedMocks func(repo *mocks.PismoRepo)
+		expectedKind  Kind
+		expectedCode  problem.Code
+	}{
+		{
+			name: "Test Created",
+			in:   CreateAccountInput{DocumentNumber: "12345678900", Metadata: map[string]string{"segment": "gold"}, Tags: []string{"vip"}},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("GetAccountByDocumentNo", mock.Anything, "12345678900").Return(false, nil).Once()
+				repo.On("CreateAccount", mock.Anything, &repository.Account{
+					DocumentNo: "12345678900",
+					Metadata:   repository.Metadata{"segment": "gold"},
+					Tags:       []string{"vip"},
+				}).Return(nil).Once()
+			},
+		},
+		{
+			name:         "Test Missing Document Number",
+			in:           CreateAccountInput{},
+			expectedKind: KindInvalid,
+			expectedCode: problem.CodeValidationFailed,
+		},
+		{
+			name:         "Test Invalid Metadata Key",
+			in:           CreateAccountInput{DocumentNumber: "12345678900", Metadata: map[string]string{"not a key": "v"}},
+			expectedKind: KindInvalid,
+			expectedCode: problem.CodeValidationFailed,
+		},
+		{
+			name:         "Test Reserved Metadata Key",
+			in:           CreateAccountInput{DocumentNumber: "12345678900", Metadata: map[string]string{"document_number": "v"}},
+			expectedKind: KindInvalid,
+			expectedCode: problem.CodeValidationFailed,
+		},
+		{
+			name:         "Test Too Many Metadata Keys",
+			in:           CreateAccountInput{DocumentNumber: "12345678900", Metadata: tooManyKeys},
+			expectedKind: KindInvalid,
+			expectedCode: problem.CodeValidationFailed,
+		},
+		{
+			name:         "Test Invalid Tag",
+			in:           CreateAccountInput{DocumentNumber: "12345678900", Tags: []string{"not a tag"}},
+			expectedKind: KindInvalid,
+			expectedCode: problem.CodeValidationFailed,
+		},
+		{
+			name: "Test Duplicate Document Number",
+			in:   CreateAccountInput{DocumentNumber: "12345678900"},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("GetAccountByDocumentNo", mock.Anything, "12345678900").Return(true, nil).Once()
+			},
+			expectedKind: KindConflict,
+			expectedCode: problem.CodeDuplicateDocumentNumber,
+		},
+		{
+			name: "Test Lookup Failure",
+			in:   CreateAccountInput{DocumentNumber: "12345678900"},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("GetAccountByDocumentNo", mock.Anything, "12345678900").Return(false, errors.New("err")).Once()
+			},
+			expectedKind: kindInternal,
+		},
+		{
+			name: "Test Store Failure",
+			in:   CreateAccountInput{DocumentNumber: "12345678900"},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("GetAccountByDocumentNo", mock.Anything, "12345678900").Return(false, nil).Once()
+				repo.On("CreateAccount", mock.Anything, mock.Anything).Return(errors.New("err")).Once()
+			},
+			expectedKind: kindInternal,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := mocks.NewPismoRepo(t)
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(repo)
+			}
+
+			acc, err := New(repo).CreateAccount(context.Background(), tc.in)
+			requireKind(t, tc.expectedKind, err)
+			if tc.expectedKind == 0 {
+				require.Equal(t, tc.in.DocumentNumber, acc.DocumentNo)
+			}
+
+			var e *Error
+			if errors.As(err, &e) {
+				require.Equal(t, tc.expectedCode, e.Code)
+			}
+		})
+	}
+}
+
+func TestGetAccountBalance(t *testing.T) {
+	asOf := time.Date(2024, 12, 5, 10, 0, 0, 0, time.UTC)
+
+	tcs := []struct {
+		name            string
+		accID           int
+		expectedMocks   func(repo *mocks.PismoRepo)
+		expectedKind    Kind
+		expectedBalance float64
+	}{
+		{
+			name:  "Test Balance",
+			accID: 1,
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("GetAccountByAccountID", mock.Anything, 1).Return(&repository.Account{AccountID: 1}, nil).Once()
+				repo.On("GetAccountBalance", mock.Anything, 1, asOf).Return(-12.5, nil).Once()
+			},
+			expectedBalance: -12.5,
+		},
+		{
+			name:         "Test Invalid Account ID",
+			accID:        0,
+			expectedKind: KindInvalid,
+		},
+		{
+			name:  "Test Account Not Found",
+			accID: 1,
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("GetAccountByAccountID", mock.Anything, 1).Return(nil, nil).Once()
+			},
+			expectedKind: KindNotFound,
+		},
+		{
+			name:  "Test Account Lookup Failure",
+			accID: 1,
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("GetAccountByAccountID", mock.Anything, 1).Return(nil, errors.New("err")).Once()
+			},
+			expectedKind: kindInternal,
+		},
+		{
+			name:  "Test Balance Failure",
+			accID: 1,
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("GetAccountByAccountID", mock.Anything, 1).Return(&repository.Account{AccountID: 1}, nil).Once()
+				repo.On("GetAccountBalance", mock.Anything, 1, asOf).Return(float64(0), errors.New("err")).Once()
+			},
+			expectedKind: kindInternal,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := mocks.NewPismoRepo(t)
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(repo)
+			}
+
+			balance, err := New(repo).GetAccountBalance(context.Background(), tc.accID, asOf)
+			requireKind(t, tc.expectedKind, err)
+			require.Equal(t, tc.expectedBalance, balance)
+		})
+	}
+}
+
+func TestUpdateAccountMetadata(t *testing.T) {
+	gold := "gold"
+	tooLong := strings.Repeat("x", MaxMetadataValueLen+1)
+
+	tcs := []struct {
+		name          string
+		accID         int
+		patch         repository.AccountMetadataPatch
+		expectedMocks func(repo *mocks.PismoRepo)
+		expectedKind  Kind
+	}{
+		{
+			name:  "Test Updated",
+			accID: 1,
+			patch: repository.AccountMetadataPatch{Metadata: map[string]*string{"segment": &gold, "legacy_id": nil}, IfMatch: repository.IfMatch{3}},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("UpdateAccountMetadata", mock.Anything, 1, mock.Anything).
+					Return(&repository.Account{AccountID: 1, Metadata: repository.Metadata{"segment": "gold"}, Version: 4}, nil).Once()
+			},
+		},
+		{
+			name:         "Test Invalid Account ID",
+			accID:        -1,
+			patch:        repository.AccountMetadataPatch{Tags: []string{}},
+			expectedKind: KindInvalid,
+		},
+		{
+			name:         "Test Empty Patch",
+			accID:        1,
+			expectedKind: KindInvalid,
+		},
+		{
+			name:         "Test Reserved Prefix",
+			accID:        1,
+			patch:        repository.AccountMetadataPatch{Metadata: map[string]*string{"pismo_internal": &gold}},
+			expectedKind: KindInvalid,
+		},
+		{
+			name:         "Test Value Too Long",
+			accID:        1,
+			patch:        repository.AccountMetadataPatch{Metadata: map[string]*string{"segment": &tooLong}},
+			expectedKind: KindInvalid,
+		},
+		{
+			name:  "Test Too Many Keys",
+			accID: 1,
+			patch: repository.AccountMetadataPatch{Metadata: map[string]*string{"segment": &gold}},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("UpdateAccountMetadata", mock.Anything, 1, mock.Anything).Return(nil, repository.ErrTooManyMetadataKeys).Once()
+			},
+			expectedKind: KindInvalid,
+		},
+		{
+			name:  "Test Account Erased",
+			accID: 1,
+			patch: repository.AccountMetadataPatch{Tags: []string{}},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("UpdateAccountMetadata", mock.Anything, 1, mock.Anything).Return(nil, repository.ErrAccountErased).Once()
+			},
+			expectedKind: KindConflict,
+		},
+		{
+			name:  "Test Version Mismatch",
+			accID: 1,
+			patch: repository.AccountMetadataPatch{Tags: []string{}, IfMatch: repository.IfMatch{2}},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("UpdateAccountMetadata", mock.Anything, 1, mock.Anything).Return(nil, repository.ErrVersionMismatch).Once()
+			},
+			expectedKind: KindPreconditionFailed,
+		},
+		{
+			name:  "Test Account Not Found",
+			accID: 1,
+			patch: repository.AccountMetadataPatch{Tags: []string{}},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("UpdateAccountMetadata", mock.Anything, 1, mock.Anything).Return(nil, nil).Once()
+			},
+			expectedKind: KindNotFound,
+		},
+		{
+			name:  "Test Update Failure",
+			accID: 1,
+			patch: repository.AccountMetadataPatch{Tags: []string{}},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("UpdateAccountMetadata", mock.Anything, 1, mock.Anything).Return(nil, errors.New("err")).Once()
+			},
+			expectedKind: kindInternal,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := mocks.NewPismoRepo(t)
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(repo)
+			}
+
+			acc, err := New(repo).UpdateAccountMetadata(context.Background(), tc.accID, tc.patch)
+			requireKind(t, tc.expectedKind, err)
+			require.Equal(t, tc.expectedKind == 0, acc != nil)
+		})
+	}
+}
+
+func TestEraseAccount(t *testing.T) {
+	tcs := []struct {
+		name          string
+		accID         int
+		expectedMocks func(repo *mocks.PismoRepo)
+		expectedKind  Kind
+	}{
+		{
+			name:  "Test Erased",
+			accID: 1,
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("EraseAccount", mock.Anything, 1, true, repository.IfMatch{3}).
+					Return(&repository.AccountErasure{AccountID: 1, DryRun: true}, nil).Once()
+			},
+		},
+		{
+			name:         "Test Invalid Account ID",
+			accID:        0,
+			expectedKind: KindInvalid,
+		},
+		{
+			name:  "Test Already Erased",
+			accID: 1,
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("EraseAccount", mock.Anything, 1, true, repository.IfMatch{3}).Return(nil, repository.ErrAccountErased).Once()
+			},
+			expectedKind: KindConflict,
+		},
+		{
+			name:  "Test Balance Not Zero",
+			accID: 1,
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("EraseAccount", mock.Anything, 1, true, repository.IfMatch{3}).Return(nil, repository.ErrAccountBalanceNotZero).Once()
+			},
+			expectedKind: KindConflict,
+		},
+		{
+			name:  "Test Version Mismatch",
+			accID: 1,
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("EraseAccount", mock.Anything, 1, true, repository.IfMatch{3}).Return(nil, repository.ErrVersionMismatch).Once()
+			},
+			expectedKind: KindPreconditionFailed,
+		},
+		{
+			name:  "Test Account Not Found",
+			accID: 1,
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("EraseAccount", mock.Anything, 1, true, repository.IfMatch{3}).Return(nil, nil).Once()
+			},
+			expectedKind: KindNotFound,
+		},
+		{
+			name:  "Test Erase Failure",
+			accID: 1,
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("EraseAccount", mock.Anything, 1, true, repository.IfMatch{3}).Return(nil, errors.New("err")).Once()
+			},
+			expectedKind: kindInternal,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := mocks.NewPismoRepo(t)
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(repo)
+			}
+
+			erasure, err := New(repo).EraseAccount(context.Background(), tc.accID, true, repository.IfMatch{3})
+			requireKind(t, tc.expectedKind, err)
+			require.Equal(t, tc.expectedKind == 0, erasure != nil)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+)
+
+const (
+	// KindInvalid is a request failing validation
+	KindInvalid Kind = iota + 1
+	// KindNotFound is a request for a resource that doesn't exist
+	KindNotFound
+	// KindConflict is a request the current state of the resource doesn't allow
+	KindConflict
+	// KindPreconditionFailed is a change conditioned on a version of the resource it's no longer at
+	KindPreconditionFailed
+)
+
+type (
+	// Service holds the business rules of the api, it's shared by every transport so a request gets the same
+	// answer whichever way it comes in
+	Service interface {
+		CreateAccount(ctx context.Context, in CreateAccountInput) (acc *repository.Account, err error)
+		GetAccount(ctx context.Context, accID int) (acc *repository.Account, err error)
+		GetAccountBalance(ctx context.Context, accID int, asOf time.Time) (balance float64, err error)
+		UpdateAccountMetadata(ctx context.Context, accID int, patch repository.AccountMetadataPatch) (acc *repository.Account, err error)
+		EraseAccount(ctx context.Context, accID int, dryRun bool, ifMatch repository.IfMatch) (erasure *repository.AccountErasure, err error)
+		CreateTransaction(ctx context.Context, in CreateTransactionInput) (err error)
+		CreateTransactions(ctx context.Context, in []CreateTransactionInput, atomic bool) (results []TransactionResult, err error)
+	}
+
+	service struct {
+		repo repository.PismoRepo
+	}
+
+	// Kind is the class of failure of an Error, each transport maps it to its own status codes
+	Kind int
+
+	// Error is a failure to report to the caller, any other error returned by the service is internal. Code is
+	// the stable code of the failure and fields are the input fields failing validation, their location is left
+	// to the transport
+	Error struct {
+		Kind    Kind
+		Code    problem.Code
+		Message string
+		Fields  []problem.FieldError
+	}
+)
+
+func New(repo repository.PismoRepo) Service {
+	return &service{
+		repo,
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func invalid(code problem.Code, format string, args ...any) error {
+	return &Error{Kind: KindInvalid, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// invalidFields reports the fields failing validation, the message is the first of them
+func invalidFields(fields ...problem.FieldError) error {
+	return &Error{
+		Kind:    KindInvalid,
+		Code:    problem.CodeValidationFailed,
+		Message: fields[0].Name + " " + fields[0].Message,
+		Fields:  fields,
+	}
+}
+
+func notFound(code problem.Code, format string, args ...any) error {
+	return &Error{Kind: KindNotFound, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func conflict(code problem.Code, format string, args ...any) error {
+	return &Error{Kind: KindConflict, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func preconditionFailed(code problem.Code, format string, args ...any) error {
+	return &Error{Kind: KindPreconditionFailed, Code: code, Message: fmt.Sprintf(format, args...)}
+}
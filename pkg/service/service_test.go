@@ -0,0 +1,29 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// kindInternal stands for the errors that aren't an Error in the test cases, 0 stands for no error
+const kindInternal Kind = -1
+
+// requireKind checks the kind of failure of err
+func requireKind(t *testing.T, expected Kind, err error) {
+	t.Helper()
+
+	switch expected {
+	case 0:
+		require.NoError(t, err)
+	case kindInternal:
+		var e *Error
+		require.Error(t, err)
+		require.False(t, errors.As(err, &e), "expected an internal error, got %v", err)
+	default:
+		var e *Error
+		require.ErrorAs(t, err, &e)
+		require.Equal(t, expected, e.Kind, e.Message)
+	}
+}
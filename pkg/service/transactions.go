@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+)
+
+// maxExternalReferenceLen is the size of transactions.external_reference
+const maxExternalReferenceLen = 64
+
+// CreateTransactionInput is a new transaction, the amount is negative for purchases and withdrawals
+type CreateTransactionInput struct {
+	AccountID         int     `json:"account_id"`
+	OperationTypeID   int     `json:"operation_type_id"`
+	Amount            float64 `json:"amount"`
+	ExternalReference string  `json:"external_reference,omitempty"`
+}
+
+// CreateTransaction creates a transaction on an account that isn't erased, the account is part of the input
+// so it not existing is a validation failure
+func (s *service) CreateTransaction(ctx context.Context, in CreateTransactionInput) error {
+	operationType, err := checkTransaction(in)
+	if err != nil {
+		return err
+	}
+
+	acc, err := s.repo.GetAccountByAccountID(ctx, in.AccountID)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve the account: %w", err)
+	}
+
+	if acc == nil {
+		return invalid(problem.CodeAccountNotFound, "account not found")
+	}
+
+	if acc.ErasedAt != nil {
+		return invalid(problem.CodeAccountErased, "account is erased")
+	}
+
+	err = s.repo.CreateTransaction(ctx, repository.Transaction{
+		AccountID:         acc.AccountID,
+		OperationTypeID:   int(operationType),
+		Amount:            in.Amount,
+		ExternalReference: in.ExternalReference,
+	})
+	switch {
+	case errors.Is(err, repository.ErrDuplicateExternalReference):
+		return conflict(problem.CodeDuplicateExternalReference, "external_reference already associated with a transaction.")
+	case errors.Is(err, repository.ErrAccountErased):
+		return invalid(problem.CodeAccountErased, "account is erased")
+	case err != nil:
+		return fmt.Errorf("failed to store the transaction: %w", err)
+	}
+
+	return nil
+}
+
+// TransactionResult is the outcome of a transaction of a batch, its id once it's created or the Error it failed with
+type TransactionResult struct {
+	TransactionID int
+	Err           error
+}
+
+// CreateTransactions creates a batch of transactions validated like CreateTransaction with a single insert, the
+// results are in the order of the input. In the atomic mode none is created unless every one of them can be, the
+// ones that didn't fail are then left without an id.
+func (s *service) CreateTransactions(ctx context.Context, in []CreateTransactionInput, atomic bool) ([]TransactionResult, error) {
+	results := make([]TransactionResult, len(in))
+
+	var (
+		txns    = make([]repository.Transaction, 0, len(in))
+		indexes = make([]int, 0, len(in))
+	)
+	for i, item := range in {
+		operationType, err := checkTransaction(item)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		txns = append(txns, repository.Transaction{
+			AccountID:         item.AccountID,
+			OperationTypeID:   int(operationType),
+			Amount:            item.Amount,
+			ExternalReference: item.ExternalReference,
+		})
+		indexes = append(indexes, i)
+	}
+
+	// the accounts aren't even looked up when the batch is already rejected
+	if len(txns) == 0 || (atomic && len(txns) < len(in)) {
+		return results, nil
+	}
+
+	created, err := s.repo.CreateTransactions(ctx, txns, atomic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store the transactions: %w", err)
+	}
+
+	for j, c := range created {
+		i := indexes[j]
+		switch {
+		case c.Err == nil:
+			results[i].TransactionID = c.TransactionID
+		case errors.Is(c.Err, repository.ErrAccountNotFound):
+			results[i].Err = invalid(problem.CodeAccountNotFound, "account not found")
+		case errors.Is(c.Err, repository.ErrAccountErased):
+			results[i].Err = invalid(problem.CodeAccountErased, "account is erased")
+		case errors.Is(c.Err, repository.ErrDuplicateExternalReference):
+			results[i].Err = conflict(problem.CodeDuplicateExternalReference, "external_reference already associated with a transaction.")
+		default:
+			return nil, fmt.Errorf("failed to store the transactions: %w", c.Err)
+		}
+	}
+
+	return results, nil
+}
+
+// checkTransaction validates the input of a transaction without looking its account up, it returns its operation type
+func checkTransaction(in CreateTransactionInput) (enums.OperationType, error) {
+	if fields := validateCreateTransaction(in); len(fields) > 0 {
+		return 0, invalidFields(fields...)
+	}
+
+	operationType, err := enums.ParseOperationType(in.OperationTypeID)
+	if err != nil {
+		return 0, invalidFields(problem.FieldError{Name: "operation_type_id", Message: "is not a supported operation type"})
+	}
+
+	if in.Amount < 0 && !enums.AllowNegative(operationType) {
+		return 0, invalidFields(problem.FieldError{Name: "amount", Message: "must be positive for the operation_type_id"})
+	}
+
+	if in.Amount > 0 && enums.AllowNegative(operationType) {
+		return 0, invalidFields(problem.FieldError{Name: "amount", Message: "must be negative for the operation_type_id"})
+	}
+
+	return operationType, nil
+}
+
+// validateCreateTransaction returns every field of the input failing validation
+func validateCreateTransaction(in CreateTransactionInput) (fields []problem.FieldError) {
+	if in.AccountID <= 0 {
+		fields = append(fields, problem.FieldError{Name: "account_id", Message: "must be a positive integer"})
+	}
+	if in.Amount == 0 {
+		fields = append(fields, problem.FieldError{Name: "amount", Message: "must not be zero"})
+	}
+
+	if in.OperationTypeID <= 0 {
+		fields = append(fields, problem.FieldError{Name: "operation_type_id", Message: "must be a positive integer"})
+	}
+
+	if len(in.ExternalReference) > maxExternalReferenceLen {
+		fields = append(fields, problem.FieldError{
+			Name:    "external_reference",
+			Message: fmt.Sprintf("must be at most %d characters", maxExternalReferenceLen),
+		})
+	}
+
+	return
+}
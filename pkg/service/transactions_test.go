@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sathishs-dev/pismo-transactions/internal/meta/problem"
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/mocks"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateTransaction(t *testing.T) {
+	erasedAt := time.Date(2024, 12, 5, 10, 0, 0, 0, time.UTC)
+
+	tcs := []struct {
+		name           string
+		in             CreateTransactionInput
+		expectedMocks  func(repo *mocks.PismoRepo)
+		expectedKind   Kind
+		expectedCode   problem.Code
+		expectedFields []string
+	}{
+		{
+			name: "Test Created",
+			in:   CreateTransactionInput{AccountID: 1, OperationTypeID: int(enums.NormalPurchase), Amount: -50, ExternalReference: "ref-1"},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("GetAccountByAccountID", mock.Anything, 1).Return(&repository.Account{AccountID: 1}, nil).Once()
+				repo.On("CreateTransaction", mock.Anything, repository.Transaction{
+					AccountID: 1, OperationTypeID: int(enums.NormalPurchase), Amount: -50, ExternalReference: "ref-1",
+				}).Return(nil).Once()
+			},
+		},
+		{
+			name:           "Test Every Invalid Field",
+			in:             CreateTransactionInput{ExternalReference: strings.Repeat("x", maxExternalReferenceLen+1)},
+			expectedKind:   KindInvalid,
+			expectedCode:   problem.CodeValidationFailed,
+			expectedFields: []string{"account_id", "amount", "operation_type_id", "external_reference"},
+		},
+		{
+			name:           "Test Unsupported Operation Type",
+			in:             CreateTransactionInput{AccountID: 1, OperationTypeID: 9, Amount: 50},
+			expectedKind:   KindInvalid,
+			expectedCode:   problem.CodeValidationFailed,
+			expectedFields: []string{"operation_type_id"},
+		},
+		{
+			name:           "Test Negative Credit Voucher",
+			in:             CreateTransactionInput{AccountID: 1, OperationTypeID: int(enums.CreditVoucher), Amount: -50},
+			expectedKind:   KindInvalid,
+			expectedCode:   problem.CodeValidationFailed,
+			expectedFields: []string{"amount"},
+		},
+		{
+			name:           "Test Positive Withdrawal",
+			in:             CreateTransactionInput{AccountID: 1, OperationTypeID: int(enums.Withdrawal), Amount: 50},
+			expectedKind:   KindInvalid,
+			expectedCode:   problem.CodeValidationFailed,
+			expectedFields: []string{"amount"},
+		},
+		{
+			name: "Test Account Not Found",
+			in:   CreateTransactionInput{AccountID: 1, OperationTypeID: int(enums.CreditVoucher), Amount: 50},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("GetAccountByAccountID", mock.Anything, 1).Return(nil, nil).Once()
+			},
+			expectedKind: KindInvalid,
+			expectedCode: problem.CodeAccountNotFound,
+		},
+		{
+			name: "Test Account Erased",
+			in:   CreateTransactionInput{AccountID: 1, OperationTypeID: int(enums.CreditVoucher), Amount: 50},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("GetAccountByAccountID", mock.Anything, 1).Return(&repository.Account{AccountID: 1, ErasedAt: &erasedAt}, nil).Once()
+			},
+			expectedKind: KindInvalid,
+			expectedCode: problem.CodeAccountErased,
+		},
+		{
+			name: "Test Account Erased Concurrently",
+			in:   CreateTransactionInput{AccountID: 1, OperationTypeID: int(enums.CreditVoucher), Amount: 50},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("GetAccountByAccountID", mock.Anything, 1).Return(&repository.Account{AccountID: 1}, nil).Once()
+				repo.On("CreateTransaction", mock.Anything, mock.Anything).Return(repository.ErrAccountErased).Once()
+			},
+			expectedKind: KindInvalid,
+			expectedCode: problem.CodeAccountErased,
+		},
+		{
+			name: "Test Duplicate External Reference",
+			in:   CreateTransactionInput{AccountID: 1, OperationTypeID: int(enums.CreditVoucher), Amount: 50, ExternalReference: "ref-1"},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("GetAccountByAccountID", mock.Anything, 1).Return(&repository.Account{AccountID: 1}, nil).Once()
+				repo.On("CreateTransaction", mock.Anything, mock.Anything).Return(repository.ErrDuplicateExternalReference).Once()
+			},
+			expectedKind: KindConflict,
+			expectedCode: problem.CodeDuplicateExternalReference,
+		},
+		{
+			name: "Test Account Lookup Failure",
+			in:   CreateTransactionInput{AccountID: 1, OperationTypeID: int(enums.CreditVoucher), Amount: 50},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("GetAccountByAccountID", mock.Anything, 1).Return(nil, errors.New("err")).Once()
+			},
+			expectedKind: kindInternal,
+		},
+		{
+			name: "Test Store Failure",
+			in:   CreateTransactionInput{AccountID: 1, OperationTypeID: int(enums.CreditVoucher), Amount: 50},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("GetAccountByAccountID", mock.Anything, 1).Return(&repository.Account{AccountID: 1}, nil).Once()
+				repo.On("CreateTransaction", mock.Anything, mock.Anything).Return(errors.New("err")).Once()
+			},
+			expectedKind: kindInternal,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := mocks.NewPismoRepo(t)
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(repo)
+			}
+
+			err := New(repo).CreateTransaction(context.Background(), tc.in)
+			requireKind(t, tc.expectedKind, err)
+
+			var e *Error
+			if errors.As(err, &e) {
+				require.Equal(t, tc.expectedCode, e.Code)
+				require.Equal(t, tc.expectedFields, fieldNames(e.Fields))
+			}
+		})
+	}
+}
+
+func TestCreateTransactions(t *testing.T) {
+	valid := CreateTransactionInput{AccountID: 1, OperationTypeID: int(enums.CreditVoucher), Amount: 50}
+	invalidInput := CreateTransactionInput{AccountID: 1, OperationTypeID: int(enums.CreditVoucher), Amount: -50}
+
+	tcs := []struct {
+		name          string
+		in            []CreateTransactionInput
+		atomic        bool
+		expectedMocks func(repo *mocks.PismoRepo)
+		expectedIDs   []int
+		expectedCodes []problem.Code
+		expectedErr   bool
+	}{
+		{
+			name: "Test Partial",
+			in:   []CreateTransactionInput{valid, invalidInput, {AccountID: 2, OperationTypeID: int(enums.CreditVoucher), Amount: 10}, valid},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("CreateTransactions", mock.Anything, mock.MatchedBy(func(txns []repository.Transaction) bool {
+					return len(txns) == 3 && txns[1].AccountID == 2
+				}), false).Return([]repository.TransactionResult{
+					{Transaction: repository.Transaction{TransactionID: 10}},
+					{Err: repository.ErrAccountNotFound},
+					{Err: repository.ErrDuplicateExternalReference},
+				}, nil).Once()
+			},
+			expectedIDs:   []int{10, 0, 0, 0},
+			expectedCodes: []problem.Code{"", problem.CodeValidationFailed, problem.CodeAccountNotFound, problem.CodeDuplicateExternalReference},
+		},
+		{
+			name:          "Test Atomic Rejected Without A Lookup",
+			in:            []CreateTransactionInput{valid, invalidInput},
+			atomic:        true,
+			expectedIDs:   []int{0, 0},
+			expectedCodes: []problem.Code{"", problem.CodeValidationFailed},
+		},
+		{
+			name:          "Test Every One Invalid",
+			in:            []CreateTransactionInput{invalidInput},
+			expectedIDs:   []int{0},
+			expectedCodes: []problem.Code{problem.CodeValidationFailed},
+		},
+		{
+			name:   "Test Atomic Account Erased",
+			in:     []CreateTransactionInput{valid, valid},
+			atomic: true,
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("CreateTransactions", mock.Anything, mock.Anything, true).Return([]repository.TransactionResult{
+					{},
+					{Err: repository.ErrAccountErased},
+				}, nil).Once()
+			},
+			expectedIDs:   []int{0, 0},
+			expectedCodes: []problem.Code{"", problem.CodeAccountErased},
+		},
+		{
+			name: "Test Store Failure",
+			in:   []CreateTransactionInput{valid},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("CreateTransactions", mock.Anything, mock.Anything, false).Return(nil, errors.New("err")).Once()
+			},
+			expectedErr: true,
+		},
+		{
+			name: "Test Transaction Failure",
+			in:   []CreateTransactionInput{valid},
+			expectedMocks: func(repo *mocks.PismoRepo) {
+				repo.On("CreateTransactions", mock.Anything, mock.Anything, false).Return([]repository.TransactionResult{
+					{Err: errors.New("err")},
+				}, nil).Once()
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := mocks.NewPismoRepo(t)
+			if tc.expectedMocks != nil {
+				tc.expectedMocks(repo)
+			}
+
+			results, err := New(repo).CreateTransactions(context.Background(), tc.in, tc.atomic)
+			if tc.expectedErr {
+				requireKind(t, kindInternal, err)
+				return
+			}
+			require.NoError(t, err)
+
+			ids := make([]int, len(results))
+			codes := make([]problem.Code, len(results))
+			for i, r := range results {
+				ids[i] = r.TransactionID
+
+				var e *Error
+				if errors.As(r.Err, &e) {
+					codes[i] = e.Code
+				}
+			}
+
+			require.Equal(t, tc.expectedIDs, ids)
+			require.Equal(t, tc.expectedCodes, codes)
+		})
+	}
+}
+
+func fieldNames(fields []problem.FieldError) (names []string) {
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+
+	return
+}
@@ -0,0 +1,101 @@
+package snapshot
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+)
+
+// DefaultDelay is how long after midnight the job runs, it leaves room for the transactions of the day to land
+const DefaultDelay = 5 * time.Minute
+
+type (
+	// Job writes the closing balance of every account at midnight once a day
+	Job struct {
+		repo     repository.PismoRepo
+		delay    time.Duration
+		location *time.Location
+		now      func() time.Time
+	}
+
+	// Option - Used to configure the job during initialization
+	Option func(*Job)
+)
+
+// WithDelay - Will run the job the given duration after midnight
+func WithDelay(d time.Duration) Option {
+	return func(j *Job) {
+		j.delay = d
+	}
+}
+
+// WithLocation - Will take the snapshots at midnight of the given location instead of UTC
+func WithLocation(l *time.Location) Option {
+	return func(j *Job) {
+		j.location = l
+	}
+}
+
+// NewJob configures and returns the snapshot Job
+func NewJob(repo repository.PismoRepo, opts ...Option) *Job {
+	j := &Job{
+		repo:     repo,
+		delay:    DefaultDelay,
+		location: time.UTC,
+		now:      time.Now,
+	}
+
+	for _, o := range opts {
+		o(j)
+	}
+
+	return j
+}
+
+// Start runs the job every night until the context is cancelled, the snapshot of the current day is
+// taken right away so a restart doesn't skip a day
+func (j *Job) Start(ctx context.Context) {
+	for {
+		if err := j.Run(ctx); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("failed to create balance snapshots")
+		}
+
+		timer := time.NewTimer(j.nextRun().Sub(j.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Run writes the snapshots for the last midnight
+func (j *Job) Run(ctx context.Context) error {
+	at := j.cutoff()
+
+	count, err := j.repo.CreateBalanceSnapshots(ctx, at)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Time("snapshot_at", at).Int64("accounts", count).Msg("balance snapshots created")
+	return nil
+}
+
+// cutoff returns the last midnight, the snapshot holds every transaction before it
+func (j *Job) cutoff() time.Time {
+	now := j.now().In(j.location)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, j.location)
+}
+
+// nextRun returns the time of the next run
+func (j *Job) nextRun() time.Time {
+	next := j.cutoff().Add(j.delay)
+	if !next.After(j.now()) {
+		next = j.cutoff().AddDate(0, 0, 1).Add(j.delay)
+	}
+	return next
+}
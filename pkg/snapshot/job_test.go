@@ -0,0 +1,90 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobRun(t *testing.T) {
+	repo := mocks.NewPismoRepo(t)
+	j := NewJob(repo)
+	j.now = func() time.Time { return time.Date(2024, 12, 5, 0, 7, 0, 0, time.UTC) }
+
+	repo.On("CreateBalanceSnapshots", mock.Anything, time.Date(2024, 12, 5, 0, 0, 0, 0, time.UTC)).
+		Return(int64(3), nil).Once()
+	require.NoError(t, j.Run(context.Background()))
+
+	repo.On("CreateBalanceSnapshots", mock.Anything, mock.Anything).
+		Return(int64(0), errors.New("err")).Once()
+	require.Error(t, j.Run(context.Background()))
+}
+
+func TestJobSchedule(t *testing.T) {
+	saoPaulo := time.FixedZone("BRT", -3*60*60)
+
+	tcs := []struct {
+		name           string
+		now            time.Time
+		opts           []Option
+		expectedCutoff time.Time
+		expectedNext   time.Time
+	}{
+		{
+			name:           "Test Before The Delay",
+			now:            time.Date(2024, 12, 5, 0, 2, 0, 0, time.UTC),
+			expectedCutoff: time.Date(2024, 12, 5, 0, 0, 0, 0, time.UTC),
+			expectedNext:   time.Date(2024, 12, 5, 0, 5, 0, 0, time.UTC),
+		},
+		{
+			name:           "Test After The Delay",
+			now:            time.Date(2024, 12, 5, 13, 0, 0, 0, time.UTC),
+			expectedCutoff: time.Date(2024, 12, 5, 0, 0, 0, 0, time.UTC),
+			expectedNext:   time.Date(2024, 12, 6, 0, 5, 0, 0, time.UTC),
+		},
+		{
+			name:           "Test Custom Location And Delay",
+			now:            time.Date(2024, 12, 5, 2, 0, 0, 0, time.UTC),
+			opts:           []Option{WithLocation(saoPaulo), WithDelay(time.Hour)},
+			expectedCutoff: time.Date(2024, 12, 4, 0, 0, 0, 0, saoPaulo),
+			expectedNext:   time.Date(2024, 12, 5, 1, 0, 0, 0, saoPaulo),
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			j := NewJob(mocks.NewPismoRepo(t), tc.opts...)
+			j.now = func() time.Time { return tc.now }
+
+			require.True(t, tc.expectedCutoff.Equal(j.cutoff()), j.cutoff())
+			require.True(t, tc.expectedNext.Equal(j.nextRun()), j.nextRun())
+		})
+	}
+}
+
+func TestJobStartStopsWithContext(t *testing.T) {
+	repo := mocks.NewPismoRepo(t)
+	j := NewJob(repo)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	repo.On("CreateBalanceSnapshots", mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) { cancel() }).
+		Return(int64(1), nil).Once()
+
+	done := make(chan struct{})
+	go func() {
+		j.Start(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("job didn't stop after the context was cancelled")
+	}
+}
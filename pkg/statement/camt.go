@@ -0,0 +1,229 @@
+package statement
+
+import (
+	"embed"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+)
+
+const (
+	camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.08"
+	camt054Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.054.001.08"
+
+	camtCredit = "CRDT"
+	camtDebit  = "DBIT"
+)
+
+// Schemas holds the published ISO 20022 schemas of the camt messages produced by this package
+//
+//go:embed schema/*.xsd
+var Schemas embed.FS
+
+type (
+	camtDocument struct {
+		XMLName xml.Name
+		Stmt    *camtMessage `xml:"BkToCstmrStmt,omitempty"`
+		Ntfctn  *camtMessage `xml:"BkToCstmrDbtCdtNtfctn,omitempty"`
+	}
+
+	camtMessage struct {
+		GrpHdr camtGroupHeader `xml:"GrpHdr"`
+		Stmt   *camtReport     `xml:"Stmt,omitempty"`
+		Ntfctn *camtReport     `xml:"Ntfctn,omitempty"`
+	}
+
+	camtGroupHeader struct {
+		MsgID   string `xml:"MsgId"`
+		CreDtTm string `xml:"CreDtTm"`
+	}
+
+	// camtReport is the AccountStatement9 of camt.053 and the AccountNotification17 of camt.054,
+	// both share the same leading elements and only statements carry balances
+	camtReport struct {
+		ID      string        `xml:"Id"`
+		CreDtTm string        `xml:"CreDtTm"`
+		FrToDt  camtPeriod    `xml:"FrToDt"`
+		Acct    camtAccount   `xml:"Acct"`
+		Bal     []camtBalance `xml:"Bal,omitempty"`
+		Ntry    []camtEntry   `xml:"Ntry"`
+	}
+
+	camtPeriod struct {
+		FrDtTm string `xml:"FrDtTm"`
+		ToDtTm string `xml:"ToDtTm"`
+	}
+
+	camtAccount struct {
+		ID  string `xml:"Id>Othr>Id"`
+		Ccy string `xml:"Ccy"`
+	}
+
+	camtAmount struct {
+		Ccy   string `xml:"Ccy,attr"`
+		Value string `xml:",chardata"`
+	}
+
+	camtDateTime struct {
+		DtTm string `xml:"DtTm"`
+	}
+
+	camtBalance struct {
+		Tp        string       `xml:"Tp>CdOrPrtry>Cd"`
+		Amt       camtAmount   `xml:"Amt"`
+		CdtDbtInd string       `xml:"CdtDbtInd"`
+		Dt        camtDateTime `xml:"Dt"`
+	}
+
+	camtEntry struct {
+		NtryRef      string       `xml:"NtryRef"`
+		Amt          camtAmount   `xml:"Amt"`
+		CdtDbtInd    string       `xml:"CdtDbtInd"`
+		Sts          string       `xml:"Sts>Cd"`
+		BookgDt      camtDateTime `xml:"BookgDt"`
+		AcctSvcrRef  string       `xml:"AcctSvcrRef"`
+		BkTxCd       camtTxCode   `xml:"BkTxCd>Prtry"`
+		AddtlNtryInf string       `xml:"AddtlNtryInf,omitempty"`
+	}
+
+	camtTxCode struct {
+		Cd   string `xml:"Cd"`
+		Issr string `xml:"Issr"`
+	}
+)
+
+// camtWriter writes the statement as an ISO 20022 camt.053 statement or camt.054 notification,
+// the balances of a statement precede its entries so the entries are kept in memory until Close
+type camtWriter struct {
+	w       io.Writer
+	format  Format
+	info    Info
+	ledger  ledger
+	entries []camtEntry
+}
+
+func newCAMTWriter(f Format, w io.Writer, info Info) *camtWriter {
+	return &camtWriter{
+		w:      w,
+		format: f,
+		info:   info,
+		ledger: ledger{balance: roundCents(info.OpeningBalance)},
+	}
+}
+
+// camtTime formats the time as an ISODateTime in UTC
+func camtTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// camtIndicator returns the credit/debit indicator of an amount, debits are negative balances
+func camtIndicator(amount float64) string {
+	if amount < 0 {
+		return camtDebit
+	}
+	return camtCredit
+}
+
+func (c *camtWriter) amount(f float64) camtAmount {
+	return camtAmount{Ccy: c.info.Currency, Value: formatAmount(math.Abs(f))}
+}
+
+// Write converts the entry to a booked ReportEntry10, the credit/debit indicator comes from the operation type
+func (c *camtWriter) Write(e Entry) error {
+	c.ledger.apply(e.Amount)
+
+	indicator := camtCredit
+	if enums.AllowNegative(e.OperationType) {
+		indicator = camtDebit
+	}
+
+	// the bank transaction code can't be empty, fall back to the operation type id
+	code := truncate(e.Memo, 35)
+	if code == "" {
+		code = fmt.Sprintf("%d", e.OperationType)
+	}
+
+	ref := fmt.Sprintf("%d", e.TransactionID)
+	c.entries = append(c.entries, camtEntry{
+		NtryRef:      ref,
+		Amt:          c.amount(e.Amount),
+		CdtDbtInd:    indicator,
+		Sts:          "BOOK",
+		BookgDt:      camtDateTime{DtTm: camtTime(e.EventDate)},
+		AcctSvcrRef:  ref,
+		BkTxCd:       camtTxCode{Cd: code, Issr: DefaultBankID},
+		AddtlNtryInf: truncate(e.Memo, 500),
+	})
+
+	return nil
+}
+
+// Close builds the document and writes it out
+func (c *camtWriter) Close() error {
+	from := c.info.From
+	if from.IsZero() {
+		from = time.Unix(0, 0)
+	}
+	to := c.info.To
+	if to.IsZero() {
+		to = c.info.GeneratedAt
+	}
+
+	created := camtTime(c.info.GeneratedAt)
+	id := fmt.Sprintf("%d-%d", c.info.AccountID, c.info.GeneratedAt.Unix())
+
+	report := &camtReport{
+		ID:      id,
+		CreDtTm: created,
+		FrToDt:  camtPeriod{FrDtTm: camtTime(from), ToDtTm: camtTime(to)},
+		Acct:    camtAccount{ID: fmt.Sprintf("%d", c.info.AccountID), Ccy: c.info.Currency},
+		Ntry:    c.entries,
+	}
+	msg := &camtMessage{
+		GrpHdr: camtGroupHeader{MsgID: fmt.Sprintf("%s-%s", DefaultBankID, id), CreDtTm: created},
+	}
+	doc := camtDocument{}
+
+	switch c.format {
+	case FormatCAMT054:
+		doc.XMLName = xml.Name{Space: camt054Namespace, Local: "Document"}
+		doc.Ntfctn = msg
+		msg.Ntfctn = report
+	default:
+		doc.XMLName = xml.Name{Space: camt053Namespace, Local: "Document"}
+		doc.Stmt = msg
+		msg.Stmt = report
+
+		opening := roundCents(c.info.OpeningBalance)
+		report.Bal = []camtBalance{
+			{Tp: "OPBD", Amt: c.amount(opening), CdtDbtInd: camtIndicator(opening), Dt: camtDateTime{DtTm: camtTime(from)}},
+			{Tp: "CLBD", Amt: c.amount(c.ledger.balance), CdtDbtInd: camtIndicator(c.ledger.balance), Dt: camtDateTime{DtTm: camtTime(to)}},
+		}
+	}
+
+	if _, err := io.WriteString(c.w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(c.w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(c.w, "\n")
+	return err
+}
+
+// truncate cuts s down to n runes, the camt text types are length restricted
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
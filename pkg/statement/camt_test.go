@@ -0,0 +1,143 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/stretchr/testify/require"
+)
+
+func camtEntries() []Entry {
+	entries := make([]Entry, len(testEntries))
+	copy(entries, testEntries)
+
+	entries[0].OperationType = enums.NormalPurchase
+	entries[1].OperationType = enums.CreditVoucher
+
+	return entries
+}
+
+func writeCAMT(t *testing.T, f Format, entries []Entry) []byte {
+	var buf bytes.Buffer
+	w := NewWriter(f, &buf, testInfo)
+
+	for _, e := range entries {
+		require.NoError(t, w.Write(e))
+	}
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func schemaValidator(t *testing.T, name string) *xsdValidator {
+	schema, err := Schemas.ReadFile("schema/" + name)
+	require.NoError(t, err)
+
+	v, err := newXSDValidator(schema)
+	require.NoError(t, err)
+
+	return v
+}
+
+func TestCAMTWriterValidatesAgainstSchema(t *testing.T) {
+	tcs := []struct {
+		name    string
+		format  Format
+		schema  string
+		entries []Entry
+	}{
+		{
+			name:    "Test camt.053 Statement",
+			format:  FormatCAMT053,
+			schema:  "camt.053.001.08.xsd",
+			entries: camtEntries(),
+		},
+		{
+			name:   "Test camt.053 Statement Without Entries",
+			format: FormatCAMT053,
+			schema: "camt.053.001.08.xsd",
+		},
+		{
+			name:    "Test camt.054 Notification",
+			format:  FormatCAMT054,
+			schema:  "camt.054.001.08.xsd",
+			entries: camtEntries(),
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			doc := writeCAMT(t, tc.format, tc.entries)
+
+			require.NoError(t, schemaValidator(t, tc.schema).Validate(doc), string(doc))
+		})
+	}
+}
+
+func TestCAMT053Content(t *testing.T) {
+	doc := writeCAMT(t, FormatCAMT053, camtEntries())
+
+	var parsed struct {
+		Bal []struct {
+			Tp        string `xml:"Tp>CdOrPrtry>Cd"`
+			Amt       string `xml:"Amt"`
+			CdtDbtInd string `xml:"CdtDbtInd"`
+		} `xml:"BkToCstmrStmt>Stmt>Bal"`
+		Ntry []struct {
+			Amt       string `xml:"Amt"`
+			CdtDbtInd string `xml:"CdtDbtInd"`
+			BookgDt   string `xml:"BookgDt>DtTm"`
+		} `xml:"BkToCstmrStmt>Stmt>Ntry"`
+	}
+	require.NoError(t, xml.Unmarshal(doc, &parsed))
+
+	require.Len(t, parsed.Bal, 2)
+	require.Equal(t, "OPBD", parsed.Bal[0].Tp)
+	require.Equal(t, "100.00", parsed.Bal[0].Amt)
+	require.Equal(t, "CLBD", parsed.Bal[1].Tp)
+	require.Equal(t, "110.10", parsed.Bal[1].Amt)
+	require.Equal(t, "CRDT", parsed.Bal[1].CdtDbtInd)
+
+	require.Len(t, parsed.Ntry, 2)
+	require.Equal(t, "50.10", parsed.Ntry[0].Amt)
+	require.Equal(t, "DBIT", parsed.Ntry[0].CdtDbtInd)
+	require.Equal(t, "2024-12-05T12:30:00Z", parsed.Ntry[0].BookgDt)
+	require.Equal(t, "CRDT", parsed.Ntry[1].CdtDbtInd)
+}
+
+func TestSchemaValidatorRejectsInvalidDocuments(t *testing.T) {
+	doc := string(writeCAMT(t, FormatCAMT053, camtEntries()))
+
+	tcs := []struct {
+		name string
+		doc  string
+	}{
+		{
+			name: "Test Missing Required Element",
+			doc:  strings.Replace(doc, "<CdtDbtInd>DBIT</CdtDbtInd>", "", 1),
+		},
+		{
+			name: "Test Invalid Enumeration",
+			doc:  strings.Replace(doc, "<CdtDbtInd>DBIT</CdtDbtInd>", "<CdtDbtInd>DEBIT</CdtDbtInd>", 1),
+		},
+		{
+			name: "Test Invalid Currency",
+			doc:  strings.Replace(doc, `Ccy="BRL"`, `Ccy="real"`, 1),
+		},
+		{
+			name: "Test Wrong Namespace",
+			doc:  strings.Replace(doc, camt053Namespace, camt054Namespace, 1),
+		},
+	}
+
+	v := schemaValidator(t, "camt.053.001.08.xsd")
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NotEqual(t, doc, tc.doc)
+			require.Error(t, v.Validate([]byte(tc.doc)))
+		})
+	}
+}
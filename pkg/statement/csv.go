@@ -0,0 +1,70 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+var csvHeader = []string{"transaction_id", "event_date", "description", "amount", "balance"}
+
+// csvWriter writes the statement as RFC 4180 CSV with a header row
+type csvWriter struct {
+	w      *csv.Writer
+	ledger ledger
+	header bool
+}
+
+func newCSVWriter(w io.Writer, info Info) *csvWriter {
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true
+
+	return &csvWriter{
+		w:      cw,
+		ledger: ledger{balance: roundCents(info.OpeningBalance)},
+	}
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+
+	return c.w.Write(csvHeader)
+}
+
+// Write writes the entry along with the running balance
+func (c *csvWriter) Write(e Entry) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	balance := c.ledger.apply(e.Amount)
+
+	err := c.w.Write([]string{
+		strconv.Itoa(e.TransactionID),
+		e.EventDate.UTC().Format(time.RFC3339),
+		e.Memo,
+		formatAmount(e.Amount),
+		formatAmount(balance),
+	})
+	if err != nil {
+		return err
+	}
+
+	// flush on every row so the client receives the rows as they are read
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// Close writes the header for empty statements and flushes the buffered data
+func (c *csvWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,131 @@
+package statement
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+`
+
+// ofxWriter writes the statement as an OFX 2.2 bank statement response
+type ofxWriter struct {
+	w       *bufio.Writer
+	info    Info
+	ledger  ledger
+	started bool
+}
+
+func newOFXWriter(w io.Writer, info Info) *ofxWriter {
+	return &ofxWriter{
+		w:      bufio.NewWriter(w),
+		info:   info,
+		ledger: ledger{balance: roundCents(info.OpeningBalance)},
+	}
+}
+
+// ofxTime formats the time as the OFX datetime in GMT
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:GMT]"
+}
+
+// ofxText escapes the value to be placed in an element
+func ofxText(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func (o *ofxWriter) begin() error {
+	if o.started {
+		return nil
+	}
+	o.started = true
+
+	from := o.info.From
+	if from.IsZero() {
+		from = time.Unix(0, 0)
+	}
+	to := o.info.To
+	if to.IsZero() {
+		to = o.info.GeneratedAt
+	}
+
+	_, err := fmt.Fprintf(o.w, ofxHeader+
+		"<OFX>\n"+
+		"<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>"+
+		"<DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>\n"+
+		"<BANKMSGSRSV1><STMTTRNRS><TRNUID>0</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n"+
+		"<STMTRS><CURDEF>%s</CURDEF>\n"+
+		"<BANKACCTFROM><BANKID>%s</BANKID><ACCTID>%d</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>\n"+
+		"<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>\n",
+		ofxTime(o.info.GeneratedAt),
+		ofxText(o.info.Currency),
+		DefaultBankID,
+		o.info.AccountID,
+		ofxTime(from),
+		ofxTime(to),
+	)
+
+	return err
+}
+
+// Write writes the entry as a STMTTRN aggregate using the entry memo as MEMO
+func (o *ofxWriter) Write(e Entry) error {
+	if err := o.begin(); err != nil {
+		return err
+	}
+
+	o.ledger.apply(e.Amount)
+
+	trnType := "CREDIT"
+	if e.Amount < 0 {
+		trnType = "DEBIT"
+	}
+
+	_, err := fmt.Fprintf(o.w,
+		"<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT><FITID>%s</FITID><MEMO>%s</MEMO></STMTTRN>\n",
+		trnType,
+		ofxTime(e.EventDate),
+		formatAmount(e.Amount),
+		strconv.Itoa(e.TransactionID),
+		ofxText(e.Memo),
+	)
+	if err != nil {
+		return err
+	}
+
+	return o.w.Flush()
+}
+
+// Close closes the transaction list and writes the ledger balance at the end of the period
+func (o *ofxWriter) Close() error {
+	if err := o.begin(); err != nil {
+		return err
+	}
+
+	asOf := o.info.To
+	if asOf.IsZero() {
+		asOf = o.info.GeneratedAt
+	}
+
+	_, err := fmt.Fprintf(o.w,
+		"</BANKTRANLIST>\n"+
+			"<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>\n"+
+			"</STMTRS></STMTTRNRS></BANKMSGSRSV1>\n"+
+			"</OFX>\n",
+		formatAmount(o.ledger.balance),
+		ofxTime(asOf),
+	)
+	if err != nil {
+		return err
+	}
+
+	return o.w.Flush()
+}
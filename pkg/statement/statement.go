@@ -0,0 +1,126 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+)
+
+type Format string
+
+const (
+	FormatCSV Format = "csv"
+	FormatOFX Format = "ofx"
+	// FormatCAMT053 is the ISO 20022 bank to customer statement
+	FormatCAMT053 Format = "camt053"
+	// FormatCAMT054 is the ISO 20022 bank to customer debit/credit notification
+	FormatCAMT054 Format = "camt054"
+
+	DefaultCurrency = "BRL"
+	DefaultBankID   = "PISMO"
+)
+
+type (
+	// Info holds the statement level details written before and after the transactions
+	Info struct {
+		AccountID      int
+		From           time.Time
+		To             time.Time
+		OpeningBalance float64
+		Currency       string
+		GeneratedAt    time.Time
+	}
+
+	// Entry is a single transaction line of the statement
+	Entry struct {
+		TransactionID int
+		EventDate     time.Time
+		OperationType enums.OperationType
+		Memo          string
+		Amount        float64
+	}
+
+	// Writer streams the statement entries to the underlying io.Writer in the order they are written,
+	// Close must be called once every entry has been written to finish the document
+	Writer interface {
+		Write(e Entry) error
+		Close() error
+	}
+)
+
+// ParseFormat validates the requested export format
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatCSV, FormatOFX, FormatCAMT053, FormatCAMT054:
+		return f, nil
+	}
+
+	return "", fmt.Errorf("%q is not a valid statement format", s)
+}
+
+// ContentType returns the media type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatOFX:
+		return "application/x-ofx"
+	case FormatCAMT053, FormatCAMT054:
+		return "application/xml"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// Extension returns the file extension used for the format
+func (f Format) Extension() string {
+	switch f {
+	case FormatCAMT053, FormatCAMT054:
+		return "xml"
+	default:
+		return string(f)
+	}
+}
+
+// NewWriter returns the statement writer for the given format
+func NewWriter(f Format, w io.Writer, info Info) Writer {
+	if info.Currency == "" {
+		info.Currency = DefaultCurrency
+	}
+	if info.GeneratedAt.IsZero() {
+		info.GeneratedAt = time.Now()
+	}
+
+	switch f {
+	case FormatOFX:
+		return newOFXWriter(w, info)
+	case FormatCAMT053, FormatCAMT054:
+		return newCAMTWriter(f, w, info)
+	default:
+		return newCSVWriter(w, info)
+	}
+}
+
+// ledger keeps the running balance of the statement
+type ledger struct {
+	balance float64
+}
+
+// apply adds the amount to the running balance and returns the new balance
+func (l *ledger) apply(amount float64) float64 {
+	l.balance = roundCents(l.balance + amount)
+	return l.balance
+}
+
+// roundCents drops the floating point noise accumulated while summing amounts
+func roundCents(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+// formatAmount formats the amount with two decimal places
+func formatAmount(f float64) string {
+	return strconv.FormatFloat(roundCents(f), 'f', 2, 64)
+}
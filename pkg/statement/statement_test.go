@@ -0,0 +1,152 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testInfo = Info{
+		AccountID:      1,
+		From:           time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+		To:             time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		OpeningBalance: 100,
+		GeneratedAt:    time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC),
+	}
+
+	testEntries = []Entry{
+		{
+			TransactionID: 1,
+			EventDate:     time.Date(2024, 12, 5, 12, 30, 0, 0, time.UTC),
+			Memo:          "Normal Purchase",
+			Amount:        -50.1,
+		},
+		{
+			TransactionID: 2,
+			EventDate:     time.Date(2024, 12, 6, 8, 0, 0, 0, time.UTC),
+			Memo:          "Credit Voucher, \"refund\" & co",
+			Amount:        60.2,
+		},
+	}
+)
+
+func TestParseFormat(t *testing.T) {
+	tcs := []struct {
+		name           string
+		format         string
+		expectedFormat Format
+		expectErr      bool
+	}{
+		{
+			name:           "Test ParseFormat_CSV",
+			format:         "csv",
+			expectedFormat: FormatCSV,
+		},
+		{
+			name:           "Test ParseFormat_OFX_UpperCase",
+			format:         "OFX",
+			expectedFormat: FormatOFX,
+		},
+		{
+			name:      "Test ParseFormat_Failure",
+			format:    "pdf",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := ParseFormat(tc.format)
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedFormat, f)
+		})
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(FormatCSV, &buf, testInfo)
+
+	for _, e := range testEntries {
+		require.NoError(t, w.Write(e))
+	}
+	require.NoError(t, w.Close())
+
+	require.Contains(t, buf.String(), "\r\n")
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"transaction_id", "event_date", "description", "amount", "balance"},
+		{"1", "2024-12-05T12:30:00Z", "Normal Purchase", "-50.10", "49.90"},
+		{"2", "2024-12-06T08:00:00Z", "Credit Voucher, \"refund\" & co", "60.20", "110.10"},
+	}, records)
+}
+
+func TestCSVWriterWithoutEntries(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(FormatCSV, &buf, testInfo)
+	require.NoError(t, w.Close())
+
+	require.Equal(t, "transaction_id,event_date,description,amount,balance\r\n", buf.String())
+}
+
+func TestOFXWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(FormatOFX, &buf, testInfo)
+
+	for _, e := range testEntries {
+		require.NoError(t, w.Write(e))
+	}
+	require.NoError(t, w.Close())
+
+	out := buf.String()
+	require.True(t, strings.HasPrefix(out, `<?xml version="1.0"`))
+	require.Contains(t, out, `<?OFX OFXHEADER="200" VERSION="220"`)
+
+	// the document has to be well formed xml
+	dec := xml.NewDecoder(strings.NewReader(out))
+	for {
+		_, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+
+	var doc struct {
+		Currency string `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>CURDEF"`
+		AcctID   string `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>BANKACCTFROM>ACCTID"`
+		Txns     []struct {
+			Type   string `xml:"TRNTYPE"`
+			Posted string `xml:"DTPOSTED"`
+			Amount string `xml:"TRNAMT"`
+			FitID  string `xml:"FITID"`
+			Memo   string `xml:"MEMO"`
+		} `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>BANKTRANLIST>STMTTRN"`
+		Balance string `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>LEDGERBAL>BALAMT"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+
+	require.Equal(t, DefaultCurrency, doc.Currency)
+	require.Equal(t, "1", doc.AcctID)
+	require.Len(t, doc.Txns, 2)
+	require.Equal(t, "DEBIT", doc.Txns[0].Type)
+	require.Equal(t, "20241205123000.000[0:GMT]", doc.Txns[0].Posted)
+	require.Equal(t, "-50.10", doc.Txns[0].Amount)
+	require.Equal(t, "CREDIT", doc.Txns[1].Type)
+	require.Equal(t, "Credit Voucher, \"refund\" & co", doc.Txns[1].Memo)
+	require.Equal(t, "110.10", doc.Balance)
+}
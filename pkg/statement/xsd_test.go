@@ -0,0 +1,382 @@
+package statement
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xsdValidator validates xml documents against the subset of XML Schema used by the ISO 20022 message schemas,
+// sequences, choices, simple content and restricted simple types, so the tests run without external tooling
+type xsdValidator struct {
+	namespace    string
+	elements     map[string]string
+	complexTypes map[string]xsdComplexType
+	simpleTypes  map[string]xsdSimpleType
+}
+
+type (
+	xsdSchema struct {
+		TargetNamespace string           `xml:"targetNamespace,attr"`
+		Elements        []xsdElement     `xml:"element"`
+		ComplexTypes    []xsdComplexType `xml:"complexType"`
+		SimpleTypes     []xsdSimpleType  `xml:"simpleType"`
+	}
+
+	xsdElement struct {
+		Name      string `xml:"name,attr"`
+		Type      string `xml:"type,attr"`
+		MinOccurs string `xml:"minOccurs,attr"`
+		MaxOccurs string `xml:"maxOccurs,attr"`
+	}
+
+	xsdGroup struct {
+		Elements []xsdElement `xml:"element"`
+		Any      []struct{}   `xml:"any"`
+	}
+
+	xsdAttribute struct {
+		Name string `xml:"name,attr"`
+		Type string `xml:"type,attr"`
+		Use  string `xml:"use,attr"`
+	}
+
+	xsdComplexType struct {
+		Name          string    `xml:"name,attr"`
+		Sequence      *xsdGroup `xml:"sequence"`
+		Choice        *xsdGroup `xml:"choice"`
+		SimpleContent *struct {
+			Extension struct {
+				Base       string         `xml:"base,attr"`
+				Attributes []xsdAttribute `xml:"attribute"`
+			} `xml:"extension"`
+		} `xml:"simpleContent"`
+	}
+
+	xsdFacet struct {
+		Value string `xml:"value,attr"`
+	}
+
+	xsdSimpleType struct {
+		Name        string `xml:"name,attr"`
+		Restriction struct {
+			Base           string     `xml:"base,attr"`
+			Enumeration    []xsdFacet `xml:"enumeration"`
+			Pattern        []xsdFacet `xml:"pattern"`
+			MinLength      *xsdFacet  `xml:"minLength"`
+			MaxLength      *xsdFacet  `xml:"maxLength"`
+			TotalDigits    *xsdFacet  `xml:"totalDigits"`
+			FractionDigits *xsdFacet  `xml:"fractionDigits"`
+			MinInclusive   *xsdFacet  `xml:"minInclusive"`
+		} `xml:"restriction"`
+	}
+
+	xmlNode struct {
+		name     xml.Name
+		attrs    []xml.Attr
+		children []*xmlNode
+		text     strings.Builder
+	}
+)
+
+func newXSDValidator(schema []byte) (*xsdValidator, error) {
+	var s xsdSchema
+	if err := xml.Unmarshal(schema, &s); err != nil {
+		return nil, err
+	}
+
+	v := &xsdValidator{
+		namespace:    s.TargetNamespace,
+		elements:     map[string]string{},
+		complexTypes: map[string]xsdComplexType{},
+		simpleTypes:  map[string]xsdSimpleType{},
+	}
+	for _, e := range s.Elements {
+		v.elements[e.Name] = e.Type
+	}
+	for _, ct := range s.ComplexTypes {
+		v.complexTypes[ct.Name] = ct
+	}
+	for _, st := range s.SimpleTypes {
+		v.simpleTypes[st.Name] = st
+	}
+
+	return v, nil
+}
+
+// Validate parses the document and validates it against the schema
+func (v *xsdValidator) Validate(doc []byte) error {
+	root, err := parseXMLTree(doc)
+	if err != nil {
+		return err
+	}
+
+	typ, ok := v.elements[root.name.Local]
+	if !ok {
+		return fmt.Errorf("undeclared root element %s", root.name.Local)
+	}
+
+	return v.validateElement(root, typ, "/"+root.name.Local)
+}
+
+func (v *xsdValidator) validateElement(n *xmlNode, typ, path string) error {
+	if n.name.Space != v.namespace {
+		return fmt.Errorf("%s: expected namespace %s, got %q", path, v.namespace, n.name.Space)
+	}
+
+	ct, ok := v.complexTypes[typ]
+	if !ok {
+		if len(n.children) > 0 {
+			return fmt.Errorf("%s: simple type %s can't have child elements", path, typ)
+		}
+		return v.validateSimple(n.text.String(), typ, path)
+	}
+
+	switch {
+	case ct.SimpleContent != nil:
+		if len(n.children) > 0 {
+			return fmt.Errorf("%s: simple content can't have child elements", path)
+		}
+		for _, a := range ct.SimpleContent.Extension.Attributes {
+			val, found := nodeAttr(n, a.Name)
+			if !found {
+				if a.Use == "required" {
+					return fmt.Errorf("%s: missing required attribute %s", path, a.Name)
+				}
+				continue
+			}
+			if err := v.validateSimple(val, a.Type, path+"@"+a.Name); err != nil {
+				return err
+			}
+		}
+		return v.validateSimple(n.text.String(), ct.SimpleContent.Extension.Base, path)
+	case ct.Sequence != nil:
+		return v.validateSequence(n, ct.Sequence, path)
+	case ct.Choice != nil:
+		return v.validateChoice(n, ct.Choice, path)
+	}
+
+	if len(n.children) > 0 {
+		return fmt.Errorf("%s: empty type %s can't have child elements", path, typ)
+	}
+	return nil
+}
+
+func (v *xsdValidator) validateSequence(n *xmlNode, g *xsdGroup, path string) error {
+	if len(g.Any) > 0 {
+		// wildcards are processed laxly, anything goes
+		return nil
+	}
+
+	i := 0
+	for _, decl := range g.Elements {
+		minOccurs, maxOccurs := occurs(decl)
+
+		count := 0
+		for i < len(n.children) && n.children[i].name.Local == decl.Name && (maxOccurs < 0 || count < maxOccurs) {
+			child := n.children[i]
+			if err := v.validateElement(child, decl.Type, fmt.Sprintf("%s/%s", path, decl.Name)); err != nil {
+				return err
+			}
+			count++
+			i++
+		}
+
+		if count < minOccurs {
+			return fmt.Errorf("%s: expected element %s", path, decl.Name)
+		}
+	}
+
+	if i < len(n.children) {
+		return fmt.Errorf("%s: unexpected element %s", path, n.children[i].name.Local)
+	}
+
+	return nil
+}
+
+func (v *xsdValidator) validateChoice(n *xmlNode, g *xsdGroup, path string) error {
+	if len(n.children) != 1 {
+		return fmt.Errorf("%s: choice expects exactly one element, got %d", path, len(n.children))
+	}
+
+	child := n.children[0]
+	for _, decl := range g.Elements {
+		if decl.Name == child.name.Local {
+			return v.validateElement(child, decl.Type, fmt.Sprintf("%s/%s", path, decl.Name))
+		}
+	}
+
+	return fmt.Errorf("%s: unexpected element %s", path, child.name.Local)
+}
+
+func (v *xsdValidator) validateSimple(val, typ, path string) error {
+	if strings.HasPrefix(typ, "xs:") {
+		return validateBuiltin(val, typ, path)
+	}
+
+	st, ok := v.simpleTypes[typ]
+	if !ok {
+		return fmt.Errorf("%s: unknown type %s", path, typ)
+	}
+	r := st.Restriction
+
+	if err := v.validateSimple(val, r.Base, path); err != nil {
+		return err
+	}
+
+	if len(r.Enumeration) > 0 {
+		found := false
+		for _, e := range r.Enumeration {
+			found = found || e.Value == val
+		}
+		if !found {
+			return fmt.Errorf("%s: %q is not one of the %s values", path, val, typ)
+		}
+	}
+
+	for _, p := range r.Pattern {
+		if !regexp.MustCompile("^(?:" + p.Value + ")$").MatchString(val) {
+			return fmt.Errorf("%s: %q doesn't match the %s pattern", path, val, typ)
+		}
+	}
+
+	length := len([]rune(val))
+	if r.MinLength != nil && length < atoi(r.MinLength.Value) {
+		return fmt.Errorf("%s: %q is shorter than the %s min length", path, val, typ)
+	}
+	if r.MaxLength != nil && length > atoi(r.MaxLength.Value) {
+		return fmt.Errorf("%s: %q is longer than the %s max length", path, val, typ)
+	}
+
+	intPart, fracPart, _ := strings.Cut(strings.TrimLeft(val, "+-"), ".")
+	if r.TotalDigits != nil && len(strings.TrimLeft(intPart, "0"))+len(fracPart) > atoi(r.TotalDigits.Value) {
+		return fmt.Errorf("%s: %q has more than %s total digits", path, val, r.TotalDigits.Value)
+	}
+	if r.FractionDigits != nil && len(fracPart) > atoi(r.FractionDigits.Value) {
+		return fmt.Errorf("%s: %q has more than %s fraction digits", path, val, r.FractionDigits.Value)
+	}
+	if r.MinInclusive != nil {
+		got, _ := new(big.Rat).SetString(val)
+		min, _ := new(big.Rat).SetString(r.MinInclusive.Value)
+		if got.Cmp(min) < 0 {
+			return fmt.Errorf("%s: %q is less than %s", path, val, r.MinInclusive.Value)
+		}
+	}
+
+	return nil
+}
+
+var xsdDecimal = regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)$`)
+
+func validateBuiltin(val, typ, path string) error {
+	var err error
+
+	switch typ {
+	case "xs:string":
+	case "xs:decimal":
+		if !xsdDecimal.MatchString(val) {
+			err = fmt.Errorf("not a decimal")
+		}
+	case "xs:boolean":
+		switch val {
+		case "true", "false", "1", "0":
+		default:
+			err = fmt.Errorf("not a boolean")
+		}
+	case "xs:date":
+		_, err = time.Parse(time.DateOnly, val)
+	case "xs:dateTime":
+		if _, err = time.Parse(time.RFC3339Nano, val); err != nil {
+			_, err = time.Parse("2006-01-02T15:04:05.999999999", val)
+		}
+	case "xs:gYearMonth":
+		_, err = time.Parse("2006-01", val)
+	default:
+		err = fmt.Errorf("unsupported builtin type")
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a valid %s: %w", path, val, typ, err)
+	}
+	return nil
+}
+
+func occurs(e xsdElement) (minOccurs, maxOccurs int) {
+	minOccurs, maxOccurs = 1, 1
+	if e.MinOccurs != "" {
+		minOccurs = atoi(e.MinOccurs)
+	}
+	switch e.MaxOccurs {
+	case "":
+	case "unbounded":
+		maxOccurs = -1
+	default:
+		maxOccurs = atoi(e.MaxOccurs)
+	}
+	return
+}
+
+func atoi(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
+
+func nodeAttr(n *xmlNode, name string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+func parseXMLTree(doc []byte) (*xmlNode, error) {
+	dec := xml.NewDecoder(strings.NewReader(string(doc)))
+
+	var (
+		root  *xmlNode
+		stack []*xmlNode
+	)
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: t.Name, attrs: t.Attr}
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, fmt.Errorf("multiple root elements")
+				}
+				root = n
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("empty document")
+	}
+
+	return root, nil
+}
@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateDestination is returned when a webhook resolves to an address that isn't publicly routable
+var ErrPrivateDestination = errors.New("webhook destination is not a public address")
+
+// nonPublic are the ranges reserved for shared, translated or special use that netip doesn't classify
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+// newClient returns the http client of the dispatcher, the address is checked once resolved right before connecting
+// so hosts resolving to a private address, even only on a later lookup, and redirects to one are refused too
+func (ds *Dispatcher) newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   ds.control,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// a proxy would be dialed instead of the webhook, leaving its address unchecked
+	transport.Proxy = nil
+
+	return &http.Client{Timeout: DefaultTimeout, Transport: transport}
+}
+
+// control refuses to connect to the addresses that aren't publicly routable unless private destinations are allowed
+func (ds *Dispatcher) control(_, address string, _ syscall.RawConn) error {
+	if ds.allowPrivate {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+
+	if !isPublic(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateDestination, ip)
+	}
+
+	return nil
+}
+
+// isPublic reports whether the address is a globally routable unicast one, loopback, private, link local, multicast
+// and reserved addresses aren't
+func isPublic(ip netip.Addr) bool {
+	ip = ip.Unmap().WithZone("")
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+
+	for _, p := range nonPublic {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
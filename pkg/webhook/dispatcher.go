@@ -0,0 +1,231 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sathishs-dev/pismo-transactions/pkg/outbox"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+)
+
+const (
+	DefaultInterval     = time.Second
+	DefaultBatchSize    = 20
+	DefaultTimeout      = 10 * time.Second
+	DefaultMaxAttempts  = 10
+	DefaultBaseBackoff  = 30 * time.Second
+	DefaultMaxBackoff   = 6 * time.Hour
+	DefaultDisableAfter = 20
+)
+
+type (
+	// Dispatcher posts the queued deliveries to their webhooks, failed deliveries are retried with exponential
+	// backoff until they run out of attempts and are moved to the dead letters
+	Dispatcher struct {
+		repo         repository.PismoRepo
+		client       *http.Client
+		interval     time.Duration
+		batchSize    int
+		maxAttempts  int
+		baseBackoff  time.Duration
+		maxBackoff   time.Duration
+		disableAfter int
+		allowPrivate bool
+		now          func() time.Time
+	}
+
+	// Option - Used to configure the dispatcher during initialization
+	Option func(*Dispatcher)
+)
+
+// WithInterval - Will poll for due deliveries at the given interval while there are none left
+func WithInterval(d time.Duration) Option {
+	return func(ds *Dispatcher) {
+		ds.interval = d
+	}
+}
+
+// WithBatchSize - Will claim up to n deliveries at a time
+func WithBatchSize(n int) Option {
+	return func(ds *Dispatcher) {
+		ds.batchSize = n
+	}
+}
+
+// WithTimeout - Will give up on a webhook request after the given timeout
+func WithTimeout(d time.Duration) Option {
+	return func(ds *Dispatcher) {
+		ds.client.Timeout = d
+	}
+}
+
+// WithMaxAttempts - Will move a delivery to the dead letters after n failed attempts
+func WithMaxAttempts(n int) Option {
+	return func(ds *Dispatcher) {
+		ds.maxAttempts = n
+	}
+}
+
+// WithBackoff - Will wait base after the first failed attempt, doubling on every further one up to max
+func WithBackoff(base, max time.Duration) Option {
+	return func(ds *Dispatcher) {
+		ds.baseBackoff = base
+		ds.maxBackoff = max
+	}
+}
+
+// WithDisableAfter - Will disable a webhook after n consecutive failed attempts, 0 never disables it
+func WithDisableAfter(n int) Option {
+	return func(ds *Dispatcher) {
+		ds.disableAfter = n
+	}
+}
+
+// WithPrivateDestinations - Will deliver to loopback, private and link local addresses too, only meant for webhooks
+// served from the same trusted network
+func WithPrivateDestinations() Option {
+	return func(ds *Dispatcher) {
+		ds.allowPrivate = true
+	}
+}
+
+// NewDispatcher configures and returns the webhook Dispatcher
+func NewDispatcher(repo repository.PismoRepo, opts ...Option) *Dispatcher {
+	ds := &Dispatcher{
+		repo:         repo,
+		interval:     DefaultInterval,
+		batchSize:    DefaultBatchSize,
+		maxAttempts:  DefaultMaxAttempts,
+		baseBackoff:  DefaultBaseBackoff,
+		maxBackoff:   DefaultMaxBackoff,
+		disableAfter: DefaultDisableAfter,
+		now:          time.Now,
+	}
+	ds.client = ds.newClient()
+
+	for _, o := range opts {
+		o(ds)
+	}
+
+	return ds
+}
+
+// Start dispatches the deliveries until the context is cancelled, full batches are followed right away by the next one
+func (ds *Dispatcher) Start(ctx context.Context) {
+	for {
+		count, err := ds.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("failed to dispatch webhook deliveries")
+		}
+
+		if err == nil && count >= ds.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ds.interval):
+		}
+	}
+}
+
+// RunOnce dispatches a single batch of due deliveries and returns the number of deliveries attempted
+func (ds *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	deliveries, err := ds.repo.ClaimWebhookDeliveries(ctx, ds.batchSize, ds.lease())
+	if err != nil {
+		return 0, err
+	}
+
+	for _, d := range deliveries {
+		if err := ds.repo.RecordWebhookAttempt(ctx, ds.attempt(ctx, d)); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// attempt posts the delivery to its webhook and returns the outcome to be recorded
+func (ds *Dispatcher) attempt(ctx context.Context, d repository.WebhookDelivery) repository.WebhookAttempt {
+	a := repository.WebhookAttempt{
+		DeliveryID:   d.DeliveryID,
+		WebhookID:    d.WebhookID,
+		DisableAfter: ds.disableAfter,
+	}
+
+	a.StatusCode, a.Err = ds.post(ctx, d)
+	if a.Err == nil {
+		return a
+	}
+
+	attempts := d.Attempts + 1
+	if attempts < ds.maxAttempts {
+		a.RetryAt = ds.now().Add(ds.backoff(attempts))
+	}
+
+	log.Warn().Err(a.Err).
+		Int64("delivery_id", d.DeliveryID).
+		Int("webhook_id", d.WebhookID).
+		Int("attempts", attempts).
+		Msg("failed to deliver webhook")
+
+	return a
+}
+
+// post sends the signed delivery, any 2xx response acknowledges it
+func (ds *Dispatcher) post(ctx context.Context, d repository.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(outbox.NewMessage(d.Event))
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	now := ds.now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, strconv.FormatInt(d.DeliveryID, 10))
+	req.Header.Set(HeaderEvent, string(d.Event.EventType))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(d.Secret, now, body))
+
+	res, err := ds.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	// drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
+
+// backoff returns the wait after the given number of failed attempts
+func (ds *Dispatcher) backoff(attempts int) time.Duration {
+	d := ds.baseBackoff
+	for i := 1; i < attempts && d < ds.maxBackoff; i++ {
+		d *= 2
+	}
+
+	return min(d, ds.maxBackoff)
+}
+
+// lease is how long the claimed deliveries are kept from other replicas, long enough to attempt a whole batch
+func (ds *Dispatcher) lease() time.Duration {
+	return time.Duration(ds.batchSize)*ds.client.Timeout + time.Minute
+}
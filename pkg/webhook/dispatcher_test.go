@@ -0,0 +1,224 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/enums"
+	"github.com/sathishs-dev/pismo-transactions/pkg/mocks"
+	"github.com/sathishs-dev/pismo-transactions/pkg/outbox"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcherRunOnce(t *testing.T) {
+	now := time.Date(2024, 12, 5, 10, 0, 0, 0, time.UTC)
+
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		require.Equal(t, "7", r.Header.Get(HeaderID))
+		require.Equal(t, "transaction.created", r.Header.Get(HeaderEvent))
+		require.NoError(t, Verify("whsec_test", r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderSignature), body, time.Minute, now))
+
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	delivery := repository.WebhookDelivery{
+		DeliveryID: 7,
+		WebhookID:  3,
+		URL:        srv.URL,
+		Secret:     "whsec_test",
+		Event: repository.OutboxEvent{
+			EventID:   11,
+			EventType: enums.TransactionCreated,
+			AccountID: 1,
+			Payload:   []byte(`{"transaction_id":5}`),
+		},
+	}
+
+	tcs := []struct {
+		name            string
+		status          int
+		attempts        int
+		expectedAttempt repository.WebhookAttempt
+	}{
+		{
+			name:   "Test Delivered",
+			status: http.StatusNoContent,
+			expectedAttempt: repository.WebhookAttempt{
+				DeliveryID: 7, WebhookID: 3, StatusCode: http.StatusNoContent, DisableAfter: DefaultDisableAfter,
+			},
+		},
+		{
+			name:     "Test Failed Is Retried",
+			status:   http.StatusInternalServerError,
+			attempts: 2,
+			expectedAttempt: repository.WebhookAttempt{
+				DeliveryID: 7, WebhookID: 3, StatusCode: http.StatusInternalServerError, DisableAfter: DefaultDisableAfter,
+				RetryAt: now.Add(4 * DefaultBaseBackoff),
+			},
+		},
+		{
+			name:     "Test Failed Runs Out Of Attempts",
+			status:   http.StatusGone,
+			attempts: DefaultMaxAttempts - 1,
+			expectedAttempt: repository.WebhookAttempt{
+				DeliveryID: 7, WebhookID: 3, StatusCode: http.StatusGone, DisableAfter: DefaultDisableAfter,
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := mocks.NewPismoRepo(t)
+			ds := NewDispatcher(repo, WithPrivateDestinations())
+			ds.now = func() time.Time { return now }
+			status = tc.status
+
+			d := delivery
+			d.Attempts = tc.attempts
+
+			var recorded repository.WebhookAttempt
+			repo.On("ClaimWebhookDeliveries", mock.Anything, DefaultBatchSize, mock.Anything).
+				Return([]repository.WebhookDelivery{d}, nil).Once()
+			repo.On("RecordWebhookAttempt", mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) { recorded = args.Get(1).(repository.WebhookAttempt) }).
+				Return(nil).Once()
+
+			count, err := ds.RunOnce(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 1, count)
+
+			if tc.expectedAttempt.StatusCode < 300 {
+				require.NoError(t, recorded.Err)
+			} else {
+				require.Error(t, recorded.Err)
+			}
+			recorded.Err = nil
+			require.Equal(t, tc.expectedAttempt, recorded)
+		})
+	}
+}
+
+func TestDispatcherPrivateDestination(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer srv.Close()
+
+	tcs := []struct {
+		name string
+		url  string
+	}{
+		{name: "Test Loopback", url: srv.URL},
+		{name: "Test Link Local", url: "http://169.254.169.254/latest/meta-data/"},
+		{name: "Test Private", url: "http://10.0.0.1:8080/hook"},
+		{name: "Test Resolved To Loopback", url: strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := mocks.NewPismoRepo(t)
+			ds := NewDispatcher(repo)
+
+			var recorded repository.WebhookAttempt
+			repo.On("ClaimWebhookDeliveries", mock.Anything, DefaultBatchSize, mock.Anything).
+				Return([]repository.WebhookDelivery{{DeliveryID: 7, WebhookID: 3, URL: tc.url, Secret: "whsec_test"}}, nil).Once()
+			repo.On("RecordWebhookAttempt", mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) { recorded = args.Get(1).(repository.WebhookAttempt) }).
+				Return(nil).Once()
+
+			_, err := ds.RunOnce(context.Background())
+			require.NoError(t, err)
+			require.ErrorIs(t, recorded.Err, ErrPrivateDestination)
+			require.Zero(t, recorded.StatusCode)
+		})
+	}
+
+	require.Zero(t, hits)
+}
+
+func TestIsPublic(t *testing.T) {
+	tcs := []struct {
+		addr     string
+		expected bool
+	}{
+		{addr: "93.184.216.34", expected: true},
+		{addr: "2606:2800:220:1:248:1893:25c8:1946", expected: true},
+		{addr: "127.0.0.1"},
+		{addr: "::1"},
+		{addr: "10.1.2.3"},
+		{addr: "172.16.0.1"},
+		{addr: "192.168.1.1"},
+		{addr: "169.254.169.254"},
+		{addr: "fe80::1"},
+		{addr: "fd00::1"},
+		{addr: "100.64.0.1"},
+		{addr: "0.0.0.0"},
+		{addr: "::"},
+		{addr: "224.0.0.1"},
+		{addr: "255.255.255.255"},
+		{addr: "::ffff:127.0.0.1"},
+		{addr: "::ffff:169.254.169.254"},
+		{addr: "64:ff9b::a9fe:a9fe"},
+	}
+
+	for _, tc := range tcs {
+		require.Equal(t, tc.expected, isPublic(netip.MustParseAddr(tc.addr)), tc.addr)
+	}
+}
+
+func TestDispatcherRunOnceClaimFailure(t *testing.T) {
+	repo := mocks.NewPismoRepo(t)
+	ds := NewDispatcher(repo)
+
+	repo.On("ClaimWebhookDeliveries", mock.Anything, DefaultBatchSize, mock.Anything).
+		Return(nil, errors.New("err")).Once()
+
+	_, err := ds.RunOnce(context.Background())
+	require.Error(t, err)
+}
+
+func TestDispatcherBackoff(t *testing.T) {
+	ds := NewDispatcher(mocks.NewPismoRepo(t), WithBackoff(time.Second, time.Minute))
+
+	tcs := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 1, expected: time.Second},
+		{attempts: 2, expected: 2 * time.Second},
+		{attempts: 6, expected: 32 * time.Second},
+		{attempts: 7, expected: time.Minute},
+		{attempts: 100, expected: time.Minute},
+	}
+
+	for _, tc := range tcs {
+		require.Equal(t, tc.expected, ds.backoff(tc.attempts), tc.attempts)
+	}
+}
+
+func TestEnqueuer(t *testing.T) {
+	repo := mocks.NewPismoRepo(t)
+
+	repo.On("EnqueueWebhookDeliveries", mock.Anything, int64(11), enums.AccountCreated).
+		Return(int64(2), nil).Once()
+
+	require.NoError(t, NewEnqueuer(repo).Publish(context.Background(), outbox.Message{
+		EventID:   11,
+		EventType: enums.AccountCreated,
+	}))
+}
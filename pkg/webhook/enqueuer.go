@@ -0,0 +1,24 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/sathishs-dev/pismo-transactions/pkg/outbox"
+	"github.com/sathishs-dev/pismo-transactions/pkg/repository"
+)
+
+// enqueuer is the outbox sink queueing every relayed event for the webhooks subscribed to it
+type enqueuer struct {
+	repo repository.PismoRepo
+}
+
+// NewEnqueuer returns the outbox sink feeding the webhook deliveries
+func NewEnqueuer(repo repository.PismoRepo) outbox.Sink {
+	return &enqueuer{repo}
+}
+
+// Publish queues the message for the subscribed webhooks, the Dispatcher takes it from there
+func (e *enqueuer) Publish(ctx context.Context, msg outbox.Message) error {
+	_, err := e.repo.EnqueueWebhookDeliveries(ctx, msg.EventID, msg.EventType)
+	return err
+}
@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderID carries the delivery id, it stays the same across the retries of a delivery
+	HeaderID = "Webhook-Id"
+	// HeaderEvent carries the event type of the delivery
+	HeaderEvent = "Webhook-Event"
+	// HeaderTimestamp carries the unix time the delivery was signed at
+	HeaderTimestamp = "Webhook-Timestamp"
+	// HeaderSignature carries the signature of the delivery as v1=<hex hmac-sha256>
+	HeaderSignature = "Webhook-Signature"
+
+	signatureVersion = "v1="
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrExpiredTimestamp = errors.New("webhook timestamp outside the tolerance")
+)
+
+// Sign returns the signature of the body sent at the given time, an HMAC-SHA256 keyed with the webhook secret
+// over "<unix timestamp>.<body>"
+func Sign(secret string, at time.Time, body []byte) string {
+	return signatureVersion + hex.EncodeToString(mac(secret, strconv.FormatInt(at.Unix(), 10), body))
+}
+
+// Verify checks the Webhook-Timestamp and Webhook-Signature headers of a delivery against its body, deliveries
+// signed more than tolerance away from now are rejected to stop replays
+func Verify(secret, timestamp, signature string, body []byte, tolerance time.Duration, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	if d := now.Sub(time.Unix(ts, 0)); d > tolerance || d < -tolerance {
+		return ErrExpiredTimestamp
+	}
+
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, signatureVersion))
+	if err != nil || !strings.HasPrefix(signature, signatureVersion) {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal(got, mac(secret, timestamp, body)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func mac(secret, timestamp string, body []byte) []byte {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(timestamp))
+	m.Write([]byte("."))
+	m.Write(body)
+	return m.Sum(nil)
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	at := time.Unix(1733395927, 0)
+	body := []byte(`{"event_id":1}`)
+	sig := Sign("whsec_test", at, body)
+	ts := strconv.FormatInt(at.Unix(), 10)
+
+	tcs := []struct {
+		name        string
+		secret      string
+		timestamp   string
+		signature   string
+		body        []byte
+		now         time.Time
+		expectedErr error
+	}{
+		{
+			name:      "Test Valid Signature",
+			secret:    "whsec_test",
+			timestamp: ts,
+			signature: sig,
+			body:      body,
+			now:       at.Add(time.Minute),
+		},
+		{
+			name:        "Test Wrong Secret",
+			secret:      "whsec_other",
+			timestamp:   ts,
+			signature:   sig,
+			body:        body,
+			now:         at,
+			expectedErr: ErrInvalidSignature,
+		},
+		{
+			name:        "Test Tampered Body",
+			secret:      "whsec_test",
+			timestamp:   ts,
+			signature:   sig,
+			body:        []byte(`{"event_id":2}`),
+			now:         at,
+			expectedErr: ErrInvalidSignature,
+		},
+		{
+			name:        "Test Tampered Timestamp",
+			secret:      "whsec_test",
+			timestamp:   strconv.FormatInt(at.Unix()+1, 10),
+			signature:   sig,
+			body:        body,
+			now:         at,
+			expectedErr: ErrInvalidSignature,
+		},
+		{
+			name:        "Test Missing Version",
+			secret:      "whsec_test",
+			timestamp:   ts,
+			signature:   sig[len("v1="):],
+			body:        body,
+			now:         at,
+			expectedErr: ErrInvalidSignature,
+		},
+		{
+			name:        "Test Expired Timestamp",
+			secret:      "whsec_test",
+			timestamp:   ts,
+			signature:   sig,
+			body:        body,
+			now:         at.Add(10 * time.Minute),
+			expectedErr: ErrExpiredTimestamp,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Verify(tc.secret, tc.timestamp, tc.signature, tc.body, 5*time.Minute, tc.now)
+			require.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,10 @@
+-- fails while any document number is only stored encrypted, they have to be decrypted back first
+ALTER TABLE accounts ALTER COLUMN document_number SET NOT NULL;
+
+DROP INDEX IF EXISTS accounts_document_number_hash_idx;
+
+ALTER TABLE accounts
+    DROP CONSTRAINT IF EXISTS accounts_document_number_present,
+    DROP COLUMN IF EXISTS document_number_hash,
+    DROP COLUMN IF EXISTS document_number_key_id,
+    DROP COLUMN IF EXISTS document_number_enc;
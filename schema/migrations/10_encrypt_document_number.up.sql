@@ -0,0 +1,11 @@
+-- document numbers are encrypted by the service, the plaintext column is emptied as the existing rows are encrypted
+-- in the background and the blind index takes over the uniqueness check
+ALTER TABLE accounts
+    ALTER COLUMN document_number DROP NOT NULL,
+    ADD COLUMN document_number_enc BYTEA,
+    ADD COLUMN document_number_key_id VARCHAR(64),
+    ADD COLUMN document_number_hash BYTEA,
+    ADD CONSTRAINT accounts_document_number_present
+        CHECK (document_number IS NOT NULL OR document_number_enc IS NOT NULL);
+
+CREATE UNIQUE INDEX accounts_document_number_hash_idx ON accounts (document_number_hash);
@@ -0,0 +1,6 @@
+-- fails while there are erased accounts, their document numbers are gone
+ALTER TABLE accounts
+    DROP CONSTRAINT IF EXISTS accounts_document_number_present,
+    ADD CONSTRAINT accounts_document_number_present
+        CHECK (document_number IS NOT NULL OR document_number_enc IS NOT NULL),
+    DROP COLUMN IF EXISTS erased_at;
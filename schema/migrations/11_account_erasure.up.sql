@@ -0,0 +1,6 @@
+-- an erased account is kept as a tombstone holding its transactions, its personal data is cleared for good
+ALTER TABLE accounts
+    ADD COLUMN erased_at TIMESTAMPTZ,
+    DROP CONSTRAINT accounts_document_number_present,
+    ADD CONSTRAINT accounts_document_number_present
+        CHECK (erased_at IS NOT NULL OR document_number IS NOT NULL OR document_number_enc IS NOT NULL);
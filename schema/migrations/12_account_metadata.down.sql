@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS accounts_tags_idx;
+DROP INDEX IF EXISTS accounts_metadata_idx;
+
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS tags,
+    DROP COLUMN IF EXISTS metadata;
@@ -0,0 +1,7 @@
+ALTER TABLE accounts
+    ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+-- serve the key existence ( ?& ) and containment ( @> ) filters of the account listing
+CREATE INDEX accounts_metadata_idx ON accounts USING GIN (metadata);
+CREATE INDEX accounts_tags_idx ON accounts USING GIN (tags);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS accounts_document_number_prefixes_idx;
+DROP INDEX IF EXISTS accounts_created_at_idx;
+
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS document_number_prefixes,
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS status;
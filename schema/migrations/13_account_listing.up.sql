@@ -0,0 +1,11 @@
+-- the accounts created before this migration get its time as their creation time
+ALTER TABLE accounts
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active',
+    ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- blind indexes of the document number prefixes, filled by the service along with the encryption
+    ADD COLUMN document_number_prefixes BYTEA[];
+
+UPDATE accounts SET status = 'closed' WHERE erased_at IS NOT NULL;
+
+CREATE INDEX accounts_created_at_idx ON accounts (created_at, account_id);
+CREATE INDEX accounts_document_number_prefixes_idx ON accounts USING GIN (document_number_prefixes);
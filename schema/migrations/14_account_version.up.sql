@@ -0,0 +1,3 @@
+-- bumped on every change to the account, its ETag is derived from it
+ALTER TABLE accounts
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
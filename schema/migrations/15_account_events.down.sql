@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS outbox_account_idx;
+
+ALTER TABLE outbox
+    DROP COLUMN IF EXISTS xact_id;
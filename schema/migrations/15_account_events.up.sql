@@ -0,0 +1,6 @@
+-- the transaction that wrote each event, the account event streams only read the events of the transactions no
+-- longer in flight so the ones committing late aren't skipped over
+ALTER TABLE outbox
+    ADD COLUMN xact_id XID8 NOT NULL DEFAULT pg_current_xact_id();
+
+CREATE INDEX outbox_account_idx ON outbox (account_id, event_id);
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS transactions_log_create ON transactions;
+DROP TRIGGER IF EXISTS accounts_log_update ON accounts;
+DROP TRIGGER IF EXISTS accounts_log_create ON accounts;
+DROP FUNCTION IF EXISTS log_change();
+DROP TABLE IF EXISTS change_log;
@@ -0,0 +1,60 @@
+-- every change to the accounts and transactions in the order of a monotonically increasing sequence, it feeds the
+-- downstream loaders syncing incrementally. The document numbers are left out of the logged rows.
+CREATE TABLE change_log (
+    sequence BIGSERIAL PRIMARY KEY,
+    entity_type VARCHAR(32) NOT NULL,
+    entity_id INT NOT NULL,
+    account_id INT NOT NULL,
+    operation VARCHAR(16) NOT NULL,
+    data JSONB NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- the next transaction id once the sequence was drawn, every transaction that drew an earlier sequence has a
+    -- lower id. The feed reads a change once all of them are done, so a change committing late is never skipped over.
+    next_xact_id XID8 NOT NULL DEFAULT pg_snapshot_xmax(pg_current_snapshot())
+);
+
+-- the function body is single quoted since the migrator expands env variables in the scripts
+CREATE FUNCTION log_change() RETURNS TRIGGER AS '
+DECLARE
+    row_data JSONB := to_jsonb(NEW) - ''document_number'' - ''document_number_enc'' - ''document_number_key_id''
+        - ''document_number_hash'' - ''document_number_prefixes'';
+    seq BIGINT := nextval(''change_log_sequence_seq'');
+BEGIN
+    -- drawn ahead so the insert takes its snapshot, and next_xact_id, after it
+    INSERT INTO change_log (sequence, entity_type, entity_id, account_id, operation, data)
+    VALUES (
+        seq,
+        TG_ARGV[0],
+        (row_data ->> TG_ARGV[1])::INT,
+        (row_data ->> ''account_id'')::INT,
+        CASE TG_OP WHEN ''INSERT'' THEN ''create'' ELSE ''update'' END,
+        row_data
+    );
+    RETURN NULL;
+END;
+' LANGUAGE plpgsql;
+
+CREATE TRIGGER accounts_log_create AFTER INSERT ON accounts
+    FOR EACH ROW EXECUTE FUNCTION log_change('account', 'account_id');
+
+-- every change clients can see bumps the version, the document numbers re-encrypted by the key rotation don't
+CREATE TRIGGER accounts_log_update AFTER UPDATE ON accounts
+    FOR EACH ROW WHEN (OLD.version <> NEW.version) EXECUTE FUNCTION log_change('account', 'account_id');
+
+CREATE TRIGGER transactions_log_create AFTER INSERT ON transactions
+    FOR EACH ROW EXECUTE FUNCTION log_change('transaction', 'transaction_id');
+
+-- the existing rows are logged as created so the loaders can start from the beginning of the feed
+INSERT INTO change_log (entity_type, entity_id, account_id, operation, data, changed_at)
+SELECT
+    'account', a.account_id, a.account_id, 'create',
+    to_jsonb(a) - 'document_number' - 'document_number_enc' - 'document_number_key_id' - 'document_number_hash'
+        - 'document_number_prefixes',
+    a.created_at
+FROM accounts a
+ORDER BY a.account_id;
+
+INSERT INTO change_log (entity_type, entity_id, account_id, operation, data, changed_at)
+SELECT 'transaction', t.transaction_id, t.account_id, 'create', to_jsonb(t), COALESCE(t.event_date, CURRENT_TIMESTAMP)
+FROM transactions t
+ORDER BY t.transaction_id;
@@ -0,0 +1,10 @@
+-- the token buckets of the rate limiter when the replicas share them, they are only worth the limits they enforce
+-- so they aren't written to the wal and a crash just refills them
+CREATE UNLOGGED TABLE rate_limit_buckets (
+    key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX rate_limit_buckets_updated_at_idx ON rate_limit_buckets (updated_at);
@@ -0,0 +1,12 @@
+-- the keys themselves are never stored, only their sha-256 which they are looked up by. The prefix is the start of
+-- the key kept to tell the keys apart.
+CREATE TABLE api_keys (
+    key_id SERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash BYTEA NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,20 @@
+-- the metadata and tags already left out aren't logged back
+CREATE OR REPLACE FUNCTION log_change() RETURNS TRIGGER AS '
+DECLARE
+    row_data JSONB := to_jsonb(NEW) - ''document_number'' - ''document_number_enc'' - ''document_number_key_id''
+        - ''document_number_hash'' - ''document_number_prefixes'';
+    seq BIGINT := nextval(''change_log_sequence_seq'');
+BEGIN
+    -- drawn ahead so the insert takes its snapshot, and next_xact_id, after it
+    INSERT INTO change_log (sequence, entity_type, entity_id, account_id, operation, data)
+    VALUES (
+        seq,
+        TG_ARGV[0],
+        (row_data ->> TG_ARGV[1])::INT,
+        (row_data ->> ''account_id'')::INT,
+        CASE TG_OP WHEN ''INSERT'' THEN ''create'' ELSE ''update'' END,
+        row_data
+    );
+    RETURN NULL;
+END;
+' LANGUAGE plpgsql;
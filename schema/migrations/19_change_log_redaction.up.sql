@@ -0,0 +1,25 @@
+-- the change log is kept for good and the erasure doesn't reach it, so the client defined metadata and tags, which
+-- may hold personal data, are left out of the logged account rows along with the document numbers
+-- the function body is single quoted since the migrator expands env variables in the scripts
+CREATE OR REPLACE FUNCTION log_change() RETURNS TRIGGER AS '
+DECLARE
+    row_data JSONB := to_jsonb(NEW) - ''document_number'' - ''document_number_enc'' - ''document_number_key_id''
+        - ''document_number_hash'' - ''document_number_prefixes'' - ''metadata'' - ''tags'';
+    seq BIGINT := nextval(''change_log_sequence_seq'');
+BEGIN
+    -- drawn ahead so the insert takes its snapshot, and next_xact_id, after it
+    INSERT INTO change_log (sequence, entity_type, entity_id, account_id, operation, data)
+    VALUES (
+        seq,
+        TG_ARGV[0],
+        (row_data ->> TG_ARGV[1])::INT,
+        (row_data ->> ''account_id'')::INT,
+        CASE TG_OP WHEN ''INSERT'' THEN ''create'' ELSE ''update'' END,
+        row_data
+    );
+    RETURN NULL;
+END;
+' LANGUAGE plpgsql;
+
+UPDATE change_log SET data = data - 'metadata' - 'tags'
+WHERE entity_type = 'account' AND (data ? 'metadata' OR data ? 'tags');
@@ -0,0 +1,4 @@
+-- the relay claims a batch of pending events until the lease expires and publishes them outside of any db
+-- transaction, a replica that dies mid batch leaves its events to the next claim once the lease is over
+ALTER TABLE outbox
+    ADD COLUMN claimed_until TIMESTAMPTZ;
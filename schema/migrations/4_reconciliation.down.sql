@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS reconciliation_items;
+DROP TABLE IF EXISTS reconciliations;
+DROP INDEX IF EXISTS transactions_external_reference_idx;
+ALTER TABLE transactions DROP COLUMN IF EXISTS external_reference;
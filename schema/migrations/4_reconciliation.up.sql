@@ -0,0 +1,30 @@
+ALTER TABLE transactions ADD COLUMN external_reference VARCHAR(64);
+
+CREATE UNIQUE INDEX transactions_external_reference_idx ON transactions (external_reference)
+    WHERE external_reference IS NOT NULL;
+
+CREATE TABLE reconciliations (
+    reconciliation_id SERIAL PRIMARY KEY,
+    source VARCHAR(255) NOT NULL,
+    date_tolerance INTERVAL NOT NULL,
+    matched INT NOT NULL DEFAULT 0,
+    missing_in_ledger INT NOT NULL DEFAULT 0,
+    missing_in_file INT NOT NULL DEFAULT 0,
+    amount_mismatches INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE reconciliation_items (
+    reconciliation_item_id SERIAL PRIMARY KEY,
+    reconciliation_id INT NOT NULL REFERENCES reconciliations(reconciliation_id) ON DELETE CASCADE,
+    status VARCHAR(32) NOT NULL,
+    line INT,
+    external_reference VARCHAR(64),
+    account_id INT,
+    file_amount DECIMAL,
+    ledger_amount DECIMAL,
+    event_date TIMESTAMPTZ,
+    transaction_id INT REFERENCES transactions(transaction_id)
+);
+
+CREATE INDEX reconciliation_items_reconciliation_id_idx ON reconciliation_items (reconciliation_id);
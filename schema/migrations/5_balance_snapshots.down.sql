@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS transactions_account_id_event_date_idx;
+DROP TABLE IF EXISTS balance_snapshots;
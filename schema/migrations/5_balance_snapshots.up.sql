@@ -0,0 +1,9 @@
+CREATE TABLE balance_snapshots (
+    account_id INT NOT NULL REFERENCES accounts(account_id),
+    snapshot_at TIMESTAMPTZ NOT NULL,
+    balance DECIMAL NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (account_id, snapshot_at)
+);
+
+CREATE INDEX transactions_account_id_event_date_idx ON transactions (account_id, event_date);
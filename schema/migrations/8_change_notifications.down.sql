@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS transactions_notify_change ON transactions;
+DROP TRIGGER IF EXISTS accounts_notify_change ON accounts;
+DROP FUNCTION IF EXISTS notify_change();
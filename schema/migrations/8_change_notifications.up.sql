@@ -0,0 +1,19 @@
+-- the function body is single quoted since the migrator expands env variables in the scripts
+CREATE FUNCTION notify_change() RETURNS TRIGGER AS '
+DECLARE
+    row_data JSONB := to_jsonb(NEW);
+BEGIN
+    PERFORM pg_notify(''pismo_changes'', json_build_object(
+        ''table'', TG_TABLE_NAME,
+        ''id'', (row_data ->> TG_ARGV[0])::BIGINT,
+        ''account_id'', (row_data ->> ''account_id'')::BIGINT
+    )::TEXT);
+    RETURN NULL;
+END;
+' LANGUAGE plpgsql;
+
+CREATE TRIGGER accounts_notify_change AFTER INSERT ON accounts
+    FOR EACH ROW EXECUTE FUNCTION notify_change('account_id');
+
+CREATE TRIGGER transactions_notify_change AFTER INSERT ON transactions
+    FOR EACH ROW EXECUTE FUNCTION notify_change('transaction_id');
@@ -0,0 +1,32 @@
+CREATE TABLE audit_log (
+    audit_id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    actor_type VARCHAR(16) NOT NULL,
+    actor_id VARCHAR(128) NOT NULL,
+    request_id VARCHAR(64),
+    entity_type VARCHAR(32) NOT NULL,
+    entity_id VARCHAR(64) NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    before_image JSONB,
+    after_image JSONB
+);
+
+CREATE INDEX audit_log_entity_idx ON audit_log (entity_type, entity_id, audit_id);
+CREATE INDEX audit_log_actor_idx ON audit_log (actor_type, actor_id, audit_id);
+CREATE INDEX audit_log_occurred_at_idx ON audit_log (occurred_at);
+
+-- the function body is single quoted since the migrator expands env variables in the scripts
+CREATE FUNCTION audit_log_append_only() RETURNS TRIGGER AS '
+BEGIN
+    RAISE EXCEPTION ''audit_log is append-only, % is not allowed'', TG_OP
+        USING ERRCODE = ''insufficient_privilege'';
+END;
+' LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_update_delete BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+REVOKE UPDATE, DELETE, TRUNCATE ON audit_log FROM PUBLIC;